@@ -4,13 +4,21 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/oharai/short-url/internal/shorturl/app"
+	"github.com/oharai/short-url/internal/shorturl/domain"
 	"github.com/oharai/short-url/internal/shorturl/infra"
 	httpHandler "github.com/oharai/short-url/internal/shorturl/interfaces/http"
 )
@@ -21,15 +29,220 @@ import (
 func main() {
 	// Configuration - In production, these would come from environment variables
 	baseURL := "http://localhost:8080"
+	dataDir := os.Getenv("SHORTURL_DATA_DIR")              // Durable storage directory; in-memory storage when empty
+	cacheSize := os.Getenv("SHORTURL_CACHE_SIZE")          // Lookup cache capacity; caching disabled when empty or 0
+	kgsKey := os.Getenv("SHORTURL_KGS_KEY")                // Secret key for permutation-based IDs; Base62 KGS when empty
+	kgsURL := os.Getenv("SHORTURL_KGS_URL")                // Base URL of a standalone KGS; local generation when empty
+	region := os.Getenv("SHORTURL_REGION")                 // Code of the local region, prefixed to generated IDs; single region when empty
+	regionList := os.Getenv("SHORTURL_REGIONS")            // Known regions as code=name=baseURL,...; used with SHORTURL_REGION
+	idAlphabet := os.Getenv("SHORTURL_ID_ALPHABET")        // ID alphabet: base62, base58 or lowercase; base62 when empty
+	idLength := os.Getenv("SHORTURL_ID_LENGTH")            // Length of generated IDs; 7 when empty
+	idStrategy := os.Getenv("SHORTURL_ID_STRATEGY")        // ID derivation: counter (KGS) or hash (of the long URL); counter when empty
+	blockedWords := os.Getenv("SHORTURL_BLOCKED_WORDS")    // Comma-separated words IDs must not contain
+	reservedWords := os.Getenv("SHORTURL_RESERVED_WORDS")  // Comma-separated words IDs must not equal, besides the route prefixes
+	aliasCase := os.Getenv("SHORTURL_ALIAS_CASE")          // Uppercase in custom aliases: preserve, fold or lower; preserve when empty
+	urlSchemes := os.Getenv("SHORTURL_URL_SCHEMES")        // Comma-separated schemes long URLs may use; http,https when empty
+	customDomains := os.Getenv("SHORTURL_CUSTOM_DOMAINS")  // Comma-separated extra hosts serving our short URLs; never valid destinations
+	shortenerMode := os.Getenv("SHORTURL_SHORTENERS")      // Links of other shorteners: allow, deny or resolve; allow when empty
+	shortenerList := os.Getenv("SHORTURL_SHORTENER_HOSTS") // Comma-separated shortener hosts besides the well-known ones
+	blocklistFile := os.Getenv("SHORTURL_BLOCKLIST_FILE")  // File of malicious destination rules, reloaded on change; none when empty
+	unlockKey := os.Getenv("SHORTURL_UNLOCK_KEY")          // Secret of at least 32 bytes signing password unlock cookies; random per process when empty
+	countryFile := os.Getenv("SHORTURL_COUNTRY_FILE")      // Table of IP prefixes and country codes for routing rules; country rules rejected when empty
 
 	// Dependency Injection Setup
 	// Create infrastructure layer implementations
-	repo := infra.NewMemoryShortURLRepository()  // Data persistence layer
-	kgs := infra.NewBase62KeyGenerationService() // Unique ID generation service
+	var repo domain.ShortURLRepository = infra.NewMemoryShortURLRepository() // Data persistence layer
+	if dataDir != "" {
+		// The default options fsync every write, so no data is lost when the process exits
+		fileRepo, err := infra.NewFileShortURLRepository(dataDir, infra.DefaultFileRepositoryOptions())
+		if err != nil {
+			log.Fatalf("Failed to open data directory %s: %v", dataDir, err)
+		}
+		// Deferred first, so it runs last: after the server has shut down and no request writes anymore
+		defer func() {
+			if err := fileRepo.Close(); err != nil {
+				log.Printf("Failed to close data directory %s: %v", dataDir, err)
+			}
+		}()
+		repo = fileRepo
+	}
+	var cache *infra.CachingShortURLRepository
+	if cacheSize != "" {
+		capacity, err := strconv.Atoi(cacheSize)
+		if err != nil || capacity < 0 {
+			log.Fatalf("Invalid SHORTURL_CACHE_SIZE %q", cacheSize)
+		}
+		if capacity > 0 {
+			// Redirects vastly outnumber writes, so lookups are served from a read-through cache
+			opts := infra.DefaultCacheOptions()
+			opts.Capacity = capacity
+			cache = infra.NewCachingShortURLRepository(repo, opts)
+			repo = cache
+		}
+	}
+	var idProfile *domain.IDProfile
+	if idAlphabet != "" || idLength != "" {
+		idProfile = parseIDProfile(idAlphabet, idLength)
+		if kgsKey != "" {
+			log.Fatalf("SHORTURL_KGS_KEY only supports the default ID profile")
+		}
+	}
+	// Keep up to 10,000 pre-generated IDs, as sized in the README, refilled in the background
+	// Counter-derived IDs are unique by construction; without a data directory the counter
+	// lives in memory, like the short URLs themselves
+	var counterStore infra.HighWaterMarkStore = infra.NewMemoryHighWaterMarkStore(0)
+	if dataDir != "" {
+		// Lease counter ranges from a durable high-water mark so restarts never reissue IDs
+		fileStore, err := infra.NewFileHighWaterMarkStore(filepath.Join(dataDir, "kgs.hwm"))
+		if err != nil {
+			log.Fatalf("Failed to open KGS counter store: %v", err)
+		}
+		counterStore = fileStore
+	}
+	base62Opts := []infra.Base62Option{infra.WithBufferWatermarks(2000, 10000), infra.WithIDProfile(idProfile), infra.WithCounterStore(counterStore, 0)}
+	base62KGS := infra.NewBase62KeyGenerationService(base62Opts...).(*infra.Base62KeyGenerationService)
+	var kgs domain.KeyGenerationService = base62KGS // Unique ID generation service
+	if kgsKey != "" {
+		// Lease the permutation's counter from the same high-water mark, so a restart resumes
+		// beyond every counter issued before; in-memory data starts over from zero anyway
+		feistelKGS, err := infra.NewLeasedFeistelKeyGenerationService([]byte(kgsKey), counterStore, 0)
+		if err != nil {
+			log.Fatalf("Failed to create key generation service: %v", err)
+		}
+		kgs = feistelKGS
+	}
+	if kgsURL != "" {
+		// Draw IDs from the shared KGS; a local generator only steps in while it is unreachable.
+		// The local counter would replay the KGS's own sequence, so the fallback issues longer
+		// IDs that the KGS never issues instead.
+		fallback, err := infra.NewFallbackKeyGenerationService(idProfile)
+		if err != nil {
+			log.Fatalf("Failed to create fallback key generation service: %v", err)
+		}
+		opts := infra.DefaultRemoteKGSOptions()
+		opts.Fallback = fallback
+		remoteKGS, err := infra.NewRemoteKeyGenerationService(kgsURL, nil, opts)
+		if err != nil {
+			log.Fatalf("Failed to create KGS client: %v", err)
+		}
+		kgs = remoteKGS
+	}
+	bufferedKGS := kgsKey == "" && kgsURL == "" // Only the generator serving requests keeps a buffer
+	if bufferedKGS {
+		if err := base62KGS.Start(0); err != nil {
+			log.Fatalf("Failed to start KGS buffer refiller: %v", err)
+		}
+		defer base62KGS.Stop()
+	}
+	var serviceOpts []app.ServiceOption
+	if idProfile != nil {
+		// Reject IDs that cannot exist before they reach the repository
+		serviceOpts = append(serviceOpts, app.WithIDProfile(idProfile))
+	}
+	if region != "" {
+		// Prefix generated IDs with the region code and route IDs of other regions to them
+		regions, err := domain.ParseRegions(regionList)
+		if err != nil {
+			log.Fatalf("Invalid SHORTURL_REGIONS: %v", err)
+		}
+		registry, err := domain.NewRegionRegistry(regions...)
+		if err != nil {
+			log.Fatalf("Invalid SHORTURL_REGIONS: %v", err)
+		}
+		if _, ok := registry.Lookup(region); !ok {
+			log.Fatalf("SHORTURL_REGION %q is not listed in SHORTURL_REGIONS", region)
+		}
+		if idProfile != nil && idProfile.Validate(region) != nil {
+			log.Fatalf("SHORTURL_REGION %q is not part of the ID alphabet", region)
+		}
+		regionKGS, err := infra.NewRegionPrefixKeyGenerationService(region, kgs)
+		if err != nil {
+			log.Fatalf("Failed to create key generation service: %v", err)
+		}
+		kgs = regionKGS
+		serviceOpts = append(serviceOpts, app.WithRegions(region, registry))
+	}
+	// Keep generated and custom IDs clear of offensive words and of the server's own routes
+	blocklist := domain.NewBlocklist(domain.ParseWordList(blockedWords), append(domain.DefaultReservedWords(), domain.ParseWordList(reservedWords)...))
+	kgs = infra.NewFilteringKeyGenerationService(kgs, blocklist)
+	serviceOpts = append(serviceOpts, app.WithBlocklist(blocklist))
+	if aliasCase != "" {
+		policy := domain.DefaultAliasPolicy()
+		var err error
+		if policy.Case, err = domain.ParseAliasCase(aliasCase); err != nil {
+			log.Fatalf("Invalid SHORTURL_ALIAS_CASE: %v", err)
+		}
+		serviceOpts = append(serviceOpts, app.WithAliasPolicy(policy))
+	}
+	if urlSchemes != "" {
+		policy := domain.DefaultLongURLPolicy()
+		policy.AllowedSchemes = domain.ParseWordList(strings.ToLower(urlSchemes))
+		serviceOpts = append(serviceOpts, app.WithLongURLPolicy(policy))
+	}
+	// Destinations on our own hosts would redirect in a loop
+	serviceOpts = append(serviceOpts, app.WithOwnHosts(domain.ParseWordList(customDomains)...))
+	shortenerHosts := domain.NewHostSet(append(domain.DefaultShortenerHosts(), domain.ParseWordList(shortenerList)...)...)
+	switch shortenerMode {
+	case "", "allow":
+	case "deny":
+		serviceOpts = append(serviceOpts, app.WithShortenerHosts(shortenerHosts, nil))
+	case "resolve":
+		serviceOpts = append(serviceOpts, app.WithShortenerHosts(shortenerHosts, infra.NewHTTPRedirectResolver(0)))
+	default:
+		log.Fatalf("Invalid SHORTURL_SHORTENERS %q; use allow, deny or resolve", shortenerMode)
+	}
+	if blocklistFile != "" {
+		// Reject malicious destinations at creation and stop redirecting to them once listed
+		destinations, err := infra.NewFileDestinationBlocklist(blocklistFile)
+		if err != nil {
+			log.Fatalf("Failed to load destination blocklist: %v", err)
+		}
+		if err := destinations.Start(0); err != nil {
+			log.Fatalf("Failed to start destination blocklist watcher: %v", err)
+		}
+		defer destinations.Stop()
+		log.Printf("Loaded destination blocklist %s with %d rules", blocklistFile, destinations.Len())
+		serviceOpts = append(serviceOpts, app.WithDestinationBlocklist(destinations))
+	}
+	switch idStrategy {
+	case "", "counter":
+	case "hash":
+		// Derive IDs from the long URL so the same destination always gets the same short URL
+		if region != "" {
+			log.Fatalf("SHORTURL_ID_STRATEGY=hash cannot be combined with SHORTURL_REGION")
+		}
+		serviceOpts = append(serviceOpts, app.WithHashIDs(infra.NewSHA256HashKeyGenerationService(idProfile)))
+	default:
+		log.Fatalf("Invalid SHORTURL_ID_STRATEGY %q; use counter or hash", idStrategy)
+	}
+	// Password-protected links: slow salted hashes, signed unlock cookies and throttled guessing.
+	// Instances behind one load balancer must share SHORTURL_UNLOCK_KEY to accept each other's cookies.
+	var signingKey []byte
+	if unlockKey != "" {
+		signingKey = []byte(unlockKey)
+	}
+	unlockSigner, err := infra.NewHMACUnlockSigner(signingKey, 0)
+	if err != nil {
+		log.Fatalf("Invalid SHORTURL_UNLOCK_KEY: %v", err)
+	}
+	serviceOpts = append(serviceOpts, app.WithPasswordProtection(
+		infra.NewPBKDF2PasswordHasher(0),
+		unlockSigner,
+		infra.NewMemoryAttemptLimiter(infra.DefaultAttemptLimiterOptions()),
+	))
+	if countryFile != "" {
+		// Locate visitors by IP address so that routing rules can match their country
+		countries, err := infra.NewFileCountryResolver(countryFile)
+		if err != nil {
+			log.Fatalf("Failed to load country table: %v", err)
+		}
+		log.Printf("Loaded country table %s with %d prefixes", countryFile, countries.Len())
+		serviceOpts = append(serviceOpts, app.WithCountryResolver(countries))
+	}
 	analytics := infra.NewMockAnalyticsService() // Analytics event processing
 
 	// Create application layer service with injected dependencies
-	service := app.NewShortURLService(repo, kgs, analytics, baseURL)
+	service := app.NewShortURLService(repo, kgs, analytics, baseURL, serviceOpts...)
 
 	// Create presentation layer handler
 	handler := httpHandler.NewShortURLHandler(service)
@@ -38,27 +251,26 @@ func main() {
 	// API endpoints following REST conventions
 	http.HandleFunc("/v1/createShortUrl", handler.CreateShortURL)
 	http.HandleFunc("/v1/getLongUrl", handler.GetLongURL)
-	http.HandleFunc("/admin/shorturls", handler.GetAllShortURLs)
+	http.HandleFunc("/admin/shorturls", handler.ListShortURLs)
 	http.HandleFunc("/admin/deactivate", handler.DeactivateShortURL)
+	http.HandleFunc("/admin/activate", handler.ActivateShortURL)
+	http.HandleFunc("/admin/pause", handler.PauseShortURL)
+	http.HandleFunc("/admin/block", handler.BlockShortURL)
+	http.HandleFunc("/admin/archive", handler.ArchiveShortURL)
+	http.HandleFunc("/admin/destination", handler.UpdateDestination)
+	if cache != nil {
+		http.HandleFunc("/admin/cache/stats", httpHandler.NewStatsHandler(func() interface{} { return cache.Stats() }).GetStats)
+	}
+	if bufferedKGS {
+		http.HandleFunc("/admin/kgs/stats", httpHandler.NewStatsHandler(func() interface{} { return base62KGS.Stats() }).GetStats)
+	}
 
 	// Catch-all handler for short URL redirection
 	// This handles GET /<shortId> requests and redirects to original URLs
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		// Handle root path requests
-		if r.URL.Path == "/" {
-			w.WriteHeader(http.StatusNotFound)
-			if _, err := w.Write([]byte("Short URL not found")); err != nil {
-				log.Printf("Failed to write response: %v", err)
-			}
-			return
-		}
-
-		// Handle unmatched API/admin paths
-		if strings.HasPrefix(r.URL.Path, "/v1/") || strings.HasPrefix(r.URL.Path, "/admin/") {
-			w.WriteHeader(http.StatusNotFound)
-			if _, err := w.Write([]byte("Endpoint not found")); err != nil {
-				log.Printf("Failed to write response: %v", err)
-			}
+		// Handle root path requests and unmatched API/admin paths
+		if r.URL.Path == "/" || strings.HasPrefix(r.URL.Path, "/v1/") || strings.HasPrefix(r.URL.Path, "/admin/") {
+			handler.NotFound(w, r)
 			return
 		}
 
@@ -76,18 +288,66 @@ func main() {
 	fmt.Printf("  GET  %s/v1/getLongUrl - Get long URL\n", baseURL)
 	fmt.Printf("  GET  %s/admin/shorturls - List all URLs\n", baseURL)
 	fmt.Printf("  DELETE %s/admin/deactivate?id=<id> - Deactivate URL\n", baseURL)
+	fmt.Printf("  POST %s/admin/{activate,pause,block,archive}?id=<id> - Change URL state\n", baseURL)
+	fmt.Printf("  PATCH %s/admin/destination?id=<id> - Change destination\n", baseURL)
+	if cache != nil {
+		fmt.Printf("  GET  %s/admin/cache/stats - Cache statistics\n", baseURL)
+	}
+	if bufferedKGS {
+		fmt.Printf("  GET  %s/admin/kgs/stats - KGS buffer statistics\n", baseURL)
+	}
 	fmt.Printf("  GET  %s/<shortId> - Redirect to long URL\n", baseURL)
+	fmt.Printf("  POST %s/<shortId> - Unlock password-protected URL\n", baseURL)
 
 	// Configure HTTP server with appropriate timeouts for security
 	server := &http.Server{
 		Addr:           port,
-		Handler:        nil, // Use default ServeMux
+		Handler:        httpHandler.WithRequestTimeout(http.DefaultServeMux, 10*time.Second), // Default ServeMux with per-request deadline
 		ReadTimeout:    15 * time.Second,
 		WriteTimeout:   15 * time.Second,
 		IdleTimeout:    60 * time.Second,
 		MaxHeaderBytes: 1 << 20, // 1 MB
 	}
 
-	// Start HTTP server - this call blocks until the server shuts down
-	log.Fatal(server.ListenAndServe())
+	// Start HTTP server and shut it down gracefully on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down server")
+
+	// Let in-flight requests finish before the deferred cleanup stops the KGS refiller and
+	// closes the data directory
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown failed: %v", err)
+	}
+}
+
+// parseIDProfile builds the ID profile from the SHORTURL_ID_ALPHABET and SHORTURL_ID_LENGTH
+// settings, exiting on invalid values.
+func parseIDProfile(alphabet, length string) *domain.IDProfile {
+	if alphabet == "" {
+		alphabet = "base62"
+	}
+	n := domain.DefaultIDLength
+	if length != "" {
+		var err error
+		if n, err = strconv.Atoi(length); err != nil {
+			log.Fatalf("Invalid SHORTURL_ID_LENGTH %q", length)
+		}
+	}
+
+	profile, err := domain.NewNamedIDProfile(alphabet, n)
+	if err != nil {
+		log.Fatalf("Invalid ID profile: %v", err)
+	}
+	return profile
 }
@@ -0,0 +1,113 @@
+// Package main is the entry point for the standalone Key Generation Service (KGS).
+// It hands out batches of unique IDs over HTTP so that every URL shortening
+// instance draws from the same ID space without coordinating with the others.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+	"github.com/oharai/short-url/internal/shorturl/infra"
+	httpHandler "github.com/oharai/short-url/internal/shorturl/interfaces/http"
+)
+
+// main initializes and starts the KGS HTTP server.
+func main() {
+	// Configuration - In production, these would come from environment variables
+	port := os.Getenv("KGS_ADDR") // Listen address; ":8081" when empty
+	if port == "" {
+		port = ":8081"
+	}
+	dataDir := os.Getenv("KGS_DATA_DIR")       // Directory of the durable counter; in-memory counter when empty
+	idAlphabet := os.Getenv("KGS_ID_ALPHABET") // ID alphabet: base62, base58 or lowercase; base62 when empty
+	idLength := os.Getenv("KGS_ID_LENGTH")     // Length of generated IDs; 7 when empty
+
+	// Dependency Injection Setup
+	// The API servers must use the same profile to validate incoming IDs
+	if idAlphabet == "" {
+		idAlphabet = "base62"
+	}
+	length := domain.DefaultIDLength
+	if idLength != "" {
+		var err error
+		if length, err = strconv.Atoi(idLength); err != nil {
+			log.Fatalf("Invalid KGS_ID_LENGTH %q", idLength)
+		}
+	}
+	idProfile, err := domain.NewNamedIDProfile(idAlphabet, length)
+	if err != nil {
+		log.Fatalf("Invalid ID profile: %v", err)
+	}
+
+	// Keep up to 10,000 pre-generated IDs, as sized in the README, refilled in the background
+	// Counter-derived IDs are unique by construction; without a data directory the counter
+	// lives in memory and starts over on every restart
+	var counterStore infra.HighWaterMarkStore = infra.NewMemoryHighWaterMarkStore(0)
+	if dataDir != "" {
+		// Lease counter ranges from a durable high-water mark so restarts never reissue IDs
+		fileStore, err := infra.NewFileHighWaterMarkStore(filepath.Join(dataDir, "kgs.hwm"))
+		if err != nil {
+			log.Fatalf("Failed to open KGS counter store: %v", err)
+		}
+		counterStore = fileStore
+	}
+	opts := []infra.Base62Option{infra.WithBufferWatermarks(2000, 10000), infra.WithIDProfile(idProfile), infra.WithCounterStore(counterStore, 0)}
+	kgs := infra.NewBase62KeyGenerationService(opts...).(*infra.Base62KeyGenerationService)
+	if err := kgs.Start(0); err != nil {
+		log.Fatalf("Failed to start KGS buffer refiller: %v", err)
+	}
+	defer kgs.Stop()
+
+	handler := httpHandler.NewKGSHandler(kgs)
+
+	// Route Configuration
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/ids", handler.GetMultipleIDs)
+	mux.HandleFunc("/admin/kgs/stats", httpHandler.NewStatsHandler(func() interface{} { return kgs.Stats() }).GetStats)
+
+	// Display startup information for development convenience
+	fmt.Printf("KGS starting on %s\n", port)
+	fmt.Printf("API Endpoints:\n")
+	fmt.Printf("  POST /v1/ids?count=<n> - Issue a batch of unique IDs\n")
+	fmt.Printf("  GET  /admin/kgs/stats - Buffer statistics\n")
+
+	// Configure HTTP server with appropriate timeouts for security
+	server := &http.Server{
+		Addr:           port,
+		Handler:        httpHandler.WithRequestTimeout(mux, 5*time.Second),
+		ReadTimeout:    5 * time.Second,
+		WriteTimeout:   10 * time.Second,
+		IdleTimeout:    60 * time.Second,
+		MaxHeaderBytes: 1 << 20, // 1 MB
+	}
+
+	// Start HTTP server and shut it down gracefully on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("KGS failed: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down KGS")
+
+	// Let in-flight requests finish before the deferred cleanup stops the buffer refiller
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("KGS shutdown failed: %v", err)
+	}
+}
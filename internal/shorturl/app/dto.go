@@ -10,9 +10,25 @@ type CreateShortURLRequest struct {
 	LongURL      string                 `json:"longUrl"`                // The original URL to be shortened (required)
 	CustomURL    string                 `json:"customUrl,omitempty"`    // Optional custom identifier for the short URL
 	Expiry       *time.Time             `json:"expiry,omitempty"`       // Optional expiration time for the URL
+	MaxClicks    int                    `json:"maxClicks,omitempty"`    // Optional number of redirects after which the URL stops working; 1 for a one-time link
+	NotBefore    *time.Time             `json:"notBefore,omitempty"`    // Optional activation time; the URL does not redirect before it
+	PreLaunchURL string                 `json:"preLaunchUrl,omitempty"` // Optional URL to redirect visitors to before notBefore
+	Password     string                 `json:"password,omitempty"`     // Optional password visitors must enter before being redirected
+	RoutingRules []RoutingRule          `json:"routingRules,omitempty"` // Optional rules sending matching visitors elsewhere, first match wins
 	UserMetadata map[string]interface{} `json:"userMetadata,omitempty"` // Additional metadata for analytics
 }
 
+// RoutingRule sends visitors matching all of its conditions to its own destination
+// instead of the long URL. Each condition lists alternatives, any of which may match;
+// an omitted condition matches every visitor.
+type RoutingRule struct {
+	Devices   []string `json:"devices,omitempty"`   // Devices by user agent: ios, android, mobile (any mobile device) or desktop
+	Countries []string `json:"countries,omitempty"` // ISO 3166-1 alpha-2 codes of the visitor's IP address
+	Languages []string `json:"languages,omitempty"` // Language tags; "ja" also matches "ja-JP" preferred by Accept-Language
+	Referrers []string `json:"referrers,omitempty"` // Referring hosts, including their subdomains
+	LongURL   string   `json:"longUrl"`             // Destination of matching visitors (required)
+}
+
 // CreateShortURLResponse contains the result of a successful short URL creation.
 type CreateShortURLResponse struct {
 	ShortURL string `json:"shortUrl"` // The complete short URL that was created
@@ -22,17 +38,96 @@ type CreateShortURLResponse struct {
 // Used in the redirect flow to resolve short URLs to their destinations.
 type GetLongURLRequest struct {
 	ShortURL     string                 `json:"shortUrl"`               // The short URL to resolve (required)
+	UserMetadata map[string]interface{} `json:"userMetadata,omitempty"` // Context data for analytics tracking; routing rules match its "user_agent", "accept_language", "referer" and "ip"
+	UnlockToken  string                 `json:"-"`                      // Token from UnlockShortURL for password-protected URLs
+}
+
+// UnlockShortURLRequest represents the password a visitor entered for a password-protected
+// short URL.
+type UnlockShortURLRequest struct {
+	ShortURL     string                 `json:"shortUrl"`               // The short URL to unlock (required)
+	Password     string                 `json:"password"`               // The password entered by the visitor
 	UserMetadata map[string]interface{} `json:"userMetadata,omitempty"` // Context data for analytics tracking
 }
 
+// UnlockShortURLResponse contains the token that lets a visitor through GetLongURL
+// without entering the password again.
+type UnlockShortURLResponse struct {
+	Token     string    `json:"token"`     // Unlock token for GetLongURLRequest.UnlockToken; empty if the URL is not protected
+	ExpiresAt time.Time `json:"expiresAt"` // When the token stops being valid
+}
+
 // ShortURLResponse represents the complete information about a short URL.
 // Used in administrative operations and listing endpoints.
 type ShortURLResponse struct {
-	ID           string                 `json:"id"`                     // Unique identifier
-	LongURL      string                 `json:"longUrl"`                // Original URL
-	ShortURL     string                 `json:"shortUrl"`               // Complete short URL
-	CreatedAt    time.Time              `json:"createdAt"`              // Creation timestamp
-	Expiry       *time.Time             `json:"expiry,omitempty"`       // Optional expiration time
-	IsActive     bool                   `json:"isActive"`               // Current status
-	UserMetadata map[string]interface{} `json:"userMetadata,omitempty"` // Associated metadata
+	ID                string                 `json:"id"`                          // Unique identifier
+	LongURL           string                 `json:"longUrl"`                     // Original URL
+	ShortURL          string                 `json:"shortUrl"`                    // Complete short URL
+	CreatedAt         time.Time              `json:"createdAt"`                   // Creation timestamp
+	Expiry            *time.Time             `json:"expiry,omitempty"`            // Optional expiration time
+	NotBefore         *time.Time             `json:"notBefore,omitempty"`         // Optional activation time
+	PreLaunchURL      string                 `json:"preLaunchUrl,omitempty"`      // Where visitors are sent before the activation time
+	PasswordProtected bool                   `json:"passwordProtected,omitempty"` // Whether visitors must enter a password; the password is never returned
+	RoutingRules      []RoutingRule          `json:"routingRules,omitempty"`      // Rules sending matching visitors elsewhere, in evaluation order
+	IsActive          bool                   `json:"isActive"`                    // Whether the URL redirects (state is active)
+	State             string                 `json:"state"`                       // Lifecycle state
+	MaxClicks         int                    `json:"maxClicks,omitempty"`         // Click limit; omitted when unlimited
+	Clicks            int                    `json:"clicks,omitempty"`            // Redirects counted against the click limit
+	UserMetadata      map[string]interface{} `json:"userMetadata,omitempty"`      // Associated metadata
+	Region            string                 `json:"region,omitempty"`            // Name of the region that minted the URL
+	History           []DestinationChange    `json:"history,omitempty"`           // Previous destinations, oldest first
+	Transitions       []StateTransition      `json:"transitions,omitempty"`       // Lifecycle state changes, oldest first
+}
+
+// StateTransition describes one change of a short URL's lifecycle state.
+type StateTransition struct {
+	From   string    `json:"from"`             // State before the change
+	To     string    `json:"to"`               // State after the change
+	Reason string    `json:"reason,omitempty"` // Why the state was changed
+	Actor  string    `json:"actor,omitempty"`  // Who made the change
+	At     time.Time `json:"at"`               // When the change happened
+}
+
+// ChangeStateRequest represents the input data for moving a short URL to another
+// lifecycle state.
+type ChangeStateRequest struct {
+	ID     string `json:"id"`               // Identifier of the short URL (required)
+	State  string `json:"state"`            // Target state: active, paused, deactivated, blocked or archived (required)
+	Reason string `json:"reason,omitempty"` // Why the state is changed
+	Actor  string `json:"actor,omitempty"`  // Who makes the change
+}
+
+// DestinationChange describes a destination a short URL pointed to before an update.
+type DestinationChange struct {
+	LongURL   string    `json:"longUrl"`         // Destination before the change
+	ChangedAt time.Time `json:"changedAt"`       // When the destination was replaced
+	Actor     string    `json:"actor,omitempty"` // Who made the change
+}
+
+// UpdateDestinationRequest represents the input data for pointing an existing short URL
+// to a new long URL.
+type UpdateDestinationRequest struct {
+	ID      string `json:"id"`              // Identifier of the short URL (required)
+	LongURL string `json:"longUrl"`         // The new destination (required)
+	Actor   string `json:"actor,omitempty"` // Who makes the change, recorded in the history
+}
+
+// ListShortURLsRequest represents the filters and paging parameters of an administrative listing.
+// All filters are optional; an empty request returns the oldest entries first.
+type ListShortURLsRequest struct {
+	Status        string     `json:"status,omitempty"`        // "active", "inactive", "expired" or "scheduled"; all statuses when empty
+	CreatedFrom   *time.Time `json:"createdFrom,omitempty"`   // Only URLs created at or after this time
+	CreatedTo     *time.Time `json:"createdTo,omitempty"`     // Only URLs created before this time
+	Domain        string     `json:"domain,omitempty"`        // Only URLs whose long URL is on this domain or a subdomain
+	MetadataKey   string     `json:"metadataKey,omitempty"`   // Only URLs with this user metadata key
+	MetadataValue string     `json:"metadataValue,omitempty"` // Only URLs whose metadata key has this value
+	Order         string     `json:"order,omitempty"`         // "asc" (default) or "desc" by creation time
+	Cursor        string     `json:"cursor,omitempty"`        // Cursor returned by the previous page
+	Limit         int        `json:"limit,omitempty"`         // Page size; a default applies when zero
+}
+
+// ListShortURLsResponse contains one page of an administrative listing.
+type ListShortURLsResponse struct {
+	Items      []*ShortURLResponse `json:"items"`                // Short URLs on this page
+	NextCursor string              `json:"nextCursor,omitempty"` // Cursor for the next page; omitted on the last page
 }
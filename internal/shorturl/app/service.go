@@ -1,14 +1,51 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/netip"
 	"strings"
 	"time"
 
 	"github.com/oharai/short-url/internal/shorturl/domain"
 )
 
+// maxIDGenerationAttempts bounds how many KGS-generated identifiers are tried
+// when the repository reports that a generated identifier is already taken.
+const maxIDGenerationAttempts = 5
+
+// maxHashProbes bounds how many hash-derived candidate identifiers are probed before
+// creation gives up because every candidate belongs to a different destination.
+const maxHashProbes = 16
+
+// maxShortenerHops bounds how many links of other URL shorteners are resolved in a row
+// before the destination is rejected as hidden behind a shortener chain.
+const maxShortenerHops = 3
+
+// Page size limits for listings. A request without a limit gets defaultPageLimit entries.
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 1000
+)
+
+// Validation errors for request fields, named after the JSON fields of the API.
+var (
+	errLongURLRequired   = domain.NewError(domain.ErrInvalidInput, "long_url_required", "longUrl is required")
+	errShortURLRequired  = domain.NewError(domain.ErrInvalidInput, "short_url_required", "shortUrl is required")
+	errInvalidStatus     = domain.NewError(domain.ErrInvalidInput, "invalid_status", "status must be one of active, inactive, expired or scheduled")
+	errInvalidOrder      = domain.NewError(domain.ErrInvalidInput, "invalid_order", "order must be asc or desc")
+	errInvalidLimit      = domain.NewError(domain.ErrInvalidInput, "invalid_limit", fmt.Sprintf("limit must be between 1 and %d", maxPageLimit))
+	errInvalidTimeRange  = domain.NewError(domain.ErrInvalidInput, "invalid_time_range", "createdFrom must be before createdTo")
+	errMetadataKeyNeeded = domain.NewError(domain.ErrInvalidInput, "metadata_key_required", "metadataValue requires metadataKey")
+	errNotBeforeRequired = domain.NewError(domain.ErrInvalidInput, "not_before_required", "preLaunchUrl requires notBefore")
+	errPasswordsDisabled = domain.NewError(domain.ErrInvalidInput, "password_not_supported", "password protection is not enabled")
+	errCountriesDisabled = domain.NewError(domain.ErrInvalidInput, "country_routing_not_supported", "routing by country is not enabled")
+)
+
+// errNothingChanged aborts a repository update that would write the entity unchanged.
+var errNothingChanged = errors.New("nothing changed")
+
 // ShortURLService is the primary application service that orchestrates
 // the URL shortening business use cases. It coordinates between domain entities,
 // repositories, and external services to implement the application's core functionality.
@@ -17,6 +54,210 @@ type ShortURLService struct {
 	kgs       domain.KeyGenerationService // Service for generating unique identifiers
 	analytics domain.AnalyticsService     // Service for sending analytics events
 	baseURL   string                      // Base URL for constructing complete short URLs
+
+	localRegion string                 // Code of the region this instance runs in; empty without regions
+	regions     *domain.RegionRegistry // Known regions for routing lookups; nil without regions
+	idProfile   *domain.IDProfile      // Format of IDs checked before lookups; nil to accept any ID
+
+	hashKGS       domain.HashKeyGenerationService // Derives IDs from long URLs; nil to use the KGS
+	blocklist     *domain.Blocklist               // Words custom and hash-derived IDs must avoid; nil to allow any ID
+	aliasPolicy   domain.AliasPolicy              // Rules for custom aliases
+	longURLPolicy domain.LongURLPolicy            // Rules for destinations
+
+	ownHosts       *domain.HostSet         // Hosts serving this service's short URLs
+	shortenerHosts *domain.HostSet         // Hosts of other URL shorteners; nil to allow them
+	resolver       domain.RedirectResolver // Resolves other shorteners' links; nil to reject them
+
+	destinations domain.DestinationBlocklist // Known malicious destinations; nil to allow any
+
+	passwords domain.PasswordHasher // Hashes link passwords; nil to reject password-protected URLs
+	unlocks   domain.UnlockSigner   // Issues and checks unlock tokens of password-protected URLs
+	attempts  domain.AttemptLimiter // Throttles wrong passwords per short URL
+
+	countries domain.CountryResolver // Locates visitors for routing rules; nil to reject country conditions
+}
+
+// ServiceOption configures optional behavior of the ShortURLService.
+type ServiceOption func(*ShortURLService)
+
+// WithRegions enables multi-region operation. New short URLs record the local region,
+// and lookups of IDs that are not stored locally but carry another region's prefix
+// fail with a domain.ForeignRegionError naming the region to route the client to.
+// The KGS must prefix generated IDs with the local region code, for example with
+// infra.RegionPrefixKeyGenerationService.
+//
+// Parameters:
+//   - localCode: Code of the region this instance runs in
+//   - regions: All known regions, including the local one
+//
+// Returns:
+//   - ServiceOption: Option to pass to NewShortURLService
+func WithRegions(localCode string, regions *domain.RegionRegistry) ServiceOption {
+	return func(s *ShortURLService) {
+		s.localRegion = localCode
+		s.regions = regions
+	}
+}
+
+// WithIDProfile makes the service check incoming IDs before touching the repository.
+// IDs with the shape of a generated ID are normalized (case-insensitive profiles fold
+// them to lowercase); other IDs are custom URLs or prefixed IDs and are kept as they are.
+// IDs with characters neither the alphabet nor the alias policy allows are rejected with
+// domain.ErrInvalidShortID. Custom URLs are validated by the alias policy alone.
+//
+// Parameters:
+//   - profile: The profile the KGS generates IDs with
+//
+// Returns:
+//   - ServiceOption: Option to pass to NewShortURLService
+func WithIDProfile(profile *domain.IDProfile) ServiceOption {
+	return func(s *ShortURLService) {
+		s.idProfile = profile
+	}
+}
+
+// WithHashIDs derives generated IDs from the long URL instead of drawing them from the KGS,
+// so that shortening the same destination twice returns the same short URL. Candidate IDs
+// are probed in order: a free candidate is claimed, a candidate already used for the same
+// destination with the same expiry is returned as is, and any other candidate is skipped.
+// Custom URLs are not affected.
+//
+// Parameters:
+//   - hashKGS: Generator of the candidate IDs, for example infra.SHA256HashKeyGenerationService
+//
+// Returns:
+//   - ServiceOption: Option to pass to NewShortURLService
+func WithHashIDs(hashKGS domain.HashKeyGenerationService) ServiceOption {
+	return func(s *ShortURLService) {
+		s.hashKGS = hashKGS
+	}
+}
+
+// WithBlocklist rejects custom URLs that equal a reserved word or contain a blocked word
+// with domain.ErrReservedShortID or domain.ErrBlockedShortID, and makes hash-derived IDs
+// skip such candidates. KGS-generated IDs are filtered by the KGS itself, for example by
+// infra.FilteringKeyGenerationService with the same blocklist.
+//
+// Parameters:
+//   - blocklist: Reserved and blocked words
+//
+// Returns:
+//   - ServiceOption: Option to pass to NewShortURLService
+func WithBlocklist(blocklist *domain.Blocklist) ServiceOption {
+	return func(s *ShortURLService) {
+		s.blocklist = blocklist
+	}
+}
+
+// WithAliasPolicy replaces domain.DefaultAliasPolicy as the rules custom aliases must satisfy.
+//
+// Parameters:
+//   - policy: Length, character, case and reserved-prefix rules for custom aliases
+//
+// Returns:
+//   - ServiceOption: Option to pass to NewShortURLService
+func WithAliasPolicy(policy domain.AliasPolicy) ServiceOption {
+	return func(s *ShortURLService) {
+		s.aliasPolicy = policy
+	}
+}
+
+// WithLongURLPolicy replaces domain.DefaultLongURLPolicy as the rules destinations must satisfy.
+//
+// Parameters:
+//   - policy: Allowed schemes and maximum length of long URLs
+//
+// Returns:
+//   - ServiceOption: Option to pass to NewShortURLService
+func WithLongURLPolicy(policy domain.LongURLPolicy) ServiceOption {
+	return func(s *ShortURLService) {
+		s.longURLPolicy = policy
+	}
+}
+
+// WithOwnHosts declares additional hosts that serve this service's short URLs, such as
+// custom domains. Long URLs pointing to them are rejected with domain.ErrSelfReferencingURL
+// like those pointing to the base URL, since a short URL redirecting to a short URL can loop.
+//
+// Parameters:
+//   - hosts: Host names, optionally with scheme and port; subdomains match too
+//
+// Returns:
+//   - ServiceOption: Option to pass to NewShortURLService
+func WithOwnHosts(hosts ...string) ServiceOption {
+	return func(s *ShortURLService) {
+		s.ownHosts.Add(hosts...)
+	}
+}
+
+// WithShortenerHosts guards against long URLs that point to other URL shorteners and so
+// hide the destination or chain back to this service. Without a resolver such long URLs
+// are rejected with domain.ErrShortenerURL. With a resolver the redirect chain is followed
+// through shortener hosts, up to maxShortenerHops links, and the first destination outside
+// them is shortened instead; it must pass the same checks as any long URL.
+//
+// Parameters:
+//   - hosts: Hosts of other URL shorteners, e.g. from domain.DefaultShortenerHosts
+//   - resolver: Resolver of their links, or nil to reject them
+//
+// Returns:
+//   - ServiceOption: Option to pass to NewShortURLService
+func WithShortenerHosts(hosts *domain.HostSet, resolver domain.RedirectResolver) ServiceOption {
+	return func(s *ShortURLService) {
+		s.shortenerHosts = hosts
+		s.resolver = resolver
+	}
+}
+
+// WithDestinationBlocklist blocks known malicious destinations, such as phishing sites.
+// Creating a short URL for a listed destination fails with domain.ErrBlockedDestination,
+// and resolving a short URL whose destination was listed after its creation fails with
+// domain.ErrShortURLBlocked. Both send a "url_blocked" analytics event naming the rule.
+//
+// Parameters:
+//   - blocklist: The destination blocklist, e.g. infra.FileDestinationBlocklist
+//
+// Returns:
+//   - ServiceOption: Option to pass to NewShortURLService
+func WithDestinationBlocklist(blocklist domain.DestinationBlocklist) ServiceOption {
+	return func(s *ShortURLService) {
+		s.destinations = blocklist
+	}
+}
+
+// WithPasswordProtection allows short URLs to be created with a password. Visitors must
+// unlock such URLs with UnlockShortURL, which returns a short-lived token to pass to
+// GetLongURL; without a valid token, GetLongURL fails with domain.ErrPasswordRequired.
+// Wrong passwords are throttled per short URL. Without this option, requests with a
+// password are rejected.
+//
+// Parameters:
+//   - hasher: Password hasher, e.g. infra.PBKDF2PasswordHasher
+//   - signer: Unlock token signer, e.g. infra.HMACUnlockSigner
+//   - limiter: Limiter of wrong passwords, e.g. infra.MemoryAttemptLimiter
+//
+// Returns:
+//   - ServiceOption: Option to pass to NewShortURLService
+func WithPasswordProtection(hasher domain.PasswordHasher, signer domain.UnlockSigner, limiter domain.AttemptLimiter) ServiceOption {
+	return func(s *ShortURLService) {
+		s.passwords = hasher
+		s.unlocks = signer
+		s.attempts = limiter
+	}
+}
+
+// WithCountryResolver allows routing rules with country conditions, matched against the
+// country of the visitor's IP address. Without this option, such rules are rejected.
+//
+// Parameters:
+//   - resolver: Country lookup of IP addresses, e.g. infra.FileCountryResolver
+//
+// Returns:
+//   - ServiceOption: Option to pass to NewShortURLService
+func WithCountryResolver(resolver domain.CountryResolver) ServiceOption {
+	return func(s *ShortURLService) {
+		s.countries = resolver
+	}
 }
 
 // NewShortURLService creates a new instance of the ShortURLService with all required dependencies.
@@ -27,16 +268,31 @@ type ShortURLService struct {
 //   - kgs: Key generation service for creating unique identifiers
 //   - analytics: Analytics service for event tracking
 //   - baseURL: Base URL used for constructing complete short URLs
+//   - opts: Optional settings such as WithRegions
 //
 // Returns:
 //   - *ShortURLService: Configured service instance ready for use
-func NewShortURLService(repo domain.ShortURLRepository, kgs domain.KeyGenerationService, analytics domain.AnalyticsService, baseURL string) *ShortURLService {
-	return &ShortURLService{
+func NewShortURLService(repo domain.ShortURLRepository, kgs domain.KeyGenerationService, analytics domain.AnalyticsService, baseURL string, opts ...ServiceOption) *ShortURLService {
+	s := &ShortURLService{
 		repo:      repo,
 		kgs:       kgs,
 		analytics: analytics,
 		baseURL:   baseURL,
+
+		aliasPolicy:   domain.DefaultAliasPolicy(),
+		longURLPolicy: domain.DefaultLongURLPolicy(),
+		ownHosts:      domain.NewHostSet(baseURL),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.regions != nil {
+		// Short URLs of the other regions can loop just like local ones
+		for _, region := range s.regions.Regions() {
+			s.ownHosts.Add(region.BaseURL)
+		}
 	}
+	return s
 }
 
 // CreateShortURL implements the URL shortening use case.
@@ -45,193 +301,946 @@ func NewShortURLService(repo domain.ShortURLRepository, kgs domain.KeyGeneration
 // persists it, and sends analytics events.
 //
 // Parameters:
+//   - ctx: Request context; cancellation aborts key generation and persistence
 //   - req: Request containing the long URL and optional parameters
 //
 // Returns:
 //   - *CreateShortURLResponse: Contains the generated short URL
 //   - error: Validation or system error
-func (s *ShortURLService) CreateShortURL(req CreateShortURLRequest) (*CreateShortURLResponse, error) {
+func (s *ShortURLService) CreateShortURL(ctx context.Context, req CreateShortURLRequest) (*CreateShortURLResponse, error) {
 	// Validate required input
 	if req.LongURL == "" {
-		return nil, errors.New("longUrl is required")
+		return nil, errLongURLRequired
+	}
+	if req.MaxClicks < 0 {
+		return nil, domain.ErrInvalidMaxClicks
+	}
+	if req.PreLaunchURL != "" && req.NotBefore == nil {
+		return nil, errNotBeforeRequired
+	}
+	if len(req.RoutingRules) > domain.MaxRoutingRules {
+		return nil, domain.ErrTooManyRoutingRules
+	}
+	if req.Password != "" {
+		if s.passwords == nil {
+			return nil, errPasswordsDisabled
+		}
+		if err := domain.ValidatePassword(req.Password); err != nil {
+			return nil, err
+		}
+	}
+	longURL, err := domain.ParseLongURL(req.LongURL, s.longURLPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if longURL, err = s.checkDestination(ctx, longURL); err != nil {
+		return nil, err
+	}
+	if s.isBlocked(ctx, "", longURL.String(), "create", req.UserMetadata) {
+		return nil, domain.ErrBlockedDestination
+	}
+
+	spec := createSpec{longURL: longURL}
+
+	// Visitors are sent to the pre-launch URL, so it gets the same checks as the destination
+	if req.PreLaunchURL != "" {
+		if spec.preLaunchURL, err = domain.ParseLongURL(req.PreLaunchURL, s.longURLPolicy); err != nil {
+			return nil, err
+		}
+		if spec.preLaunchURL, err = s.checkDestination(ctx, spec.preLaunchURL); err != nil {
+			return nil, err
+		}
+		if s.isBlocked(ctx, "", spec.preLaunchURL.String(), "create", req.UserMetadata) {
+			return nil, domain.ErrBlockedDestination
+		}
+	}
+
+	for i, rule := range req.RoutingRules {
+		routingRule, err := s.parseRoutingRule(ctx, rule, req.UserMetadata)
+		if err != nil {
+			return nil, routingRuleError(i, err)
+		}
+		spec.routingRules = append(spec.routingRules, routingRule)
+	}
+
+	// Hash once up front: hashing is deliberately slow and ID collisions retry the insert
+	if req.Password != "" {
+		if spec.passwordHash, err = s.passwords.Hash(req.Password); err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
 	}
 
 	var shortURL *domain.ShortURL
-	var err error
+	created := true
 
 	// Handle custom URL path vs. automatic generation
 	if req.CustomURL != "" {
-		// Check if custom URL is already taken
-		existing, _ := s.repo.FindByID(req.CustomURL)
-		if existing != nil {
-			return nil, errors.New("custom URL already exists")
+		shortURL, err = s.createCustomShortURL(ctx, req, spec)
+	} else if s.hashKGS != nil {
+		shortURL, created, err = s.createHashedShortURL(ctx, req, spec)
+	} else {
+		shortURL, err = s.createGeneratedShortURL(ctx, req, spec)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Send analytics event for tracking; returning an existing short URL creates nothing
+	if created {
+		s.sendEvent(ctx, domain.AnalyticsEvent{
+			EventType:    "url_created",
+			ShortURL:     shortURL.ShortURL(),
+			LongURL:      shortURL.LongURL(),
+			UserMetadata: shortURL.UserMetadata(),
+			Timestamp:    time.Now(),
+		})
+	}
+
+	return &CreateShortURLResponse{
+		ShortURL: shortURL.ShortURL(),
+	}, nil
+}
+
+// checkDestination rejects long URLs pointing to this service and handles long URLs
+// pointing to other URL shorteners as configured by WithShortenerHosts. It returns the
+// destination to shorten, which differs from longURL when a shortener link was resolved.
+func (s *ShortURLService) checkDestination(ctx context.Context, longURL domain.LongURL) (domain.LongURL, error) {
+	for hops := 0; ; hops++ {
+		if s.ownHosts.Contains(longURL.Host()) {
+			return domain.LongURL{}, domain.ErrSelfReferencingURL
+		}
+		if s.shortenerHosts == nil || !s.shortenerHosts.Contains(longURL.Host()) {
+			return longURL, nil
+		}
+		if s.resolver == nil || hops == maxShortenerHops {
+			return domain.LongURL{}, domain.ErrShortenerURL
 		}
 
-		// Create entity with custom identifier
-		shortURL, err = domain.NewCustomShortURL(req.CustomURL, req.LongURL, req.Expiry, req.UserMetadata)
+		target, err := s.resolver.ResolveRedirect(ctx, longURL.String())
+		if err != nil {
+			if ctx.Err() != nil {
+				return domain.LongURL{}, ctx.Err()
+			}
+			return domain.LongURL{}, fmt.Errorf("%w: %v", domain.ErrUnresolvableURL, err)
+		}
+		next, err := domain.ParseLongURL(target, s.longURLPolicy)
 		if err != nil {
+			return domain.LongURL{}, err
+		}
+		if next == longURL {
+			// The shortener did not redirect, e.g. for an unknown or removed link
+			return domain.LongURL{}, domain.ErrUnresolvableURL
+		}
+		longURL = next
+	}
+}
+
+// parseRoutingRule validates a routing rule of a create request. Matching visitors are
+// sent to the rule's destination, so it gets the same checks as the long URL.
+func (s *ShortURLService) parseRoutingRule(ctx context.Context, rule RoutingRule, metadata map[string]interface{}) (domain.RoutingRule, error) {
+	if len(rule.Countries) > 0 && s.countries == nil {
+		return domain.RoutingRule{}, errCountriesDisabled
+	}
+	longURL, err := domain.ParseLongURL(rule.LongURL, s.longURLPolicy)
+	if err != nil {
+		return domain.RoutingRule{}, err
+	}
+	if longURL, err = s.checkDestination(ctx, longURL); err != nil {
+		return domain.RoutingRule{}, err
+	}
+	if s.isBlocked(ctx, "", longURL.String(), "create", metadata) {
+		return domain.RoutingRule{}, domain.ErrBlockedDestination
+	}
+	return domain.NewRoutingRule(rule.Devices, rule.Countries, rule.Languages, rule.Referrers, longURL)
+}
+
+// routingRuleError prefixes the message of a domain error with the position of the
+// routing rule it was raised for, keeping its kind and code. Other errors are returned as is.
+func routingRuleError(index int, err error) error {
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) {
+		return err
+	}
+	return domain.NewError(domainErr.Kind, domainErr.Code, fmt.Sprintf("routingRules[%d]: %s", index, domainErr.Message))
+}
+
+// createSpec holds the validated parts of a CreateShortURLRequest that the creation
+// helpers apply to the new entity.
+type createSpec struct {
+	longURL      domain.LongURL       // Normalized destination
+	preLaunchURL domain.LongURL       // Normalized pre-launch URL; zero if none
+	passwordHash string               // Encoded password hash; empty if the URL is not protected
+	routingRules []domain.RoutingRule // Validated routing rules in evaluation order
+}
+
+// createCustomShortURL creates and atomically inserts an entity with the user-supplied identifier.
+// Concurrent requests for the same custom URL are resolved by the repository: exactly one
+// insert succeeds and the others fail with a conflict instead of overwriting it.
+func (s *ShortURLService) createCustomShortURL(ctx context.Context, req CreateShortURLRequest, spec createSpec) (*domain.ShortURL, error) {
+	alias, err := domain.NewAlias(req.CustomURL, s.aliasPolicy)
+	if err != nil {
+		return nil, err
+	}
+	// An alias shaped like a generated ID is looked up in normalized form, so it is stored so
+	if id, _ := s.normalizeID(alias.String()); id != alias.String() {
+		if alias, err = domain.NewAlias(id, s.aliasPolicy); err != nil {
 			return nil, err
 		}
-	} else {
+	}
+	if s.blocklist != nil {
+		if err := s.blocklist.Check(alias.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	shortURL, err := domain.NewCustomShortURL(alias, spec.longURL, req.Expiry, req.UserMetadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.prepare(shortURL, req, spec); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Insert(ctx, shortURL); err != nil {
+		if errors.Is(err, domain.ErrShortURLAlreadyExists) {
+			return nil, domain.ErrCustomURLAlreadyExists
+		}
+		return nil, err
+	}
+	return shortURL, nil
+}
+
+// createGeneratedShortURL creates and atomically inserts an entity with a KGS-generated identifier.
+// If the generated identifier is already taken, a new one is requested, up to maxIDGenerationAttempts times.
+func (s *ShortURLService) createGeneratedShortURL(ctx context.Context, req CreateShortURLRequest, spec createSpec) (*domain.ShortURL, error) {
+	for attempt := 0; attempt < maxIDGenerationAttempts; attempt++ {
 		// Generate unique identifier using KGS
-		id, err := s.kgs.GenerateUniqueID()
+		id, err := s.kgs.GenerateUniqueID(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate unique ID: %w", err)
 		}
 
 		// Build complete short URL and create entity
-		shortURLStr := s.buildShortURL(id)
-		shortURL, err = domain.NewShortURL(id, req.LongURL, shortURLStr, req.Expiry, req.UserMetadata)
+		shortURL, err := domain.NewShortURL(id, spec.longURL, s.buildShortURL(id), req.Expiry, req.UserMetadata)
 		if err != nil {
 			return nil, err
 		}
+		if err := s.prepare(shortURL, req, spec); err != nil {
+			return nil, err
+		}
+
+		err = s.repo.Insert(ctx, shortURL)
+		if err == nil {
+			return shortURL, nil
+		}
+		if !errors.Is(err, domain.ErrShortURLAlreadyExists) {
+			return nil, err
+		}
+		// The generated identifier collided with an existing one; try the next one
 	}
 
-	// Persist the entity
-	if err := s.repo.Save(shortURL); err != nil {
-		return nil, err
+	return nil, fmt.Errorf("failed to generate unique ID: no free ID after %d attempts", maxIDGenerationAttempts)
+}
+
+// createHashedShortURL finds or inserts the entity for the long URL among the hash-derived
+// candidate identifiers, up to maxHashProbes of them. It reports whether a new entity was
+// inserted. A candidate that another request claims concurrently is looked up again, so
+// concurrent requests for the same destination agree on one identifier.
+func (s *ShortURLService) createHashedShortURL(ctx context.Context, req CreateShortURLRequest, spec createSpec) (*domain.ShortURL, bool, error) {
+	destination, err := s.hashKGS.NormalizeURL(spec.longURL.String())
+	if err != nil {
+		return nil, false, domain.ErrInvalidLongURL
+	}
+	candidates, err := s.hashKGS.CandidateIDs(spec.longURL.String(), maxHashProbes)
+	if err != nil {
+		return nil, false, domain.ErrInvalidLongURL
 	}
 
-	// Send analytics event for tracking
-	event := domain.AnalyticsEvent{
-		EventType:    "url_created",
-		ShortURL:     shortURL.ShortURL(),
-		LongURL:      shortURL.LongURL(),
-		UserMetadata: shortURL.UserMetadata(),
-		Timestamp:    time.Now(),
+	for _, id := range candidates {
+		if s.blocklist != nil && !s.blocklist.Allows(id) {
+			continue
+		}
+		existing, err := s.repo.FindByID(ctx, id)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing == nil {
+			shortURL, err := domain.NewShortURL(id, spec.longURL, s.buildShortURL(id), req.Expiry, req.UserMetadata)
+			if err != nil {
+				return nil, false, err
+			}
+			if err := s.prepare(shortURL, req, spec); err != nil {
+				return nil, false, err
+			}
+
+			err = s.repo.Insert(ctx, shortURL)
+			if err == nil {
+				return shortURL, true, nil
+			}
+			if !errors.Is(err, domain.ErrShortURLAlreadyExists) {
+				return nil, false, err
+			}
+			// Another request claimed the candidate first; check whether it was for this destination
+			if existing, err = s.repo.FindByID(ctx, id); err != nil {
+				return nil, false, err
+			}
+		}
+		if existing != nil && s.isReusable(existing, destination, req, spec) {
+			return existing, false, nil
+		}
+		// The candidate belongs to a different destination; probe the next window
 	}
-	// Note: Analytics errors are not critical and should not affect core functionality
-	_ = s.analytics.SendEvent(event)
 
-	return &CreateShortURLResponse{
-		ShortURL: shortURL.ShortURL(),
-	}, nil
+	return nil, false, fmt.Errorf("failed to generate unique ID: all %d hash candidates are taken", maxHashProbes)
+}
+
+// prepare applies the request's settings that NewShortURL does not take to a new entity
+// before it is inserted: the minting region, the click limit, the activation time, the
+// password and the routing rules.
+func (s *ShortURLService) prepare(shortURL *domain.ShortURL, req CreateShortURLRequest, spec createSpec) error {
+	shortURL.AssignRegion(s.localRegion)
+	shortURL.Protect(spec.passwordHash)
+	if err := shortURL.SetRoutingRules(spec.routingRules); err != nil {
+		return err
+	}
+	if req.MaxClicks > 0 {
+		if err := shortURL.LimitClicks(req.MaxClicks); err != nil {
+			return err
+		}
+	}
+	if req.NotBefore != nil {
+		return shortURL.Schedule(*req.NotBefore, spec.preLaunchURL)
+	}
+	return nil
+}
+
+// isReusable reports whether an existing short URL can be returned for a request to
+// shorten destination: it must point to the same normalized URL, still redirect and
+// expire at the same time as requested. Scheduled URLs are only shared with requests
+// for the same activation time and pre-launch URL. Click-limited, password-protected and
+// routed URLs are never shared, since every requester expects the full number of clicks,
+// their own password, or their own routing rules for themselves.
+func (s *ShortURLService) isReusable(existing *domain.ShortURL, destination string, req CreateShortURLRequest, spec createSpec) bool {
+	if !existing.IsActive() || existing.IsExpired() {
+		return false
+	}
+	if existing.MaxClicks() > 0 || req.MaxClicks > 0 || existing.IsProtected() || spec.passwordHash != "" {
+		return false
+	}
+	if len(existing.RoutingRules()) > 0 || len(spec.routingRules) > 0 {
+		return false
+	}
+	if !sameTime(existing.Expiry(), req.Expiry) || !sameTime(existing.NotBefore(), req.NotBefore) {
+		return false
+	}
+	if existing.PreLaunchURL() != spec.preLaunchURL.String() {
+		return false
+	}
+	normalized, err := s.hashKGS.NormalizeURL(existing.LongURL())
+	return err == nil && normalized == destination
+}
+
+// sameTime reports whether two optional times are both unset or both set to the same instant.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
 }
 
 // GetLongURL implements the URL resolution use case for redirection.
 // It extracts the identifier from the short URL, validates the entity's status,
 // tracks the access event, and returns the original URL for redirection.
+// Short URLs with routing rules return the destination of the first rule the visitor
+// matches, and record its position as "routing_rule" in the access event (0 for the
+// long URL).
 //
 // Parameters:
+//   - ctx: Request context; cancellation aborts the lookup
 //   - req: Request containing the short URL to resolve and context metadata
 //
 // Returns:
-//   - string: The original long URL for redirection
-//   - error: Error if URL not found, expired, or inactive
-func (s *ShortURLService) GetLongURL(req GetLongURLRequest) (string, error) {
+//   - string: The original long URL, or the destination of a routing rule, for redirection
+//   - error: domain.ErrShortURLNotFound, or the error of ShortURL.CheckAvailable
+//     (paused, deactivated, blocked, archived, exhausted or expired) if the URL cannot be used,
+//     *domain.NotYetActiveError before its activation time, domain.ErrPasswordRequired
+//     if it is password protected and req.UnlockToken is not valid for it,
+//     domain.ErrShortURLBlocked if its destination, or its pre-launch URL before the activation
+//     time, is blocklisted,
+//     *domain.ForeignRegionError if another region owns it, or a validation/system error
+func (s *ShortURLService) GetLongURL(ctx context.Context, req GetLongURLRequest) (string, error) {
 	// Validate required input
 	if req.ShortURL == "" {
-		return "", errors.New("shortUrl is required")
+		return "", errShortURLRequired
 	}
 
 	// Extract the identifier from the complete short URL
-	id := s.extractIDFromShortURL(req.ShortURL)
-	shortURL, err := s.repo.FindByID(id)
+	id, err := s.normalizeID(s.extractIDFromShortURL(req.ShortURL))
+	if err != nil {
+		return "", err
+	}
+	shortURL, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return "", err
 	}
 
-	// Check if URL exists
+	// Check if URL exists, locally or in the region that minted it
 	if shortURL == nil {
-		return "", errors.New("short URL not found")
+		if foreign := s.foreignRegion(id); foreign != nil {
+			return "", foreign
+		}
+		return "", domain.ErrShortURLNotFound
 	}
 
-	// Validate URL status and expiration
-	if !shortURL.IsActive() || shortURL.IsExpired() {
-		return "", errors.New("short URL is not active or expired")
+	// Validate lifecycle state and expiration
+	if err := shortURL.CheckAvailable(); err != nil {
+		// Visitors are sent to the pre-launch URL instead, which can be listed after creation too
+		var notYetActive *domain.NotYetActiveError
+		if errors.As(err, &notYetActive) && notYetActive.PreLaunchURL != "" &&
+			s.isBlocked(ctx, shortURL.ShortURL(), notYetActive.PreLaunchURL, "redirect", req.UserMetadata) {
+			return "", domain.ErrShortURLBlocked
+		}
+		return "", err
+	}
+	// Pick the destination for this visitor; without routing rules it is the long URL
+	destination, rule := shortURL.LongURL(), 0
+	routed := len(shortURL.RoutingRules()) > 0
+	if routed {
+		destination, rule = shortURL.Route(s.visitorOf(shortURL, req.UserMetadata))
+	}
+	// Destinations can be listed as malicious long after the short URL was created
+	if s.isBlocked(ctx, shortURL.ShortURL(), destination, "redirect", req.UserMetadata) {
+		return "", domain.ErrShortURLBlocked
+	}
+	// Password-protected URLs only redirect visitors who unlocked them
+	if shortURL.IsProtected() && !s.isUnlocked(req.UnlockToken, shortURL) {
+		return "", domain.ErrPasswordRequired
 	}
 
-	// Track access event for analytics
-	event := domain.AnalyticsEvent{
+	// Click-limited URLs count the click atomically, so concurrent visitors can never
+	// use more clicks than the limit allows
+	if shortURL.MaxClicks() > 0 {
+		exhausted := false
+		shortURL, err = s.repo.Update(ctx, id, func(u *domain.ShortURL) error {
+			var err error
+			exhausted, err = u.RecordClick(time.Now())
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+		if exhausted {
+			s.sendEvent(ctx, domain.AnalyticsEvent{
+				EventType:    "url_exhausted",
+				ShortURL:     shortURL.ShortURL(),
+				LongURL:      shortURL.LongURL(),
+				UserMetadata: map[string]interface{}{"max_clicks": shortURL.MaxClicks()},
+				Timestamp:    time.Now(),
+			})
+		}
+	}
+
+	// Track access event for analytics, recording which routing rule matched
+	metadata := req.UserMetadata
+	if routed {
+		metadata = make(map[string]interface{}, len(req.UserMetadata)+1)
+		for key, value := range req.UserMetadata {
+			metadata[key] = value
+		}
+		metadata["routing_rule"] = rule
+	}
+	s.sendEvent(ctx, domain.AnalyticsEvent{
 		EventType:    "url_accessed",
 		ShortURL:     shortURL.ShortURL(),
-		LongURL:      shortURL.LongURL(),
-		UserMetadata: req.UserMetadata,
+		LongURL:      destination,
+		UserMetadata: metadata,
 		Timestamp:    time.Now(),
+	})
+
+	return destination, nil
+}
+
+// visitorOf derives the routing attributes of a redirect request from its metadata.
+// The country is only looked up for short URLs with country conditions.
+func (s *ShortURLService) visitorOf(shortURL *domain.ShortURL, metadata map[string]interface{}) domain.Visitor {
+	country := ""
+	if s.countries != nil && routesByCountry(shortURL.RoutingRules()) {
+		if addr, ok := clientAddr(metadataString(metadata, "ip")); ok {
+			country = s.countries.Country(addr)
+		}
+	}
+	return domain.NewVisitor(
+		metadataString(metadata, "user_agent"),
+		metadataString(metadata, "accept_language"),
+		metadataString(metadata, "referer"),
+		country,
+	)
+}
+
+// routesByCountry reports whether any of the rules has a country condition.
+func routesByCountry(rules []domain.RoutingRule) bool {
+	for _, rule := range rules {
+		if len(rule.Countries) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAddr parses a client address given as "host:port", as in http.Request.RemoteAddr,
+// or as a bare IP address.
+func clientAddr(remote string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(remote); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(remote)
+	return addr.Unmap(), err == nil
+}
+
+// metadataString returns a string value of request metadata, or an empty string if the
+// key is missing or not a string.
+func metadataString(metadata map[string]interface{}, key string) string {
+	value, _ := metadata[key].(string)
+	return value
+}
+
+// UnlockShortURL implements the password check of password-protected short URLs.
+// On the right password it returns a short-lived token that GetLongURL accepts instead
+// of the password. Wrong passwords are counted per short URL, and once too many were
+// entered, every attempt fails until the lockout ends, even with the right password.
+// Every attempt sends a "url_unlocked" or "url_unlock_failed" analytics event.
+//
+// Parameters:
+//   - ctx: Request context; cancellation aborts the lookup
+//   - req: Request containing the short URL and the entered password
+//
+// Returns:
+//   - *UnlockShortURLResponse: The unlock token and its expiry; without a token if the URL
+//     is not protected
+//   - error: domain.ErrShortURLNotFound, the error of ShortURL.CheckAvailable,
+//     domain.ErrWrongPassword, domain.ErrTooManyAttempts, or a validation error
+func (s *ShortURLService) UnlockShortURL(ctx context.Context, req UnlockShortURLRequest) (*UnlockShortURLResponse, error) {
+	// Validate required input
+	if req.ShortURL == "" {
+		return nil, errShortURLRequired
+	}
+
+	id, err := s.normalizeID(s.extractIDFromShortURL(req.ShortURL))
+	if err != nil {
+		return nil, err
+	}
+	shortURL, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if shortURL == nil {
+		if foreign := s.foreignRegion(id); foreign != nil {
+			return nil, foreign
+		}
+		return nil, domain.ErrShortURLNotFound
+	}
+	if err := shortURL.CheckAvailable(); err != nil {
+		return nil, err
+	}
+	if !shortURL.IsProtected() {
+		return &UnlockShortURLResponse{}, nil
+	}
+	if s.passwords == nil || s.unlocks == nil {
+		return nil, errPasswordsDisabled
 	}
-	// Note: Analytics errors are not critical and should not affect core functionality
-	_ = s.analytics.SendEvent(event)
 
-	return shortURL.LongURL(), nil
+	// The attempt is counted before the slow hash check, so parallel guesses are throttled too
+	now := time.Now()
+	if s.attempts != nil && !s.attempts.Acquire(id, now) {
+		s.sendUnlockEvent(ctx, "url_unlock_failed", shortURL, "too_many_attempts", req.UserMetadata)
+		return nil, domain.ErrTooManyAttempts
+	}
+	if !s.passwords.Verify(shortURL.PasswordHash(), req.Password) {
+		s.sendUnlockEvent(ctx, "url_unlock_failed", shortURL, "wrong_password", req.UserMetadata)
+		return nil, domain.ErrWrongPassword
+	}
+	if s.attempts != nil {
+		s.attempts.Reset(id)
+	}
+
+	token, expiresAt := s.unlocks.Sign(id, shortURL.PasswordHash(), now)
+	s.sendUnlockEvent(ctx, "url_unlocked", shortURL, "", req.UserMetadata)
+	return &UnlockShortURLResponse{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// isUnlocked reports whether token proves that the visitor entered the current password
+// of the short URL.
+func (s *ShortURLService) isUnlocked(token string, shortURL *domain.ShortURL) bool {
+	return s.unlocks != nil && token != "" && s.unlocks.Verify(token, shortURL.ID(), shortURL.PasswordHash(), time.Now())
 }
 
-// GetAllShortURLs retrieves all short URLs for administrative purposes.
-// This method is typically used by admin interfaces to display URL statistics
-// and management information.
+// sendUnlockEvent sends an analytics event for an unlock attempt, recording why it
+// failed alongside the visitor's metadata.
+func (s *ShortURLService) sendUnlockEvent(ctx context.Context, eventType string, shortURL *domain.ShortURL, reason string, metadata map[string]interface{}) {
+	eventMetadata := make(map[string]interface{}, len(metadata)+1)
+	for key, value := range metadata {
+		eventMetadata[key] = value
+	}
+	if reason != "" {
+		eventMetadata["reason"] = reason
+	}
+	s.sendEvent(ctx, domain.AnalyticsEvent{
+		EventType:    eventType,
+		ShortURL:     shortURL.ShortURL(),
+		LongURL:      shortURL.LongURL(),
+		UserMetadata: eventMetadata,
+		Timestamp:    time.Now(),
+	})
+}
+
+// ListShortURLs retrieves one page of short URLs for administrative purposes.
+// Entries are ordered by creation time and can be filtered by status, creation time range,
+// long URL domain and user metadata. The returned cursor fetches the following page
+// with the same filters.
+//
+// Parameters:
+//   - ctx: Request context; cancellation aborts the listing
+//   - req: Filters, sort order, cursor and page size
 //
 // Returns:
-//   - []*ShortURLResponse: List of all short URLs with complete information
-//   - error: Repository error if data access fails
-func (s *ShortURLService) GetAllShortURLs() ([]*ShortURLResponse, error) {
-	// Retrieve all entities from repository
-	shortURLs, err := s.repo.FindAll()
+//   - *ListShortURLsResponse: The page of short URLs and the cursor for the next page
+//   - error: Validation error for invalid parameters, domain.ErrInvalidCursor,
+//     domain.ErrCursorMismatch if the cursor belongs to other filters or order, or a repository error
+func (s *ShortURLService) ListShortURLs(ctx context.Context, req ListShortURLsRequest) (*ListShortURLsResponse, error) {
+	query, err := buildQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.repo.FindPage(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 
 	// Convert domain entities to response DTOs
-	var responses []*ShortURLResponse
-	for _, shortURL := range shortURLs {
-		responses = append(responses, &ShortURLResponse{
-			ID:           shortURL.ID(),
-			LongURL:      shortURL.LongURL(),
-			ShortURL:     shortURL.ShortURL(),
-			CreatedAt:    shortURL.CreatedAt(),
-			Expiry:       shortURL.Expiry(),
-			IsActive:     shortURL.IsActive(),
-			UserMetadata: shortURL.UserMetadata(),
-		})
+	responses := make([]*ShortURLResponse, 0, len(page.Items))
+	for _, shortURL := range page.Items {
+		responses = append(responses, s.toResponse(shortURL))
 	}
 
-	return responses, nil
+	return &ListShortURLsResponse{
+		Items:      responses,
+		NextCursor: page.NextCursor,
+	}, nil
 }
 
-// DeactivateShortURL implements the URL deactivation use case.
-// This prevents the URL from being used for redirection while preserving
-// the record for analytics and audit purposes.
+// UpdateDestination implements the destination update use case, pointing an existing
+// short URL to a new long URL. The new destination passes the same checks as at
+// creation, the previous one is kept in the URL's history, and a "url_updated"
+// analytics event records both. Updating to the current destination changes nothing
+// and sends no event.
 //
 // Parameters:
-//   - id: The unique identifier of the URL to deactivate
+//   - ctx: Request context; cancellation aborts the operation
+//   - req: Identifier of the URL, the new destination and the acting user
 //
 // Returns:
-//   - error: Error if URL not found or persistence fails
-func (s *ShortURLService) DeactivateShortURL(id string) error {
+//   - *ShortURLResponse: The URL after the update, including its history
+//   - error: domain.ErrShortURLNotFound if the URL does not exist, a validation error
+//     for the new destination, or a persistence error
+func (s *ShortURLService) UpdateDestination(ctx context.Context, req UpdateDestinationRequest) (*ShortURLResponse, error) {
 	// Validate required input
-	if id == "" {
-		return errors.New("ID is required")
+	if req.ID == "" {
+		return nil, domain.ErrIDRequired
+	}
+	id, err := s.normalizeID(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	longURL, err := domain.ParseLongURL(req.LongURL, s.longURLPolicy)
+	if err != nil {
+		return nil, err
+	}
+	longURL, err = s.checkDestination(ctx, longURL)
+	if err != nil {
+		return nil, err
 	}
 
 	// Retrieve the entity
-	shortURL, err := s.repo.FindByID(id)
+	shortURL, err := s.repo.FindByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
 	if shortURL == nil {
-		return errors.New("short URL not found")
+		return nil, domain.ErrShortURLNotFound
+	}
+	if s.isBlocked(ctx, shortURL.ShortURL(), longURL.String(), "update", map[string]interface{}{"actor": req.Actor}) {
+		return nil, domain.ErrBlockedDestination
 	}
 
-	// Apply business operation
-	shortURL.Deactivate()
+	// Apply business operation atomically, so that clicks and state changes committed
+	// since the lookup are kept
+	var previous string
+	var unchanged *domain.ShortURL
+	shortURL, err = s.repo.Update(ctx, id, func(u *domain.ShortURL) error {
+		previous = u.LongURL()
+		changed, err := u.UpdateDestination(longURL, req.Actor, time.Now())
+		if err != nil {
+			return err
+		}
+		if !changed {
+			unchanged = u
+			return errNothingChanged
+		}
+		return nil
+	})
+	if errors.Is(err, errNothingChanged) {
+		return s.toResponse(unchanged), nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	// Persist the change
-	err = s.repo.Save(shortURL)
+	// Track update event
+	s.sendEvent(ctx, domain.AnalyticsEvent{
+		EventType: "url_updated",
+		ShortURL:  shortURL.ShortURL(),
+		LongURL:   shortURL.LongURL(),
+		UserMetadata: map[string]interface{}{
+			"previous_long_url": previous,
+			"actor":             req.Actor,
+		},
+		Timestamp: time.Now(),
+	})
+
+	return s.toResponse(shortURL), nil
+}
+
+// stateEvents names the analytics event sent when a URL enters each lifecycle state.
+var stateEvents = map[domain.LifecycleState]string{
+	domain.StateActive:      "url_activated",
+	domain.StatePaused:      "url_paused",
+	domain.StateDeactivated: "url_deactivated",
+	domain.StateBlocked:     "url_blocked",
+	domain.StateArchived:    "url_archived",
+}
+
+// ChangeState implements the lifecycle transition use case, moving a short URL to
+// another state such as paused, blocked or archived, or back to active. The domain
+// decides which transitions are allowed; every transition is recorded on the URL with
+// its reason and actor, and an analytics event named after the new state is sent.
+//
+// Parameters:
+//   - ctx: Request context; cancellation aborts the operation
+//   - req: Identifier of the URL, the target state, and the reason and actor
+//
+// Returns:
+//   - *ShortURLResponse: The URL after the transition, including its transition log
+//   - error: domain.ErrShortURLNotFound if the URL does not exist, domain.ErrInvalidState
+//     for an unknown state, an "invalid_state_transition" conflict if the lifecycle does
+//     not allow the change, or a persistence error
+func (s *ShortURLService) ChangeState(ctx context.Context, req ChangeStateRequest) (*ShortURLResponse, error) {
+	// Validate required input
+	if req.ID == "" {
+		return nil, domain.ErrIDRequired
+	}
+	id, err := s.normalizeID(req.ID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	state, err := domain.ParseLifecycleState(req.State)
+	if err != nil {
+		return nil, err
 	}
 
-	// Track deactivation event
-	event := domain.AnalyticsEvent{
-		EventType:    "url_deactivated",
+	// Apply business operation atomically, so that the transition is checked against the
+	// current state and clicks counted in the meantime are kept
+	var previous domain.LifecycleState
+	shortURL, err := s.repo.Update(ctx, id, func(u *domain.ShortURL) error {
+		previous = u.State()
+		return u.Transition(state, req.Reason, req.Actor, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Track the transition, keeping the URL's metadata alongside the transition details
+	metadata := make(map[string]interface{}, len(shortURL.UserMetadata())+3)
+	for key, value := range shortURL.UserMetadata() {
+		metadata[key] = value
+	}
+	metadata["previous_state"] = string(previous)
+	metadata["reason"] = req.Reason
+	metadata["actor"] = req.Actor
+	if state == domain.StateBlocked {
+		metadata["block_stage"] = "admin"
+	}
+	s.sendEvent(ctx, domain.AnalyticsEvent{
+		EventType:    stateEvents[state],
 		ShortURL:     shortURL.ShortURL(),
 		LongURL:      shortURL.LongURL(),
-		UserMetadata: shortURL.UserMetadata(),
+		UserMetadata: metadata,
 		Timestamp:    time.Now(),
+	})
+
+	return s.toResponse(shortURL), nil
+}
+
+// DeactivateShortURL implements the URL deactivation use case.
+// This prevents the URL from being used for redirection while preserving
+// the record for analytics and audit purposes. It is ChangeState to the
+// deactivated state without reason or actor. Deactivating a URL that is
+// already deactivated succeeds without changing it, so repeated deletes are idempotent.
+//
+// Parameters:
+//   - ctx: Request context; cancellation aborts the operation
+//   - id: The unique identifier of the URL to deactivate
+//
+// Returns:
+//   - error: domain.ErrShortURLNotFound if the URL does not exist, an
+//     "invalid_state_transition" conflict if it cannot be deactivated, or a persistence error
+func (s *ShortURLService) DeactivateShortURL(ctx context.Context, id string) error {
+	_, err := s.ChangeState(ctx, ChangeStateRequest{ID: id, State: string(domain.StateDeactivated)})
+	if !errors.Is(err, domain.ErrConflict) {
+		return err
 	}
-	// Note: Analytics errors are not critical and should not affect core functionality
-	_ = s.analytics.SendEvent(event)
 
-	return nil
+	// The transition was refused; that is only a failure if the URL is not deactivated yet
+	normalized, _ := s.normalizeID(id)
+	shortURL, findErr := s.repo.FindByID(ctx, normalized)
+	if findErr == nil && shortURL != nil && shortURL.State() == domain.StateDeactivated {
+		return nil
+	}
+	return err
+}
+
+// sendEvent forwards an analytics event for an operation that has already completed.
+// The event is sent with a context that is detached from the request's cancellation,
+// so a client disconnecting right after a successful operation does not drop its event.
+// Note: Analytics errors are not critical and should not affect core functionality
+func (s *ShortURLService) sendEvent(ctx context.Context, event domain.AnalyticsEvent) {
+	_ = s.analytics.SendEvent(context.WithoutCancel(ctx), event)
+}
+
+// isBlocked reports whether the destination blocklist matches longURL and, if so, sends
+// a "url_blocked" analytics event recording the stage (create or redirect) and the rule.
+func (s *ShortURLService) isBlocked(ctx context.Context, shortURL, longURL, stage string, metadata map[string]interface{}) bool {
+	if s.destinations == nil {
+		return false
+	}
+	rule, blocked := s.destinations.Match(longURL)
+	if !blocked {
+		return false
+	}
+
+	eventMetadata := make(map[string]interface{}, len(metadata)+2)
+	for key, value := range metadata {
+		eventMetadata[key] = value
+	}
+	eventMetadata["block_stage"] = stage
+	eventMetadata["block_rule"] = rule.String()
+	s.sendEvent(ctx, domain.AnalyticsEvent{
+		EventType:    "url_blocked",
+		ShortURL:     shortURL,
+		LongURL:      longURL,
+		UserMetadata: eventMetadata,
+		Timestamp:    time.Now(),
+	})
+	return true
+}
+
+// toResponse converts a domain entity into its administrative representation.
+func (s *ShortURLService) toResponse(shortURL *domain.ShortURL) *ShortURLResponse {
+	var history []DestinationChange
+	for _, change := range shortURL.History() {
+		history = append(history, DestinationChange{LongURL: change.LongURL, ChangedAt: change.ChangedAt, Actor: change.Actor})
+	}
+	var routingRules []RoutingRule
+	for _, rule := range shortURL.RoutingRules() {
+		devices := make([]string, 0, len(rule.Devices))
+		for _, device := range rule.Devices {
+			devices = append(devices, string(device))
+		}
+		routingRules = append(routingRules, RoutingRule{
+			Devices:   devices,
+			Countries: rule.Countries,
+			Languages: rule.Languages,
+			Referrers: rule.Referrers,
+			LongURL:   rule.LongURL,
+		})
+	}
+	var transitions []StateTransition
+	for _, transition := range shortURL.Transitions() {
+		transitions = append(transitions, StateTransition{
+			From:   string(transition.From),
+			To:     string(transition.To),
+			Reason: transition.Reason,
+			Actor:  transition.Actor,
+			At:     transition.At,
+		})
+	}
+
+	return &ShortURLResponse{
+		ID:                shortURL.ID(),
+		LongURL:           shortURL.LongURL(),
+		ShortURL:          shortURL.ShortURL(),
+		CreatedAt:         shortURL.CreatedAt(),
+		Expiry:            shortURL.Expiry(),
+		NotBefore:         shortURL.NotBefore(),
+		PreLaunchURL:      shortURL.PreLaunchURL(),
+		PasswordProtected: shortURL.IsProtected(),
+		RoutingRules:      routingRules,
+		IsActive:          shortURL.IsActive(),
+		State:             string(shortURL.State()),
+		MaxClicks:         shortURL.MaxClicks(),
+		Clicks:            shortURL.Clicks(),
+		UserMetadata:      shortURL.UserMetadata(),
+		Region:            s.regionName(shortURL.Region()),
+		History:           history,
+		Transitions:       transitions,
+	}
+}
+
+// normalizeID brings an incoming ID into the form it is stored in and rejects IDs that
+// no short URL can have without a repository lookup. The profile only applies to IDs with
+// the shape of a generated ID; custom URLs, which may use the alias policy's symbols and
+// may predate the profile, as well as region-prefixed IDs are passed through unchanged.
+// IDs are passed through unchanged when no profile is configured.
+func (s *ShortURLService) normalizeID(id string) (string, error) {
+	if s.idProfile == nil {
+		return id, nil
+	}
+	if normalized := s.idProfile.Normalize(id); s.idProfile.Matches(normalized) {
+		return normalized, nil
+	}
+	for _, c := range id {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') &&
+			(c >= 0x80 || !strings.ContainsRune(s.aliasPolicy.Symbols, c)) {
+			return "", domain.ErrInvalidShortID
+		}
+	}
+	return id, nil
+}
+
+// foreignRegion returns the routing error for an ID that is not stored locally but carries
+// the prefix of another routable region, or nil if the lookup should report not found.
+// The local store is always checked first, so custom URLs that happen to start with a
+// region code are still found.
+func (s *ShortURLService) foreignRegion(id string) *domain.ForeignRegionError {
+	if s.regions == nil {
+		return nil
+	}
+	region, ok := s.regions.RegionOfID(id)
+	if !ok || region.Code == s.localRegion || region.BaseURL == "" {
+		return nil
+	}
+	return &domain.ForeignRegionError{Region: region, ID: id}
+}
+
+// regionName returns the name of the region with the given code for display,
+// or the code itself if the region is unknown.
+func (s *ShortURLService) regionName(code string) string {
+	if s.regions != nil {
+		if region, ok := s.regions.Lookup(code); ok {
+			return region.Name
+		}
+	}
+	return code
 }
 
 // buildShortURL constructs the complete short URL by combining the base URL with the identifier.
@@ -242,10 +1251,57 @@ func (s *ShortURLService) buildShortURL(id string) string {
 
 // extractIDFromShortURL extracts the unique identifier from a complete short URL.
 // This is used to resolve short URLs back to their stored identifiers.
+// The identifier is the last path segment; a query string, fragment or trailing slash is ignored.
 func (s *ShortURLService) extractIDFromShortURL(shortURL string) string {
-	parts := strings.Split(shortURL, "/")
-	if len(parts) > 0 {
-		return parts[len(parts)-1]
+	if i := strings.IndexAny(shortURL, "?#"); i >= 0 {
+		shortURL = shortURL[:i]
 	}
-	return shortURL
+	shortURL = strings.TrimSuffix(shortURL, "/")
+	return shortURL[strings.LastIndex(shortURL, "/")+1:]
+}
+
+// buildQuery validates a listing request and converts it into a repository query.
+func buildQuery(req ListShortURLsRequest) (domain.ShortURLQuery, error) {
+	status := domain.ShortURLStatus(req.Status)
+	switch status {
+	case domain.StatusAny, domain.StatusActive, domain.StatusInactive, domain.StatusExpired, domain.StatusScheduled:
+	default:
+		return domain.ShortURLQuery{}, errInvalidStatus
+	}
+
+	order := domain.SortOrder(req.Order)
+	switch order {
+	case "":
+		order = domain.SortAscending
+	case domain.SortAscending, domain.SortDescending:
+	default:
+		return domain.ShortURLQuery{}, errInvalidOrder
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+	if limit < 0 || limit > maxPageLimit {
+		return domain.ShortURLQuery{}, errInvalidLimit
+	}
+
+	if req.CreatedFrom != nil && req.CreatedTo != nil && !req.CreatedFrom.Before(*req.CreatedTo) {
+		return domain.ShortURLQuery{}, errInvalidTimeRange
+	}
+	if req.MetadataValue != "" && req.MetadataKey == "" {
+		return domain.ShortURLQuery{}, errMetadataKeyNeeded
+	}
+
+	return domain.ShortURLQuery{
+		Status:        status,
+		CreatedFrom:   req.CreatedFrom,
+		CreatedTo:     req.CreatedTo,
+		LongURLDomain: req.Domain,
+		MetadataKey:   req.MetadataKey,
+		MetadataValue: req.MetadataValue,
+		Order:         order,
+		Cursor:        req.Cursor,
+		Limit:         limit,
+	}, nil
 }
@@ -1,20 +1,28 @@
 package app
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/netip"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/oharai/short-url/internal/shorturl/domain"
+	"github.com/oharai/short-url/internal/shorturl/infra"
 )
 
 // Mock implementations for testing
 
 type mockRepository struct {
-	data    map[string]*domain.ShortURL
-	saveErr error
-	findErr error
+	data      map[string]*domain.ShortURL
+	saveErr   error
+	findErr   error
+	lastQuery domain.ShortURLQuery
 }
 
 func newMockRepository() *mockRepository {
@@ -23,7 +31,7 @@ func newMockRepository() *mockRepository {
 	}
 }
 
-func (m *mockRepository) Save(shortURL *domain.ShortURL) error {
+func (m *mockRepository) Save(ctx context.Context, shortURL *domain.ShortURL) error {
 	if m.saveErr != nil {
 		return m.saveErr
 	}
@@ -31,7 +39,37 @@ func (m *mockRepository) Save(shortURL *domain.ShortURL) error {
 	return nil
 }
 
-func (m *mockRepository) FindByID(id string) (*domain.ShortURL, error) {
+func (m *mockRepository) Insert(ctx context.Context, shortURL *domain.ShortURL) error {
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	if _, exists := m.data[shortURL.ID()]; exists {
+		return domain.ErrShortURLAlreadyExists
+	}
+	m.data[shortURL.ID()] = shortURL
+	return nil
+}
+
+func (m *mockRepository) Update(ctx context.Context, id string, fn func(*domain.ShortURL) error) (*domain.ShortURL, error) {
+	if m.findErr != nil {
+		return nil, m.findErr
+	}
+	if m.saveErr != nil {
+		return nil, m.saveErr
+	}
+	existing, exists := m.data[id]
+	if !exists {
+		return nil, domain.ErrShortURLNotFound
+	}
+	updated := existing.Clone()
+	if err := fn(updated); err != nil {
+		return nil, err
+	}
+	m.data[id] = updated
+	return updated, nil
+}
+
+func (m *mockRepository) FindByID(ctx context.Context, id string) (*domain.ShortURL, error) {
 	if m.findErr != nil {
 		return nil, m.findErr
 	}
@@ -42,7 +80,7 @@ func (m *mockRepository) FindByID(id string) (*domain.ShortURL, error) {
 	return url, nil
 }
 
-func (m *mockRepository) FindAll() ([]*domain.ShortURL, error) {
+func (m *mockRepository) FindAll(ctx context.Context) ([]*domain.ShortURL, error) {
 	if m.findErr != nil {
 		return nil, m.findErr
 	}
@@ -53,7 +91,25 @@ func (m *mockRepository) FindAll() ([]*domain.ShortURL, error) {
 	return urls, nil
 }
 
-func (m *mockRepository) Delete(id string) error {
+func (m *mockRepository) FindPage(ctx context.Context, query domain.ShortURLQuery) (*domain.ShortURLPage, error) {
+	m.lastQuery = query
+	if m.findErr != nil {
+		return nil, m.findErr
+	}
+	page := &domain.ShortURLPage{}
+	for _, url := range m.data {
+		if query.Matches(url) {
+			page.Items = append(page.Items, url)
+		}
+	}
+	if len(page.Items) > query.Limit {
+		page.Items = page.Items[:query.Limit]
+		page.NextCursor = query.EncodeCursor(page.Items[query.Limit-1])
+	}
+	return page, nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, id string) error {
 	delete(m.data, id)
 	return nil
 }
@@ -67,7 +123,7 @@ func newMockKGS() *mockKGS {
 	return &mockKGS{counter: 1}
 }
 
-func (m *mockKGS) GenerateUniqueID() (string, error) {
+func (m *mockKGS) GenerateUniqueID(ctx context.Context) (string, error) {
 	if m.genErr != nil {
 		return "", m.genErr
 	}
@@ -76,21 +132,112 @@ func (m *mockKGS) GenerateUniqueID() (string, error) {
 	return id, nil
 }
 
-func (m *mockKGS) GetMultipleIDs(count int) ([]string, error) {
+func (m *mockKGS) GetMultipleIDs(ctx context.Context, count int) ([]string, error) {
 	if m.genErr != nil {
 		return nil, m.genErr
 	}
 	var ids []string
 	for i := 0; i < count; i++ {
-		id, _ := m.GenerateUniqueID()
+		id, _ := m.GenerateUniqueID(ctx)
 		ids = append(ids, id)
 	}
 	return ids, nil
 }
 
+type mockHashKGS struct {
+	candidates map[string][]string // Candidate IDs by normalized URL
+}
+
+func (m *mockHashKGS) NormalizeURL(longURL string) (string, error) {
+	return strings.ToLower(longURL), nil
+}
+
+func (m *mockHashKGS) CandidateIDs(longURL string, count int) ([]string, error) {
+	normalized, err := m.NormalizeURL(longURL)
+	if err != nil {
+		return nil, err
+	}
+	candidates := m.candidates[normalized]
+	return candidates[:min(count, len(candidates))], nil
+}
+
+type mockResolver struct {
+	redirects map[string]string // Redirect targets by URL
+	err       error
+	calls     int
+}
+
+func (m *mockResolver) ResolveRedirect(ctx context.Context, url string) (string, error) {
+	m.calls++
+	if m.err != nil {
+		return "", m.err
+	}
+	if target, ok := m.redirects[url]; ok {
+		return target, nil
+	}
+	return url, nil
+}
+
+type mockDestinationBlocklist struct {
+	rules *domain.DestinationRules // Rules in effect; replaceable to simulate a reload
+}
+
+func (m *mockDestinationBlocklist) Match(longURL string) (domain.DestinationRule, bool) {
+	return m.rules.Match(longURL)
+}
+
+// mockPasswordHasher "hashes" by prefixing, so tests do not pay for a slow hash.
+type mockPasswordHasher struct{}
+
+func (mockPasswordHasher) Hash(password string) (string, error) {
+	return "hashed:" + password, nil
+}
+
+func (mockPasswordHasher) Verify(encodedHash, password string) bool {
+	return encodedHash == "hashed:"+password
+}
+
+// mockUnlockSigner issues tokens naming the ID and password hash; tokens never expire.
+type mockUnlockSigner struct{}
+
+func (mockUnlockSigner) Sign(id, passwordHash string, now time.Time) (string, time.Time) {
+	return "unlocked:" + id + ":" + passwordHash, now.Add(time.Minute)
+}
+
+func (mockUnlockSigner) Verify(token, id, passwordHash string, now time.Time) bool {
+	return token == "unlocked:"+id+":"+passwordHash
+}
+
+// mockAttemptLimiter refuses a key after maxFailures attempts until it is reset.
+type mockAttemptLimiter struct {
+	maxFailures int
+	failures    map[string]int
+}
+
+func (m *mockAttemptLimiter) Acquire(key string, now time.Time) bool {
+	if m.failures[key] >= m.maxFailures {
+		return false
+	}
+	m.failures[key]++
+	return true
+}
+
+func (m *mockAttemptLimiter) Reset(key string) {
+	delete(m.failures, key)
+}
+
+// mockCountryResolver locates the IP addresses it knows.
+type mockCountryResolver map[string]string
+
+func (m mockCountryResolver) Country(ip netip.Addr) string {
+	return m[ip.String()]
+}
+
 type mockAnalytics struct {
+	mu      sync.Mutex // Guards the fields below for tests sending events concurrently
 	events  []domain.AnalyticsEvent
 	sendErr error
+	lastCtx context.Context
 }
 
 func newMockAnalytics() *mockAnalytics {
@@ -99,7 +246,10 @@ func newMockAnalytics() *mockAnalytics {
 	}
 }
 
-func (m *mockAnalytics) SendEvent(event domain.AnalyticsEvent) error {
+func (m *mockAnalytics) SendEvent(ctx context.Context, event domain.AnalyticsEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastCtx = ctx
 	if m.sendErr != nil {
 		return m.sendErr
 	}
@@ -190,12 +340,47 @@ func TestShortURLService_CreateShortURL(t *testing.T) {
 				CustomURL: "existing-url",
 			},
 			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
-				existingURL, _ := domain.NewCustomShortURL("existing-url", "https://old.com", nil, nil)
-				repo.Save(existingURL)
+				alias, _ := domain.NewAlias("existing-url", domain.DefaultAliasPolicy())
+				existingURL, _ := domain.NewCustomShortURL(alias, domain.MustParseLongURL("https://old.com"), nil, nil)
+				repo.Save(context.Background(), existingURL)
 			},
 			expectError: true,
 			errorMsg:    "custom URL already exists",
 		},
+		{
+			name: "generated ID collision is retried",
+			request: CreateShortURLRequest{
+				LongURL: "https://example.com",
+			},
+			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
+				existingURL, _ := domain.NewShortURL("test0001", domain.MustParseLongURL("https://old.com"), "http://test.com/test0001", nil, nil)
+				repo.Save(context.Background(), existingURL)
+			},
+			expectError: false,
+			validate: func(t *testing.T, resp *CreateShortURLResponse, repo *mockRepository, analytics *mockAnalytics) {
+				if resp.ShortURL != "http://test.com/test0002" {
+					t.Errorf("expected short URL 'http://test.com/test0002', got %q", resp.ShortURL)
+				}
+				if repo.data["test0001"].LongURL() != "https://old.com" {
+					t.Error("expected existing URL not to be overwritten")
+				}
+			},
+		},
+		{
+			name: "generated ID collisions exhaust retries",
+			request: CreateShortURLRequest{
+				LongURL: "https://example.com",
+			},
+			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
+				for i := 1; i <= maxIDGenerationAttempts; i++ {
+					id := "test000" + string(rune('0'+i))
+					existingURL, _ := domain.NewShortURL(id, domain.MustParseLongURL("https://old.com"), "http://test.com/"+id, nil, nil)
+					repo.Save(context.Background(), existingURL)
+				}
+			},
+			expectError: true,
+			errorMsg:    "failed to generate unique ID: no free ID after 5 attempts",
+		},
 		{
 			name: "KGS generation error",
 			request: CreateShortURLRequest{
@@ -229,7 +414,7 @@ func TestShortURLService_CreateShortURL(t *testing.T) {
 
 			tt.setupMocks(repo, kgs, analytics)
 
-			resp, err := service.CreateShortURL(tt.request)
+			resp, err := service.CreateShortURL(context.Background(), tt.request)
 
 			if tt.expectError {
 				if err == nil {
@@ -272,8 +457,8 @@ func TestShortURLService_GetLongURL(t *testing.T) {
 				},
 			},
 			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
-				shortURL, _ := domain.NewShortURL("abc123", "https://example.com", "http://test.com/abc123", nil, nil)
-				repo.Save(shortURL)
+				shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://test.com/abc123", nil, nil)
+				repo.Save(context.Background(), shortURL)
 			},
 			expectError: false,
 			expectedURL: "https://example.com",
@@ -302,12 +487,51 @@ func TestShortURLService_GetLongURL(t *testing.T) {
 				ShortURL: "http://test.com/inactive",
 			},
 			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
-				shortURL, _ := domain.NewShortURL("inactive", "https://example.com", "http://test.com/inactive", nil, nil)
+				shortURL, _ := domain.NewShortURL("inactive", domain.MustParseLongURL("https://example.com"), "http://test.com/inactive", nil, nil)
 				shortURL.Deactivate()
-				repo.Save(shortURL)
+				repo.Save(context.Background(), shortURL)
+			},
+			expectError: true,
+			errorMsg:    "short URL is not active",
+		},
+		{
+			name: "paused URL",
+			request: GetLongURLRequest{
+				ShortURL: "http://test.com/paused",
+			},
+			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
+				shortURL, _ := domain.NewShortURL("paused", domain.MustParseLongURL("https://example.com"), "http://test.com/paused", nil, nil)
+				shortURL.Transition(domain.StatePaused, "", "", time.Now())
+				repo.Save(context.Background(), shortURL)
+			},
+			expectError: true,
+			errorMsg:    "short URL is paused",
+		},
+		{
+			name: "blocked URL",
+			request: GetLongURLRequest{
+				ShortURL: "http://test.com/blocked",
+			},
+			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
+				shortURL, _ := domain.NewShortURL("blocked", domain.MustParseLongURL("https://example.com"), "http://test.com/blocked", nil, nil)
+				shortURL.Transition(domain.StateBlocked, "", "", time.Now())
+				repo.Save(context.Background(), shortURL)
+			},
+			expectError: true,
+			errorMsg:    "short URL destination is blocked as malicious",
+		},
+		{
+			name: "archived URL",
+			request: GetLongURLRequest{
+				ShortURL: "http://test.com/archived",
+			},
+			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
+				shortURL, _ := domain.NewShortURL("archived", domain.MustParseLongURL("https://example.com"), "http://test.com/archived", nil, nil)
+				shortURL.Transition(domain.StateArchived, "", "", time.Now())
+				repo.Save(context.Background(), shortURL)
 			},
 			expectError: true,
-			errorMsg:    "short URL is not active or expired",
+			errorMsg:    "short URL has been archived",
 		},
 		{
 			name: "expired URL",
@@ -316,11 +540,11 @@ func TestShortURLService_GetLongURL(t *testing.T) {
 			},
 			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
 				expiry := time.Now().Add(-24 * time.Hour)
-				shortURL, _ := domain.NewShortURL("expired", "https://example.com", "http://test.com/expired", &expiry, nil)
-				repo.Save(shortURL)
+				shortURL, _ := domain.NewShortURL("expired", domain.MustParseLongURL("https://example.com"), "http://test.com/expired", &expiry, nil)
+				repo.Save(context.Background(), shortURL)
 			},
 			expectError: true,
-			errorMsg:    "short URL is not active or expired",
+			errorMsg:    "short URL has expired",
 		},
 		{
 			name: "repository error",
@@ -344,7 +568,7 @@ func TestShortURLService_GetLongURL(t *testing.T) {
 
 			tt.setupMocks(repo, kgs, analytics)
 
-			longURL, err := service.GetLongURL(tt.request)
+			longURL, err := service.GetLongURL(context.Background(), tt.request)
 
 			if tt.expectError {
 				if err == nil {
@@ -376,53 +600,149 @@ func TestShortURLService_GetLongURL(t *testing.T) {
 	}
 }
 
-func TestShortURLService_GetAllShortURLs(t *testing.T) {
+func TestShortURLService_ListShortURLs(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
 	tests := []struct {
-		name        string
-		setupMocks  func(*mockRepository, *mockKGS, *mockAnalytics)
-		expectError bool
-		expectCount int
+		name          string
+		req           ListShortURLsRequest
+		setupMocks    func(*mockRepository)
+		expectedError string
+		expectCount   int
+		expectCursor  bool
+		checkQuery    func(*testing.T, domain.ShortURLQuery)
 	}{
 		{
-			name: "successful retrieval",
-			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
-				url1, _ := domain.NewShortURL("abc123", "https://example1.com", "http://test.com/abc123", nil, nil)
-				url2, _ := domain.NewShortURL("def456", "https://example2.com", "http://test.com/def456", nil, nil)
-				repo.Save(url1)
-				repo.Save(url2)
+			name: "defaults",
+			setupMocks: func(repo *mockRepository) {
+				url1, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example1.com"), "http://test.com/abc123", nil, nil)
+				url2, _ := domain.NewShortURL("def456", domain.MustParseLongURL("https://example2.com"), "http://test.com/def456", nil, nil)
+				repo.Save(context.Background(), url1)
+				repo.Save(context.Background(), url2)
 			},
-			expectError: false,
 			expectCount: 2,
+			checkQuery: func(t *testing.T, q domain.ShortURLQuery) {
+				if q.Limit != defaultPageLimit {
+					t.Errorf("expected default limit %d, got %d", defaultPageLimit, q.Limit)
+				}
+				if q.Order != domain.SortAscending {
+					t.Errorf("expected ascending order, got %q", q.Order)
+				}
+			},
 		},
 		{
-			name:        "empty repository",
-			setupMocks:  func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {},
-			expectError: false,
-			expectCount: 0,
+			name: "filters are passed to the repository",
+			req: ListShortURLsRequest{
+				Status:        "inactive",
+				CreatedFrom:   &from,
+				CreatedTo:     &to,
+				Domain:        "example.com",
+				MetadataKey:   "campaign",
+				MetadataValue: "spring",
+				Order:         "desc",
+				Cursor:        "cursor",
+				Limit:         10,
+			},
+			setupMocks: func(repo *mockRepository) {},
+			checkQuery: func(t *testing.T, q domain.ShortURLQuery) {
+				want := domain.ShortURLQuery{
+					Status:        domain.StatusInactive,
+					CreatedFrom:   &from,
+					CreatedTo:     &to,
+					LongURLDomain: "example.com",
+					MetadataKey:   "campaign",
+					MetadataValue: "spring",
+					Order:         domain.SortDescending,
+					Cursor:        "cursor",
+					Limit:         10,
+				}
+				if q != want {
+					t.Errorf("expected query %+v, got %+v", want, q)
+				}
+			},
+		},
+		{
+			name: "next cursor is returned",
+			req:  ListShortURLsRequest{Limit: 1},
+			setupMocks: func(repo *mockRepository) {
+				url1, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example1.com"), "http://test.com/abc123", nil, nil)
+				url2, _ := domain.NewShortURL("def456", domain.MustParseLongURL("https://example2.com"), "http://test.com/def456", nil, nil)
+				repo.Save(context.Background(), url1)
+				repo.Save(context.Background(), url2)
+			},
+			expectCount:  1,
+			expectCursor: true,
+		},
+		{
+			name:       "scheduled status",
+			req:        ListShortURLsRequest{Status: "scheduled"},
+			setupMocks: func(repo *mockRepository) {},
+			checkQuery: func(t *testing.T, q domain.ShortURLQuery) {
+				if q.Status != domain.StatusScheduled {
+					t.Errorf("expected status scheduled, got %q", q.Status)
+				}
+			},
+		},
+		{
+			name:          "invalid status",
+			req:           ListShortURLsRequest{Status: "deleted"},
+			setupMocks:    func(repo *mockRepository) {},
+			expectedError: "status must be one of active, inactive, expired or scheduled",
+		},
+		{
+			name:          "invalid order",
+			req:           ListShortURLsRequest{Order: "up"},
+			setupMocks:    func(repo *mockRepository) {},
+			expectedError: "order must be asc or desc",
+		},
+		{
+			name:          "limit too large",
+			req:           ListShortURLsRequest{Limit: maxPageLimit + 1},
+			setupMocks:    func(repo *mockRepository) {},
+			expectedError: "limit must be between 1 and 1000",
+		},
+		{
+			name:          "negative limit",
+			req:           ListShortURLsRequest{Limit: -1},
+			setupMocks:    func(repo *mockRepository) {},
+			expectedError: "limit must be between 1 and 1000",
+		},
+		{
+			name:          "empty time range",
+			req:           ListShortURLsRequest{CreatedFrom: &to, CreatedTo: &from},
+			setupMocks:    func(repo *mockRepository) {},
+			expectedError: "createdFrom must be before createdTo",
+		},
+		{
+			name:          "metadata value without key",
+			req:           ListShortURLsRequest{MetadataValue: "spring"},
+			setupMocks:    func(repo *mockRepository) {},
+			expectedError: "metadataValue requires metadataKey",
 		},
 		{
 			name: "repository error",
-			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
+			setupMocks: func(repo *mockRepository) {
 				repo.findErr = errors.New("database error")
 			},
-			expectError: true,
+			expectedError: "database error",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := newMockRepository()
-			kgs := newMockKGS()
-			analytics := newMockAnalytics()
-			service := NewShortURLService(repo, kgs, analytics, "http://test.com")
+			service := NewShortURLService(repo, newMockKGS(), newMockAnalytics(), "http://test.com")
 
-			tt.setupMocks(repo, kgs, analytics)
+			tt.setupMocks(repo)
 
-			urls, err := service.GetAllShortURLs()
+			resp, err := service.ListShortURLs(context.Background(), tt.req)
 
-			if tt.expectError {
+			if tt.expectedError != "" {
 				if err == nil {
 					t.Error("expected error but got none")
+				} else if err.Error() != tt.expectedError {
+					t.Errorf("expected error %q, got %q", tt.expectedError, err.Error())
 				}
 				return
 			}
@@ -432,8 +752,14 @@ func TestShortURLService_GetAllShortURLs(t *testing.T) {
 				return
 			}
 
-			if len(urls) != tt.expectCount {
-				t.Errorf("expected %d URLs, got %d", tt.expectCount, len(urls))
+			if len(resp.Items) != tt.expectCount {
+				t.Errorf("expected %d URLs, got %d", tt.expectCount, len(resp.Items))
+			}
+			if (resp.NextCursor != "") != tt.expectCursor {
+				t.Errorf("expected next cursor present=%v, got %q", tt.expectCursor, resp.NextCursor)
+			}
+			if tt.checkQuery != nil {
+				tt.checkQuery(t, repo.lastQuery)
 			}
 		})
 	}
@@ -446,13 +772,14 @@ func TestShortURLService_DeactivateShortURL(t *testing.T) {
 		setupMocks  func(*mockRepository, *mockKGS, *mockAnalytics)
 		expectError bool
 		errorMsg    string
+		noEvent     bool // Whether no analytics event is expected
 	}{
 		{
 			name: "successful deactivation",
 			id:   "abc123",
 			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
-				shortURL, _ := domain.NewShortURL("abc123", "https://example.com", "http://test.com/abc123", nil, nil)
-				repo.Save(shortURL)
+				shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://test.com/abc123", nil, nil)
+				repo.Save(context.Background(), shortURL)
 			},
 			expectError: false,
 		},
@@ -483,7 +810,7 @@ func TestShortURLService_DeactivateShortURL(t *testing.T) {
 			name: "repository save error",
 			id:   "abc123",
 			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
-				shortURL, _ := domain.NewShortURL("abc123", "https://example.com", "http://test.com/abc123", nil, nil)
+				shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://test.com/abc123", nil, nil)
 				repo.data["abc123"] = shortURL
 				repo.findErr = nil // Allow find to succeed
 				repo.saveErr = errors.New("save failed")
@@ -491,6 +818,28 @@ func TestShortURLService_DeactivateShortURL(t *testing.T) {
 			expectError: true,
 			errorMsg:    "save failed",
 		},
+		{
+			name: "already deactivated",
+			id:   "abc123",
+			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
+				shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://test.com/abc123", nil, nil)
+				shortURL.Deactivate()
+				repo.data["abc123"] = shortURL
+			},
+			expectError: false,
+			noEvent:     true,
+		},
+		{
+			name: "archived URL",
+			id:   "abc123",
+			setupMocks: func(repo *mockRepository, kgs *mockKGS, analytics *mockAnalytics) {
+				shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://test.com/abc123", nil, nil)
+				shortURL.Transition(domain.StateArchived, "", "", time.Now())
+				repo.data["abc123"] = shortURL
+			},
+			expectError: true,
+			errorMsg:    "cannot change state from archived to deactivated",
+		},
 	}
 
 	for _, tt := range tests {
@@ -502,7 +851,7 @@ func TestShortURLService_DeactivateShortURL(t *testing.T) {
 
 			tt.setupMocks(repo, kgs, analytics)
 
-			err := service.DeactivateShortURL(tt.id)
+			err := service.DeactivateShortURL(context.Background(), tt.id)
 
 			if tt.expectError {
 				if err == nil {
@@ -526,7 +875,11 @@ func TestShortURLService_DeactivateShortURL(t *testing.T) {
 			}
 
 			// Check analytics event was sent
-			if len(analytics.events) != 1 {
+			if tt.noEvent {
+				if len(analytics.events) != 0 {
+					t.Errorf("expected no analytics event, got %d", len(analytics.events))
+				}
+			} else if len(analytics.events) != 1 {
 				t.Errorf("expected 1 analytics event, got %d", len(analytics.events))
 			} else if analytics.events[0].EventType != "url_deactivated" {
 				t.Errorf("expected event type 'url_deactivated', got %q", analytics.events[0].EventType)
@@ -535,63 +888,1595 @@ func TestShortURLService_DeactivateShortURL(t *testing.T) {
 	}
 }
 
-func TestShortURLService_buildShortURL(t *testing.T) {
-	service := &ShortURLService{baseURL: "http://test.com"}
-
+func TestShortURLService_UpdateDestination(t *testing.T) {
 	tests := []struct {
-		name     string
-		id       string
-		expected string
+		name         string
+		req          UpdateDestinationRequest
+		setupMocks   func(*mockRepository)
+		expectedErr  error
+		expectedCode string
 	}{
 		{
-			name:     "simple ID",
-			id:       "abc123",
-			expected: "http://test.com/abc123",
+			name: "successful update",
+			req:  UpdateDestinationRequest{ID: "abc123", LongURL: "https://example.com/new", Actor: "marketing"},
 		},
 		{
-			name:     "base URL with trailing slash",
-			id:       "def456",
-			expected: "http://test.com/def456",
+			name:        "empty ID",
+			req:         UpdateDestinationRequest{LongURL: "https://example.com/new"},
+			expectedErr: domain.ErrIDRequired,
+		},
+		{
+			name:        "URL not found",
+			req:         UpdateDestinationRequest{ID: "notfound", LongURL: "https://example.com/new"},
+			expectedErr: domain.ErrShortURLNotFound,
+		},
+		{
+			name:        "empty destination",
+			req:         UpdateDestinationRequest{ID: "abc123"},
+			expectedErr: domain.ErrLongURLRequired,
+		},
+		{
+			name:         "invalid destination",
+			req:          UpdateDestinationRequest{ID: "abc123", LongURL: "javascript:alert(1)"},
+			expectedCode: "long_url_scheme_not_allowed",
+		},
+		{
+			name:         "self-referencing destination",
+			req:          UpdateDestinationRequest{ID: "abc123", LongURL: "http://test.com/xyz789"},
+			expectedCode: "long_url_self_reference",
+		},
+		{
+			name: "repository save error",
+			req:  UpdateDestinationRequest{ID: "abc123", LongURL: "https://example.com/new"},
+			setupMocks: func(repo *mockRepository) {
+				repo.saveErr = errors.New("save failed")
+			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.name == "base URL with trailing slash" {
-				service.baseURL = "http://test.com/"
+			repo := newMockRepository()
+			analytics := newMockAnalytics()
+			service := NewShortURLService(repo, newMockKGS(), analytics, "http://test.com")
+			shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com/old"), "http://test.com/abc123", nil, nil)
+			repo.data["abc123"] = shortURL
+			if tt.setupMocks != nil {
+				tt.setupMocks(repo)
 			}
 
-			result := service.buildShortURL(tt.id)
-			if result != tt.expected {
-				t.Errorf("expected %q, got %q", tt.expected, result)
+			resp, err := service.UpdateDestination(context.Background(), tt.req)
+			if repo.saveErr != nil {
+				if err == nil || err.Error() != "save failed" {
+					t.Errorf("expected save error, got %v", err)
+				}
+				if len(analytics.events) != 0 {
+					t.Errorf("expected no analytics event, got %d", len(analytics.events))
+				}
+				return
+			}
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if tt.expectedCode != "" {
+				var domainErr *domain.Error
+				if !errors.As(err, &domainErr) || domainErr.Code != tt.expectedCode {
+					t.Errorf("expected error code %q, got %v", tt.expectedCode, err)
+				}
+				if repo.data["abc123"].LongURL() != "https://example.com/old" {
+					t.Error("expected destination to be unchanged")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if resp.LongURL != "https://example.com/new" || repo.data["abc123"].LongURL() != "https://example.com/new" {
+				t.Errorf("expected new destination, got %q", resp.LongURL)
+			}
+			if len(resp.History) != 1 || resp.History[0].LongURL != "https://example.com/old" || resp.History[0].Actor != "marketing" {
+				t.Errorf("unexpected history %+v", resp.History)
+			}
+			if len(analytics.events) != 1 {
+				t.Fatalf("expected 1 analytics event, got %d", len(analytics.events))
+			}
+			event := analytics.events[0]
+			if event.EventType != "url_updated" || event.LongURL != "https://example.com/new" ||
+				event.UserMetadata["previous_long_url"] != "https://example.com/old" || event.UserMetadata["actor"] != "marketing" {
+				t.Errorf("unexpected event %+v", event)
 			}
 		})
 	}
 }
 
-func TestShortURLService_extractIDFromShortURL(t *testing.T) {
-	service := &ShortURLService{}
+func TestShortURLService_UpdateDestination_Unchanged(t *testing.T) {
+	repo := newMockRepository()
+	analytics := newMockAnalytics()
+	service := NewShortURLService(repo, newMockKGS(), analytics, "http://test.com")
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com/page"), "http://test.com/abc123", nil, nil)
+	repo.data["abc123"] = shortURL
+
+	// The new destination is normalized before it is compared
+	resp, err := service.UpdateDestination(context.Background(), UpdateDestinationRequest{ID: "abc123", LongURL: "HTTPS://Example.com:443/page"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.History) != 0 || len(analytics.events) != 0 {
+		t.Errorf("expected no change, got history %+v and %d events", resp.History, len(analytics.events))
+	}
+}
+
+func TestShortURLService_UpdateDestination_Concurrent(t *testing.T) {
+	// The memory repository hands out its stored entities, so updating them in place
+	// would race with redirects reading them; run with -race to catch that
+	service := NewShortURLService(infra.NewMemoryShortURLRepository(), newMockKGS(), newMockAnalytics(), "http://test.com")
+	if _, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com/0", CustomURL: "moving"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const updates = 20
+	var wg sync.WaitGroup
+	for i := 1; i <= updates; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/moving"}); err != nil {
+				t.Errorf("unexpected redirect error: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			req := UpdateDestinationRequest{ID: "moving", LongURL: fmt.Sprintf("https://example.com/%d", i)}
+			if _, err := service.UpdateDestination(context.Background(), req); err != nil {
+				t.Errorf("unexpected update error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every update lands in the history; none overwrites another
+	list, err := service.ListShortURLs(context.Background(), ListShortURLsRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if history := list.Items[0].History; len(history) != updates {
+		t.Errorf("expected %d history entries, got %d", updates, len(history))
+	}
+}
+
+func TestShortURLService_UpdateDestination_Blocklist(t *testing.T) {
+	rules, _ := domain.NewDestinationRules(domain.DestinationRule{Kind: domain.RuleHost, Pattern: "evil.example"})
+	analytics := newMockAnalytics()
+	repo := newMockRepository()
+	service := NewShortURLService(repo, newMockKGS(), analytics, "http://test.com", WithDestinationBlocklist(&mockDestinationBlocklist{rules: rules}))
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com/page"), "http://test.com/abc123", nil, nil)
+	repo.data["abc123"] = shortURL
 
+	_, err := service.UpdateDestination(context.Background(), UpdateDestinationRequest{ID: "abc123", LongURL: "https://evil.example/", Actor: "bob"})
+	if !errors.Is(err, domain.ErrBlockedDestination) {
+		t.Fatalf("expected ErrBlockedDestination, got %v", err)
+	}
+	if len(analytics.events) != 1 || analytics.events[0].UserMetadata["block_stage"] != "update" || analytics.events[0].UserMetadata["actor"] != "bob" {
+		t.Errorf("unexpected events %+v", analytics.events)
+	}
+	if shortURL.LongURL() != "https://example.com/page" {
+		t.Error("expected destination to be unchanged")
+	}
+}
+
+func TestShortURLService_ChangeState(t *testing.T) {
 	tests := []struct {
-		name     string
-		shortURL string
-		expected string
+		name          string
+		initial       domain.LifecycleState
+		req           ChangeStateRequest
+		expectedErr   error
+		expectedCode  string
+		expectedEvent string
 	}{
 		{
-			name:     "full URL",
-			shortURL: "http://test.com/abc123",
-			expected: "abc123",
+			name:          "pause",
+			initial:       domain.StateActive,
+			req:           ChangeStateRequest{ID: "abc123", State: "paused", Reason: "campaign over", Actor: "marketing"},
+			expectedEvent: "url_paused",
 		},
 		{
-			name:     "just ID",
-			shortURL: "abc123",
-			expected: "abc123",
+			name:          "reactivate",
+			initial:       domain.StateDeactivated,
+			req:           ChangeStateRequest{ID: "abc123", State: "active", Reason: "false alarm", Actor: "support"},
+			expectedEvent: "url_activated",
 		},
 		{
-			name:     "URL with path",
-			shortURL: "http://test.com/path/def456",
+			name:          "block",
+			initial:       domain.StatePaused,
+			req:           ChangeStateRequest{ID: "abc123", State: "blocked", Reason: "phishing report", Actor: "trust-team"},
+			expectedEvent: "url_blocked",
+		},
+		{
+			name:          "archive",
+			initial:       domain.StateBlocked,
+			req:           ChangeStateRequest{ID: "abc123", State: "archived", Actor: "ops"},
+			expectedEvent: "url_archived",
+		},
+		{
+			name:         "archived is final",
+			initial:      domain.StateArchived,
+			req:          ChangeStateRequest{ID: "abc123", State: "active"},
+			expectedCode: "invalid_state_transition",
+		},
+		{
+			name:         "same state",
+			initial:      domain.StatePaused,
+			req:          ChangeStateRequest{ID: "abc123", State: "paused"},
+			expectedCode: "invalid_state_transition",
+		},
+		{
+			name:        "unknown state",
+			initial:     domain.StateActive,
+			req:         ChangeStateRequest{ID: "abc123", State: "deleted"},
+			expectedErr: domain.ErrInvalidState,
+		},
+		{
+			name:        "empty ID",
+			initial:     domain.StateActive,
+			req:         ChangeStateRequest{State: "paused"},
+			expectedErr: domain.ErrIDRequired,
+		},
+		{
+			name:        "URL not found",
+			initial:     domain.StateActive,
+			req:         ChangeStateRequest{ID: "notfound", State: "paused"},
+			expectedErr: domain.ErrShortURLNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockRepository()
+			analytics := newMockAnalytics()
+			service := NewShortURLService(repo, newMockKGS(), analytics, "http://test.com")
+			shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://test.com/abc123", nil, map[string]interface{}{"campaign": "spring"})
+			shortURL.RestoreLifecycle(tt.initial, nil)
+			repo.data["abc123"] = shortURL
+
+			resp, err := service.ChangeState(context.Background(), tt.req)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if tt.expectedCode != "" {
+				var domainErr *domain.Error
+				if !errors.As(err, &domainErr) || domainErr.Code != tt.expectedCode || !errors.Is(err, domain.ErrConflict) {
+					t.Errorf("expected error code %q, got %v", tt.expectedCode, err)
+				}
+				if shortURL.State() != tt.initial || len(analytics.events) != 0 {
+					t.Errorf("expected no change, got state %q and %d events", shortURL.State(), len(analytics.events))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if resp.State != tt.req.State || repo.data["abc123"].State() != domain.LifecycleState(tt.req.State) {
+				t.Errorf("expected state %q, got %q", tt.req.State, resp.State)
+			}
+			if resp.IsActive != (tt.req.State == "active") {
+				t.Errorf("expected isActive %v, got %v", tt.req.State == "active", resp.IsActive)
+			}
+			expected := StateTransition{From: string(tt.initial), To: tt.req.State, Reason: tt.req.Reason, Actor: tt.req.Actor}
+			if len(resp.Transitions) != 1 {
+				t.Fatalf("expected 1 transition, got %+v", resp.Transitions)
+			}
+			if got := resp.Transitions[0]; got.From != expected.From || got.To != expected.To || got.Reason != expected.Reason || got.Actor != expected.Actor || got.At.IsZero() {
+				t.Errorf("expected transition %+v, got %+v", expected, got)
+			}
+
+			if len(analytics.events) != 1 {
+				t.Fatalf("expected 1 analytics event, got %d", len(analytics.events))
+			}
+			event := analytics.events[0]
+			if event.EventType != tt.expectedEvent || event.UserMetadata["previous_state"] != string(tt.initial) ||
+				event.UserMetadata["reason"] != tt.req.Reason || event.UserMetadata["actor"] != tt.req.Actor ||
+				event.UserMetadata["campaign"] != "spring" {
+				t.Errorf("unexpected event %+v", event)
+			}
+		})
+	}
+}
+
+func TestShortURLService_ChangeState_Concurrent(t *testing.T) {
+	// Transitions must not modify the entity that concurrent redirects are reading;
+	// run with -race to catch that
+	service := NewShortURLService(infra.NewMemoryShortURLRepository(), newMockKGS(), newMockAnalytics(), "http://test.com")
+	if _, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com", CustomURL: "toggle"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const toggles = 10
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < toggles; j++ {
+				_, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/toggle"})
+				if err != nil && !errors.Is(err, domain.ErrShortURLPaused) {
+					t.Errorf("unexpected redirect error: %v", err)
+				}
+			}
+		}()
+	}
+	for j := 0; j < toggles; j++ {
+		for _, state := range []string{"paused", "active"} {
+			if _, err := service.ChangeState(context.Background(), ChangeStateRequest{ID: "toggle", State: state}); err != nil {
+				t.Fatalf("unexpected error changing state to %s: %v", state, err)
+			}
+		}
+	}
+	wg.Wait()
+
+	list, err := service.ListShortURLs(context.Background(), ListShortURLsRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transitions := list.Items[0].Transitions; len(transitions) != 2*toggles {
+		t.Errorf("expected %d transitions, got %d", 2*toggles, len(transitions))
+	}
+}
+
+func TestShortURLService_ClickLimit(t *testing.T) {
+	repo := newMockRepository()
+	analytics := newMockAnalytics()
+	service := NewShortURLService(repo, newMockKGS(), analytics, "http://test.com")
+
+	resp, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com", CustomURL: "once", MaxClicks: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.data["once"].MaxClicks() != 1 {
+		t.Fatalf("expected click limit 1, got %d", repo.data["once"].MaxClicks())
+	}
+
+	// The first click redirects and uses up the link
+	analytics.events = nil
+	longURL, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: resp.ShortURL})
+	if err != nil || longURL != "https://example.com" {
+		t.Fatalf("expected redirect, got %q, %v", longURL, err)
+	}
+	if len(analytics.events) != 2 || analytics.events[0].EventType != "url_exhausted" || analytics.events[1].EventType != "url_accessed" {
+		t.Fatalf("expected url_exhausted and url_accessed events, got %+v", analytics.events)
+	}
+	if repo.data["once"].State() != domain.StateExhausted {
+		t.Errorf("expected state exhausted, got %q", repo.data["once"].State())
+	}
+
+	// Every later click is rejected
+	analytics.events = nil
+	_, err = service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: resp.ShortURL})
+	if !errors.Is(err, domain.ErrShortURLExhausted) {
+		t.Errorf("expected ErrShortURLExhausted, got %v", err)
+	}
+	if len(analytics.events) != 0 {
+		t.Errorf("expected no analytics event, got %d", len(analytics.events))
+	}
+
+	// Unlimited links are not counted
+	if _, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com", CustomURL: "many"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/many"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if clicks := repo.data["many"].Clicks(); clicks != 0 {
+		t.Errorf("expected unlimited link not to count clicks, got %d", clicks)
+	}
+
+	_, err = service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com", MaxClicks: -1})
+	if !errors.Is(err, domain.ErrInvalidMaxClicks) {
+		t.Errorf("expected ErrInvalidMaxClicks, got %v", err)
+	}
+}
+
+func TestShortURLService_ClickLimit_ConcurrentWriters(t *testing.T) {
+	// State and destination changes made while clicks are counted must not write back
+	// a stale click count, or used clicks would become available again
+	repo := infra.NewMemoryShortURLRepository()
+	service := NewShortURLService(repo, newMockKGS(), newMockAnalytics(), "http://test.com")
+	if _, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com/0", CustomURL: "limited", MaxClicks: 1000}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const visitors, clicksPerVisitor = 8, 25
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		redirects int
+	)
+	for i := 0; i < visitors; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < clicksPerVisitor; j++ {
+				_, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/limited"})
+				if errors.Is(err, domain.ErrShortURLPaused) {
+					continue
+				}
+				if err != nil {
+					t.Errorf("unexpected redirect error: %v", err)
+					return
+				}
+				mu.Lock()
+				redirects++
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 1; i <= 10; i++ {
+		for _, state := range []string{"paused", "active"} {
+			if _, err := service.ChangeState(context.Background(), ChangeStateRequest{ID: "limited", State: state}); err != nil {
+				t.Fatalf("unexpected error changing state to %s: %v", state, err)
+			}
+		}
+		if _, err := service.UpdateDestination(context.Background(), UpdateDestinationRequest{ID: "limited", LongURL: fmt.Sprintf("https://example.com/%d", i)}); err != nil {
+			t.Fatalf("unexpected update error: %v", err)
+		}
+	}
+	wg.Wait()
+
+	shortURL, err := repo.FindByID(context.Background(), "limited")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if shortURL.Clicks() != redirects {
+		t.Errorf("expected %d counted clicks, got %d", redirects, shortURL.Clicks())
+	}
+	if remaining := shortURL.MaxClicks() - shortURL.Clicks(); remaining != 1000-redirects {
+		t.Errorf("expected %d remaining clicks, got %d", 1000-redirects, remaining)
+	}
+}
+
+// clickAfterFind is a repository that counts a click right after every lookup, as a
+// visitor clicking between a writer's lookup and its write would.
+type clickAfterFind struct {
+	domain.ShortURLRepository
+}
+
+func (r clickAfterFind) FindByID(ctx context.Context, id string) (*domain.ShortURL, error) {
+	shortURL, err := r.ShortURLRepository.FindByID(ctx, id)
+	if shortURL != nil && err == nil {
+		_, err = r.ShortURLRepository.Update(ctx, id, func(u *domain.ShortURL) error {
+			_, err := u.RecordClick(time.Now())
+			return err
+		})
+	}
+	return shortURL, err
+}
+
+func TestShortURLService_ClickLimit_WritersKeepClicks(t *testing.T) {
+	repo := infra.NewMemoryShortURLRepository()
+	service := NewShortURLService(clickAfterFind{repo}, newMockKGS(), newMockAnalytics(), "http://test.com")
+	if _, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com", CustomURL: "once", MaxClicks: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A one-time link used while an admin changes it must stay used
+	if _, err := service.UpdateDestination(context.Background(), UpdateDestinationRequest{ID: "once", LongURL: "https://example.com/new"}); err != nil {
+		t.Fatalf("unexpected update error: %v", err)
+	}
+	if _, err := service.ChangeState(context.Background(), ChangeStateRequest{ID: "once", State: "archived"}); err != nil {
+		t.Fatalf("unexpected state change error: %v", err)
+	}
+
+	shortURL, _ := repo.FindByID(context.Background(), "once")
+	if shortURL.Clicks() != 1 || shortURL.State() != domain.StateArchived {
+		t.Errorf("expected the used click to be kept, got %d clicks in state %s", shortURL.Clicks(), shortURL.State())
+	}
+	if len(shortURL.Transitions()) != 2 || shortURL.Transitions()[0].To != domain.StateExhausted {
+		t.Errorf("expected exhausted then archived, got %+v", shortURL.Transitions())
+	}
+}
+
+func TestShortURLService_ClickLimit_HashIDs(t *testing.T) {
+	hashKGS := &mockHashKGS{candidates: map[string][]string{
+		"https://example.com/a": {"hashA1", "hashA2", "hashA3"},
+	}}
+	service := NewShortURLService(newMockRepository(), newMockKGS(), newMockAnalytics(), "http://test.com", WithHashIDs(hashKGS))
+
+	// Click-limited links are never shared, in either direction
+	requests := []struct {
+		req         CreateShortURLRequest
+		expectedURL string
+	}{
+		{CreateShortURLRequest{LongURL: "https://example.com/a", MaxClicks: 5}, "http://test.com/hashA1"},
+		{CreateShortURLRequest{LongURL: "https://example.com/a", MaxClicks: 5}, "http://test.com/hashA2"},
+		{CreateShortURLRequest{LongURL: "https://example.com/a"}, "http://test.com/hashA3"},
+	}
+	for _, r := range requests {
+		resp, err := service.CreateShortURL(context.Background(), r.req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.ShortURL != r.expectedURL {
+			t.Errorf("expected %q, got %q", r.expectedURL, resp.ShortURL)
+		}
+	}
+	if _, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com/a", MaxClicks: 1}); err == nil {
+		t.Error("expected a click-limited link not to reuse an unlimited one")
+	}
+}
+
+func TestShortURLService_Schedule(t *testing.T) {
+	notBefore := time.Now().Add(time.Hour)
+	expiry := time.Now().Add(24 * time.Hour)
+	rules, _ := domain.NewDestinationRules(domain.DestinationRule{Kind: domain.RuleHost, Pattern: "evil.example"})
+	blocklist := &mockDestinationBlocklist{rules: rules}
+
+	tests := []struct {
+		name              string
+		req               CreateShortURLRequest
+		expectedCode      string
+		expectedPreLaunch string
+	}{
+		{
+			name:              "with pre-launch URL",
+			req:               CreateShortURLRequest{LongURL: "https://example.com/sale", CustomURL: "sale", NotBefore: &notBefore, PreLaunchURL: "HTTPS://Example.com/coming-soon"},
+			expectedPreLaunch: "https://example.com/coming-soon",
+		},
+		{
+			name: "without pre-launch URL",
+			req:  CreateShortURLRequest{LongURL: "https://example.com/sale", CustomURL: "sale", NotBefore: &notBefore, Expiry: &expiry},
+		},
+		{
+			name:         "pre-launch URL without activation time",
+			req:          CreateShortURLRequest{LongURL: "https://example.com/sale", PreLaunchURL: "https://example.com/coming-soon"},
+			expectedCode: "not_before_required",
+		},
+		{
+			name:         "activation after expiry",
+			req:          CreateShortURLRequest{LongURL: "https://example.com/sale", NotBefore: &expiry, Expiry: &notBefore},
+			expectedCode: "invalid_not_before",
+		},
+		{
+			name:         "invalid pre-launch URL",
+			req:          CreateShortURLRequest{LongURL: "https://example.com/sale", NotBefore: &notBefore, PreLaunchURL: "javascript:alert(1)"},
+			expectedCode: "long_url_scheme_not_allowed",
+		},
+		{
+			name:         "blocked pre-launch URL",
+			req:          CreateShortURLRequest{LongURL: "https://example.com/sale", NotBefore: &notBefore, PreLaunchURL: "https://evil.example/"},
+			expectedCode: "long_url_blocked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockRepository()
+			service := NewShortURLService(repo, newMockKGS(), newMockAnalytics(), "http://test.com", WithDestinationBlocklist(blocklist))
+
+			_, err := service.CreateShortURL(context.Background(), tt.req)
+			if tt.expectedCode != "" {
+				var domainErr *domain.Error
+				if !errors.As(err, &domainErr) || domainErr.Code != tt.expectedCode {
+					t.Fatalf("expected error code %q, got %v", tt.expectedCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Lookups before the activation time carry the pre-launch URL
+			_, err = service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/sale"})
+			var notYetActive *domain.NotYetActiveError
+			if !errors.As(err, &notYetActive) {
+				t.Fatalf("expected NotYetActiveError, got %v", err)
+			}
+			if !notYetActive.ActivatesAt.Equal(notBefore) || notYetActive.PreLaunchURL != tt.expectedPreLaunch {
+				t.Errorf("unexpected error details %+v", notYetActive)
+			}
+
+			// The URL goes live at the activation time
+			live := time.Now().Add(-time.Minute)
+			repo.data["sale"].RestoreSchedule(&live, tt.expectedPreLaunch)
+			longURL, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/sale"})
+			if err != nil || longURL != "https://example.com/sale" {
+				t.Errorf("expected redirect after activation, got %q, %v", longURL, err)
+			}
+		})
+	}
+}
+
+func TestShortURLService_Schedule_PreLaunchBlocklist(t *testing.T) {
+	notBefore := time.Now().Add(time.Hour)
+	rules, _ := domain.NewDestinationRules()
+	blocklist := &mockDestinationBlocklist{rules: rules}
+	analytics := newMockAnalytics()
+	service := NewShortURLService(newMockRepository(), newMockKGS(), analytics, "http://test.com", WithDestinationBlocklist(blocklist))
+
+	_, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com/sale", CustomURL: "sale", NotBefore: &notBefore, PreLaunchURL: "https://teaser.example/soon"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The pre-launch URL is listed as malicious after the short URL was created
+	blocklist.rules, _ = domain.NewDestinationRules(domain.DestinationRule{Kind: domain.RuleSuffix, Pattern: "teaser.example"})
+	analytics.events = nil
+	_, err = service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/sale"})
+	if !errors.Is(err, domain.ErrShortURLBlocked) {
+		t.Fatalf("expected ErrShortURLBlocked instead of a redirect to the pre-launch URL, got %v", err)
+	}
+	var notYetActive *domain.NotYetActiveError
+	if errors.As(err, &notYetActive) {
+		t.Error("expected the pre-launch URL not to be offered")
+	}
+	if len(analytics.events) != 1 || analytics.events[0].EventType != "url_blocked" || analytics.events[0].LongURL != "https://teaser.example/soon" {
+		t.Errorf("expected a url_blocked event for the pre-launch URL, got %+v", analytics.events)
+	}
+}
+
+func TestShortURLService_PasswordProtection(t *testing.T) {
+	repo := newMockRepository()
+	analytics := newMockAnalytics()
+	limiter := &mockAttemptLimiter{maxFailures: 3, failures: make(map[string]int)}
+	service := NewShortURLService(repo, newMockKGS(), analytics, "http://test.com", WithPasswordProtection(mockPasswordHasher{}, mockUnlockSigner{}, limiter))
+
+	if _, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com/internal", CustomURL: "internal", Password: "s3cret-pass"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hash := repo.data["internal"].PasswordHash(); hash != "hashed:s3cret-pass" {
+		t.Fatalf("expected the hashed password to be stored, got %q", hash)
+	}
+
+	// The listing says the URL is protected but never returns the hash
+	list, err := service.ListShortURLs(context.Background(), ListShortURLsRequest{})
+	if err != nil || len(list.Items) != 1 || !list.Items[0].PasswordProtected {
+		t.Fatalf("expected a protected URL in the listing, got %+v, %v", list, err)
+	}
+	if encoded, _ := json.Marshal(list); strings.Contains(string(encoded), "s3cret-pass") || strings.Contains(string(encoded), "hashed:") {
+		t.Errorf("expected the listing not to contain the password, got %s", encoded)
+	}
+
+	// Lookups need an unlock token
+	if _, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/internal"}); err != domain.ErrPasswordRequired {
+		t.Errorf("expected ErrPasswordRequired, got %v", err)
+	}
+	if _, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/internal", UnlockToken: "unlocked:other:hashed:s3cret-pass"}); err != domain.ErrPasswordRequired {
+		t.Errorf("expected a token of another URL to be rejected, got %v", err)
+	}
+
+	// A wrong password fails and is reported
+	analytics.events = nil
+	if _, err := service.UnlockShortURL(context.Background(), UnlockShortURLRequest{ShortURL: "http://test.com/internal", Password: "guess"}); err != domain.ErrWrongPassword {
+		t.Errorf("expected ErrWrongPassword, got %v", err)
+	}
+	if len(analytics.events) != 1 || analytics.events[0].EventType != "url_unlock_failed" || analytics.events[0].UserMetadata["reason"] != "wrong_password" {
+		t.Errorf("expected url_unlock_failed event, got %+v", analytics.events)
+	}
+
+	// The right password returns a token that GetLongURL accepts and resets the failures
+	resp, err := service.UnlockShortURL(context.Background(), UnlockShortURLRequest{ShortURL: "http://test.com/internal", Password: "s3cret-pass"})
+	if err != nil || resp.Token != "unlocked:internal:hashed:s3cret-pass" {
+		t.Fatalf("expected unlock token, got %+v, %v", resp, err)
+	}
+	if limiter.failures["internal"] != 0 {
+		t.Errorf("expected failures to be reset, got %d", limiter.failures["internal"])
+	}
+	longURL, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/internal", UnlockToken: resp.Token})
+	if err != nil || longURL != "https://example.com/internal" {
+		t.Errorf("expected redirect with the token, got %q, %v", longURL, err)
+	}
+
+	// Replacing the password revokes the tokens issued for the old one
+	repo.data["internal"].Protect("hashed:n3w-pass")
+	if _, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/internal", UnlockToken: resp.Token}); err != domain.ErrPasswordRequired {
+		t.Errorf("expected a token of the old password to be rejected, got %v", err)
+	}
+	repo.data["internal"].Protect("hashed:s3cret-pass")
+
+	// Too many wrong passwords lock the URL, even for the right password
+	for i := 0; i < 3; i++ {
+		service.UnlockShortURL(context.Background(), UnlockShortURLRequest{ShortURL: "http://test.com/internal", Password: "guess"})
+	}
+	analytics.events = nil
+	if _, err := service.UnlockShortURL(context.Background(), UnlockShortURLRequest{ShortURL: "http://test.com/internal", Password: "s3cret-pass"}); err != domain.ErrTooManyAttempts {
+		t.Errorf("expected ErrTooManyAttempts, got %v", err)
+	}
+	if len(analytics.events) != 1 || analytics.events[0].UserMetadata["reason"] != "too_many_attempts" {
+		t.Errorf("expected throttled attempt to be reported, got %+v", analytics.events)
+	}
+}
+
+// slowPasswordHasher counts and slows down password checks, as PBKDF2 does.
+type slowPasswordHasher struct {
+	mockPasswordHasher
+	verified atomic.Int32
+}
+
+func (h *slowPasswordHasher) Verify(encodedHash, password string) bool {
+	h.verified.Add(1)
+	time.Sleep(10 * time.Millisecond)
+	return h.mockPasswordHasher.Verify(encodedHash, password)
+}
+
+func TestShortURLService_PasswordProtection_ConcurrentGuesses(t *testing.T) {
+	hasher := &slowPasswordHasher{}
+	limiter := infra.NewMemoryAttemptLimiter(infra.AttemptLimiterOptions{MaxFailures: 5})
+	service := NewShortURLService(infra.NewMemoryShortURLRepository(), newMockKGS(), newMockAnalytics(), "http://test.com", WithPasswordProtection(hasher, mockUnlockSigner{}, limiter))
+	if _, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com/internal", CustomURL: "internal", Password: "s3cret-pass"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Parallel guesses must not all reach the slow hash check before a failure is counted
+	var wrong, throttled atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.UnlockShortURL(context.Background(), UnlockShortURLRequest{ShortURL: "http://test.com/internal", Password: fmt.Sprintf("guess-%d", i)})
+			switch {
+			case errors.Is(err, domain.ErrWrongPassword):
+				wrong.Add(1)
+			case errors.Is(err, domain.ErrTooManyAttempts):
+				throttled.Add(1)
+			default:
+				t.Errorf("unexpected result: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := hasher.verified.Load(); got != 5 {
+		t.Errorf("expected 5 guesses to be checked against the hash, got %d", got)
+	}
+	if wrong.Load() != 5 || throttled.Load() != 45 {
+		t.Errorf("expected 5 wrong and 45 throttled guesses, got %d and %d", wrong.Load(), throttled.Load())
+	}
+}
+
+func TestShortURLService_PasswordProtection_Create(t *testing.T) {
+	withPasswords := NewShortURLService(newMockRepository(), newMockKGS(), newMockAnalytics(), "http://test.com", WithPasswordProtection(mockPasswordHasher{}, mockUnlockSigner{}, nil))
+	withoutPasswords := NewShortURLService(newMockRepository(), newMockKGS(), newMockAnalytics(), "http://test.com")
+
+	tests := []struct {
+		name         string
+		service      *ShortURLService
+		password     string
+		expectedCode string
+	}{
+		{"valid password", withPasswords, "s3cret-pass", ""},
+		{"too short", withPasswords, "short", "invalid_password"},
+		{"not enabled", withoutPasswords, "s3cret-pass", "password_not_supported"},
+		{"no password without the option", withoutPasswords, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com", Password: tt.password})
+			if tt.expectedCode == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var domainErr *domain.Error
+			if !errors.As(err, &domainErr) || domainErr.Code != tt.expectedCode {
+				t.Errorf("expected error code %q, got %v", tt.expectedCode, err)
+			}
+		})
+	}
+
+	// Unprotected URLs unlock without a token
+	withPasswords.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com", CustomURL: "open"})
+	resp, err := withPasswords.UnlockShortURL(context.Background(), UnlockShortURLRequest{ShortURL: "http://test.com/open"})
+	if err != nil || resp.Token != "" {
+		t.Errorf("expected no token for an unprotected URL, got %+v, %v", resp, err)
+	}
+	if _, err := withPasswords.UnlockShortURL(context.Background(), UnlockShortURLRequest{ShortURL: "http://test.com/missing"}); err != domain.ErrShortURLNotFound {
+		t.Errorf("expected ErrShortURLNotFound, got %v", err)
+	}
+}
+
+func TestShortURLService_PasswordProtection_HashIDs(t *testing.T) {
+	hashKGS := &mockHashKGS{candidates: map[string][]string{
+		"https://example.com/a": {"hashA1", "hashA2", "hashA3"},
+	}}
+	service := NewShortURLService(newMockRepository(), newMockKGS(), newMockAnalytics(), "http://test.com", WithHashIDs(hashKGS), WithPasswordProtection(mockPasswordHasher{}, mockUnlockSigner{}, nil))
+
+	// Protected links are never shared, not even with the same password
+	expected := []string{"http://test.com/hashA1", "http://test.com/hashA2", "http://test.com/hashA3"}
+	for i, password := range []string{"s3cret-pass", "s3cret-pass", ""} {
+		resp, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com/a", Password: password})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.ShortURL != expected[i] {
+			t.Errorf("request %d: expected %q, got %q", i+1, expected[i], resp.ShortURL)
+		}
+	}
+}
+
+func TestShortURLService_RoutingRules(t *testing.T) {
+	analytics := newMockAnalytics()
+	countries := mockCountryResolver{"203.0.113.7": "JP", "2001:db8::1": "KR"}
+	service := NewShortURLService(newMockRepository(), newMockKGS(), analytics, "http://test.com", WithCountryResolver(countries))
+
+	_, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{
+		LongURL:   "https://example.com/app",
+		CustomURL: "app",
+		RoutingRules: []RoutingRule{
+			{Devices: []string{"ios"}, LongURL: "https://apps.apple.com/app/id123"},
+			{Devices: []string{"android"}, LongURL: "https://play.google.com/store/apps/details?id=com.example"},
+			{Countries: []string{"jp"}, LongURL: "https://example.com/ja/app"},
+			{Languages: []string{"ko"}, LongURL: "https://example.com/ko/app"},
+			{Referrers: []string{"news.example"}, LongURL: "https://example.com/app?ref=news"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const (
+		iPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+		android = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/124.0 Mobile Safari/537.36"
+		desktop = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/124.0 Safari/537.36"
+	)
+	tests := []struct {
+		name            string
+		metadata        map[string]interface{}
+		expectedLongURL string
+		expectedRule    int
+	}{
+		{
+			name:            "iOS",
+			metadata:        map[string]interface{}{"user_agent": iPhone, "ip": "203.0.113.7:52000"},
+			expectedLongURL: "https://apps.apple.com/app/id123",
+			expectedRule:    1,
+		},
+		{
+			name:            "Android",
+			metadata:        map[string]interface{}{"user_agent": android},
+			expectedLongURL: "https://play.google.com/store/apps/details?id=com.example",
+			expectedRule:    2,
+		},
+		{
+			name:            "country of the IP address",
+			metadata:        map[string]interface{}{"user_agent": desktop, "ip": "203.0.113.7:52000"},
+			expectedLongURL: "https://example.com/ja/app",
+			expectedRule:    3,
+		},
+		{
+			name:            "preferred language",
+			metadata:        map[string]interface{}{"user_agent": desktop, "accept_language": "ko-KR,ko;q=0.9,en;q=0.8"},
+			expectedLongURL: "https://example.com/ko/app",
+			expectedRule:    4,
+		},
+		{
+			name:            "referrer",
+			metadata:        map[string]interface{}{"user_agent": desktop, "referer": "https://www.news.example/today"},
+			expectedLongURL: "https://example.com/app?ref=news",
+			expectedRule:    5,
+		},
+		{
+			name:            "IPv6 address of another country",
+			metadata:        map[string]interface{}{"user_agent": desktop, "ip": "[2001:db8::1]:52000", "accept_language": "en"},
+			expectedLongURL: "https://example.com/app",
+		},
+		{
+			name:            "no metadata falls back to the long URL",
+			expectedLongURL: "https://example.com/app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			longURL, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/app", UserMetadata: tt.metadata})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if longURL != tt.expectedLongURL {
+				t.Errorf("expected %q, got %q", tt.expectedLongURL, longURL)
+			}
+
+			event := analytics.events[len(analytics.events)-1]
+			if event.EventType != "url_accessed" || event.LongURL != tt.expectedLongURL {
+				t.Errorf("expected url_accessed event for %q, got %+v", tt.expectedLongURL, event)
+			}
+			if rule := event.UserMetadata["routing_rule"]; rule != tt.expectedRule {
+				t.Errorf("expected routing_rule %d, got %v", tt.expectedRule, rule)
+			}
+			if _, exists := tt.metadata["routing_rule"]; exists {
+				t.Error("expected the request metadata to be left unchanged")
+			}
+		})
+	}
+
+	// The rules are part of the administrative listing
+	list, err := service.ListShortURLs(context.Background(), ListShortURLsRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rules := list.Items[0].RoutingRules
+	if len(rules) != 5 || rules[0].Devices[0] != "ios" || rules[2].Countries[0] != "JP" || rules[4].Referrers[0] != "news.example" {
+		t.Errorf("unexpected routing rules in listing: %+v", rules)
+	}
+}
+
+func TestShortURLService_RoutingRules_Unrouted(t *testing.T) {
+	analytics := newMockAnalytics()
+	service := NewShortURLService(newMockRepository(), newMockKGS(), analytics, "http://test.com")
+
+	if _, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com", CustomURL: "plain"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/plain"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// URLs without rules do not record a routing decision
+	event := analytics.events[len(analytics.events)-1]
+	if _, exists := event.UserMetadata["routing_rule"]; exists {
+		t.Errorf("expected no routing_rule without routing rules, got %v", event.UserMetadata)
+	}
+}
+
+func TestShortURLService_RoutingRules_Create(t *testing.T) {
+	rules, _ := domain.NewDestinationRules(domain.DestinationRule{Kind: domain.RuleHost, Pattern: "evil.example"})
+	blocklist := &mockDestinationBlocklist{rules: rules}
+
+	tests := []struct {
+		name            string
+		rules           []RoutingRule
+		countries       domain.CountryResolver
+		expectedCode    string
+		expectedMessage string
+	}{
+		{
+			name:            "rule without conditions",
+			rules:           []RoutingRule{{Devices: []string{"ios"}, LongURL: "https://apps.apple.com/"}, {LongURL: "https://example.com/other"}},
+			expectedCode:    "invalid_routing_rule",
+			expectedMessage: "routingRules[1]: ",
+		},
+		{
+			name:         "unknown device",
+			rules:        []RoutingRule{{Devices: []string{"fridge"}, LongURL: "https://example.com/fridge"}},
+			expectedCode: "invalid_routing_rule",
+		},
+		{
+			name:            "rule without destination",
+			rules:           []RoutingRule{{Devices: []string{"ios"}}},
+			expectedCode:    "long_url_required",
+			expectedMessage: "routingRules[0]: ",
+		},
+		{
+			name:         "invalid destination",
+			rules:        []RoutingRule{{Devices: []string{"ios"}, LongURL: "javascript:alert(1)"}},
+			expectedCode: "long_url_scheme_not_allowed",
+		},
+		{
+			name:         "self-referencing destination",
+			rules:        []RoutingRule{{Devices: []string{"ios"}, LongURL: "http://test.com/other"}},
+			expectedCode: "long_url_self_reference",
+		},
+		{
+			name:         "blocked destination",
+			rules:        []RoutingRule{{Devices: []string{"android"}, LongURL: "https://evil.example/app.apk"}},
+			expectedCode: "long_url_blocked",
+		},
+		{
+			name:         "country without resolver",
+			rules:        []RoutingRule{{Countries: []string{"JP"}, LongURL: "https://example.com/ja"}},
+			expectedCode: "country_routing_not_supported",
+		},
+		{
+			name:      "country with resolver",
+			rules:     []RoutingRule{{Countries: []string{"JP"}, LongURL: "https://example.com/ja"}},
+			countries: mockCountryResolver{},
+		},
+		{
+			name:         "too many rules",
+			rules:        make([]RoutingRule, domain.MaxRoutingRules+1),
+			expectedCode: "too_many_routing_rules",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []ServiceOption{WithDestinationBlocklist(blocklist)}
+			if tt.countries != nil {
+				opts = append(opts, WithCountryResolver(tt.countries))
+			}
+			service := NewShortURLService(newMockRepository(), newMockKGS(), newMockAnalytics(), "http://test.com", opts...)
+
+			_, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com", RoutingRules: tt.rules})
+			if tt.expectedCode == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var domainErr *domain.Error
+			if !errors.As(err, &domainErr) || domainErr.Code != tt.expectedCode {
+				t.Fatalf("expected error code %q, got %v", tt.expectedCode, err)
+			}
+			if !errors.Is(err, domain.ErrInvalidInput) {
+				t.Errorf("expected an invalid input error, got %v", err)
+			}
+			if !strings.HasPrefix(domainErr.Message, tt.expectedMessage) {
+				t.Errorf("expected message starting with %q, got %q", tt.expectedMessage, domainErr.Message)
+			}
+		})
+	}
+}
+
+func TestShortURLService_RoutingRules_HashIDs(t *testing.T) {
+	hashKGS := &mockHashKGS{candidates: map[string][]string{
+		"https://example.com/a": {"hashA1", "hashA2", "hashA3"},
+	}}
+	service := NewShortURLService(newMockRepository(), newMockKGS(), newMockAnalytics(), "http://test.com", WithHashIDs(hashKGS))
+
+	// Routed links are never shared, not even with the same rules
+	rules := []RoutingRule{{Devices: []string{"ios"}, LongURL: "https://apps.apple.com/app/id123"}}
+	expected := []string{"http://test.com/hashA1", "http://test.com/hashA2", "http://test.com/hashA3"}
+	for i, routingRules := range [][]RoutingRule{rules, rules, nil} {
+		resp, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com/a", RoutingRules: routingRules})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.ShortURL != expected[i] {
+			t.Errorf("request %d: expected %q, got %q", i+1, expected[i], resp.ShortURL)
+		}
+	}
+}
+
+func TestShortURLService_AnalyticsDetachedFromCancellation(t *testing.T) {
+	repo := newMockRepository()
+	analytics := newMockAnalytics()
+	service := NewShortURLService(repo, newMockKGS(), analytics, "http://test.com")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := service.CreateShortURL(ctx, CreateShortURLRequest{LongURL: "https://example.com"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The client going away after a successful operation must not cancel the event
+	cancel()
+	if analytics.lastCtx == nil {
+		t.Fatal("expected analytics event to be sent")
+	}
+	if err := analytics.lastCtx.Err(); err != nil {
+		t.Errorf("expected analytics context to stay alive, got %v", err)
+	}
+}
+
+func TestShortURLService_Regions(t *testing.T) {
+	registry, _ := domain.NewRegionRegistry(
+		domain.Region{Code: "1", Name: "us-east-1", BaseURL: "https://us.short.ly"},
+		domain.Region{Code: "2", Name: "eu-west-1", BaseURL: "https://eu.short.ly"},
+		domain.Region{Code: "3", Name: "ap-south-1"},
+	)
+	repo := newMockRepository()
+	service := NewShortURLService(repo, newMockKGS(), newMockAnalytics(), "https://us.short.ly", WithRegions("1", registry))
+
+	// New URLs record the region that minted them, custom ones included
+	created, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com/custom", CustomURL: "2go"})
+
+	list, err := service.ListShortURLs(context.Background(), ListShortURLsRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, item := range list.Items {
+		if item.Region != "us-east-1" {
+			t.Errorf("expected %s to be minted in us-east-1, got %q", item.ID, item.Region)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		shortURL      string
+		expectedURL   string
+		expectedError error
+		foreignRegion string
+	}{
+		{"local URL", created.ShortURL, "https://example.com", nil, ""},
+		{"custom URL with a foreign prefix is found locally", "https://us.short.ly/2go", "https://example.com/custom", nil, ""},
+		{"local prefix not found", "https://us.short.ly/1missing", "", domain.ErrShortURLNotFound, ""},
+		{"foreign prefix routed", "https://us.short.ly/2abcdefg", "", nil, "eu-west-1"},
+		{"foreign region without base URL", "https://us.short.ly/3abcdefg", "", domain.ErrShortURLNotFound, ""},
+		{"unknown prefix", "https://us.short.ly/9abcdefg", "", domain.ErrShortURLNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			longURL, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: tt.shortURL})
+
+			if tt.foreignRegion != "" {
+				var foreign *domain.ForeignRegionError
+				if !errors.As(err, &foreign) {
+					t.Fatalf("expected ForeignRegionError, got %v", err)
+				}
+				if foreign.Region.Name != tt.foreignRegion {
+					t.Errorf("expected region %q, got %q", tt.foreignRegion, foreign.Region.Name)
+				}
+				return
+			}
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected error %v, got %v", tt.expectedError, err)
+			}
+			if longURL != tt.expectedURL {
+				t.Errorf("expected URL %q, got %q", tt.expectedURL, longURL)
+			}
+		})
+	}
+}
+
+func TestShortURLService_IDProfile(t *testing.T) {
+	lowercase, _ := domain.NewIDProfile(domain.AlphabetLowercase, 7)
+	repo := newMockRepository()
+	service := NewShortURLService(repo, newMockKGS(), newMockAnalytics(), "http://test.com", WithIDProfile(lowercase))
+
+	// Custom URLs shaped like generated IDs are normalized to the profile
+	if _, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com", CustomURL: "Promo24"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.data["promo24"] == nil {
+		t.Error("expected custom URL of the generated shape to be stored lowercased")
+	}
+
+	// Lookups through case-folding channels still resolve
+	longURL, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/PROMO24"})
+	if err != nil || longURL != "https://example.com" {
+		t.Errorf("expected uppercase lookup to resolve, got %q, %v", longURL, err)
+	}
+
+	// Malformed IDs are rejected before the repository is consulted
+	repo.findErr = errors.New("repository must not be called")
+	if _, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/wp-admin.php"}); !errors.Is(err, domain.ErrInvalidShortID) {
+		t.Errorf("GetLongURL: expected ErrInvalidShortID, got %v", err)
+	}
+	if err := service.DeactivateShortURL(context.Background(), "../etc"); !errors.Is(err, domain.ErrInvalidShortID) {
+		t.Errorf("DeactivateShortURL: expected ErrInvalidShortID, got %v", err)
+	}
+}
+
+func TestShortURLService_IDProfileWithAliasPolicy(t *testing.T) {
+	base62 := domain.DefaultIDProfile()
+	repo := infra.NewMemoryShortURLRepository()
+
+	// An alias created before the profile was configured
+	before := NewShortURLService(repo, newMockKGS(), newMockAnalytics(), "http://test.com")
+	if _, err := before.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com/old", CustomURL: "Old_Campaign"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	policy := domain.DefaultAliasPolicy()
+	policy.Symbols = "-_."
+	service := NewShortURLService(repo, newMockKGS(), newMockAnalytics(), "http://test.com", WithIDProfile(base62), WithAliasPolicy(policy))
+
+	// Aliases are validated by the alias policy, not by the ID alphabet
+	for _, alias := range []string{"spring-sale", "summer_sale", "v2.launch-page"} {
+		if _, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com/" + alias, CustomURL: alias}); err != nil {
+			t.Errorf("%s: unexpected error: %v", alias, err)
+			continue
+		}
+		longURL, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/" + alias})
+		if err != nil || longURL != "https://example.com/"+alias {
+			t.Errorf("%s: expected the alias to resolve, got %q, %v", alias, longURL, err)
+		}
+	}
+	if _, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com", CustomURL: "bad~alias"}); err == nil || errors.Is(err, domain.ErrInvalidShortID) {
+		t.Errorf("expected an alias policy error, got %v", err)
+	}
+
+	// Aliases created before the profile stay reachable
+	longURL, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/Old_Campaign"})
+	if err != nil || longURL != "https://example.com/old" {
+		t.Errorf("expected the earlier alias to resolve, got %q, %v", longURL, err)
+	}
+
+	// Characters neither the alphabet nor the policy allows are still rejected up front
+	if _, err := service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: "http://test.com/sale~1"}); !errors.Is(err, domain.ErrInvalidShortID) {
+		t.Errorf("expected ErrInvalidShortID, got %v", err)
+	}
+}
+
+func TestShortURLService_HashIDs(t *testing.T) {
+	expiry := time.Now().Add(time.Hour)
+	hashKGS := &mockHashKGS{candidates: map[string][]string{
+		"https://example.com/a": {"hashA1", "hashA2", "hashA3"},
+		"https://example.com/b": {"hashA1", "hashB2"},
+		"https://example.com/c": {"hashA1", "hashB2"},
+	}}
+	repo := newMockRepository()
+	analytics := newMockAnalytics()
+	service := NewShortURLService(repo, newMockKGS(), analytics, "http://test.com", WithHashIDs(hashKGS))
+
+	// Requests run in order against the same repository
+	tests := []struct {
+		name          string
+		req           CreateShortURLRequest
+		expectedURL   string
+		expectCreated bool
+		expectError   bool
+	}{
+		{"first candidate is free", CreateShortURLRequest{LongURL: "https://example.com/a"}, "http://test.com/hashA1", true, false},
+		{"same destination reuses the ID", CreateShortURLRequest{LongURL: "HTTPS://EXAMPLE.COM/a"}, "http://test.com/hashA1", false, false},
+		{"collision probes the next window", CreateShortURLRequest{LongURL: "https://example.com/b"}, "http://test.com/hashB2", true, false},
+		{"different expiry is a new short URL", CreateShortURLRequest{LongURL: "https://example.com/a", Expiry: &expiry}, "http://test.com/hashA2", true, false},
+		{"same expiry reuses the ID", CreateShortURLRequest{LongURL: "https://example.com/a", Expiry: &expiry}, "http://test.com/hashA2", false, false},
+		{"custom URLs are unaffected", CreateShortURLRequest{LongURL: "https://example.com/a", CustomURL: "mine"}, "mine", true, false},
+		{"all candidates taken", CreateShortURLRequest{LongURL: "https://example.com/c"}, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := len(analytics.events)
+			resp, err := service.CreateShortURL(context.Background(), tt.req)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error, got %q", resp.ShortURL)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.ShortURL != tt.expectedURL {
+				t.Errorf("expected %q, got %q", tt.expectedURL, resp.ShortURL)
+			}
+			if created := len(analytics.events) > events; created != tt.expectCreated {
+				t.Errorf("expected url_created event=%v, got %v", tt.expectCreated, created)
+			}
+		})
+	}
+
+	// A deactivated short URL is not handed out again
+	if err := service.DeactivateShortURL(context.Background(), "hashA1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com/a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ShortURL != "http://test.com/hashA3" {
+		t.Errorf("expected next free window hashA3, got %q", resp.ShortURL)
+	}
+}
+
+func TestShortURLService_Blocklist(t *testing.T) {
+	blocklist := domain.NewBlocklist([]string{"scam"}, domain.DefaultReservedWords())
+	hashKGS := &mockHashKGS{candidates: map[string][]string{
+		"https://example.com/hashed": {"5c4mxyz", "hashOK1"},
+	}}
+	service := NewShortURLService(newMockRepository(), newMockKGS(), newMockAnalytics(), "http://test.com", WithBlocklist(blocklist))
+	hashService := NewShortURLService(newMockRepository(), newMockKGS(), newMockAnalytics(), "http://test.com", WithBlocklist(blocklist), WithHashIDs(hashKGS))
+
+	tests := []struct {
+		name        string
+		service     *ShortURLService
+		req         CreateShortURLRequest
+		expectedURL string
+		expectError error
+	}{
+		{"allowed custom URL", service, CreateShortURLRequest{LongURL: "https://example.com", CustomURL: "spring-sale"}, "spring-sale", nil},
+		{"reserved custom URL", service, CreateShortURLRequest{LongURL: "https://example.com", CustomURL: "health"}, "", domain.ErrReservedShortID},
+		{"reserved custom URL in another case", service, CreateShortURLRequest{LongURL: "https://example.com", CustomURL: "Metrics"}, "", domain.ErrReservedShortID},
+		{"blocked custom URL", service, CreateShortURLRequest{LongURL: "https://example.com", CustomURL: "free-5CAM"}, "", domain.ErrBlockedShortID},
+		{"generated IDs are left to the KGS", service, CreateShortURLRequest{LongURL: "https://example.com"}, "http://test.com/test0001", nil},
+		{"blocked hash candidate is skipped", hashService, CreateShortURLRequest{LongURL: "https://example.com/hashed"}, "http://test.com/hashOK1", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.service.CreateShortURL(context.Background(), tt.req)
+			if tt.expectError != nil {
+				if !errors.Is(err, tt.expectError) {
+					t.Errorf("expected %v, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.ShortURL != tt.expectedURL {
+				t.Errorf("expected %q, got %q", tt.expectedURL, resp.ShortURL)
+			}
+		})
+	}
+}
+
+func TestShortURLService_AliasPolicy(t *testing.T) {
+	policy := domain.DefaultAliasPolicy()
+	policy.Case = domain.AliasCaseFold
+	repo := newMockRepository()
+	service := NewShortURLService(repo, newMockKGS(), newMockAnalytics(), "http://test.com", WithAliasPolicy(policy))
+
+	tests := []struct {
+		name         string
+		customURL    string
+		expectedID   string
+		expectedCode string
+	}{
+		{"valid alias is folded", "Summer-Sale", "summer-sale", ""},
+		{"folded alias collides", "SUMMER-SALE", "", "custom_url_exists"},
+		{"slash", "a/b", "", "alias_invalid_character"},
+		{"too long", strings.Repeat("x", 100), "", "alias_too_long"},
+		{"reserved prefix", "admin-tools", "", "alias_reserved_prefix"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://example.com", CustomURL: tt.customURL})
+			if tt.expectedCode != "" {
+				var domainErr *domain.Error
+				if !errors.As(err, &domainErr) || domainErr.Code != tt.expectedCode {
+					t.Errorf("expected error code %q, got %v", tt.expectedCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if repo.data[tt.expectedID] == nil {
+				t.Errorf("expected alias stored as %q", tt.expectedID)
+			}
+		})
+	}
+}
+
+func TestShortURLService_LongURLValidation(t *testing.T) {
+	policy := domain.DefaultLongURLPolicy()
+	policy.MaxLength = 64
+	repo := newMockRepository()
+	service := NewShortURLService(repo, newMockKGS(), newMockAnalytics(), "http://test.com", WithLongURLPolicy(policy))
+
+	tests := []struct {
+		name         string
+		req          CreateShortURLRequest
+		expectedURL  string
+		expectedCode string
+	}{
+		{"stored normalized", CreateShortURLRequest{LongURL: "HTTPS://Example.COM:443/Path?q=1"}, "https://example.com/Path?q=1", ""},
+		{"internationalized host", CreateShortURLRequest{LongURL: "https://bücher.example/"}, "https://xn--bcher-kva.example/", ""},
+		{"custom URL stored normalized", CreateShortURLRequest{LongURL: "http://Example.com:80/x", CustomURL: "normalized"}, "http://example.com/x", ""},
+		{"javascript scheme", CreateShortURLRequest{LongURL: "javascript:alert(1)"}, "", "long_url_scheme_not_allowed"},
+		{"relative path", CreateShortURLRequest{LongURL: "/relative/path"}, "", "long_url_not_absolute"},
+		{"garbage", CreateShortURLRequest{LongURL: "not a url"}, "", "long_url_not_absolute"},
+		{"invalid port", CreateShortURLRequest{LongURL: "https://example.com:port/"}, "", "invalid_long_url"},
+		{"too long", CreateShortURLRequest{LongURL: "https://example.com/" + strings.Repeat("a", 64)}, "", "long_url_too_long"},
+		{"invalid custom URL request", CreateShortURLRequest{LongURL: "ftp://example.com/file", CustomURL: "ftpfile"}, "", "long_url_scheme_not_allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := service.CreateShortURL(context.Background(), tt.req)
+			if tt.expectedCode != "" {
+				var domainErr *domain.Error
+				if !errors.As(err, &domainErr) || domainErr.Code != tt.expectedCode {
+					t.Errorf("expected error code %q, got %v", tt.expectedCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			id := service.extractIDFromShortURL(resp.ShortURL)
+			if stored := repo.data[id]; stored == nil || stored.LongURL() != tt.expectedURL {
+				t.Errorf("expected stored long URL %q, got %v", tt.expectedURL, stored)
+			}
+		})
+	}
+}
+
+func TestShortURLService_DestinationChecks(t *testing.T) {
+	shorteners := domain.NewHostSet(domain.DefaultShortenerHosts()...)
+	resolver := &mockResolver{redirects: map[string]string{
+		"https://bit.ly/abc":       "https://example.com/landing",
+		"https://bit.ly/chain":     "https://tinyurl.com/next",
+		"https://tinyurl.com/next": "https://example.com/chained",
+		"https://bit.ly/loop":      "http://short.test/abc1234",
+		"https://bit.ly/script":    "javascript:alert(1)",
+		"https://bit.ly/a":         "https://bit.ly/b",
+		"https://bit.ly/b":         "https://bit.ly/c",
+		"https://bit.ly/c":         "https://bit.ly/d",
+		"https://bit.ly/d":         "https://example.com/too-deep",
+	}}
+	registry, _ := domain.NewRegionRegistry(domain.Region{Code: "2", Name: "eu-west-1", BaseURL: "https://eu.short.test"})
+
+	plain := NewShortURLService(newMockRepository(), newMockKGS(), newMockAnalytics(), "http://short.test", WithOwnHosts("go.brand.test"), WithRegions("1", registry))
+	deny := NewShortURLService(newMockRepository(), newMockKGS(), newMockAnalytics(), "http://short.test", WithShortenerHosts(shorteners, nil))
+	resolving := NewShortURLService(newMockRepository(), newMockKGS(), newMockAnalytics(), "http://short.test", WithShortenerHosts(shorteners, resolver))
+
+	tests := []struct {
+		name         string
+		service      *ShortURLService
+		longURL      string
+		expectedURL  string
+		expectedCode string
+	}{
+		{"own base URL", plain, "http://short.test/abc1234", "", "long_url_self_reference"},
+		{"own base URL other case and port", plain, "https://SHORT.test:8443/x", "", "long_url_self_reference"},
+		{"own custom domain", plain, "https://go.brand.test/sale", "", "long_url_self_reference"},
+		{"other region", plain, "https://eu.short.test/2abc123", "", "long_url_self_reference"},
+		{"shortener allowed by default", plain, "https://bit.ly/abc", "https://bit.ly/abc", ""},
+		{"shortener denied", deny, "https://bit.ly/abc", "", "long_url_shortener"},
+		{"shortener subdomain denied", deny, "https://www.tinyurl.com/x", "", "long_url_shortener"},
+		{"regular URL with shortener guard", deny, "https://example.com/", "https://example.com/", ""},
+		{"shortener resolved", resolving, "https://bit.ly/abc", "https://example.com/landing", ""},
+		{"shortener chain resolved", resolving, "https://bit.ly/chain", "https://example.com/chained", ""},
+		{"shortener resolving to this service", resolving, "https://bit.ly/loop", "", "long_url_self_reference"},
+		{"shortener resolving to invalid URL", resolving, "https://bit.ly/script", "", "long_url_scheme_not_allowed"},
+		{"shortener chain too long", resolving, "https://bit.ly/a", "", "long_url_shortener"},
+		{"shortener not redirecting", resolving, "https://bit.ly/unknown", "", "long_url_unresolvable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: tt.longURL})
+			if tt.expectedCode != "" {
+				var domainErr *domain.Error
+				if !errors.As(err, &domainErr) || domainErr.Code != tt.expectedCode {
+					t.Errorf("expected error code %q, got %v", tt.expectedCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			longURL, err := tt.service.GetLongURL(context.Background(), GetLongURLRequest{ShortURL: resp.ShortURL})
+			if err != nil || longURL != tt.expectedURL {
+				t.Errorf("expected destination %q, got %q, %v", tt.expectedURL, longURL, err)
+			}
+		})
+	}
+}
+
+func TestShortURLService_DestinationChecks_ResolverFailure(t *testing.T) {
+	resolver := &mockResolver{err: errors.New("connection refused")}
+	service := NewShortURLService(newMockRepository(), newMockKGS(), newMockAnalytics(), "http://short.test",
+		WithShortenerHosts(domain.NewHostSet("bit.ly"), resolver))
+
+	_, err := service.CreateShortURL(context.Background(), CreateShortURLRequest{LongURL: "https://bit.ly/abc"})
+	if !errors.Is(err, domain.ErrUnresolvableURL) {
+		t.Errorf("expected ErrUnresolvableURL, got %v", err)
+	}
+
+	// A canceled request reports the cancellation rather than a validation error
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resolver.err = context.Canceled
+	if _, err := service.CreateShortURL(ctx, CreateShortURLRequest{LongURL: "https://bit.ly/abc"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestShortURLService_DestinationBlocklist(t *testing.T) {
+	rules, _ := domain.NewDestinationRules(domain.DestinationRule{Kind: domain.RuleSuffix, Pattern: "phish.example"})
+	blocklist := &mockDestinationBlocklist{rules: rules}
+	analytics := newMockAnalytics()
+	service := NewShortURLService(newMockRepository(), newMockKGS(), analytics, "http://short.test", WithDestinationBlocklist(blocklist))
+	ctx := context.Background()
+
+	// A listed destination is rejected at creation
+	_, err := service.CreateShortURL(ctx, CreateShortURLRequest{LongURL: "https://login.phish.example/", UserMetadata: map[string]interface{}{"ip": "203.0.113.7"}})
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) || domainErr.Code != "long_url_blocked" || !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("expected long_url_blocked, got %v", err)
+	}
+	if len(analytics.events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(analytics.events))
+	}
+	event := analytics.events[0]
+	if event.EventType != "url_blocked" || event.LongURL != "https://login.phish.example/" ||
+		event.UserMetadata["block_stage"] != "create" || event.UserMetadata["block_rule"] != "suffix:phish.example" ||
+		event.UserMetadata["ip"] != "203.0.113.7" {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	// A link created before its destination was listed is blocked at redirect time
+	resp, err := service.CreateShortURL(ctx, CreateShortURLRequest{LongURL: "https://evil.example/payload"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.GetLongURL(ctx, GetLongURLRequest{ShortURL: resp.ShortURL}); err != nil {
+		t.Fatalf("unexpected error before listing: %v", err)
+	}
+	blocklist.rules, _ = domain.NewDestinationRules(domain.DestinationRule{Kind: domain.RuleHost, Pattern: "evil.example"})
+	analytics.events = nil
+
+	_, err = service.GetLongURL(ctx, GetLongURLRequest{ShortURL: resp.ShortURL})
+	if !errors.Is(err, domain.ErrShortURLBlocked) || !errors.Is(err, domain.ErrBlocked) {
+		t.Fatalf("expected ErrShortURLBlocked, got %v", err)
+	}
+	if len(analytics.events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(analytics.events))
+	}
+	event = analytics.events[0]
+	if event.EventType != "url_blocked" || event.ShortURL != resp.ShortURL ||
+		event.UserMetadata["block_stage"] != "redirect" || event.UserMetadata["block_rule"] != "host:evil.example" {
+		t.Errorf("unexpected event %+v", event)
+	}
+}
+
+func TestShortURLService_buildShortURL(t *testing.T) {
+	service := &ShortURLService{baseURL: "http://test.com"}
+
+	tests := []struct {
+		name     string
+		id       string
+		expected string
+	}{
+		{
+			name:     "simple ID",
+			id:       "abc123",
+			expected: "http://test.com/abc123",
+		},
+		{
+			name:     "base URL with trailing slash",
+			id:       "def456",
+			expected: "http://test.com/def456",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "base URL with trailing slash" {
+				service.baseURL = "http://test.com/"
+			}
+
+			result := service.buildShortURL(tt.id)
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestShortURLService_extractIDFromShortURL(t *testing.T) {
+	service := &ShortURLService{}
+
+	tests := []struct {
+		name     string
+		shortURL string
+		expected string
+	}{
+		{
+			name:     "full URL",
+			shortURL: "http://test.com/abc123",
+			expected: "abc123",
+		},
+		{
+			name:     "just ID",
+			shortURL: "abc123",
+			expected: "abc123",
+		},
+		{
+			name:     "URL with path",
+			shortURL: "http://test.com/path/def456",
 			expected: "def456",
 		},
+		{
+			name:     "URL with query and fragment",
+			shortURL: "http://test.com/abc123?utm_source=mail#top",
+			expected: "abc123",
+		},
+		{
+			name:     "URL with trailing slash",
+			shortURL: "http://test.com/abc123/",
+			expected: "abc123",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AliasCase is the policy for uppercase letters in custom aliases.
+type AliasCase int
+
+const (
+	// AliasCasePreserve keeps aliases as given, so "Promo" and "promo" are different aliases.
+	AliasCasePreserve AliasCase = iota
+	// AliasCaseFold lowercases aliases, so "Promo" and "promo" are the same alias.
+	AliasCaseFold
+	// AliasCaseLower rejects aliases containing uppercase letters.
+	AliasCaseLower
+)
+
+// aliasCases maps the configuration names of the case policies to their values.
+var aliasCases = map[string]AliasCase{
+	"preserve": AliasCasePreserve,
+	"fold":     AliasCaseFold,
+	"lower":    AliasCaseLower,
+}
+
+// ParseAliasCase returns the case policy with the given configuration name.
+//
+// Parameters:
+//   - name: "preserve", "fold" or "lower"
+//
+// Returns:
+//   - AliasCase: The case policy
+//   - error: Error if the name is unknown
+func ParseAliasCase(name string) (AliasCase, error) {
+	policy, ok := aliasCases[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown alias case policy %q; use preserve, fold or lower", name)
+	}
+	return policy, nil
+}
+
+// AliasPolicy holds the rules custom aliases must satisfy. Aliases become a path segment
+// of the short URL, so they are restricted to ASCII letters, digits and a few symbols
+// that need no escaping, and must start and end with a letter or digit.
+type AliasPolicy struct {
+	MinLength        int       // Minimum length in characters
+	MaxLength        int       // Maximum length in characters
+	Symbols          string    // Characters allowed besides letters and digits, never at either end
+	Case             AliasCase // Treatment of uppercase letters
+	ReservedPrefixes []string  // Prefixes reserved for the service's own paths, matched ignoring case
+}
+
+// DefaultAliasPolicy returns the rules used when none are configured: 3 to 64 letters,
+// digits, hyphens and underscores, case preserved, and no alias starting with the
+// prefix of an API route.
+func DefaultAliasPolicy() AliasPolicy {
+	return AliasPolicy{
+		MinLength:        3,
+		MaxLength:        64,
+		Symbols:          "-_",
+		Case:             AliasCasePreserve,
+		ReservedPrefixes: []string{"admin", "api", "v1"},
+	}
+}
+
+// Alias is a validated custom short URL identifier. The zero value is the empty alias,
+// which no policy accepts.
+type Alias struct {
+	value string // The alias after the case policy has been applied
+}
+
+// NewAlias validates a custom alias against a policy.
+//
+// Parameters:
+//   - raw: The alias as supplied by the user
+//   - policy: The rules the alias must satisfy
+//
+// Returns:
+//   - Alias: The alias, lowercased under AliasCaseFold
+//   - error: ErrCustomURLRequired if raw is empty, or an ErrInvalidInput domain error
+//     describing the first rule the alias breaks
+func NewAlias(raw string, policy AliasPolicy) (Alias, error) {
+	if raw == "" {
+		return Alias{}, ErrCustomURLRequired
+	}
+	// Check the length first so that huge inputs are rejected without scanning them
+	if len(raw) > policy.MaxLength {
+		return Alias{}, NewError(ErrInvalidInput, "alias_too_long",
+			fmt.Sprintf("customUrl must be at most %d characters, got %d", policy.MaxLength, len(raw)))
+	}
+	if policy.Case == AliasCaseFold {
+		raw = strings.ToLower(raw)
+	}
+
+	for i, r := range raw {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case r >= 'A' && r <= 'Z':
+			if policy.Case == AliasCaseLower {
+				return Alias{}, NewError(ErrInvalidInput, "alias_uppercase",
+					fmt.Sprintf("customUrl must be lowercase, got %q at position %d", r, i))
+			}
+		case r < 0x80 && strings.ContainsRune(policy.Symbols, r):
+			if i == 0 || i == len(raw)-1 {
+				return Alias{}, NewError(ErrInvalidInput, "alias_invalid_boundary",
+					fmt.Sprintf("customUrl must start and end with a letter or digit, got %q", r))
+			}
+		default:
+			return Alias{}, NewError(ErrInvalidInput, "alias_invalid_character",
+				fmt.Sprintf("customUrl may only contain letters, digits and %q, got %q at position %d", policy.Symbols, r, i))
+		}
+	}
+
+	// Every remaining character is ASCII, so the byte length is the character count
+	if len(raw) < policy.MinLength {
+		return Alias{}, NewError(ErrInvalidInput, "alias_too_short",
+			fmt.Sprintf("customUrl must be at least %d characters, got %d", policy.MinLength, len(raw)))
+	}
+	lower := strings.ToLower(raw)
+	for _, prefix := range policy.ReservedPrefixes {
+		if prefix != "" && strings.HasPrefix(lower, strings.ToLower(prefix)) {
+			return Alias{}, NewError(ErrInvalidInput, "alias_reserved_prefix",
+				fmt.Sprintf("customUrl must not start with the reserved prefix %q", prefix))
+		}
+	}
+	return Alias{value: raw}, nil
+}
+
+// String returns the alias.
+func (a Alias) String() string {
+	return a.value
+}
+
+// IsZero reports whether the alias is the empty zero value.
+func (a Alias) IsZero() bool {
+	return a.value == ""
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewAlias(t *testing.T) {
+	fold := DefaultAliasPolicy()
+	fold.Case = AliasCaseFold
+	lower := DefaultAliasPolicy()
+	lower.Case = AliasCaseLower
+
+	tests := []struct {
+		name         string
+		raw          string
+		policy       AliasPolicy
+		expected     string
+		expectedCode string
+	}{
+		{"letters and digits", "Promo2024", DefaultAliasPolicy(), "Promo2024", ""},
+		{"inner symbols", "spring-sale_24", DefaultAliasPolicy(), "spring-sale_24", ""},
+		{"minimum length", "abc", DefaultAliasPolicy(), "abc", ""},
+		{"maximum length", strings.Repeat("a", 64), DefaultAliasPolicy(), strings.Repeat("a", 64), ""},
+		{"case folded", "Promo2024", fold, "promo2024", ""},
+		{"lowercase under lower policy", "promo2024", lower, "promo2024", ""},
+		{"empty", "", DefaultAliasPolicy(), "", "custom_url_required"},
+		{"too short", "ab", DefaultAliasPolicy(), "", "alias_too_short"},
+		{"too long", strings.Repeat("a", 65), DefaultAliasPolicy(), "", "alias_too_long"},
+		{"huge input", strings.Repeat("/", 1<<20), DefaultAliasPolicy(), "", "alias_too_long"},
+		{"slash", "a/b/c", DefaultAliasPolicy(), "", "alias_invalid_character"},
+		{"space", "my alias", DefaultAliasPolicy(), "", "alias_invalid_character"},
+		{"control character", "abc\x00def", DefaultAliasPolicy(), "", "alias_invalid_character"},
+		{"unicode letter", "café", DefaultAliasPolicy(), "", "alias_invalid_character"},
+		{"percent escape", "a%2Fb", DefaultAliasPolicy(), "", "alias_invalid_character"},
+		{"leading symbol", "-abc", DefaultAliasPolicy(), "", "alias_invalid_boundary"},
+		{"trailing symbol", "abc_", DefaultAliasPolicy(), "", "alias_invalid_boundary"},
+		{"uppercase under lower policy", "Promo", lower, "", "alias_uppercase"},
+		{"reserved prefix", "admin-panel", DefaultAliasPolicy(), "", "alias_reserved_prefix"},
+		{"reserved prefix any case", "V1promo", DefaultAliasPolicy(), "", "alias_reserved_prefix"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alias, err := NewAlias(tt.raw, tt.policy)
+			if tt.expectedCode != "" {
+				var domainErr *Error
+				if !errors.As(err, &domainErr) || domainErr.Code != tt.expectedCode {
+					t.Fatalf("expected error code %q, got %v", tt.expectedCode, err)
+				}
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Errorf("expected ErrInvalidInput kind, got %v", err)
+				}
+				if !alias.IsZero() {
+					t.Errorf("expected zero alias on error, got %q", alias)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if alias.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, alias)
+			}
+		})
+	}
+}
+
+func TestParseAliasCase(t *testing.T) {
+	tests := []struct {
+		name        string
+		expected    AliasCase
+		expectError bool
+	}{
+		{"preserve", AliasCasePreserve, false},
+		{"FOLD", AliasCaseFold, false},
+		{"lower", AliasCaseLower, false},
+		{"upper", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParseAliasCase(tt.name)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected error=%v, got %v", tt.expectError, err)
+			}
+			if policy != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, policy)
+			}
+		})
+	}
+}
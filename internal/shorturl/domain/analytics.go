@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // AnalyticsEvent represents an event that occurred in the URL shortening system.
 // These events are used for tracking user behavior, system performance, and business metrics.
@@ -17,6 +20,7 @@ type AnalyticsEvent struct {
 // (Kafka, HTTP endpoints, local files, etc.) to be used.
 type AnalyticsService interface {
 	// SendEvent transmits an analytics event to the configured analytics system.
-	// The implementation should handle event formatting, batching, and error recovery.
-	SendEvent(event AnalyticsEvent) error
+	// The implementation should handle event formatting, batching, and error recovery,
+	// and should give up and return the context's error once the context is canceled.
+	SendEvent(ctx context.Context, event AnalyticsEvent) error
 }
@@ -0,0 +1,132 @@
+package domain
+
+import "strings"
+
+// Errors returned for IDs rejected by a Blocklist.
+var (
+	// ErrReservedShortID is returned when an ID equals a reserved word such as a route prefix.
+	ErrReservedShortID = NewError(ErrInvalidInput, "reserved_short_id", "short URL ID is reserved")
+	// ErrBlockedShortID is returned when an ID contains a blocked word.
+	ErrBlockedShortID = NewError(ErrInvalidInput, "blocked_short_id", "short URL ID contains a blocked word")
+)
+
+// defaultReservedWords are the path segments the server routes itself. An ID equal to
+// one of them would be shadowed by the route or make the route ambiguous.
+var defaultReservedWords = []string{
+	"admin", "api", "v1", "v2", "health", "healthz", "metrics", "static", "assets",
+	"login", "logout", "favicon.ico", "robots.txt",
+}
+
+// leetFold maps characters commonly substituted for letters to the letter they stand
+// for, so that "h3ll0" and "hello" fold to the same text. Look-alike letters share
+// a class too: "l" and "1" both fold to "i".
+var leetFold = map[byte]byte{
+	'4': 'a', '@': 'a',
+	'8': 'b',
+	'3': 'e',
+	'9': 'g',
+	'1': 'i', 'l': 'i', '!': 'i', '|': 'i',
+	'0': 'o',
+	'5': 's', '$': 's',
+	'7': 't', '+': 't',
+	'2': 'z',
+}
+
+// Blocklist decides which IDs may be handed out. Blocked words are matched anywhere in
+// the ID, ignoring case, separators and leetspeak substitutions, because generated IDs
+// can spell them by chance. Reserved words are matched against the whole ID, ignoring
+// case, because only an exact match collides with a route.
+type Blocklist struct {
+	words    []string        // Blocked words in folded form
+	reserved map[string]bool // Reserved words in lowercase
+}
+
+// NewBlocklist creates a blocklist. Empty words are ignored.
+//
+// Parameters:
+//   - words: Words that IDs must not contain
+//   - reserved: Words that IDs must not equal, typically DefaultReservedWords plus additions
+//
+// Returns:
+//   - *Blocklist: The blocklist
+func NewBlocklist(words, reserved []string) *Blocklist {
+	b := &Blocklist{reserved: make(map[string]bool, len(reserved))}
+	seen := make(map[string]bool, len(words))
+	for _, word := range words {
+		folded := foldLeetspeak(word)
+		if folded == "" || seen[folded] {
+			continue
+		}
+		seen[folded] = true
+		b.words = append(b.words, folded)
+	}
+	for _, word := range reserved {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			b.reserved[word] = true
+		}
+	}
+	return b
+}
+
+// DefaultReservedWords returns the path segments used by the server's own routes.
+func DefaultReservedWords() []string {
+	return append([]string(nil), defaultReservedWords...)
+}
+
+// ParseWordList parses a comma-separated word list such as "foo,bar". Surrounding
+// whitespace and empty entries are dropped.
+func ParseWordList(spec string) []string {
+	var words []string
+	for _, word := range strings.Split(spec, ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// Check reports why an ID may not be handed out, if it may not.
+//
+// Parameters:
+//   - id: The ID to check
+//
+// Returns:
+//   - error: ErrReservedShortID, ErrBlockedShortID, or nil if the ID is allowed
+func (b *Blocklist) Check(id string) error {
+	if b.reserved[strings.ToLower(id)] {
+		return ErrReservedShortID
+	}
+	folded := foldLeetspeak(id)
+	for _, word := range b.words {
+		if strings.Contains(folded, word) {
+			return ErrBlockedShortID
+		}
+	}
+	return nil
+}
+
+// Allows reports whether an ID may be handed out.
+func (b *Blocklist) Allows(id string) bool {
+	return b.Check(id) == nil
+}
+
+// foldLeetspeak lowercases text, replaces leetspeak substitutions with the letters
+// they stand for and drops separators.
+func foldLeetspeak(text string) string {
+	folded := make([]byte, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		switch c {
+		case '-', '_', '.', ' ':
+			continue
+		}
+		if letter, ok := leetFold[c]; ok {
+			c = letter
+		}
+		folded = append(folded, c)
+	}
+	return string(folded)
+}
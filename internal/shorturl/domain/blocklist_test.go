@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBlocklist_Check(t *testing.T) {
+	blocklist := NewBlocklist([]string{"hell", "Scam", ""}, DefaultReservedWords())
+
+	tests := []struct {
+		name        string
+		id          string
+		expectError error
+	}{
+		{"allowed", "aZ3kP9q", nil},
+		{"blocked word", "hello12", ErrBlockedShortID},
+		{"blocked word in the middle", "xxHELLx", ErrBlockedShortID},
+		{"leetspeak digits", "h3ll0ab", ErrBlockedShortID},
+		{"look-alike letters", "he11abc", ErrBlockedShortID},
+		{"separators ignored", "s-c-a-m", ErrBlockedShortID},
+		{"case of blocked word ignored", "xSCAMx", ErrBlockedShortID},
+		{"leetspeak in blocked word", "5c4m000", ErrBlockedShortID},
+		{"reserved word", "admin", ErrReservedShortID},
+		{"reserved word any case", "V1", ErrReservedShortID},
+		{"reserved word only as whole ID", "adminxyz", nil},
+		{"reserved file name", "favicon.ico", ErrReservedShortID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := blocklist.Check(tt.id)
+			if !errors.Is(err, tt.expectError) || (err == nil) != (tt.expectError == nil) {
+				t.Errorf("expected %v, got %v", tt.expectError, err)
+			}
+			if blocklist.Allows(tt.id) != (tt.expectError == nil) {
+				t.Errorf("Allows disagrees with Check for %q", tt.id)
+			}
+		})
+	}
+}
+
+func TestNewBlocklist_Empty(t *testing.T) {
+	blocklist := NewBlocklist(nil, nil)
+	for _, id := range []string{"admin", "hello", ""} {
+		if !blocklist.Allows(id) {
+			t.Errorf("expected empty blocklist to allow %q", id)
+		}
+	}
+}
+
+func TestParseWordList(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected []string
+	}{
+		{"", nil},
+		{"foo", []string{"foo"}},
+		{" foo , bar,,baz ", []string{"foo", "bar", "baz"}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseWordList(tt.spec); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ParseWordList(%q) = %v, expected %v", tt.spec, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package domain
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Errors returned for destinations on the destination blocklist.
+var (
+	// ErrBlockedDestination is returned when a long URL to be shortened is blocklisted.
+	ErrBlockedDestination = NewError(ErrInvalidInput, "long_url_blocked", "longUrl is on the malicious destination blocklist")
+	// ErrShortURLBlocked is returned when the destination of an existing short URL has
+	// been blocklisted since it was created, or the URL itself is in StateBlocked.
+	ErrShortURLBlocked = NewError(ErrBlocked, "short_url_blocked", "short URL destination is blocked as malicious")
+)
+
+// DestinationRuleKind selects how a DestinationRule matches long URLs.
+type DestinationRuleKind string
+
+const (
+	// RuleHost matches URLs whose host equals the pattern.
+	RuleHost DestinationRuleKind = "host"
+	// RuleSuffix matches URLs whose host equals the pattern or is a subdomain of it.
+	RuleSuffix DestinationRuleKind = "suffix"
+	// RulePrefix matches URLs that start with the pattern, a normalized URL.
+	RulePrefix DestinationRuleKind = "prefix"
+)
+
+// prefixRulePolicy normalizes the patterns of prefix rules like long URLs.
+var prefixRulePolicy = LongURLPolicy{AllowedSchemes: []string{"http", "https"}, MaxLength: 8192}
+
+// DestinationRule is a single entry of the destination blocklist.
+type DestinationRule struct {
+	Kind    DestinationRuleKind // How the pattern is matched
+	Pattern string              // Normalized host name or URL prefix
+}
+
+// String returns the rule in the blocklist file syntax, e.g. "suffix:phish.example".
+func (r DestinationRule) String() string {
+	return string(r.Kind) + ":" + r.Pattern
+}
+
+// DestinationBlocklist decides whether a destination is known to be malicious,
+// for example a phishing or malware site.
+type DestinationBlocklist interface {
+	// Match returns the rule that blocks the long URL, if any.
+	Match(longURL string) (DestinationRule, bool)
+}
+
+// DestinationRules is an immutable set of blocklist rules, indexed for lookups
+// that do not depend on the number of host and suffix rules.
+type DestinationRules struct {
+	hosts    map[string]DestinationRule // Host rules by host
+	suffixes map[string]DestinationRule // Suffix rules by domain
+	prefixes []DestinationRule          // Prefix rules in the order given
+}
+
+// NewDestinationRules creates a rule set, normalizing the patterns.
+//
+// Parameters:
+//   - rules: The rules; host patterns may be internationalized, prefix patterns must be http(s) URLs
+//
+// Returns:
+//   - *DestinationRules: The rule set
+//   - error: Error if a rule has an unknown kind or an invalid pattern
+func NewDestinationRules(rules ...DestinationRule) (*DestinationRules, error) {
+	set := &DestinationRules{
+		hosts:    make(map[string]DestinationRule),
+		suffixes: make(map[string]DestinationRule),
+	}
+	for _, rule := range rules {
+		switch rule.Kind {
+		case RuleHost, RuleSuffix:
+			host := normalizeHost(rule.Pattern)
+			if host == "" {
+				return nil, fmt.Errorf("rule %s has an invalid host", rule)
+			}
+			rule.Pattern = host
+			if rule.Kind == RuleHost {
+				set.hosts[host] = rule
+			} else {
+				set.suffixes[host] = rule
+			}
+		case RulePrefix:
+			prefix, err := ParseLongURL(rule.Pattern, prefixRulePolicy)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s has an invalid URL: %w", rule, err)
+			}
+			rule.Pattern = prefix.String()
+			set.prefixes = append(set.prefixes, rule)
+		default:
+			return nil, fmt.Errorf("rule %s has unknown kind %q", rule, rule.Kind)
+		}
+	}
+	return set, nil
+}
+
+// ParseDestinationRules reads blocklist rules, one per line. A line is a rule of the form
+// "host:evil.example", "suffix:phish.example" or "prefix:https://sites.example/attacker/".
+// Lines without a kind are read as a prefix rule if they contain "://", as a suffix rule
+// if they start with "*." or ".", and as a host rule otherwise. Blank lines and lines
+// starting with "#" are ignored.
+//
+// Parameters:
+//   - r: Reader of the rule list
+//
+// Returns:
+//   - *DestinationRules: The rule set
+//   - error: Error naming the line of the first invalid rule, or a read error
+func ParseDestinationRules(r io.Reader) (*DestinationRules, error) {
+	var rules []DestinationRule
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var rule DestinationRule
+		kind, pattern, found := strings.Cut(text, ":")
+		switch {
+		case found && (kind == string(RuleHost) || kind == string(RuleSuffix) || kind == string(RulePrefix)):
+			rule = DestinationRule{Kind: DestinationRuleKind(kind), Pattern: strings.TrimSpace(pattern)}
+		case strings.Contains(text, "://"):
+			rule = DestinationRule{Kind: RulePrefix, Pattern: text}
+		case strings.HasPrefix(text, "*.") || strings.HasPrefix(text, "."):
+			rule = DestinationRule{Kind: RuleSuffix, Pattern: strings.TrimLeft(text, "*.")}
+		default:
+			rule = DestinationRule{Kind: RuleHost, Pattern: text}
+		}
+		if rule.Pattern == "" {
+			return nil, fmt.Errorf("line %d: empty pattern", line)
+		}
+		rules = append(rules, rule)
+		if _, err := NewDestinationRules(rule); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewDestinationRules(rules...)
+}
+
+// Match returns the rule that blocks the long URL, if any. Host rules are checked
+// first, then suffix rules from the full host up to its parent domains, then prefix rules.
+//
+// Parameters:
+//   - longURL: The long URL, normalized as by ParseLongURL
+//
+// Returns:
+//   - DestinationRule: The matching rule
+//   - bool: Whether a rule matches
+func (d *DestinationRules) Match(longURL string) (DestinationRule, bool) {
+	parsed, err := url.Parse(longURL)
+	if err != nil {
+		return DestinationRule{}, false
+	}
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if rule, ok := d.hosts[host]; ok {
+		return rule, true
+	}
+	for domain := host; domain != ""; {
+		if rule, ok := d.suffixes[domain]; ok {
+			return rule, true
+		}
+		_, domain, _ = strings.Cut(domain, ".")
+	}
+	for _, rule := range d.prefixes {
+		if strings.HasPrefix(longURL, rule.Pattern) {
+			return rule, true
+		}
+	}
+	return DestinationRule{}, false
+}
+
+// Len returns the number of rules.
+func (d *DestinationRules) Len() int {
+	return len(d.hosts) + len(d.suffixes) + len(d.prefixes)
+}
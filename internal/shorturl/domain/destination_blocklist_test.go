@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDestinationRules(t *testing.T) {
+	rules, err := ParseDestinationRules(strings.NewReader(`
+# Phishing feed
+host:login-paypa1.example
+Evil.Example
+suffix:phish.example
+*.malware.example
+.tracker.example
+prefix:https://sites.example/attacker/
+HTTPS://Docs.Example:443/forms/d/evil
+bücher-login.example
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rules.Len() != 8 {
+		t.Errorf("expected 8 rules, got %d", rules.Len())
+	}
+
+	tests := []struct {
+		name         string
+		longURL      string
+		expectedRule string
+	}{
+		{"host rule", "https://login-paypa1.example/signin", "host:login-paypa1.example"},
+		{"bare host rule is case-insensitive", "http://evil.example", "host:evil.example"},
+		{"host rule does not match subdomains", "https://www.evil.example/", ""},
+		{"suffix rule matches the domain", "https://phish.example/", "suffix:phish.example"},
+		{"suffix rule matches subdomains", "https://a.b.phish.example/x", "suffix:phish.example"},
+		{"wildcard suffix", "https://cdn.malware.example/payload", "suffix:malware.example"},
+		{"dot suffix", "https://tracker.example/", "suffix:tracker.example"},
+		{"suffix rule needs a label boundary", "https://notphish.example/", ""},
+		{"prefix rule", "https://sites.example/attacker/page", "prefix:https://sites.example/attacker/"},
+		{"prefix rule does not match siblings", "https://sites.example/someone-else/", ""},
+		{"bare prefix rule is normalized", "https://docs.example/forms/d/evil/viewform", "prefix:https://docs.example/forms/d/evil"},
+		{"internationalized host", MustParseLongURL("https://bücher-login.example/").String(), "host:xn--bcher-login-thb.example"},
+		{"clean URL", "https://example.com/", ""},
+		{"unparsable URL", "http://[::1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, blocked := rules.Match(tt.longURL)
+			if blocked != (tt.expectedRule != "") {
+				t.Fatalf("expected blocked=%v, got %v (rule %s)", tt.expectedRule != "", blocked, rule)
+			}
+			if blocked && rule.String() != tt.expectedRule {
+				t.Errorf("expected rule %q, got %q", tt.expectedRule, rule)
+			}
+		})
+	}
+}
+
+func TestParseDestinationRules_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"empty pattern", "ok.example\nhost:", "line 2"},
+		{"invalid prefix", "prefix:javascript:alert(1)", "line 1"},
+		{"empty host", "# comment\n\nsuffix:.", "line 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDestinationRules(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error mentioning %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestNewDestinationRules_UnknownKind(t *testing.T) {
+	if _, err := NewDestinationRules(DestinationRule{Kind: "regex", Pattern: ".*"}); err == nil {
+		t.Error("expected error for unknown rule kind")
+	}
+}
@@ -0,0 +1,88 @@
+package domain
+
+import "errors"
+
+// Error kinds classify domain errors independently of their wording.
+// Every Error wraps exactly one kind, so callers can use errors.Is to decide
+// how to react (e.g. which HTTP status to return) without inspecting messages.
+var (
+	// ErrNotFound indicates that the requested entity does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict indicates that the operation conflicts with existing state.
+	ErrConflict = errors.New("conflict")
+	// ErrExpired indicates that the entity exists but has passed its expiration time.
+	ErrExpired = errors.New("expired")
+	// ErrInactive indicates that the entity exists but is not active.
+	ErrInactive = errors.New("inactive")
+	// ErrInvalidInput indicates that the caller supplied invalid data.
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrBlocked indicates that the entity exists but must not be served, e.g. because it is unsafe.
+	ErrBlocked = errors.New("blocked")
+	// ErrUnavailable indicates that the entity exists but is temporarily not served.
+	ErrUnavailable = errors.New("unavailable")
+	// ErrUnauthorized indicates that the entity exists but the caller has not proven access to it.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRateLimited indicates that the caller made too many attempts and must wait before retrying.
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// Error is a domain error carrying a stable, machine-readable code.
+// The code is part of the public API contract: clients switch on it, so it must
+// never change once published, while the message may be reworded freely.
+type Error struct {
+	Kind    error  // One of the error kinds above (ErrNotFound, ErrConflict, ...)
+	Code    string // Stable identifier such as "short_url_not_found"
+	Message string // Human-readable description
+}
+
+// NewError creates a domain error of the given kind.
+//
+// Parameters:
+//   - kind: The error kind, one of ErrNotFound, ErrConflict, ErrExpired, ErrInactive, ErrInvalidInput, ErrBlocked,
+//     ErrUnavailable, ErrUnauthorized or ErrRateLimited
+//   - code: Stable machine-readable error code
+//   - message: Human-readable description
+//
+// Returns:
+//   - *Error: The created domain error
+func NewError(kind error, code, message string) *Error {
+	return &Error{
+		Kind:    kind,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Error returns the human-readable message.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the error kind so that errors.Is(err, ErrNotFound) and similar checks work.
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// Predefined domain errors returned by entities, repositories and application services.
+var (
+	// ErrShortURLNotFound is returned when no short URL exists for the requested identifier.
+	ErrShortURLNotFound = NewError(ErrNotFound, "short_url_not_found", "short URL not found")
+	// ErrShortURLAlreadyExists is returned by ShortURLRepository.Insert when an entity
+	// with the same identifier is already stored.
+	ErrShortURLAlreadyExists = NewError(ErrConflict, "short_url_exists", "short URL already exists")
+	// ErrCustomURLAlreadyExists is returned when a requested custom URL is already taken.
+	ErrCustomURLAlreadyExists = NewError(ErrConflict, "custom_url_exists", "custom URL already exists")
+	// ErrShortURLExpired is returned when a short URL has passed its expiration time.
+	ErrShortURLExpired = NewError(ErrExpired, "short_url_expired", "short URL has expired")
+	// ErrShortURLInactive is returned when a short URL has been deactivated.
+	// Its code predates the lifecycle states and is kept for compatibility.
+	ErrShortURLInactive = NewError(ErrInactive, "short_url_inactive", "short URL is not active")
+	// ErrLongURLRequired is returned when the long URL is missing.
+	ErrLongURLRequired = NewError(ErrInvalidInput, "long_url_required", "long URL cannot be empty")
+	// ErrShortURLRequired is returned when the short URL is missing.
+	ErrShortURLRequired = NewError(ErrInvalidInput, "short_url_required", "short URL cannot be empty")
+	// ErrCustomURLRequired is returned when an empty custom URL is supplied.
+	ErrCustomURLRequired = NewError(ErrInvalidInput, "custom_url_required", "custom URL cannot be empty")
+	// ErrIDRequired is returned when an operation is missing the short URL identifier.
+	ErrIDRequired = NewError(ErrInvalidInput, "id_required", "ID is required")
+)
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"net"
+	"strings"
+)
+
+// defaultShortenerHosts are widely used public URL shorteners. Their links hide the
+// destination and can chain back to this service.
+var defaultShortenerHosts = []string{
+	"bit.ly", "bitly.com", "buff.ly", "cutt.ly", "goo.gl", "is.gd", "lnkd.in", "ow.ly",
+	"rb.gy", "rebrand.ly", "s.id", "shorturl.at", "t.co", "t.ly", "tiny.cc", "tinyurl.com", "v.gd",
+}
+
+// HostSet is a set of host names that also matches their subdomains, so that a set
+// containing "bit.ly" matches "bit.ly" and "www.bit.ly" but not "orbit.ly".
+type HostSet struct {
+	hosts map[string]bool // Lowercase ASCII host names
+}
+
+// NewHostSet creates a host set. Entries may carry a scheme or port, which are ignored,
+// and may be internationalized; empty entries are skipped.
+//
+// Parameters:
+//   - hosts: Host names such as "example.com", "https://example.com:8443" or "bücher.example"
+//
+// Returns:
+//   - *HostSet: The host set
+func NewHostSet(hosts ...string) *HostSet {
+	set := &HostSet{hosts: make(map[string]bool, len(hosts))}
+	for _, host := range hosts {
+		if host = normalizeHost(host); host != "" {
+			set.hosts[host] = true
+		}
+	}
+	return set
+}
+
+// DefaultShortenerHosts returns the host names of well-known public URL shorteners.
+func DefaultShortenerHosts() []string {
+	return append([]string(nil), defaultShortenerHosts...)
+}
+
+// Contains reports whether host or one of its parent domains is in the set.
+//
+// Parameters:
+//   - host: Host name without port, as returned by LongURL.Host
+//
+// Returns:
+//   - bool: Whether the host matches
+func (s *HostSet) Contains(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for host != "" {
+		if s.hosts[host] {
+			return true
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			return false
+		}
+		host = parent
+	}
+	return false
+}
+
+// Add adds hosts to the set, normalized as by NewHostSet.
+func (s *HostSet) Add(hosts ...string) {
+	for _, host := range hosts {
+		if host = normalizeHost(host); host != "" {
+			s.hosts[host] = true
+		}
+	}
+}
+
+// normalizeHost reduces a host entry to its lowercase ASCII host name.
+func normalizeHost(host string) string {
+	host = strings.TrimSpace(host)
+	if _, rest, found := strings.Cut(host, "://"); found {
+		host = rest
+	}
+	host, _, _ = strings.Cut(host, "/")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	host = strings.TrimSuffix(host, ".")
+	ascii, err := toASCIIHost(host)
+	if err != nil {
+		return ""
+	}
+	return ascii
+}
@@ -0,0 +1,55 @@
+package domain
+
+import "testing"
+
+func TestHostSet_Contains(t *testing.T) {
+	set := NewHostSet("bit.ly", "https://Short.example:8443/", "bücher.example", "[::1]:8080", "")
+
+	tests := []struct {
+		host     string
+		expected bool
+	}{
+		{"bit.ly", true},
+		{"BIT.LY", true},
+		{"www.bit.ly", true},
+		{"bit.ly.", true},
+		{"orbit.ly", false},
+		{"ly", false},
+		{"short.example", true},
+		{"go.short.example", true},
+		{"xn--bcher-kva.example", true},
+		{"::1", true},
+		{"example.com", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := set.Contains(tt.host); got != tt.expected {
+				t.Errorf("Contains(%q) = %v, expected %v", tt.host, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHostSet_Add(t *testing.T) {
+	set := NewHostSet()
+	if set.Contains("example.com") {
+		t.Fatal("expected empty set")
+	}
+	set.Add("http://localhost:8080", "Example.com")
+	for _, host := range []string{"localhost", "example.com", "www.example.com"} {
+		if !set.Contains(host) {
+			t.Errorf("expected %q after Add", host)
+		}
+	}
+}
+
+func TestDefaultShortenerHosts(t *testing.T) {
+	set := NewHostSet(DefaultShortenerHosts()...)
+	for _, host := range []string{"bit.ly", "tinyurl.com", "t.co"} {
+		if !set.Contains(host) {
+			t.Errorf("expected %q to be a known shortener", host)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// ID alphabets supported by IDProfile.
+const (
+	// AlphabetBase62 contains digits, lowercase and uppercase letters.
+	AlphabetBase62 = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	// AlphabetBase58 is Base62 without the easily confused characters 0, O, l and I.
+	AlphabetBase58 = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	// AlphabetLowercase contains digits and lowercase letters only, for channels that
+	// do not preserve case such as SMS or spoken links.
+	AlphabetLowercase = "0123456789abcdefghijklmnopqrstuvwxyz"
+)
+
+// DefaultIDLength is the length of generated IDs when no profile is configured.
+const DefaultIDLength = 7
+
+// idAlphabets maps the configuration names of the supported alphabets to their characters.
+var idAlphabets = map[string]string{
+	"base62":    AlphabetBase62,
+	"base58":    AlphabetBase58,
+	"lowercase": AlphabetLowercase,
+}
+
+// ErrInvalidShortID is returned when an incoming ID contains characters that no ID of
+// the configured profile can contain.
+var ErrInvalidShortID = NewError(ErrInvalidInput, "invalid_short_id", "short URL ID contains characters that are not allowed")
+
+// IDProfile describes the format of short URL IDs: the alphabet they are written in and
+// the length of generated IDs. Alphabets without uppercase letters are case-insensitive:
+// incoming IDs are lowercased before they are validated or looked up.
+type IDProfile struct {
+	alphabet        string    // Characters in digit order
+	length          int       // Length of generated IDs
+	space           uint64    // Number of distinct generated IDs, len(alphabet)^length
+	caseInsensitive bool      // Whether uppercase input is folded to lowercase
+	digits          [256]int8 // Digit value of each byte, -1 for bytes outside the alphabet
+}
+
+// NewIDProfile creates an ID profile.
+//
+// Parameters:
+//   - alphabet: Distinct ASCII characters in digit order, at least two
+//   - length: Length of generated IDs, at least one
+//
+// Returns:
+//   - *IDProfile: The profile
+//   - error: Error if the alphabet is invalid or the ID space does not fit in 63 bits
+func NewIDProfile(alphabet string, length int) (*IDProfile, error) {
+	if len(alphabet) < 2 || len(alphabet) > math.MaxInt8 {
+		return nil, fmt.Errorf("alphabet must have between 2 and %d characters, got %d", math.MaxInt8, len(alphabet))
+	}
+	if length < 1 {
+		return nil, fmt.Errorf("ID length must be positive, got %d", length)
+	}
+
+	p := &IDProfile{
+		alphabet:        alphabet,
+		length:          length,
+		caseInsensitive: strings.ToLower(alphabet) == alphabet,
+	}
+	for i := range p.digits {
+		p.digits[i] = -1
+	}
+	for i := 0; i < len(alphabet); i++ {
+		c := alphabet[i]
+		if c < '!' || c > '~' {
+			return nil, fmt.Errorf("alphabet character %q is not printable ASCII", c)
+		}
+		if p.digits[c] >= 0 {
+			return nil, fmt.Errorf("alphabet character %q appears twice", c)
+		}
+		p.digits[c] = int8(i)
+	}
+
+	// Counters are int64, so the whole ID space must stay below 2^63
+	p.space = 1
+	for range length {
+		hi, lo := bits.Mul64(p.space, uint64(len(alphabet)))
+		if hi != 0 || lo > math.MaxInt64 {
+			return nil, fmt.Errorf("%d-character IDs over %d characters exceed the 63-bit ID space", length, len(alphabet))
+		}
+		p.space = lo
+	}
+	return p, nil
+}
+
+// NewNamedIDProfile creates an ID profile for one of the named alphabets.
+//
+// Parameters:
+//   - name: "base62", "base58" or "lowercase"
+//   - length: Length of generated IDs
+//
+// Returns:
+//   - *IDProfile: The profile
+//   - error: Error if the name is unknown or the length is invalid
+func NewNamedIDProfile(name string, length int) (*IDProfile, error) {
+	alphabet, ok := idAlphabets[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown ID alphabet %q; use base62, base58 or lowercase", name)
+	}
+	return NewIDProfile(alphabet, length)
+}
+
+// DefaultIDProfile returns the profile of 7-character Base62 IDs.
+func DefaultIDProfile() *IDProfile {
+	profile, _ := NewIDProfile(AlphabetBase62, DefaultIDLength)
+	return profile
+}
+
+// Alphabet returns the characters of the profile in digit order.
+func (p *IDProfile) Alphabet() string {
+	return p.alphabet
+}
+
+// Length returns the length of generated IDs.
+func (p *IDProfile) Length() int {
+	return p.length
+}
+
+// Space returns the number of distinct generated IDs.
+func (p *IDProfile) Space() uint64 {
+	return p.space
+}
+
+// Encode writes value in the profile's alphabet, left-padded with the zero digit to the
+// profile's length. Values of at least Space() are reduced modulo Space().
+//
+// Parameters:
+//   - value: The value to encode
+//
+// Returns:
+//   - string: The encoded ID of exactly Length() characters
+func (p *IDProfile) Encode(value uint64) string {
+	value %= p.space
+	base := uint64(len(p.alphabet))
+	encoded := make([]byte, p.length)
+	for i := p.length - 1; i >= 0; i-- {
+		encoded[i] = p.alphabet[value%base]
+		value /= base
+	}
+	return string(encoded)
+}
+
+// EncodeVariable writes value in the profile's alphabet without a fixed length,
+// left-padded with the zero digit to at least minLength characters.
+//
+// Parameters:
+//   - value: The value to encode
+//   - minLength: Minimum length of the result
+//
+// Returns:
+//   - string: The encoded value
+func (p *IDProfile) EncodeVariable(value uint64, minLength int) string {
+	base := uint64(len(p.alphabet))
+	var encoded []byte
+	for value > 0 {
+		encoded = append(encoded, p.alphabet[value%base])
+		value /= base
+	}
+	for len(encoded) < minLength || len(encoded) == 0 {
+		encoded = append(encoded, p.alphabet[0])
+	}
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
+
+// Decode converts an ID written in the profile's alphabet back to its value.
+// IDs of any length are accepted as long as the value fits in 64 bits.
+//
+// Parameters:
+//   - id: The ID to decode
+//
+// Returns:
+//   - uint64: The decoded value
+//   - error: Error if the ID is empty, contains characters outside the alphabet or overflows
+func (p *IDProfile) Decode(id string) (uint64, error) {
+	if id == "" {
+		return 0, fmt.Errorf("cannot decode an empty ID")
+	}
+
+	id = p.Normalize(id)
+	base := uint64(len(p.alphabet))
+	var value uint64
+	for i := 0; i < len(id); i++ {
+		digit := p.digits[id[i]]
+		if digit < 0 {
+			return 0, fmt.Errorf("invalid character in ID: %c", id[i])
+		}
+		hi, lo := bits.Mul64(value, base)
+		sum, carry := bits.Add64(lo, uint64(digit), 0)
+		if hi != 0 || carry != 0 {
+			return 0, fmt.Errorf("ID %q overflows 64 bits", id)
+		}
+		value = sum
+	}
+	return value, nil
+}
+
+// Normalize folds the case of an incoming ID for case-insensitive profiles and returns
+// it unchanged otherwise.
+func (p *IDProfile) Normalize(id string) string {
+	if p.caseInsensitive {
+		return strings.ToLower(id)
+	}
+	return id
+}
+
+// Validate checks that a normalized ID only contains characters of the alphabet.
+// The length is not checked, since custom URLs and region prefixes make IDs of other
+// lengths legitimate.
+//
+// Parameters:
+//   - id: The normalized ID
+//
+// Returns:
+//   - error: ErrInvalidShortID if a character is outside the alphabet
+func (p *IDProfile) Validate(id string) error {
+	for i := 0; i < len(id); i++ {
+		if p.digits[id[i]] < 0 {
+			return ErrInvalidShortID
+		}
+	}
+	return nil
+}
+
+// Matches reports whether id has exactly the shape of a generated ID: the profile's
+// length and only characters of the alphabet. No case folding is applied.
+func (p *IDProfile) Matches(id string) bool {
+	return len(id) == p.length && p.Validate(id) == nil
+}
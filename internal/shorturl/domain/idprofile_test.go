@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewIDProfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		alphabet    string
+		length      int
+		expectError bool
+	}{
+		{"base62", AlphabetBase62, 7, false},
+		{"base58", AlphabetBase58, 8, false},
+		{"lowercase", AlphabetLowercase, 6, false},
+		{"largest base62 length", AlphabetBase62, 10, false},
+		{"ID space beyond 63 bits", AlphabetBase62, 11, true},
+		{"single character alphabet", "a", 7, true},
+		{"duplicate character", "abca", 7, true},
+		{"non-printable character", "ab\n", 7, true},
+		{"zero length", AlphabetBase62, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewIDProfile(tt.alphabet, tt.length)
+			if (err != nil) != tt.expectError {
+				t.Errorf("expected error=%v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestNewNamedIDProfile(t *testing.T) {
+	profile, err := NewNamedIDProfile("Base58", 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Alphabet() != AlphabetBase58 || profile.Length() != 7 {
+		t.Errorf("unexpected profile %q/%d", profile.Alphabet(), profile.Length())
+	}
+	if strings.ContainsAny(profile.Alphabet(), "0OlI") {
+		t.Error("expected Base58 to exclude 0, O, l and I")
+	}
+
+	if _, err := NewNamedIDProfile("base64", 7); err == nil {
+		t.Error("expected error for unknown alphabet")
+	}
+}
+
+func TestIDProfile_EncodeDecode(t *testing.T) {
+	for _, alphabet := range []string{AlphabetBase62, AlphabetBase58, AlphabetLowercase} {
+		profile, _ := NewIDProfile(alphabet, 7)
+
+		for _, value := range []uint64{0, 1, 57, 1000000, profile.Space() - 1} {
+			id := profile.Encode(value)
+			if !profile.Matches(id) {
+				t.Errorf("Encode(%d) = %q does not match the profile", value, id)
+			}
+			decoded, err := profile.Decode(id)
+			if err != nil || decoded != value {
+				t.Errorf("Decode(%q) = %d, %v; expected %d", id, decoded, err, value)
+			}
+		}
+
+		// Values beyond the ID space wrap around instead of growing the ID
+		if got := profile.Encode(profile.Space()); got != profile.Encode(0) {
+			t.Errorf("expected Encode(Space()) to wrap to %q, got %q", profile.Encode(0), got)
+		}
+	}
+
+	profile := DefaultIDProfile()
+	if got := profile.Encode(61); got != "000000Z" {
+		t.Errorf("expected '000000Z', got %q", got)
+	}
+	if got := profile.EncodeVariable(62, 0); got != "10" {
+		t.Errorf("expected '10', got %q", got)
+	}
+	if got := profile.EncodeVariable(0, 0); got != "0" {
+		t.Errorf("expected '0', got %q", got)
+	}
+}
+
+func TestIDProfile_DecodeErrors(t *testing.T) {
+	profile := DefaultIDProfile()
+
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{"empty", ""},
+		{"invalid character", "abc@"},
+		{"overflow", "ZZZZZZZZZZZZ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := profile.Decode(tt.id); err == nil {
+				t.Errorf("expected error decoding %q", tt.id)
+			}
+		})
+	}
+}
+
+func TestIDProfile_Validate(t *testing.T) {
+	base58, _ := NewIDProfile(AlphabetBase58, 7)
+	lowercase, _ := NewIDProfile(AlphabetLowercase, 7)
+
+	tests := []struct {
+		name       string
+		profile    *IDProfile
+		id         string
+		normalized string
+		valid      bool
+	}{
+		{"base62 keeps case", DefaultIDProfile(), "AbC1234", "AbC1234", true},
+		{"base62 rejects punctuation", DefaultIDProfile(), "abc-123", "abc-123", false},
+		{"base58 rejects ambiguous characters", base58, "abc0O1l", "abc0O1l", false},
+		{"base58 accepts its alphabet", base58, "abc23Xy", "abc23Xy", true},
+		{"lowercase folds case", lowercase, "AbC1234", "abc1234", true},
+		{"lowercase rejects punctuation", lowercase, "abc_123", "abc_123", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized := tt.profile.Normalize(tt.id)
+			if normalized != tt.normalized {
+				t.Errorf("expected normalized %q, got %q", tt.normalized, normalized)
+			}
+			err := tt.profile.Validate(normalized)
+			if tt.valid && err != nil {
+				t.Errorf("expected %q to be valid, got %v", normalized, err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("expected invalid input error for %q, got %v", normalized, err)
+			}
+		})
+	}
+
+	// Matches additionally requires the exact length of generated IDs
+	if DefaultIDProfile().Matches("abc123") {
+		t.Error("expected 6-character ID not to match a 7-character profile")
+	}
+}
@@ -1,15 +1,31 @@
 package domain
 
+import "context"
+
 // KeyGenerationService defines the contract for generating unique identifiers
 // for short URLs. This interface enables different ID generation strategies
 // to be implemented (Base62, UUID, etc.) while maintaining consistency.
+// Implementations must stop generating and return the context's error once it is canceled.
 type KeyGenerationService interface {
 	// GenerateUniqueID creates a single unique identifier for a short URL.
 	// The generated ID should be URL-safe and follow the configured format.
-	GenerateUniqueID() (string, error)
+	GenerateUniqueID(ctx context.Context) (string, error)
 
 	// GetMultipleIDs generates multiple unique identifiers in a single operation.
 	// This is useful for performance optimization when many IDs are needed.
 	// The count parameter specifies how many IDs to generate.
-	GetMultipleIDs(count int) ([]string, error)
+	GetMultipleIDs(ctx context.Context, count int) ([]string, error)
+}
+
+// HashKeyGenerationService derives short URL identifiers from the long URL itself, so that
+// the same destination always maps to the same identifier. Since distinct URLs can share
+// a hash prefix, it yields several candidate identifiers that the caller probes in order.
+type HashKeyGenerationService interface {
+	// NormalizeURL returns the canonical form of a long URL. Long URLs with the same
+	// canonical form are the same destination and yield the same candidates.
+	NormalizeURL(longURL string) (string, error)
+
+	// CandidateIDs returns count candidate identifiers for the long URL in probing order.
+	// The result depends only on the canonical form of the URL.
+	CandidateIDs(longURL string, count int) ([]string, error)
 }
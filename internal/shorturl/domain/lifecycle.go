@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// LifecycleState is the state of a short URL in its lifecycle. Only active URLs redirect;
+// every other state rejects lookups with its own error, so clients can tell them apart.
+type LifecycleState string
+
+const (
+	StateActive      LifecycleState = "active"      // Redirects to its destination
+	StatePaused      LifecycleState = "paused"      // Temporarily switched off, e.g. between campaigns
+	StateDeactivated LifecycleState = "deactivated" // Switched off; can be reactivated
+	StateBlocked     LifecycleState = "blocked"     // Switched off for abuse; visitors see a warning page
+	StateArchived    LifecycleState = "archived"    // Retired for good; kept only for analytics and audit
+	StateExhausted   LifecycleState = "exhausted"   // Reached its click limit; entered only through RecordClick
+)
+
+// MaxStateTransitions is the number of state transitions kept per short URL.
+// Older entries are dropped first.
+const MaxStateTransitions = 50
+
+// allowedTransitions lists the states each state can move to through Transition.
+// Archived is final. No state lists exhausted: only the click that uses up a URL's
+// click limit moves it there.
+var allowedTransitions = map[LifecycleState][]LifecycleState{
+	StateActive:      {StatePaused, StateDeactivated, StateBlocked, StateArchived},
+	StatePaused:      {StateActive, StateDeactivated, StateBlocked, StateArchived},
+	StateDeactivated: {StateActive, StateBlocked, StateArchived},
+	StateBlocked:     {StateActive, StateDeactivated, StateArchived},
+	StateArchived:    {},
+	StateExhausted:   {StateDeactivated, StateBlocked, StateArchived},
+}
+
+// Errors returned for lookups of short URLs that are not active, and for invalid states.
+var (
+	// ErrShortURLPaused is returned when a short URL has been paused.
+	ErrShortURLPaused = NewError(ErrUnavailable, "short_url_paused", "short URL is paused")
+	// ErrShortURLArchived is returned when a short URL has been archived.
+	ErrShortURLArchived = NewError(ErrInactive, "short_url_archived", "short URL has been archived")
+	// ErrShortURLExhausted is returned when a short URL has used up its click limit.
+	ErrShortURLExhausted = NewError(ErrInactive, "short_url_exhausted", "short URL has reached its click limit")
+	// ErrInvalidState is returned when a lifecycle state name is unknown.
+	ErrInvalidState = NewError(ErrInvalidInput, "invalid_state", "state must be active, paused, deactivated, blocked, archived or exhausted")
+	// ErrInvalidMaxClicks is returned when a click limit is not a positive number.
+	ErrInvalidMaxClicks = NewError(ErrInvalidInput, "invalid_max_clicks", "max clicks must be a positive number")
+)
+
+// ParseLifecycleState converts a state name into a LifecycleState.
+//
+// Parameters:
+//   - name: "active", "paused", "deactivated", "blocked", "archived" or "exhausted"
+//
+// Returns:
+//   - LifecycleState: The parsed state
+//   - error: ErrInvalidState if the name is unknown
+func ParseLifecycleState(name string) (LifecycleState, error) {
+	state := LifecycleState(name)
+	if _, ok := allowedTransitions[state]; !ok {
+		return "", ErrInvalidState
+	}
+	return state, nil
+}
+
+// CanTransitionTo reports whether a URL in this state may move to the target state.
+// Staying in the same state is not a transition.
+//
+// Parameters:
+//   - to: The target state
+//
+// Returns:
+//   - bool: Whether the transition is allowed
+func (s LifecycleState) CanTransitionTo(to LifecycleState) bool {
+	for _, allowed := range allowedTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StateTransition records one change of a short URL's lifecycle state.
+type StateTransition struct {
+	From   LifecycleState // State before the change
+	To     LifecycleState // State after the change
+	Reason string         // Why the state was changed; empty if not given
+	Actor  string         // Who made the change; empty if unknown
+	At     time.Time      // When the change happened
+}
+
+// newInvalidTransitionError reports a transition the lifecycle does not allow.
+func newInvalidTransitionError(from, to LifecycleState) *Error {
+	return NewError(ErrConflict, "invalid_state_transition", fmt.Sprintf("cannot change state from %s to %s", from, to))
+}
@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLifecycleState(t *testing.T) {
+	for _, name := range []string{"active", "paused", "deactivated", "blocked", "archived", "exhausted"} {
+		state, err := ParseLifecycleState(name)
+		if err != nil || string(state) != name {
+			t.Errorf("ParseLifecycleState(%q): got %q, %v", name, state, err)
+		}
+	}
+	for _, name := range []string{"", "Active", "deleted"} {
+		if _, err := ParseLifecycleState(name); err != ErrInvalidState {
+			t.Errorf("ParseLifecycleState(%q): expected ErrInvalidState, got %v", name, err)
+		}
+	}
+}
+
+func TestLifecycleState_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from     LifecycleState
+		to       LifecycleState
+		expected bool
+	}{
+		{StateActive, StatePaused, true},
+		{StateActive, StateDeactivated, true},
+		{StateActive, StateBlocked, true},
+		{StateActive, StateArchived, true},
+		{StateActive, StateActive, false},
+		{StatePaused, StateActive, true},
+		{StatePaused, StatePaused, false},
+		{StateDeactivated, StateActive, true},
+		{StateDeactivated, StatePaused, false},
+		{StateBlocked, StateActive, true},
+		{StateBlocked, StatePaused, false},
+		{StateArchived, StateActive, false},
+		{StateArchived, StateDeactivated, false},
+		{StateActive, StateExhausted, false},
+		{StateExhausted, StateActive, false},
+		{StateExhausted, StateArchived, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestShortURL_Transition(t *testing.T) {
+	shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	at := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	if shortURL.State() != StateActive {
+		t.Fatalf("expected new URL to be active, got %q", shortURL.State())
+	}
+
+	steps := []struct {
+		to           LifecycleState
+		expectedCode string
+	}{
+		{StatePaused, ""},
+		{StatePaused, "invalid_state_transition"},
+		{StateActive, ""},
+		{StateBlocked, ""},
+		{StatePaused, "invalid_state_transition"},
+		{StateArchived, ""},
+		{StateActive, "invalid_state_transition"},
+		{"deleted", "invalid_state"},
+	}
+	for i, step := range steps {
+		err := shortURL.Transition(step.to, "reason", "alice", at.Add(time.Duration(i)*time.Minute))
+		if step.expectedCode == "" {
+			if err != nil {
+				t.Fatalf("step %d to %s: unexpected error: %v", i, step.to, err)
+			}
+			continue
+		}
+		var domainErr *Error
+		if !errors.As(err, &domainErr) || domainErr.Code != step.expectedCode {
+			t.Errorf("step %d to %s: expected error code %q, got %v", i, step.to, step.expectedCode, err)
+		}
+	}
+
+	if shortURL.State() != StateArchived || shortURL.IsActive() {
+		t.Errorf("expected archived URL, got %q", shortURL.State())
+	}
+	transitions := shortURL.Transitions()
+	if len(transitions) != 4 {
+		t.Fatalf("expected 4 transitions, got %d", len(transitions))
+	}
+	expected := StateTransition{From: StateActive, To: StatePaused, Reason: "reason", Actor: "alice", At: at}
+	if transitions[0] != expected {
+		t.Errorf("expected first transition %+v, got %+v", expected, transitions[0])
+	}
+	if transitions[3].From != StateBlocked || transitions[3].To != StateArchived {
+		t.Errorf("unexpected last transition %+v", transitions[3])
+	}
+}
+
+func TestShortURL_Transition_Limit(t *testing.T) {
+	shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	for i := range MaxStateTransitions + 6 {
+		to := StatePaused
+		if i%2 == 1 {
+			to = StateActive
+		}
+		if err := shortURL.Transition(to, "", "", time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	transitions := shortURL.Transitions()
+	if len(transitions) != MaxStateTransitions {
+		t.Fatalf("expected %d transitions, got %d", MaxStateTransitions, len(transitions))
+	}
+	if transitions[0].From != StateActive {
+		t.Errorf("expected oldest transitions to be dropped, first is %+v", transitions[0])
+	}
+}
+
+func TestShortURL_CheckAvailable(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    LifecycleState
+		expiry   *time.Time
+		expected error
+	}{
+		{"active", StateActive, nil, nil},
+		{"active before expiry", StateActive, timePtr(time.Now().Add(time.Hour)), nil},
+		{"expired", StateActive, timePtr(time.Now().Add(-time.Hour)), ErrShortURLExpired},
+		{"paused", StatePaused, nil, ErrShortURLPaused},
+		{"deactivated", StateDeactivated, nil, ErrShortURLInactive},
+		{"blocked", StateBlocked, nil, ErrShortURLBlocked},
+		{"archived", StateArchived, nil, ErrShortURLArchived},
+		{"exhausted", StateExhausted, nil, ErrShortURLExhausted},
+		{"state takes precedence over expiry", StatePaused, timePtr(time.Now().Add(-time.Hour)), ErrShortURLPaused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com"), "http://short.ly/abc123", tt.expiry, nil)
+			shortURL.RestoreLifecycle(tt.state, nil)
+			if err := shortURL.CheckAvailable(); err != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
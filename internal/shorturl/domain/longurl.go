@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Errors returned for long URLs that cannot be shortened.
+var (
+	// ErrInvalidLongURL is returned when a long URL cannot be parsed.
+	ErrInvalidLongURL = NewError(ErrInvalidInput, "invalid_long_url", "long URL is not a valid URL")
+	// ErrSelfReferencingURL is returned when a long URL points to this service, which
+	// would create a redirect loop.
+	ErrSelfReferencingURL = NewError(ErrInvalidInput, "long_url_self_reference", "longUrl must not point to this URL shortener")
+	// ErrShortenerURL is returned when a long URL points to another URL shortener, which
+	// would hide the destination.
+	ErrShortenerURL = NewError(ErrInvalidInput, "long_url_shortener", "longUrl must not point to another URL shortener")
+	// ErrUnresolvableURL is returned when the destination behind another shortener's URL
+	// cannot be determined.
+	ErrUnresolvableURL = NewError(ErrInvalidInput, "long_url_unresolvable", "longUrl points to another URL shortener whose destination could not be resolved")
+)
+
+// defaultPorts maps schemes to the port implied when a URL names none.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// LongURLPolicy holds the rules destinations must satisfy.
+type LongURLPolicy struct {
+	AllowedSchemes []string // Lowercase schemes that may be shortened, e.g. "https"
+	MaxLength      int      // Maximum length of the URL in bytes, before and after normalization
+}
+
+// DefaultLongURLPolicy returns the rules used when none are configured: http and https
+// URLs of at most 2048 bytes, the length browsers and crawlers reliably handle.
+func DefaultLongURLPolicy() LongURLPolicy {
+	return LongURLPolicy{
+		AllowedSchemes: []string{"http", "https"},
+		MaxLength:      2048,
+	}
+}
+
+// LongURL is a validated, normalized destination URL. Normalization lowercases the
+// scheme and host, converts internationalized host names to Punycode and drops the
+// default port of the scheme, so equivalent spellings of a destination compare equal.
+// The path, query and fragment are kept, since servers may treat them case-sensitively.
+// The zero value is the empty URL, which no policy accepts.
+type LongURL struct {
+	value string // The normalized URL
+	host  string // The normalized host without port
+}
+
+// ParseLongURL validates and normalizes a destination URL.
+//
+// Parameters:
+//   - raw: The URL as supplied by the user; surrounding whitespace is ignored
+//   - policy: The rules the URL must satisfy
+//
+// Returns:
+//   - LongURL: The normalized URL
+//   - error: ErrLongURLRequired if raw is empty, or an ErrInvalidInput domain error
+//     describing why the URL is rejected
+func ParseLongURL(raw string, policy LongURLPolicy) (LongURL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return LongURL{}, ErrLongURLRequired
+	}
+	if len(raw) > policy.MaxLength {
+		return LongURL{}, longURLTooLong(policy, len(raw))
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return LongURL{}, NewError(ErrInvalidInput, ErrInvalidLongURL.Code, fmt.Sprintf("longUrl is not a valid URL: %v", unwrapURLError(err)))
+	}
+	// url.Parse already lowercases the scheme
+	if parsed.Scheme != "" && !slices.Contains(policy.AllowedSchemes, parsed.Scheme) {
+		return LongURL{}, NewError(ErrInvalidInput, "long_url_scheme_not_allowed",
+			fmt.Sprintf("longUrl scheme %q is not allowed; use one of %s", parsed.Scheme, strings.Join(policy.AllowedSchemes, ", ")))
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return LongURL{}, NewError(ErrInvalidInput, "long_url_not_absolute", "longUrl must be an absolute URL with a scheme and host")
+	}
+
+	host, err := toASCIIHost(parsed.Hostname())
+	if err != nil {
+		return LongURL{}, NewError(ErrInvalidInput, ErrInvalidLongURL.Code, fmt.Sprintf("longUrl host is invalid: %v", err))
+	}
+	hostPort := host
+	if strings.Contains(host, ":") {
+		hostPort = "[" + host + "]" // IPv6 literal
+	}
+	if port := parsed.Port(); port != "" && port != defaultPorts[parsed.Scheme] {
+		hostPort += ":" + port
+	}
+	parsed.Host = hostPort
+
+	normalized := parsed.String()
+	if len(normalized) > policy.MaxLength {
+		return LongURL{}, longURLTooLong(policy, len(normalized))
+	}
+	return LongURL{value: normalized, host: host}, nil
+}
+
+// MustParseLongURL is like ParseLongURL with the default policy but panics if the URL is
+// invalid. It simplifies initializing URLs known to be valid, such as in tests.
+func MustParseLongURL(raw string) LongURL {
+	longURL, err := ParseLongURL(raw, DefaultLongURLPolicy())
+	if err != nil {
+		panic(fmt.Sprintf("domain: ParseLongURL(%q): %v", raw, err))
+	}
+	return longURL
+}
+
+// String returns the normalized URL.
+func (u LongURL) String() string {
+	return u.value
+}
+
+// Host returns the normalized host without port, in Punycode for internationalized hosts.
+func (u LongURL) Host() string {
+	return u.host
+}
+
+// IsZero reports whether the URL is the empty zero value.
+func (u LongURL) IsZero() bool {
+	return u.value == ""
+}
+
+// longURLTooLong returns the error for a URL exceeding the policy's maximum length.
+func longURLTooLong(policy LongURLPolicy, length int) *Error {
+	return NewError(ErrInvalidInput, "long_url_too_long",
+		fmt.Sprintf("longUrl must be at most %d bytes, got %d", policy.MaxLength, length))
+}
+
+// unwrapURLError strips the operation and URL that *url.Error adds, which would
+// repeat the whole input in the message.
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseLongURL(t *testing.T) {
+	tests := []struct {
+		name         string
+		raw          string
+		expected     string
+		expectedHost string
+		expectedCode string
+	}{
+		{"already normalized", "https://example.com/path?q=1#top", "https://example.com/path?q=1#top", "example.com", ""},
+		{"scheme and host case", "HTTPS://Example.COM/Path", "https://example.com/Path", "example.com", ""},
+		{"default https port", "https://example.com:443/a", "https://example.com/a", "example.com", ""},
+		{"default http port", "http://example.com:80/a", "http://example.com/a", "example.com", ""},
+		{"other port kept", "https://example.com:8443/a", "https://example.com:8443/a", "example.com", ""},
+		{"http port on https kept", "https://example.com:80/a", "https://example.com:80/a", "example.com", ""},
+		{"IPv6 host", "http://[::1]:80/a", "http://[::1]/a", "::1", ""},
+		{"IPv6 host with port", "http://[::1]:8080/a", "http://[::1]:8080/a", "::1", ""},
+		{"internationalized host", "https://Bücher.example/ä", "https://xn--bcher-kva.example/%C3%A4", "xn--bcher-kva.example", ""},
+		{"surrounding whitespace", "  https://example.com/a\\n", "https://example.com/a%5Cn", "example.com", ""},
+		{"userinfo kept", "https://user@example.com/", "https://user@example.com/", "example.com", ""},
+		{"empty", "", "", "", "long_url_required"},
+		{"whitespace only", "   ", "", "", "long_url_required"},
+		{"javascript scheme", "javascript:alert(document.cookie)", "", "", "long_url_scheme_not_allowed"},
+		{"data scheme", "data:text/html,<script>alert(1)</script>", "", "", "long_url_scheme_not_allowed"},
+		{"ftp scheme", "ftp://example.com/file", "", "", "long_url_scheme_not_allowed"},
+		{"relative path", "/path/only", "", "", "long_url_not_absolute"},
+		{"missing scheme", "example.com/path", "", "", "long_url_not_absolute"},
+		{"missing host", "https:///path", "", "", "long_url_not_absolute"},
+		{"control character", "https://example.com/\x7f", "", "", "invalid_long_url"},
+		{"invalid port", "https://example.com:http/", "", "", "invalid_long_url"},
+		{"too long", "https://example.com/" + strings.Repeat("a", 2048), "", "", "long_url_too_long"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			longURL, err := ParseLongURL(tt.raw, DefaultLongURLPolicy())
+			if tt.expectedCode != "" {
+				var domainErr *Error
+				if !errors.As(err, &domainErr) || domainErr.Code != tt.expectedCode {
+					t.Fatalf("expected error code %q, got %v", tt.expectedCode, err)
+				}
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Errorf("expected ErrInvalidInput kind, got %v", err)
+				}
+				if !longURL.IsZero() {
+					t.Errorf("expected zero URL on error, got %q", longURL)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if longURL.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, longURL)
+			}
+			if longURL.Host() != tt.expectedHost {
+				t.Errorf("expected host %q, got %q", tt.expectedHost, longURL.Host())
+			}
+		})
+	}
+}
+
+func TestParseLongURL_Policy(t *testing.T) {
+	policy := LongURLPolicy{AllowedSchemes: []string{"https"}, MaxLength: 30}
+
+	if _, err := ParseLongURL("https://example.com/", policy); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := ParseLongURL("http://example.com/", policy); err == nil {
+		t.Error("expected http to be rejected")
+	}
+	// Punycode can make a URL longer than its input
+	if _, err := ParseLongURL("https://bücherbücher.de/", policy); err == nil {
+		t.Error("expected normalized URL exceeding the limit to be rejected")
+	}
+}
+
+func TestMustParseLongURL(t *testing.T) {
+	if got := MustParseLongURL("HTTPS://Example.com"); got.String() != "https://example.com" {
+		t.Errorf("expected normalized URL, got %q", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid URL")
+		}
+	}()
+	MustParseLongURL("javascript:alert(1)")
+}
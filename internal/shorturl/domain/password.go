@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// Length limits of link passwords, in characters. The upper limit bounds the cost of
+// hashing a submitted password.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 128
+)
+
+// Errors returned for password-protected short URLs.
+var (
+	// ErrInvalidPassword is returned when a password for a new short URL is too short or too long.
+	ErrInvalidPassword = NewError(ErrInvalidInput, "invalid_password", "password must be between 8 and 128 characters")
+	// ErrPasswordRequired is returned when a password-protected short URL is looked up without
+	// having been unlocked.
+	ErrPasswordRequired = NewError(ErrUnauthorized, "password_required", "short URL is password protected")
+	// ErrWrongPassword is returned when a short URL is unlocked with a wrong password.
+	ErrWrongPassword = NewError(ErrUnauthorized, "wrong_password", "wrong password")
+	// ErrTooManyAttempts is returned when a short URL is unlocked too often with wrong passwords.
+	ErrTooManyAttempts = NewError(ErrRateLimited, "too_many_attempts", "too many wrong passwords; try again later")
+)
+
+// ValidatePassword checks a password chosen for a new short URL against the length limits.
+//
+// Parameters:
+//   - password: The password in plain text
+//
+// Returns:
+//   - error: ErrInvalidPassword if the password is too short or too long
+func ValidatePassword(password string) error {
+	if n := utf8.RuneCountInString(password); n < MinPasswordLength || n > MaxPasswordLength {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
+// PasswordHasher turns link passwords into salted, deliberately slow hashes, so that a
+// leaked data store does not reveal the passwords.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of the password, including its salt and parameters.
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches an encoded hash returned by Hash.
+	Verify(encodedHash, password string) bool
+}
+
+// UnlockSigner issues and checks short-lived tokens proving that a visitor entered the
+// right password for a short URL, so that the password is not asked again on every visit.
+// Tokens are bound to the password hash, so replacing the password revokes them.
+type UnlockSigner interface {
+	// Sign returns a token for the short URL ID and password hash, and the time it stops
+	// being valid.
+	Sign(id, passwordHash string, now time.Time) (string, time.Time)
+	// Verify reports whether the token was issued for the ID and password hash and is
+	// still valid at now.
+	Verify(token, id, passwordHash string, now time.Time) bool
+}
+
+// AttemptLimiter throttles password guessing by counting attempts per key, typically
+// the short URL ID. Every attempt is counted before the password is checked, so that
+// concurrent guesses cannot all pass the check before the first failure is recorded.
+type AttemptLimiter interface {
+	// Acquire reports whether another attempt may be made for the key at now and, if so,
+	// counts it, in one atomic step.
+	Acquire(key string, now time.Time) bool
+	// Reset forgets the counted attempts for the key after a successful one.
+	Reset(key string)
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name        string
+		password    string
+		expectedErr error
+	}{
+		{"minimum length", "12345678", nil},
+		{"maximum length", strings.Repeat("a", MaxPasswordLength), nil},
+		{"multibyte characters count once", "パスワードです!!", nil},
+		{"too short", "1234567", ErrInvalidPassword},
+		{"empty", "", ErrInvalidPassword},
+		{"too long", strings.Repeat("a", MaxPasswordLength+1), ErrInvalidPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePassword(tt.password); err != tt.expectedErr {
+				t.Errorf("expected %v, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Punycode parameters from RFC 3492, section 5.
+const (
+	punycodeBase        = 36
+	punycodeTMin        = 1
+	punycodeTMax        = 26
+	punycodeSkew        = 38
+	punycodeDamp        = 700
+	punycodeInitialBias = 72
+	punycodeInitialN    = 128
+	punycodeACEPrefix   = "xn--"
+)
+
+// toASCIIHost converts an internationalized host name to its ASCII form by lowercasing
+// each label and Punycode-encoding the labels that contain non-ASCII characters.
+// The full-width and ideographic full stops are treated as label separators.
+// Unicode normalization (NFKC) of IDNA 2008 is not applied, so hosts are expected
+// in their usual composed form.
+//
+// Parameters:
+//   - host: Host name, possibly containing non-ASCII characters
+//
+// Returns:
+//   - string: The ASCII host name
+//   - error: Error if a label cannot be encoded
+func toASCIIHost(host string) (string, error) {
+	host = strings.NewReplacer("。", ".", "．", ".", "｡", ".").Replace(host)
+	labels := strings.Split(strings.ToLower(host), ".")
+	for i, label := range labels {
+		if isASCII(label) {
+			continue
+		}
+		encoded, err := punycodeEncode(label)
+		if err != nil {
+			return "", fmt.Errorf("cannot encode host label %q: %w", label, err)
+		}
+		labels[i] = punycodeACEPrefix + encoded
+	}
+	return strings.Join(labels, "."), nil
+}
+
+// isASCII reports whether s contains only ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// punycodeEncode encodes a label with the Punycode algorithm of RFC 3492, section 6.3.
+func punycodeEncode(label string) (string, error) {
+	if !utf8.ValidString(label) {
+		return "", fmt.Errorf("invalid UTF-8")
+	}
+	input := []rune(label)
+	var output strings.Builder
+
+	// Copy the basic code points first, followed by a delimiter if there were any
+	basic := 0
+	for _, r := range input {
+		if r < punycodeInitialN {
+			output.WriteRune(r)
+			basic++
+		}
+	}
+	if basic > 0 {
+		output.WriteByte('-')
+	}
+
+	n, delta, bias := rune(punycodeInitialN), 0, punycodeInitialBias
+	for handled := basic; handled < len(input); {
+		// The next code point to insert is the smallest one not handled yet
+		next := rune(math.MaxInt32)
+		for _, r := range input {
+			if r >= n && r < next {
+				next = r
+			}
+		}
+		if int(next-n) > (math.MaxInt32-delta)/(handled+1) {
+			return "", fmt.Errorf("label overflows the Punycode encoder")
+		}
+		delta += int(next-n) * (handled + 1)
+		n = next
+
+		for _, r := range input {
+			if r < n {
+				delta++
+			}
+			if r != n {
+				continue
+			}
+			q := delta
+			for k := punycodeBase; ; k += punycodeBase {
+				t := min(max(k-bias, punycodeTMin), punycodeTMax)
+				if q < t {
+					break
+				}
+				output.WriteByte(punycodeDigit(t + (q-t)%(punycodeBase-t)))
+				q = (q - t) / (punycodeBase - t)
+			}
+			output.WriteByte(punycodeDigit(q))
+			bias = punycodeAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return output.String(), nil
+}
+
+// punycodeDigit returns the character of a Punycode digit: a-z for 0-25 and 0-9 for 26-35.
+func punycodeDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
+
+// punycodeAdapt is the bias adaptation function of RFC 3492, section 6.1.
+func punycodeAdapt(delta, numPoints int, firstTime bool) int {
+	if firstTime {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+	k := 0
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
@@ -0,0 +1,39 @@
+package domain
+
+import "testing"
+
+func TestToASCIIHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		expected string
+	}{
+		{"ASCII", "example.com", "example.com"},
+		{"ASCII uppercase", "Example.COM", "example.com"},
+		{"German", "bücher.example", "xn--bcher-kva.example"},
+		{"Japanese", "例え.テスト", "xn--r8jz45g.xn--zckzah"},
+		{"Japanese uppercase-free with ideographic stop", "日本語。jp", "xn--wgv71a119e.jp"},
+		{"Uppercase non-ASCII", "MÜNCHEN.de", "xn--mnchen-3ya.de"},
+		{"RFC 3492 sample (Arabic)", "ليهمابتكلموشعربي؟", "xn--egbpdaj6bu4bxfgehfvwxn"},
+		{"RFC 3492 sample (Chinese)", "他们为什么不说中文", "xn--ihqwcrb4cv8a8dqg056pqjye"},
+		{"RFC 3492 sample (mixed)", "3年b組金八先生", "xn--3b-ww4c5e180e575a65lsy2b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toASCIIHost(tt.host)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPunycodeEncode_InvalidUTF8(t *testing.T) {
+	if _, err := punycodeEncode("\xff"); err == nil {
+		t.Error("expected error for invalid UTF-8")
+	}
+}
@@ -0,0 +1,227 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ShortURLStatus classifies short URLs for listing. The statuses are mutually exclusive:
+// a deactivated URL is inactive regardless of its expiry, only active URLs can be expired
+// or scheduled, and an expired URL is never scheduled.
+type ShortURLStatus string
+
+const (
+	StatusAny       ShortURLStatus = ""          // No status filter
+	StatusActive    ShortURLStatus = "active"    // Active, live and not expired
+	StatusInactive  ShortURLStatus = "inactive"  // Deactivated
+	StatusExpired   ShortURLStatus = "expired"   // Active but past its expiration time
+	StatusScheduled ShortURLStatus = "scheduled" // Active but before its activation time
+)
+
+// SortOrder is the direction in which a listing is ordered by creation time.
+type SortOrder string
+
+const (
+	SortAscending  SortOrder = "asc"  // Oldest first
+	SortDescending SortOrder = "desc" // Newest first
+)
+
+// Errors returned for pagination cursors.
+var (
+	// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+	ErrInvalidCursor = NewError(ErrInvalidInput, "invalid_cursor", "invalid pagination cursor")
+	// ErrCursorMismatch is returned when a pagination cursor was issued for a listing with
+	// other filters or another order, whose pages it would skip or repeat entries of.
+	ErrCursorMismatch = NewError(ErrInvalidInput, "cursor_mismatch", "pagination cursor belongs to a listing with other filters or order")
+)
+
+// ShortURLQuery describes a filtered, cursor-paginated listing of short URLs.
+// Results are ordered by creation time, with the ID as a tie-breaker so that the
+// order is total and pages never skip or repeat entries.
+type ShortURLQuery struct {
+	Status        ShortURLStatus // Only URLs in this status; StatusAny for all
+	CreatedFrom   *time.Time     // Only URLs created at or after this time (inclusive)
+	CreatedTo     *time.Time     // Only URLs created before this time (exclusive)
+	LongURLDomain string         // Only URLs whose long URL host is this domain or one of its subdomains
+	MetadataKey   string         // Only URLs whose user metadata contains this key
+	MetadataValue string         // With MetadataKey, only URLs whose value for the key has this string form
+	Order         SortOrder      // Sort direction; ascending when empty
+	Cursor        string         // Position after which the page starts; empty for the first page
+	Limit         int            // Maximum number of entries in the page; 0 or less for no limit
+}
+
+// ShortURLPage is one page of a listing.
+type ShortURLPage struct {
+	Items      []*ShortURL // Entries in the requested order
+	NextCursor string      // Cursor for the following page; empty when this is the last page
+}
+
+// PageCursor is the decoded position of a pagination cursor: the sort key of the
+// last entry on the previous page.
+type PageCursor struct {
+	CreatedAt time.Time // Creation time of the last entry returned
+	ID        string    // Identifier of the last entry returned
+}
+
+// Matches reports whether a short URL satisfies every filter of the query.
+// Order, Cursor and Limit are not considered.
+//
+// Parameters:
+//   - shortURL: The entity to test
+//
+// Returns:
+//   - bool: True if the entity passes all filters
+func (q ShortURLQuery) Matches(shortURL *ShortURL) bool {
+	if q.Status != StatusAny && shortURL.Status() != q.Status {
+		return false
+	}
+	if q.CreatedFrom != nil && shortURL.CreatedAt().Before(*q.CreatedFrom) {
+		return false
+	}
+	if q.CreatedTo != nil && !shortURL.CreatedAt().Before(*q.CreatedTo) {
+		return false
+	}
+	if q.LongURLDomain != "" && !hostInDomain(shortURL.LongURL(), q.LongURLDomain) {
+		return false
+	}
+	if q.MetadataKey != "" {
+		value, ok := shortURL.UserMetadata()[q.MetadataKey]
+		if !ok {
+			return false
+		}
+		if q.MetadataValue != "" && fmt.Sprint(value) != q.MetadataValue {
+			return false
+		}
+	}
+	return true
+}
+
+// Less reports whether a sorts before b in the query's order.
+//
+// Parameters:
+//   - a: The first entity
+//   - b: The second entity
+//
+// Returns:
+//   - bool: True if a comes before b
+func (q ShortURLQuery) Less(a, b *ShortURL) bool {
+	return q.before(PageCursor{CreatedAt: a.CreatedAt(), ID: a.ID()}, PageCursor{CreatedAt: b.CreatedAt(), ID: b.ID()})
+}
+
+// After reports whether a short URL comes after the cursor position in the query's order,
+// i.e. whether it belongs on a page that starts at the cursor.
+//
+// Parameters:
+//   - shortURL: The entity to test
+//   - cursor: The decoded cursor position
+//
+// Returns:
+//   - bool: True if the entity sorts strictly after the cursor
+func (q ShortURLQuery) After(shortURL *ShortURL, cursor PageCursor) bool {
+	return q.before(cursor, PageCursor{CreatedAt: shortURL.CreatedAt(), ID: shortURL.ID()})
+}
+
+// before compares two sort keys in the query's order.
+func (q ShortURLQuery) before(a, b PageCursor) bool {
+	if q.Order == SortDescending {
+		a, b = b, a
+	}
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+// EncodeCursor returns the opaque cursor pointing just past the given entry. The cursor
+// carries a fingerprint of the query's filters and order, so that it is only accepted
+// for the same listing.
+//
+// Parameters:
+//   - shortURL: The last entry of the current page
+//
+// Returns:
+//   - string: URL-safe cursor string
+func (q ShortURLQuery) EncodeCursor(shortURL *ShortURL) string {
+	raw := q.fingerprint() + ":" + strconv.FormatInt(shortURL.CreatedAt().UnixNano(), 10) + ":" + shortURL.ID()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor produced by EncodeCursor for a query with the same
+// filters and order.
+//
+// Parameters:
+//   - cursor: The opaque cursor string
+//
+// Returns:
+//   - PageCursor: The decoded position
+//   - error: ErrInvalidCursor if the cursor is malformed, or ErrCursorMismatch if it
+//     was issued for other filters or another order
+func (q ShortURLQuery) DecodeCursor(cursor string) (PageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return PageCursor{}, ErrInvalidCursor
+	}
+
+	fingerprint, position, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return PageCursor{}, ErrInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(position, ":")
+	if !ok || id == "" {
+		return PageCursor{}, ErrInvalidCursor
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return PageCursor{}, ErrInvalidCursor
+	}
+	if fingerprint != q.fingerprint() {
+		return PageCursor{}, ErrCursorMismatch
+	}
+
+	return PageCursor{CreatedAt: time.Unix(0, unixNano), ID: id}, nil
+}
+
+// fingerprint returns a short digest of the query's filters and order, normalized the
+// way Matches and before interpret them. Cursor and Limit are not part of it, since
+// following pages may be fetched with another page size.
+func (q ShortURLQuery) fingerprint() string {
+	order := q.Order
+	if order != SortDescending {
+		order = SortAscending
+	}
+	timeKey := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return strconv.FormatInt(t.UnixNano(), 10)
+	}
+	fields := []string{
+		string(q.Status),
+		timeKey(q.CreatedFrom),
+		timeKey(q.CreatedTo),
+		strings.ToLower(strings.TrimSuffix(q.LongURLDomain, ".")),
+		q.MetadataKey,
+		q.MetadataValue,
+		string(order),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:6])
+}
+
+// hostInDomain reports whether the host of rawURL equals domain or is a subdomain of it.
+func hostInDomain(rawURL, domain string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShortURLQuery_Matches(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	past := createdAt.Add(-time.Hour)
+	before := createdAt.Add(-time.Minute)
+	after := createdAt.Add(time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	active := ReconstructShortURL("abc123", "https://www.Example.com/page", "http://short.ly/abc123", createdAt, nil, true, map[string]interface{}{"campaign": "spring", "version": 2})
+	inactive := ReconstructShortURL("def456", "https://example.org", "http://short.ly/def456", createdAt, nil, false, nil)
+	expired := ReconstructShortURL("ghi789", "https://example.org", "http://short.ly/ghi789", createdAt, &past, true, nil)
+	scheduled := ReconstructShortURL("jkl012", "https://example.org", "http://short.ly/jkl012", createdAt, nil, true, nil)
+	scheduled.RestoreSchedule(&future, "")
+
+	tests := []struct {
+		name     string
+		query    ShortURLQuery
+		shortURL *ShortURL
+		expected bool
+	}{
+		{"empty query matches", ShortURLQuery{}, active, true},
+		{"active status", ShortURLQuery{Status: StatusActive}, active, true},
+		{"active status excludes inactive", ShortURLQuery{Status: StatusActive}, inactive, false},
+		{"active status excludes expired", ShortURLQuery{Status: StatusActive}, expired, false},
+		{"inactive status", ShortURLQuery{Status: StatusInactive}, inactive, true},
+		{"expired status", ShortURLQuery{Status: StatusExpired}, expired, true},
+		{"scheduled status", ShortURLQuery{Status: StatusScheduled}, scheduled, true},
+		{"active status excludes scheduled", ShortURLQuery{Status: StatusActive}, scheduled, false},
+		{"created from is inclusive", ShortURLQuery{CreatedFrom: &createdAt}, active, true},
+		{"created from excludes older", ShortURLQuery{CreatedFrom: &after}, active, false},
+		{"created to is exclusive", ShortURLQuery{CreatedTo: &createdAt}, active, false},
+		{"created to includes older", ShortURLQuery{CreatedTo: &after}, active, true},
+		{"created range", ShortURLQuery{CreatedFrom: &before, CreatedTo: &after}, active, true},
+		{"subdomain matches domain", ShortURLQuery{LongURLDomain: "example.com"}, active, true},
+		{"domain is case insensitive", ShortURLQuery{LongURLDomain: "EXAMPLE.COM"}, active, true},
+		{"other domain", ShortURLQuery{LongURLDomain: "example.org"}, active, false},
+		{"domain suffix is not a subdomain", ShortURLQuery{LongURLDomain: "ample.com"}, active, false},
+		{"metadata key", ShortURLQuery{MetadataKey: "campaign"}, active, true},
+		{"metadata key and value", ShortURLQuery{MetadataKey: "campaign", MetadataValue: "spring"}, active, true},
+		{"metadata non-string value", ShortURLQuery{MetadataKey: "version", MetadataValue: "2"}, active, true},
+		{"metadata value mismatch", ShortURLQuery{MetadataKey: "campaign", MetadataValue: "autumn"}, active, false},
+		{"metadata key missing", ShortURLQuery{MetadataKey: "campaign"}, inactive, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.query.Matches(tt.shortURL); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestShortURLQuery_Order(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	older := ReconstructShortURL("zzz", "https://example.com", "http://short.ly/zzz", createdAt, nil, true, nil)
+	newer := ReconstructShortURL("aaa", "https://example.com", "http://short.ly/aaa", createdAt.Add(time.Second), nil, true, nil)
+	tie := ReconstructShortURL("bbb", "https://example.com", "http://short.ly/bbb", createdAt.Add(time.Second), nil, true, nil)
+
+	asc := ShortURLQuery{}
+	desc := ShortURLQuery{Order: SortDescending}
+
+	if !asc.Less(older, newer) || asc.Less(newer, older) {
+		t.Error("expected ascending order by creation time")
+	}
+	if !desc.Less(newer, older) || desc.Less(older, newer) {
+		t.Error("expected descending order by creation time")
+	}
+	if !asc.Less(newer, tie) || !desc.Less(tie, newer) {
+		t.Error("expected ties to be broken by ID")
+	}
+
+	cursor, err := asc.DecodeCursor(asc.EncodeCursor(newer))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !asc.After(tie, cursor) || asc.After(older, cursor) || asc.After(newer, cursor) {
+		t.Error("expected only later entries to be after the cursor")
+	}
+	if !desc.After(older, cursor) || desc.After(tie, cursor) {
+		t.Error("expected only earlier entries to be after the cursor in descending order")
+	}
+}
+
+func TestCursor(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 123456789, time.UTC)
+	shortURL := ReconstructShortURL("id:with:colons", "https://example.com", "http://short.ly/x", createdAt, nil, true, nil)
+
+	createdFrom := createdAt.Add(-time.Hour)
+	query := ShortURLQuery{Status: StatusActive, CreatedFrom: &createdFrom, LongURLDomain: "example.com", Order: SortDescending, Limit: 10}
+
+	// Another page size and a differently written domain are still the same listing
+	same := query
+	same.Limit = 50
+	same.LongURLDomain = "Example.com."
+	cursor, err := same.DecodeCursor(query.EncodeCursor(shortURL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cursor.CreatedAt.Equal(createdAt) || cursor.ID != "id:with:colons" {
+		t.Errorf("cursor did not round-trip: %+v", cursor)
+	}
+
+	for _, invalid := range []string{"!!!", "bm9jb2xvbg", "eHl6OmFiYw", "MTIzOg"} {
+		if _, err := query.DecodeCursor(invalid); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q): expected ErrInvalidCursor, got %v", invalid, err)
+		}
+	}
+
+	otherFrom := createdFrom.Add(time.Minute)
+	mismatches := map[string]func(q *ShortURLQuery){
+		"status":   func(q *ShortURLQuery) { q.Status = StatusExpired },
+		"from":     func(q *ShortURLQuery) { q.CreatedFrom = &otherFrom },
+		"to":       func(q *ShortURLQuery) { q.CreatedTo = &createdAt },
+		"domain":   func(q *ShortURLQuery) { q.LongURLDomain = "example.org" },
+		"metadata": func(q *ShortURLQuery) { q.MetadataKey = "campaign" },
+		"order":    func(q *ShortURLQuery) { q.Order = SortAscending },
+	}
+	for name, change := range mismatches {
+		other := query
+		change(&other)
+		if _, err := other.DecodeCursor(query.EncodeCursor(shortURL)); !errors.Is(err, ErrCursorMismatch) {
+			t.Errorf("%s changed: expected ErrCursorMismatch, got %v", name, err)
+		}
+	}
+}
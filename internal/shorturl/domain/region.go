@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Region describes a datacenter that mints short URLs. Every ID generated in a region
+// starts with the region's code, so IDs stay unique across regions without coordination
+// and any instance can tell from an ID which region owns it.
+type Region struct {
+	Code    string // Single Base62 character prefixed to generated IDs, e.g. "1"
+	Name    string // Datacenter name, e.g. "us-east-1"
+	BaseURL string // Base URL of the region's short URL service; empty when it cannot be routed to
+}
+
+// RegionRegistry is the set of known regions, indexed by code.
+type RegionRegistry struct {
+	regions map[string]Region // Regions by code
+}
+
+// NewRegionRegistry creates a registry of the given regions.
+//
+// Parameters:
+//   - regions: The known regions; codes must be single Base62 characters and distinct
+//
+// Returns:
+//   - *RegionRegistry: The registry
+//   - error: Error if a code is invalid or used twice, or a name is missing
+func NewRegionRegistry(regions ...Region) (*RegionRegistry, error) {
+	registry := &RegionRegistry{regions: make(map[string]Region, len(regions))}
+	for _, region := range regions {
+		if !IsRegionCode(region.Code) {
+			return nil, fmt.Errorf("region code %q must be a single Base62 character", region.Code)
+		}
+		if region.Name == "" {
+			return nil, fmt.Errorf("region %q has no name", region.Code)
+		}
+		if _, exists := registry.regions[region.Code]; exists {
+			return nil, fmt.Errorf("region code %q is used twice", region.Code)
+		}
+		region.BaseURL = strings.TrimSuffix(region.BaseURL, "/")
+		registry.regions[region.Code] = region
+	}
+	return registry, nil
+}
+
+// ParseRegions parses a region list of the form "1=us-east-1=https://us.example.com,2=eu-west-1".
+// The base URL of each entry is optional.
+//
+// Parameters:
+//   - spec: Comma-separated entries of code=name or code=name=baseURL
+//
+// Returns:
+//   - []Region: The parsed regions in the order given
+//   - error: Error if an entry is malformed
+func ParseRegions(spec string) ([]Region, error) {
+	var regions []Region
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 3)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("region entry %q must be code=name or code=name=baseURL", entry)
+		}
+		region := Region{Code: parts[0], Name: parts[1]}
+		if len(parts) == 3 {
+			region.BaseURL = parts[2]
+		}
+		regions = append(regions, region)
+	}
+	return regions, nil
+}
+
+// IsRegionCode reports whether code is a valid region code: a single Base62 character.
+func IsRegionCode(code string) bool {
+	if len(code) != 1 {
+		return false
+	}
+	c := code[0]
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Lookup returns the region with the given code.
+//
+// Parameters:
+//   - code: Region code
+//
+// Returns:
+//   - Region: The region, if known
+//   - bool: Whether the code is known
+func (r *RegionRegistry) Lookup(code string) (Region, bool) {
+	region, ok := r.regions[code]
+	return region, ok
+}
+
+// RegionOfID returns the region whose code prefixes the ID. Custom URLs carry no
+// prefix, so a match only means that the ID could have been minted in that region.
+//
+// Parameters:
+//   - id: Short URL identifier
+//
+// Returns:
+//   - Region: The region the prefix belongs to, if known
+//   - bool: Whether the prefix is a known region code
+func (r *RegionRegistry) RegionOfID(id string) (Region, bool) {
+	if id == "" {
+		return Region{}, false
+	}
+	return r.Lookup(id[:1])
+}
+
+// Regions returns all known regions ordered by code.
+func (r *RegionRegistry) Regions() []Region {
+	regions := make([]Region, 0, len(r.regions))
+	for _, region := range r.regions {
+		regions = append(regions, region)
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i].Code < regions[j].Code })
+	return regions
+}
+
+// ForeignRegionError is returned when a short URL is not stored locally but its ID was
+// minted in another region. The caller should send the client to that region.
+type ForeignRegionError struct {
+	Region Region // Region that owns the ID
+	ID     string // The requested identifier
+}
+
+// Error returns a description naming the owning region.
+func (e *ForeignRegionError) Error() string {
+	return fmt.Sprintf("short URL %s belongs to region %s", e.ID, e.Region.Name)
+}
+
+// Location returns the URL of the short URL in its owning region.
+func (e *ForeignRegionError) Location() string {
+	return e.Region.BaseURL + "/" + e.ID
+}
@@ -0,0 +1,104 @@
+package domain
+
+import "testing"
+
+func TestNewRegionRegistry(t *testing.T) {
+	tests := []struct {
+		name        string
+		regions     []Region
+		expectError bool
+	}{
+		{"valid", []Region{{Code: "1", Name: "us-east-1"}, {Code: "2", Name: "eu-west-1"}}, false},
+		{"no regions", nil, false},
+		{"empty code", []Region{{Code: "", Name: "us-east-1"}}, true},
+		{"multi-character code", []Region{{Code: "us", Name: "us-east-1"}}, true},
+		{"non-Base62 code", []Region{{Code: "-", Name: "us-east-1"}}, true},
+		{"missing name", []Region{{Code: "1"}}, true},
+		{"duplicate code", []Region{{Code: "1", Name: "us-east-1"}, {Code: "1", Name: "eu-west-1"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRegionRegistry(tt.regions...)
+			if (err != nil) != tt.expectError {
+				t.Errorf("expected error=%v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestParseRegions(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		expected    []Region
+		expectError bool
+	}{
+		{"empty", "", nil, false},
+		{
+			name: "with and without base URL",
+			spec: "1=us-east-1=https://us.short.ly, 2=eu-west-1",
+			expected: []Region{
+				{Code: "1", Name: "us-east-1", BaseURL: "https://us.short.ly"},
+				{Code: "2", Name: "eu-west-1"},
+			},
+		},
+		{"missing name", "1", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regions, err := ParseRegions(tt.spec)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected error=%v, got %v", tt.expectError, err)
+			}
+			if len(regions) != len(tt.expected) {
+				t.Fatalf("expected %d regions, got %d", len(tt.expected), len(regions))
+			}
+			for i, region := range regions {
+				if region != tt.expected[i] {
+					t.Errorf("region %d: expected %+v, got %+v", i, tt.expected[i], region)
+				}
+			}
+		})
+	}
+}
+
+func TestRegionRegistry_RegionOfID(t *testing.T) {
+	registry, _ := NewRegionRegistry(
+		Region{Code: "2", Name: "eu-west-1", BaseURL: "https://eu.short.ly/"},
+		Region{Code: "1", Name: "us-east-1"},
+	)
+
+	tests := []struct {
+		id       string
+		expected string
+		ok       bool
+	}{
+		{"1abcdefg", "us-east-1", true},
+		{"2abcdefg", "eu-west-1", true},
+		{"3abcdefg", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			region, ok := registry.RegionOfID(tt.id)
+			if ok != tt.ok || region.Name != tt.expected {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tt.expected, tt.ok, region.Name, ok)
+			}
+		})
+	}
+
+	regions := registry.Regions()
+	if len(regions) != 2 || regions[0].Code != "1" || regions[1].Code != "2" {
+		t.Errorf("expected regions ordered by code, got %+v", regions)
+	}
+
+	// Trailing slashes of base URLs are dropped when building locations
+	eu, _ := registry.Lookup("2")
+	err := &ForeignRegionError{Region: eu, ID: "2abcdefg"}
+	if err.Location() != "https://eu.short.ly/2abcdefg" {
+		t.Errorf("expected location 'https://eu.short.ly/2abcdefg', got %q", err.Location())
+	}
+}
@@ -1,22 +1,43 @@
 package domain
 
+import "context"
+
 // ShortURLRepository defines the contract for persisting ShortURL entities.
 // This interface abstracts the data access layer and enables dependency inversion,
 // allowing different implementations (memory, database, etc.) to be used.
+// Every method accepts a context so that implementations can honor cancellation
+// and deadlines; a canceled context results in the context's error being returned.
 type ShortURLRepository interface {
 	// Save persists a ShortURL entity to the data store.
 	// If the entity already exists, it will be updated.
-	Save(shortURL *ShortURL) error
+	Save(ctx context.Context, shortURL *ShortURL) error
+
+	// Insert persists a new ShortURL entity only if no entity with the same ID exists.
+	// The existence check and the write happen atomically, so concurrent inserts of
+	// the same ID cannot both succeed. Returns ErrShortURLAlreadyExists on conflict.
+	Insert(ctx context.Context, shortURL *ShortURL) error
+
+	// Update atomically applies fn to the entity with the given ID and persists the result.
+	// No other write to the same entity can happen between the read and the write, so
+	// read-modify-write operations such as counting clicks never lose updates. fn receives
+	// a copy of the stored entity; if fn returns an error, nothing is written and the error
+	// is returned. Returns ErrShortURLNotFound if the entity does not exist.
+	Update(ctx context.Context, id string, fn func(*ShortURL) error) (*ShortURL, error)
 
 	// FindByID retrieves a ShortURL entity by its unique identifier.
 	// Returns nil if the entity is not found.
-	FindByID(id string) (*ShortURL, error)
+	FindByID(ctx context.Context, id string) (*ShortURL, error)
 
 	// FindAll retrieves all ShortURL entities from the data store.
 	// Returns an empty slice if no entities are found.
-	FindAll() ([]*ShortURL, error)
+	FindAll(ctx context.Context) ([]*ShortURL, error)
+
+	// FindPage retrieves one page of entities matching the query's filters, ordered by
+	// creation time (ties broken by ID) and starting after the query's cursor.
+	// Returns ErrInvalidCursor if the cursor cannot be decoded.
+	FindPage(ctx context.Context, query ShortURLQuery) (*ShortURLPage, error)
 
 	// Delete removes a ShortURL entity from the data store by its ID.
-	// Returns an error if the entity is not found.
-	Delete(id string) error
+	// Returns ErrShortURLNotFound if the entity is not found.
+	Delete(ctx context.Context, id string) error
 }
@@ -0,0 +1,12 @@
+package domain
+
+import "context"
+
+// RedirectResolver looks up where a URL redirects to. It is used to reveal the
+// destination behind links of other URL shorteners.
+type RedirectResolver interface {
+	// ResolveRedirect returns the target of the redirect served at url without
+	// following it further, or url itself if the response is not a redirect.
+	// It should give up and return the context's error once the context is canceled.
+	ResolveRedirect(ctx context.Context, url string) (string, error)
+}
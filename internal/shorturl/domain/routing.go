@@ -0,0 +1,272 @@
+package domain
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MaxRoutingRules bounds the routing rules of a short URL, since every redirect
+// evaluates them in order.
+const MaxRoutingRules = 20
+
+// Errors returned for invalid routing rules.
+var (
+	// ErrInvalidRoutingRule is returned when a routing rule has no condition or an invalid one.
+	// Validation returns it with a message naming the offending value.
+	ErrInvalidRoutingRule = NewError(ErrInvalidInput, "invalid_routing_rule", "routing rule is invalid")
+	// ErrTooManyRoutingRules is returned when a short URL is given more than MaxRoutingRules rules.
+	ErrTooManyRoutingRules = NewError(ErrInvalidInput, "too_many_routing_rules", fmt.Sprintf("at most %d routing rules are allowed", MaxRoutingRules))
+)
+
+// Device classifies the device a visitor uses, as told by its User-Agent header.
+type Device string
+
+const (
+	// DeviceIOS is an iPhone, iPad or iPod.
+	DeviceIOS Device = "ios"
+	// DeviceAndroid is an Android phone or tablet.
+	DeviceAndroid Device = "android"
+	// DeviceMobile is any other mobile device. As a rule condition, it matches every
+	// mobile device, including iOS and Android ones.
+	DeviceMobile Device = "mobile"
+	// DeviceDesktop is a desktop browser or any other client with a user agent.
+	DeviceDesktop Device = "desktop"
+)
+
+// DetectDevice classifies a User-Agent header. Note that iPads in desktop mode identify
+// themselves as Macs and are classified as desktops.
+//
+// Parameters:
+//   - userAgent: Value of the User-Agent header
+//
+// Returns:
+//   - Device: The device; empty if the user agent is empty, matching no device condition
+func DetectDevice(userAgent string) Device {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return ""
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad") || strings.Contains(ua, "ipod"):
+		return DeviceIOS
+	case strings.Contains(ua, "android"):
+		return DeviceAndroid
+	case strings.Contains(ua, "mobi") || strings.Contains(ua, "windows phone"):
+		return DeviceMobile
+	default:
+		return DeviceDesktop
+	}
+}
+
+// Includes reports whether a visitor's device satisfies a device condition of d: the same
+// device, or for DeviceMobile any mobile device.
+//
+// Parameters:
+//   - device: The visitor's device from DetectDevice
+//
+// Returns:
+//   - bool: True if the condition matches the device
+func (d Device) Includes(device Device) bool {
+	if d == DeviceMobile {
+		return device == DeviceIOS || device == DeviceAndroid || device == DeviceMobile
+	}
+	return d == device
+}
+
+// PreferredLanguage returns the language a visitor prefers most according to an
+// Accept-Language header: the tag with the highest quality value, the first one among
+// equals. The wildcard "*" and tags with a quality of 0 are ignored.
+//
+// Parameters:
+//   - acceptLanguage: Value of the Accept-Language header, e.g. "ja-JP,ja;q=0.9,en;q=0.8"
+//
+// Returns:
+//   - string: The language tag in lower case; empty if the header names none
+func PreferredLanguage(acceptLanguage string) string {
+	preferred, best := "", 0.0
+	for _, entry := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(entry, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = q
+		}
+		if quality > best {
+			preferred, best = tag, quality
+		}
+	}
+	return preferred
+}
+
+// Visitor holds the attributes of a redirect request that routing rules match against.
+type Visitor struct {
+	Device      Device // Device from the User-Agent header
+	Country     string // ISO 3166-1 alpha-2 code in upper case; empty if unknown
+	Language    string // Preferred language tag in lower case; empty if unknown
+	RefererHost string // Host of the Referer header in lower case ASCII; empty if none
+}
+
+// NewVisitor derives the routing attributes from the headers of a redirect request.
+//
+// Parameters:
+//   - userAgent: Value of the User-Agent header
+//   - acceptLanguage: Value of the Accept-Language header
+//   - referer: Value of the Referer header
+//   - country: Country of the client's IP address from a CountryResolver; empty if unknown
+//
+// Returns:
+//   - Visitor: The visitor's attributes
+func NewVisitor(userAgent, acceptLanguage, referer, country string) Visitor {
+	visitor := Visitor{
+		Device:   DetectDevice(userAgent),
+		Country:  strings.ToUpper(country),
+		Language: PreferredLanguage(acceptLanguage),
+	}
+	if parsed, err := url.Parse(referer); err == nil && parsed.Hostname() != "" {
+		visitor.RefererHost, _ = toASCIIHost(strings.TrimSuffix(parsed.Hostname(), "."))
+	}
+	return visitor
+}
+
+// RoutingRule sends visitors matching all of its conditions to its own destination
+// instead of the short URL's long URL. Each condition lists alternatives, any of which
+// may match, and an empty condition matches every visitor.
+type RoutingRule struct {
+	Devices   []Device // Devices of the visitor
+	Countries []string // ISO 3166-1 alpha-2 codes in upper case
+	Languages []string // Language tags in lower case; "ja" also matches "ja-jp"
+	Referrers []string // Referring hosts, including their subdomains
+	LongURL   string   // Destination of matching visitors
+}
+
+// NewRoutingRule validates and normalizes a routing rule.
+//
+// Parameters:
+//   - devices: Device names: ios, android, mobile (any mobile device) or desktop
+//   - countries: ISO 3166-1 alpha-2 country codes in any case, e.g. "JP"
+//   - languages: Language tags such as "ja" or "pt-BR"
+//   - referrers: Referring host names such as "twitter.com"; schemes and ports are ignored
+//   - longURL: Destination of matching visitors
+//
+// Returns:
+//   - RoutingRule: The rule
+//   - error: ErrInvalidRoutingRule if the rule has no condition or an invalid value,
+//     or ErrLongURLRequired if longURL is the zero LongURL
+func NewRoutingRule(devices, countries, languages, referrers []string, longURL LongURL) (RoutingRule, error) {
+	if longURL.IsZero() {
+		return RoutingRule{}, ErrLongURLRequired
+	}
+	if len(devices)+len(countries)+len(languages)+len(referrers) == 0 {
+		return RoutingRule{}, invalidRoutingRule("routing rule needs at least one condition")
+	}
+
+	rule := RoutingRule{LongURL: longURL.String()}
+	for _, name := range devices {
+		device := Device(strings.ToLower(strings.TrimSpace(name)))
+		switch device {
+		case DeviceIOS, DeviceAndroid, DeviceMobile, DeviceDesktop:
+			rule.Devices = append(rule.Devices, device)
+		default:
+			return RoutingRule{}, invalidRoutingRule(fmt.Sprintf("device %q is unknown; use ios, android, mobile or desktop", name))
+		}
+	}
+	for _, code := range countries {
+		country := strings.ToUpper(strings.TrimSpace(code))
+		if len(country) != 2 || !isLetters(country) {
+			return RoutingRule{}, invalidRoutingRule(fmt.Sprintf("country %q is not a two-letter ISO 3166-1 code", code))
+		}
+		rule.Countries = append(rule.Countries, country)
+	}
+	for _, tag := range languages {
+		language := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+		if !isLanguageTag(language) {
+			return RoutingRule{}, invalidRoutingRule(fmt.Sprintf("language %q is not a valid language tag", tag))
+		}
+		rule.Languages = append(rule.Languages, language)
+	}
+	for _, name := range referrers {
+		host := normalizeHost(name)
+		if host == "" {
+			return RoutingRule{}, invalidRoutingRule(fmt.Sprintf("referrer %q is not a valid host name", name))
+		}
+		rule.Referrers = append(rule.Referrers, host)
+	}
+	return rule, nil
+}
+
+// Matches reports whether the visitor satisfies every condition of the rule.
+func (r RoutingRule) Matches(visitor Visitor) bool {
+	return matchesAny(r.Devices, func(device Device) bool { return device.Includes(visitor.Device) }) &&
+		matchesAny(r.Countries, func(country string) bool { return country == visitor.Country }) &&
+		matchesAny(r.Languages, func(language string) bool {
+			return visitor.Language == language || strings.HasPrefix(visitor.Language, language+"-")
+		}) &&
+		matchesAny(r.Referrers, func(host string) bool {
+			return visitor.RefererHost == host || strings.HasSuffix(visitor.RefererHost, "."+host)
+		})
+}
+
+// matchesAny reports whether a condition is empty or one of its alternatives matches.
+func matchesAny[T any](alternatives []T, match func(T) bool) bool {
+	if len(alternatives) == 0 {
+		return true
+	}
+	for _, alternative := range alternatives {
+		if match(alternative) {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidRoutingRule returns ErrInvalidRoutingRule with a specific message.
+func invalidRoutingRule(message string) *Error {
+	return NewError(ErrInvalidInput, ErrInvalidRoutingRule.Code, message)
+}
+
+// isLetters reports whether s consists of ASCII letters only.
+func isLetters(s string) bool {
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// isLanguageTag reports whether tag looks like a BCP 47 language tag: a primary language
+// of 2 to 8 letters followed by subtags of 1 to 8 letters or digits.
+func isLanguageTag(tag string) bool {
+	subtags := strings.Split(tag, "-")
+	if n := len(subtags[0]); n < 2 || n > 8 || !isLetters(subtags[0]) {
+		return false
+	}
+	for _, subtag := range subtags[1:] {
+		if len(subtag) < 1 || len(subtag) > 8 {
+			return false
+		}
+		for _, c := range subtag {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// CountryResolver looks up where a client is located, so that routing rules can send
+// visitors to a country-specific destination.
+type CountryResolver interface {
+	// Country returns the ISO 3166-1 alpha-2 code of the country the IP address belongs
+	// to, in upper case, or an empty string if it is unknown.
+	Country(ip netip.Addr) string
+}
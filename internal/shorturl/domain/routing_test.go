@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDetectDevice(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		expected  Device
+	}{
+		{"iPhone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148", DeviceIOS},
+		{"iPad", "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148", DeviceIOS},
+		{"Android phone", "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/124.0 Mobile Safari/537.36", DeviceAndroid},
+		{"Android tablet", "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 Chrome/124.0 Safari/537.36", DeviceAndroid},
+		{"other mobile", "Opera/9.80 (J2ME/MIDP; Opera Mini/9.80; U; en) Presto/2.8.119 Version/11.10 Opera Mobi", DeviceMobile},
+		{"desktop", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/124.0 Safari/537.36", DeviceDesktop},
+		{"command line client", "curl/8.5.0", DeviceDesktop},
+		{"no user agent", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if device := DetectDevice(tt.userAgent); device != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, device)
+			}
+		})
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		expected       string
+	}{
+		{"single tag", "ja", "ja"},
+		{"first of equals", "ja-JP,en-US", "ja-jp"},
+		{"highest quality", "en;q=0.5, fr;q=0.9, de;q=0.7", "fr"},
+		{"implicit quality", "en;q=0.9,ja", "ja"},
+		{"wildcard ignored", "*,de;q=0.5", "de"},
+		{"zero quality ignored", "fr;q=0,it;q=0.1", "it"},
+		{"invalid quality ignored", "fr;q=high,it;q=0.1", "it"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if language := PreferredLanguage(tt.acceptLanguage); language != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, language)
+			}
+		})
+	}
+}
+
+func TestNewVisitor(t *testing.T) {
+	visitor := NewVisitor("Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X)", "ja-JP,ja;q=0.9", "https://M.Facebook.com./story", "jp")
+	expected := Visitor{Device: DeviceIOS, Country: "JP", Language: "ja-jp", RefererHost: "m.facebook.com"}
+	if visitor != expected {
+		t.Errorf("expected %+v, got %+v", expected, visitor)
+	}
+
+	if visitor := NewVisitor("", "", "not a url", ""); visitor != (Visitor{}) {
+		t.Errorf("expected empty visitor, got %+v", visitor)
+	}
+}
+
+func TestNewRoutingRule(t *testing.T) {
+	destination := MustParseLongURL("https://example.com/ja")
+
+	tests := []struct {
+		name         string
+		devices      []string
+		countries    []string
+		languages    []string
+		referrers    []string
+		longURL      LongURL
+		expected     RoutingRule
+		expectedCode string
+	}{
+		{
+			name:      "normalizes conditions",
+			devices:   []string{"iOS", " android "},
+			countries: []string{"jp"},
+			languages: []string{"pt_BR", "JA"},
+			referrers: []string{"https://Twitter.com:443"},
+			longURL:   destination,
+			expected: RoutingRule{
+				Devices:   []Device{DeviceIOS, DeviceAndroid},
+				Countries: []string{"JP"},
+				Languages: []string{"pt-br", "ja"},
+				Referrers: []string{"twitter.com"},
+				LongURL:   "https://example.com/ja",
+			},
+		},
+		{name: "no condition", longURL: destination, expectedCode: "invalid_routing_rule"},
+		{name: "unknown device", devices: []string{"tablet"}, longURL: destination, expectedCode: "invalid_routing_rule"},
+		{name: "three-letter country", countries: []string{"JPN"}, longURL: destination, expectedCode: "invalid_routing_rule"},
+		{name: "invalid language", languages: []string{"j"}, longURL: destination, expectedCode: "invalid_routing_rule"},
+		{name: "invalid language subtag", languages: []string{"en-"}, longURL: destination, expectedCode: "invalid_routing_rule"},
+		{name: "empty referrer", referrers: []string{" "}, longURL: destination, expectedCode: "invalid_routing_rule"},
+		{name: "no destination", devices: []string{"ios"}, expectedCode: "long_url_required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := NewRoutingRule(tt.devices, tt.countries, tt.languages, tt.referrers, tt.longURL)
+			if tt.expectedCode != "" {
+				var domainErr *Error
+				if !errors.As(err, &domainErr) || domainErr.Code != tt.expectedCode {
+					t.Fatalf("expected error code %q, got %v", tt.expectedCode, err)
+				}
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Errorf("expected an invalid input error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(rule, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, rule)
+			}
+		})
+	}
+}
+
+func TestRoutingRule_Matches(t *testing.T) {
+	rule := RoutingRule{
+		Devices:   []Device{DeviceIOS, DeviceAndroid},
+		Languages: []string{"ja"},
+		LongURL:   "https://example.com/ja/app",
+	}
+	referrerRule := RoutingRule{Referrers: []string{"facebook.com"}, LongURL: "https://example.com/fb"}
+	countryRule := RoutingRule{Countries: []string{"JP", "KR"}, LongURL: "https://example.com/asia"}
+	mobileRule, err := NewRoutingRule([]string{"mobile"}, nil, nil, nil, MustParseLongURL("https://m.example.com"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	iPhone := NewVisitor("Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148", "", "", "")
+
+	tests := []struct {
+		name     string
+		rule     RoutingRule
+		visitor  Visitor
+		expected bool
+	}{
+		{"all conditions match", rule, Visitor{Device: DeviceIOS, Language: "ja"}, true},
+		{"language subtag matches primary language", rule, Visitor{Device: DeviceAndroid, Language: "ja-jp"}, true},
+		{"one condition fails", rule, Visitor{Device: DeviceDesktop, Language: "ja"}, false},
+		{"language prefix is not a subtag", rule, Visitor{Device: DeviceIOS, Language: "jav"}, false},
+		{"unknown attributes", rule, Visitor{}, false},
+		{"referrer subdomain", referrerRule, Visitor{RefererHost: "m.facebook.com"}, true},
+		{"referrer lookalike", referrerRule, Visitor{RefererHost: "notfacebook.com"}, false},
+		{"any country", countryRule, Visitor{Country: "KR"}, true},
+		{"other country", countryRule, Visitor{Country: "US"}, false},
+		{"mobile includes iPhone", mobileRule, iPhone, true},
+		{"mobile includes Android", mobileRule, Visitor{Device: DeviceAndroid}, true},
+		{"mobile includes other mobile", mobileRule, Visitor{Device: DeviceMobile}, true},
+		{"mobile excludes desktop", mobileRule, Visitor{Device: DeviceDesktop}, false},
+		{"ios excludes other mobile", rule, Visitor{Device: DeviceMobile, Language: "ja"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if matches := tt.rule.Matches(tt.visitor); matches != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, matches)
+			}
+		})
+	}
+}
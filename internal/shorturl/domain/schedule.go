@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// Errors returned for short URLs scheduled to go live later.
+var (
+	// ErrShortURLNotYetActive is returned when a short URL is looked up before its activation time.
+	// Lookups return it wrapped in a NotYetActiveError.
+	ErrShortURLNotYetActive = NewError(ErrUnavailable, "short_url_not_yet_active", "short URL is not active yet")
+	// ErrInvalidNotBefore is returned when an activation time is not before the expiration time.
+	ErrInvalidNotBefore = NewError(ErrInvalidInput, "invalid_not_before", "activation time must be before the expiration time")
+)
+
+// NotYetActiveError is returned when a short URL is looked up before its activation time.
+// It unwraps to ErrShortURLNotYetActive. If PreLaunchURL is set, the caller should send
+// the client there until the short URL goes live.
+type NotYetActiveError struct {
+	ActivatesAt  time.Time // When the short URL starts redirecting
+	PreLaunchURL string    // Where to send visitors in the meantime; empty for none
+}
+
+// Error returns the message of ErrShortURLNotYetActive.
+func (e *NotYetActiveError) Error() string {
+	return ErrShortURLNotYetActive.Message
+}
+
+// Unwrap returns ErrShortURLNotYetActive so that errors.Is and errors.As see the domain error.
+func (e *NotYetActiveError) Unwrap() error {
+	return ErrShortURLNotYetActive
+}
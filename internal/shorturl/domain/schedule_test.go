@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNotYetActiveError(t *testing.T) {
+	activatesAt := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	var err error = &NotYetActiveError{ActivatesAt: activatesAt, PreLaunchURL: "https://example.com/soon"}
+
+	if !errors.Is(err, ErrShortURLNotYetActive) {
+		t.Error("expected error to match ErrShortURLNotYetActive")
+	}
+	if !errors.Is(err, ErrUnavailable) {
+		t.Error("expected error to be of kind ErrUnavailable")
+	}
+	var domainErr *Error
+	if !errors.As(err, &domainErr) || domainErr.Code != "short_url_not_yet_active" {
+		t.Errorf("expected domain error with code short_url_not_yet_active, got %v", domainErr)
+	}
+	if err.Error() != ErrShortURLNotYetActive.Message {
+		t.Errorf("expected message %q, got %q", ErrShortURLNotYetActive.Message, err.Error())
+	}
+}
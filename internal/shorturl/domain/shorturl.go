@@ -1,10 +1,7 @@
 // Package domain contains the core business logic and entities for the URL shortening service.
 package domain
 
-import (
-	"errors"
-	"time"
-)
+import "time"
 
 // ShortURL represents the core entity of the URL shortening service.
 // It encapsulates all business rules related to URL shortening and management.
@@ -14,8 +11,28 @@ type ShortURL struct {
 	shortURL     string                 // Complete short URL including domain
 	createdAt    time.Time              // Timestamp when the URL was created
 	expiry       *time.Time             // Optional expiration time for the URL
-	isActive     bool                   // Flag indicating if the URL is currently active
+	state        LifecycleState         // Current lifecycle state; only active URLs redirect
 	userMetadata map[string]interface{} // Additional user-defined metadata
+	region       string                 // Code of the region that minted the URL; empty in single-region deployments
+	history      []DestinationChange    // Previous destinations, oldest first
+	transitions  []StateTransition      // Lifecycle state changes, oldest first
+	maxClicks    int                    // Number of redirects after which the URL is exhausted; 0 for no limit
+	clicks       int                    // Redirects recorded by RecordClick
+	notBefore    *time.Time             // Optional activation time; the URL does not redirect before it
+	preLaunchURL string                 // Where to send visitors before notBefore; empty for none
+	passwordHash string                 // Encoded hash of the password visitors must enter; empty if unprotected
+	routingRules []RoutingRule          // Ordered rules sending matching visitors elsewhere than longURL
+}
+
+// MaxDestinationHistory is the number of previous destinations kept per short URL.
+// Older entries are dropped first.
+const MaxDestinationHistory = 50
+
+// DestinationChange records a destination that a short URL pointed to before an update.
+type DestinationChange struct {
+	LongURL   string    // Destination before the change
+	ChangedAt time.Time // When the destination was replaced
+	Actor     string    // Who made the change; empty if unknown
 }
 
 // NewShortURL creates a new ShortURL entity with automatically generated ID.
@@ -23,7 +40,7 @@ type ShortURL struct {
 //
 // Parameters:
 //   - id: Unique identifier for the short URL
-//   - longURL: Original URL to be shortened
+//   - longURL: Original URL to be shortened, validated by ParseLongURL
 //   - shortURL: Complete short URL string
 //   - expiry: Optional expiration time
 //   - userMetadata: Additional metadata associated with the URL
@@ -31,21 +48,21 @@ type ShortURL struct {
 // Returns:
 //   - *ShortURL: The created entity
 //   - error: Validation error if any required field is empty
-func NewShortURL(id, longURL, shortURL string, expiry *time.Time, userMetadata map[string]interface{}) (*ShortURL, error) {
-	if longURL == "" {
-		return nil, errors.New("long URL cannot be empty")
+func NewShortURL(id string, longURL LongURL, shortURL string, expiry *time.Time, userMetadata map[string]interface{}) (*ShortURL, error) {
+	if longURL.IsZero() {
+		return nil, ErrLongURLRequired
 	}
 	if shortURL == "" {
-		return nil, errors.New("short URL cannot be empty")
+		return nil, ErrShortURLRequired
 	}
 
 	return &ShortURL{
 		id:           id,
-		longURL:      longURL,
+		longURL:      longURL.String(),
 		shortURL:     shortURL,
 		createdAt:    time.Now(),
 		expiry:       expiry,
-		isActive:     true,
+		state:        StateActive,
 		userMetadata: userMetadata,
 	}, nil
 }
@@ -54,29 +71,29 @@ func NewShortURL(id, longURL, shortURL string, expiry *time.Time, userMetadata m
 // This is used when users want to specify their own custom short URL.
 //
 // Parameters:
-//   - customURL: User-defined custom identifier
-//   - longURL: Original URL to be shortened
+//   - alias: User-defined custom identifier, validated by NewAlias
+//   - longURL: Original URL to be shortened, validated by ParseLongURL
 //   - expiry: Optional expiration time
 //   - userMetadata: Additional metadata associated with the URL
 //
 // Returns:
 //   - *ShortURL: The created entity
 //   - error: Validation error if any required field is empty
-func NewCustomShortURL(customURL, longURL string, expiry *time.Time, userMetadata map[string]interface{}) (*ShortURL, error) {
-	if longURL == "" {
-		return nil, errors.New("long URL cannot be empty")
+func NewCustomShortURL(alias Alias, longURL LongURL, expiry *time.Time, userMetadata map[string]interface{}) (*ShortURL, error) {
+	if longURL.IsZero() {
+		return nil, ErrLongURLRequired
 	}
-	if customURL == "" {
-		return nil, errors.New("custom URL cannot be empty")
+	if alias.IsZero() {
+		return nil, ErrCustomURLRequired
 	}
 
 	return &ShortURL{
-		id:           customURL,
-		longURL:      longURL,
-		shortURL:     customURL,
+		id:           alias.String(),
+		longURL:      longURL.String(),
+		shortURL:     alias.String(),
 		createdAt:    time.Now(),
 		expiry:       expiry,
-		isActive:     true,
+		state:        StateActive,
 		userMetadata: userMetadata,
 	}, nil
 }
@@ -90,19 +107,23 @@ func NewCustomShortURL(customURL, longURL string, expiry *time.Time, userMetadat
 //   - shortURL: Complete short URL string
 //   - createdAt: Creation timestamp
 //   - expiry: Optional expiration time
-//   - isActive: Current active status
+//   - isActive: Current active status; use RestoreLifecycle for the other states
 //   - userMetadata: Associated metadata
 //
 // Returns:
 //   - *ShortURL: The reconstructed entity
 func ReconstructShortURL(id, longURL, shortURL string, createdAt time.Time, expiry *time.Time, isActive bool, userMetadata map[string]interface{}) *ShortURL {
+	state := StateActive
+	if !isActive {
+		state = StateDeactivated
+	}
 	return &ShortURL{
 		id:           id,
 		longURL:      longURL,
 		shortURL:     shortURL,
 		createdAt:    createdAt,
 		expiry:       expiry,
-		isActive:     isActive,
+		state:        state,
 		userMetadata: userMetadata,
 	}
 }
@@ -133,9 +154,108 @@ func (s *ShortURL) Expiry() *time.Time {
 	return s.expiry
 }
 
+// NotBefore returns the activation time of the URL, if scheduled.
+// Returns nil if the URL redirects as soon as it is created.
+func (s *ShortURL) NotBefore() *time.Time {
+	return s.notBefore
+}
+
+// PreLaunchURL returns the URL visitors are sent to before the activation time,
+// or an empty string if none is configured.
+func (s *ShortURL) PreLaunchURL() string {
+	return s.preLaunchURL
+}
+
+// PasswordHash returns the encoded hash of the URL's password, or an empty string if the
+// URL is not password protected. It must never be exposed to clients.
+func (s *ShortURL) PasswordHash() string {
+	return s.passwordHash
+}
+
+// IsProtected returns whether visitors must enter a password before being redirected.
+func (s *ShortURL) IsProtected() bool {
+	return s.passwordHash != ""
+}
+
+// Protect requires visitors to enter a password before being redirected. It is also used
+// when reconstructing an entity from stored data.
+//
+// Parameters:
+//   - passwordHash: Encoded hash from a PasswordHasher; empty to remove the protection
+func (s *ShortURL) Protect(passwordHash string) {
+	s.passwordHash = passwordHash
+}
+
+// RoutingRules returns the ordered routing rules of the URL, or nil if every visitor is
+// sent to the long URL.
+func (s *ShortURL) RoutingRules() []RoutingRule {
+	return s.routingRules
+}
+
+// SetRoutingRules replaces the routing rules of the URL. It is also used when
+// reconstructing an entity from stored data.
+//
+// Parameters:
+//   - rules: Rules from NewRoutingRule in the order they are evaluated; empty to remove them
+//
+// Returns:
+//   - error: ErrTooManyRoutingRules if there are more than MaxRoutingRules rules
+func (s *ShortURL) SetRoutingRules(rules []RoutingRule) error {
+	if len(rules) > MaxRoutingRules {
+		return ErrTooManyRoutingRules
+	}
+	s.routingRules = nil
+	if len(rules) > 0 {
+		s.routingRules = append([]RoutingRule(nil), rules...)
+	}
+	return nil
+}
+
+// Route picks the destination for a visitor: the destination of the first routing rule
+// the visitor matches, or the long URL if none matches.
+//
+// Parameters:
+//   - visitor: Attributes of the redirect request
+//
+// Returns:
+//   - string: The destination
+//   - int: Position of the matching rule, starting at 1; 0 if the long URL was chosen
+func (s *ShortURL) Route(visitor Visitor) (string, int) {
+	for i, rule := range s.routingRules {
+		if rule.Matches(visitor) {
+			return rule.LongURL, i + 1
+		}
+	}
+	return s.longURL, 0
+}
+
 // IsActive returns whether the URL is currently active and can be used for redirection.
 func (s *ShortURL) IsActive() bool {
-	return s.isActive
+	return s.state == StateActive
+}
+
+// State returns the current lifecycle state of the URL.
+func (s *ShortURL) State() LifecycleState {
+	return s.state
+}
+
+// MaxClicks returns the number of redirects after which the URL is exhausted,
+// or 0 if the URL has no click limit.
+func (s *ShortURL) MaxClicks() int {
+	return s.maxClicks
+}
+
+// Clicks returns the number of redirects recorded by RecordClick.
+func (s *ShortURL) Clicks() int {
+	return s.clicks
+}
+
+// Transitions returns the lifecycle state changes of the URL, oldest first.
+//
+// Returns:
+//   - []StateTransition: A copy of the transition log; empty if the state never changed
+func (s *ShortURL) Transitions() []StateTransition {
+	return append([]StateTransition(nil), s.transitions...)
 }
 
 // UserMetadata returns the additional metadata associated with the URL.
@@ -152,8 +272,267 @@ func (s *ShortURL) IsExpired() bool {
 	return time.Now().After(*s.expiry)
 }
 
+// IsScheduled checks if the URL has an activation time that has not been reached yet.
+// Returns false if no activation time is set.
+func (s *ShortURL) IsScheduled() bool {
+	if s.notBefore == nil {
+		return false
+	}
+	return time.Now().Before(*s.notBefore)
+}
+
+// Status classifies the URL as active, inactive, expired or scheduled for listings.
+// Every state other than active counts as inactive, regardless of expiry.
+//
+// Returns:
+//   - ShortURLStatus: StatusInactive, StatusExpired, StatusScheduled or StatusActive
+func (s *ShortURL) Status() ShortURLStatus {
+	switch {
+	case s.state != StateActive:
+		return StatusInactive
+	case s.IsExpired():
+		return StatusExpired
+	case s.IsScheduled():
+		return StatusScheduled
+	default:
+		return StatusActive
+	}
+}
+
+// Region returns the code of the region that minted the URL, or an empty string
+// if it was created without region configuration.
+func (s *ShortURL) Region() string {
+	return s.region
+}
+
+// AssignRegion records the region that minted the URL.
+//
+// Parameters:
+//   - code: Region code
+func (s *ShortURL) AssignRegion(code string) {
+	s.region = code
+}
+
+// History returns the destinations the URL pointed to before, oldest first.
+//
+// Returns:
+//   - []DestinationChange: A copy of the change history; empty if the destination never changed
+func (s *ShortURL) History() []DestinationChange {
+	return append([]DestinationChange(nil), s.history...)
+}
+
+// RestoreHistory sets the change history when reconstructing an entity from stored data.
+//
+// Parameters:
+//   - history: Previous destinations, oldest first
+func (s *ShortURL) RestoreHistory(history []DestinationChange) {
+	s.history = append([]DestinationChange(nil), history...)
+}
+
+// UpdateDestination points the URL to a new long URL, recording the previous one in
+// the change history. Updating to the current destination is a no-op.
+// This is a business operation that implements the destination update use case.
+//
+// Parameters:
+//   - longURL: The new destination, validated by ParseLongURL
+//   - actor: Who makes the change; may be empty
+//   - at: Time of the change
+//
+// Returns:
+//   - bool: Whether the destination changed
+//   - error: ErrLongURLRequired if longURL is zero
+func (s *ShortURL) UpdateDestination(longURL LongURL, actor string, at time.Time) (bool, error) {
+	if longURL.IsZero() {
+		return false, ErrLongURLRequired
+	}
+	if longURL.String() == s.longURL {
+		return false, nil
+	}
+
+	s.history = append(s.history, DestinationChange{LongURL: s.longURL, ChangedAt: at, Actor: actor})
+	if excess := len(s.history) - MaxDestinationHistory; excess > 0 {
+		s.history = append([]DestinationChange(nil), s.history[excess:]...)
+	}
+	s.longURL = longURL.String()
+	return true, nil
+}
+
+// RestoreLifecycle sets the lifecycle state and transition log when reconstructing an
+// entity from stored data.
+//
+// Parameters:
+//   - state: Current lifecycle state
+//   - transitions: State changes, oldest first
+func (s *ShortURL) RestoreLifecycle(state LifecycleState, transitions []StateTransition) {
+	s.state = state
+	s.transitions = append([]StateTransition(nil), transitions...)
+}
+
+// LimitClicks makes the URL usable for a limited number of redirects, e.g. 1 for a
+// one-time link.
+//
+// Parameters:
+//   - maxClicks: Number of redirects after which the URL is exhausted
+//
+// Returns:
+//   - error: ErrInvalidMaxClicks if maxClicks is not positive
+func (s *ShortURL) LimitClicks(maxClicks int) error {
+	if maxClicks <= 0 {
+		return ErrInvalidMaxClicks
+	}
+	s.maxClicks = maxClicks
+	return nil
+}
+
+// RestoreClicks sets the click limit and count when reconstructing an entity from stored data.
+//
+// Parameters:
+//   - maxClicks: Click limit; 0 for no limit
+//   - clicks: Redirects recorded so far
+func (s *ShortURL) RestoreClicks(maxClicks, clicks int) {
+	s.maxClicks = maxClicks
+	s.clicks = clicks
+}
+
+// Schedule makes the URL start redirecting only at notBefore, e.g. for a campaign that
+// goes live at a fixed time. Until then, visitors can be sent to a pre-launch page.
+//
+// Parameters:
+//   - notBefore: Activation time
+//   - preLaunchURL: Where to send visitors before the activation time; the zero LongURL for none
+//
+// Returns:
+//   - error: ErrInvalidNotBefore if notBefore is not before the expiration time
+func (s *ShortURL) Schedule(notBefore time.Time, preLaunchURL LongURL) error {
+	if s.expiry != nil && !notBefore.Before(*s.expiry) {
+		return ErrInvalidNotBefore
+	}
+	s.notBefore = &notBefore
+	s.preLaunchURL = preLaunchURL.String()
+	return nil
+}
+
+// RestoreSchedule sets the activation time and pre-launch URL when reconstructing an
+// entity from stored data.
+//
+// Parameters:
+//   - notBefore: Activation time; nil if the URL is not scheduled
+//   - preLaunchURL: Where to send visitors before the activation time; empty for none
+func (s *ShortURL) RestoreSchedule(notBefore *time.Time, preLaunchURL string) {
+	s.notBefore = notBefore
+	s.preLaunchURL = preLaunchURL
+}
+
+// RecordClick counts a redirect of the URL. The click that uses up the click limit
+// moves the URL to StateExhausted, so that it cannot be used again. Callers must
+// record clicks through ShortURLRepository.Update so that concurrent clicks are
+// counted one at a time and never exceed the limit.
+//
+// Parameters:
+//   - at: Time of the click
+//
+// Returns:
+//   - bool: Whether this click exhausted the URL
+//   - error: The error of CheckAvailable if the URL cannot be used
+func (s *ShortURL) RecordClick(at time.Time) (bool, error) {
+	if err := s.CheckAvailable(); err != nil {
+		return false, err
+	}
+
+	s.clicks++
+	if s.maxClicks == 0 || s.clicks < s.maxClicks {
+		return false, nil
+	}
+	s.recordTransition(StateExhausted, "click limit reached", "", at)
+	return true, nil
+}
+
+// Transition moves the URL to another lifecycle state, recording the change with its
+// reason and actor. The allowed transitions are:
+//
+//   - active → paused, deactivated, blocked, archived
+//   - paused → active, deactivated, blocked, archived
+//   - deactivated → active, blocked, archived
+//   - blocked → active, deactivated, archived
+//   - exhausted → deactivated, blocked, archived
+//
+// Archived URLs cannot change state any more, and only RecordClick moves a URL to
+// StateExhausted.
+//
+// Parameters:
+//   - to: The target state
+//   - reason: Why the state is changed; may be empty
+//   - actor: Who makes the change; may be empty
+//   - at: Time of the change
+//
+// Returns:
+//   - error: ErrInvalidState for an unknown target, or an "invalid_state_transition"
+//     conflict error if the lifecycle does not allow the change
+func (s *ShortURL) Transition(to LifecycleState, reason, actor string, at time.Time) error {
+	if _, err := ParseLifecycleState(string(to)); err != nil {
+		return err
+	}
+	if !s.state.CanTransitionTo(to) {
+		return newInvalidTransitionError(s.state, to)
+	}
+
+	s.recordTransition(to, reason, actor, at)
+	return nil
+}
+
+// recordTransition changes the state and appends the change to the transition log.
+func (s *ShortURL) recordTransition(to LifecycleState, reason, actor string, at time.Time) {
+	s.transitions = append(s.transitions, StateTransition{From: s.state, To: to, Reason: reason, Actor: actor, At: at})
+	if excess := len(s.transitions) - MaxStateTransitions; excess > 0 {
+		s.transitions = append([]StateTransition(nil), s.transitions[excess:]...)
+	}
+	s.state = to
+}
+
+// Clone returns a copy of the entity that can be modified without affecting the
+// original. Repositories use it to apply updates without racing with readers of
+// the stored entity.
+//
+// Returns:
+//   - *ShortURL: The copy; user metadata is shared, since no operation modifies it
+func (s *ShortURL) Clone() *ShortURL {
+	clone := *s
+	clone.history = append([]DestinationChange(nil), s.history...)
+	clone.transitions = append([]StateTransition(nil), s.transitions...)
+	return &clone
+}
+
 // Deactivate marks the URL as inactive, preventing it from being used for redirection.
-// This is a business operation that implements the URL deactivation use case.
+// It is a shorthand for a transition to StateDeactivated without reason or actor, and
+// does nothing if the current state does not allow that transition.
 func (s *ShortURL) Deactivate() {
-	s.isActive = false
+	_ = s.Transition(StateDeactivated, "", "", time.Now())
+}
+
+// CheckAvailable reports whether the URL may be used for redirection.
+//
+// Returns:
+//   - error: nil for an active URL between its activation and expiration times; otherwise
+//     ErrShortURLPaused, ErrShortURLInactive, ErrShortURLBlocked, ErrShortURLArchived,
+//     ErrShortURLExhausted, ErrShortURLExpired or a *NotYetActiveError
+func (s *ShortURL) CheckAvailable() error {
+	switch s.state {
+	case StatePaused:
+		return ErrShortURLPaused
+	case StateDeactivated:
+		return ErrShortURLInactive
+	case StateBlocked:
+		return ErrShortURLBlocked
+	case StateArchived:
+		return ErrShortURLArchived
+	case StateExhausted:
+		return ErrShortURLExhausted
+	}
+	if s.IsExpired() {
+		return ErrShortURLExpired
+	}
+	if s.IsScheduled() {
+		return &NotYetActiveError{ActivatesAt: *s.notBefore, PreLaunchURL: s.preLaunchURL}
+	}
+	return nil
 }
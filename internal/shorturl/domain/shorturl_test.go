@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 )
@@ -55,7 +57,8 @@ func TestNewShortURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			shortURL, err := NewShortURL(tt.id, tt.longURL, tt.shortURL, tt.expiry, tt.userMetadata)
+			longURL, _ := ParseLongURL(tt.longURL, DefaultLongURLPolicy())
+			shortURL, err := NewShortURL(tt.id, longURL, tt.shortURL, tt.expiry, tt.userMetadata)
 
 			if tt.expectError {
 				if err == nil {
@@ -138,7 +141,9 @@ func TestNewCustomShortURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			shortURL, err := NewCustomShortURL(tt.customURL, tt.longURL, tt.expiry, tt.userMetadata)
+			alias, _ := NewAlias(tt.customURL, DefaultAliasPolicy())
+			longURL, _ := ParseLongURL(tt.longURL, DefaultLongURLPolicy())
+			shortURL, err := NewCustomShortURL(alias, longURL, tt.expiry, tt.userMetadata)
 
 			if tt.expectError {
 				if err == nil {
@@ -230,7 +235,7 @@ func TestShortURL_IsExpired(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			shortURL, _ := NewShortURL("abc123", "https://example.com", "http://short.ly/abc123", tt.expiry, nil)
+			shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com"), "http://short.ly/abc123", tt.expiry, nil)
 
 			if shortURL.IsExpired() != tt.expected {
 				t.Errorf("expected IsExpired() to return %v, got %v", tt.expected, shortURL.IsExpired())
@@ -240,7 +245,7 @@ func TestShortURL_IsExpired(t *testing.T) {
 }
 
 func TestShortURL_Deactivate(t *testing.T) {
-	shortURL, _ := NewShortURL("abc123", "https://example.com", "http://short.ly/abc123", nil, nil)
+	shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
 
 	if !shortURL.IsActive() {
 		t.Errorf("expected URL to be active initially")
@@ -253,6 +258,71 @@ func TestShortURL_Deactivate(t *testing.T) {
 	}
 }
 
+func TestShortURL_UpdateDestination(t *testing.T) {
+	shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com/v1"), "http://short.ly/abc123", nil, nil)
+	first := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	changed, err := shortURL.UpdateDestination(MustParseLongURL("https://example.com/v2"), "alice", first)
+	if err != nil || !changed {
+		t.Fatalf("expected change, got %v, %v", changed, err)
+	}
+	changed, err = shortURL.UpdateDestination(MustParseLongURL("https://example.com/v3"), "", second)
+	if err != nil || !changed {
+		t.Fatalf("expected change, got %v, %v", changed, err)
+	}
+
+	if shortURL.LongURL() != "https://example.com/v3" {
+		t.Errorf("expected new destination, got %q", shortURL.LongURL())
+	}
+	expected := []DestinationChange{
+		{LongURL: "https://example.com/v1", ChangedAt: first, Actor: "alice"},
+		{LongURL: "https://example.com/v2", ChangedAt: second},
+	}
+	history := shortURL.History()
+	if len(history) != len(expected) {
+		t.Fatalf("expected %d history entries, got %d", len(expected), len(history))
+	}
+	for i := range expected {
+		if history[i] != expected[i] {
+			t.Errorf("history[%d]: expected %+v, got %+v", i, expected[i], history[i])
+		}
+	}
+
+	// The returned history is a copy
+	history[0].Actor = "mallory"
+	if shortURL.History()[0].Actor != "alice" {
+		t.Error("expected history to be unaffected by changes to the returned slice")
+	}
+
+	// Updating to the current destination is a no-op
+	changed, err = shortURL.UpdateDestination(MustParseLongURL("https://example.com/v3"), "alice", second)
+	if err != nil || changed || len(shortURL.History()) != 2 {
+		t.Errorf("expected no-op, got %v, %v with %d entries", changed, err, len(shortURL.History()))
+	}
+
+	if _, err := shortURL.UpdateDestination(LongURL{}, "alice", second); err != ErrLongURLRequired {
+		t.Errorf("expected ErrLongURLRequired, got %v", err)
+	}
+}
+
+func TestShortURL_UpdateDestination_HistoryLimit(t *testing.T) {
+	shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com/0"), "http://short.ly/abc123", nil, nil)
+	for i := 1; i <= MaxDestinationHistory+5; i++ {
+		if _, err := shortURL.UpdateDestination(MustParseLongURL(fmt.Sprintf("https://example.com/%d", i)), "", time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	history := shortURL.History()
+	if len(history) != MaxDestinationHistory {
+		t.Fatalf("expected %d history entries, got %d", MaxDestinationHistory, len(history))
+	}
+	if history[0].LongURL != "https://example.com/5" {
+		t.Errorf("expected oldest entries to be dropped, first entry is %q", history[0].LongURL)
+	}
+}
+
 func TestShortURL_Getters(t *testing.T) {
 	id := "abc123"
 	longURL := "https://example.com"
@@ -263,7 +333,7 @@ func TestShortURL_Getters(t *testing.T) {
 		"source": "api",
 	}
 
-	url, _ := NewShortURL(id, longURL, shortURL, expiry, userMetadata)
+	url, _ := NewShortURL(id, MustParseLongURL(longURL), shortURL, expiry, userMetadata)
 
 	if url.ID() != id {
 		t.Errorf("ID() returned %q, expected %q", url.ID(), id)
@@ -299,3 +369,247 @@ func TestShortURL_Getters(t *testing.T) {
 func timePtr(t time.Time) *time.Time {
 	return &t
 }
+
+func TestShortURL_LimitClicks(t *testing.T) {
+	shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	for _, invalid := range []int{0, -1} {
+		if err := shortURL.LimitClicks(invalid); err != ErrInvalidMaxClicks {
+			t.Errorf("LimitClicks(%d): expected ErrInvalidMaxClicks, got %v", invalid, err)
+		}
+	}
+	if err := shortURL.LimitClicks(3); err != nil || shortURL.MaxClicks() != 3 {
+		t.Errorf("expected limit 3, got %d, %v", shortURL.MaxClicks(), err)
+	}
+}
+
+func TestShortURL_RecordClick(t *testing.T) {
+	tests := []struct {
+		name             string
+		maxClicks        int
+		clicks           int
+		expectedExhaust  []bool
+		expectedErrAfter int // Number of successful clicks before ErrShortURLExhausted; -1 for none
+	}{
+		{"one-time link", 1, 0, []bool{true}, 1},
+		{"three clicks", 3, 0, []bool{false, false, true}, 3},
+		{"restored count", 3, 2, []bool{true}, 1},
+		{"no limit", 0, 0, []bool{false, false, false, false}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+			shortURL.RestoreClicks(tt.maxClicks, tt.clicks)
+
+			for i, expected := range tt.expectedExhaust {
+				exhausted, err := shortURL.RecordClick(time.Now())
+				if err != nil || exhausted != expected {
+					t.Fatalf("click %d: expected exhausted=%v, got %v, %v", i+1, expected, exhausted, err)
+				}
+			}
+			if tt.expectedErrAfter < 0 {
+				if shortURL.State() != StateActive || shortURL.Clicks() != len(tt.expectedExhaust) {
+					t.Errorf("expected active URL with %d clicks, got %q with %d", len(tt.expectedExhaust), shortURL.State(), shortURL.Clicks())
+				}
+				return
+			}
+
+			if shortURL.State() != StateExhausted || shortURL.Clicks() != tt.maxClicks {
+				t.Errorf("expected exhausted URL with %d clicks, got %q with %d", tt.maxClicks, shortURL.State(), shortURL.Clicks())
+			}
+			if _, err := shortURL.RecordClick(time.Now()); err != ErrShortURLExhausted {
+				t.Errorf("expected ErrShortURLExhausted, got %v", err)
+			}
+			if shortURL.Clicks() != tt.maxClicks {
+				t.Errorf("expected rejected click not to be counted, got %d", shortURL.Clicks())
+			}
+			transitions := shortURL.Transitions()
+			if len(transitions) != 1 || transitions[0].To != StateExhausted || transitions[0].Reason != "click limit reached" {
+				t.Errorf("unexpected transitions %+v", transitions)
+			}
+		})
+	}
+}
+
+func TestShortURL_RecordClick_Unavailable(t *testing.T) {
+	shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	shortURL.LimitClicks(5)
+	shortURL.Transition(StatePaused, "", "", time.Now())
+
+	if _, err := shortURL.RecordClick(time.Now()); err != ErrShortURLPaused {
+		t.Errorf("expected ErrShortURLPaused, got %v", err)
+	}
+	if shortURL.Clicks() != 0 {
+		t.Errorf("expected no click to be counted, got %d", shortURL.Clicks())
+	}
+}
+
+func TestShortURL_Clone(t *testing.T) {
+	original, _ := NewShortURL("abc123", MustParseLongURL("https://example.com/a"), "http://short.ly/abc123", nil, nil)
+	original.LimitClicks(2)
+	original.UpdateDestination(MustParseLongURL("https://example.com/b"), "alice", time.Now())
+
+	clone := original.Clone()
+	clone.RecordClick(time.Now())
+	clone.RecordClick(time.Now())
+	clone.UpdateDestination(MustParseLongURL("https://example.com/c"), "bob", time.Now())
+
+	if original.Clicks() != 0 || original.State() != StateActive || original.LongURL() != "https://example.com/b" {
+		t.Errorf("expected original to be unchanged, got %d clicks, %q, %q", original.Clicks(), original.State(), original.LongURL())
+	}
+	if len(original.History()) != 1 || len(original.Transitions()) != 0 {
+		t.Errorf("expected original logs to be unchanged, got %+v and %+v", original.History(), original.Transitions())
+	}
+	if clone.Clicks() != 2 || clone.State() != StateExhausted || len(clone.History()) != 2 {
+		t.Errorf("unexpected clone %d clicks, %q, %+v", clone.Clicks(), clone.State(), clone.History())
+	}
+}
+
+func TestShortURL_Schedule(t *testing.T) {
+	expiry := time.Now().Add(24 * time.Hour)
+	tests := []struct {
+		name        string
+		expiry      *time.Time
+		notBefore   time.Time
+		preLaunch   LongURL
+		expectedErr error
+	}{
+		{"without expiry", nil, time.Now().Add(time.Hour), LongURL{}, nil},
+		{"before expiry with pre-launch URL", &expiry, time.Now().Add(time.Hour), MustParseLongURL("https://example.com/soon"), nil},
+		{"at expiry", &expiry, expiry, LongURL{}, ErrInvalidNotBefore},
+		{"after expiry", &expiry, expiry.Add(time.Hour), LongURL{}, ErrInvalidNotBefore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com"), "http://short.ly/abc123", tt.expiry, nil)
+			err := shortURL.Schedule(tt.notBefore, tt.preLaunch)
+			if err != tt.expectedErr {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if err != nil {
+				if shortURL.NotBefore() != nil {
+					t.Error("expected a rejected schedule not to be applied")
+				}
+				return
+			}
+			if !shortURL.NotBefore().Equal(tt.notBefore) || shortURL.PreLaunchURL() != tt.preLaunch.String() {
+				t.Errorf("expected schedule %v %q, got %v %q", tt.notBefore, tt.preLaunch.String(), shortURL.NotBefore(), shortURL.PreLaunchURL())
+			}
+		})
+	}
+}
+
+func TestShortURL_CheckAvailable_Scheduled(t *testing.T) {
+	notBefore := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name              string
+		notBefore         *time.Time
+		preLaunchURL      string
+		transition        LifecycleState
+		expectedErr       error
+		expectedWait      bool
+		expectedScheduled bool
+	}{
+		{"before activation", &notBefore, "", "", ErrShortURLNotYetActive, true, true},
+		{"before activation with pre-launch URL", &notBefore, "https://example.com/soon", "", ErrShortURLNotYetActive, true, true},
+		{"after activation", &past, "https://example.com/soon", "", nil, false, false},
+		{"paused before activation", &notBefore, "https://example.com/soon", StatePaused, ErrShortURLPaused, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+			shortURL.RestoreSchedule(tt.notBefore, tt.preLaunchURL)
+			if tt.transition != "" {
+				shortURL.Transition(tt.transition, "", "", time.Now())
+			}
+
+			err := shortURL.CheckAvailable()
+			if !errors.Is(err, tt.expectedErr) && err != tt.expectedErr {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			var notYetActive *NotYetActiveError
+			if errors.As(err, &notYetActive) != tt.expectedWait {
+				t.Fatalf("expected NotYetActiveError=%v, got %v", tt.expectedWait, err)
+			}
+			if tt.expectedWait && (!notYetActive.ActivatesAt.Equal(*tt.notBefore) || notYetActive.PreLaunchURL != tt.preLaunchURL) {
+				t.Errorf("unexpected error details %+v", notYetActive)
+			}
+			if scheduled := shortURL.IsScheduled(); scheduled != tt.expectedScheduled {
+				t.Errorf("expected IsScheduled %v, got %v", tt.expectedScheduled, scheduled)
+			}
+		})
+	}
+
+	// Clicks before the activation time are not counted
+	shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	shortURL.LimitClicks(1)
+	shortURL.Schedule(notBefore, LongURL{})
+	if _, err := shortURL.RecordClick(time.Now()); !errors.Is(err, ErrShortURLNotYetActive) || shortURL.Clicks() != 0 {
+		t.Errorf("expected click to be rejected, got %v with %d clicks", err, shortURL.Clicks())
+	}
+}
+
+func TestShortURL_Protect(t *testing.T) {
+	shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	if shortURL.IsProtected() {
+		t.Fatal("expected a new URL not to be protected")
+	}
+
+	shortURL.Protect("pbkdf2-sha256$1$c2FsdA$aGFzaA")
+	if !shortURL.IsProtected() || shortURL.PasswordHash() != "pbkdf2-sha256$1$c2FsdA$aGFzaA" {
+		t.Errorf("expected protected URL, got hash %q", shortURL.PasswordHash())
+	}
+
+	shortURL.Protect("")
+	if shortURL.IsProtected() {
+		t.Error("expected an empty hash to remove the protection")
+	}
+}
+
+func TestShortURL_Route(t *testing.T) {
+	shortURL, _ := NewShortURL("abc123", MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	visitor := Visitor{Device: DeviceIOS, Language: "ja"}
+	if longURL, rule := shortURL.Route(visitor); longURL != "https://example.com" || rule != 0 {
+		t.Errorf("expected the long URL without rules, got %q (rule %d)", longURL, rule)
+	}
+
+	appStore, _ := NewRoutingRule([]string{"ios"}, nil, nil, nil, MustParseLongURL("https://apps.apple.com/app/id1"))
+	japanese, _ := NewRoutingRule(nil, nil, []string{"ja"}, nil, MustParseLongURL("https://example.com/ja"))
+	if err := shortURL.SetRoutingRules([]RoutingRule{appStore, japanese}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		visitor         Visitor
+		expectedLongURL string
+		expectedRule    int
+	}{
+		{"first matching rule wins", Visitor{Device: DeviceIOS, Language: "ja"}, "https://apps.apple.com/app/id1", 1},
+		{"second rule", Visitor{Device: DeviceAndroid, Language: "ja-jp"}, "https://example.com/ja", 2},
+		{"default", Visitor{Device: DeviceDesktop, Language: "en"}, "https://example.com", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			longURL, rule := shortURL.Route(tt.visitor)
+			if longURL != tt.expectedLongURL || rule != tt.expectedRule {
+				t.Errorf("expected %q (rule %d), got %q (rule %d)", tt.expectedLongURL, tt.expectedRule, longURL, rule)
+			}
+		})
+	}
+
+	if err := shortURL.SetRoutingRules(make([]RoutingRule, MaxRoutingRules+1)); err != ErrTooManyRoutingRules {
+		t.Errorf("expected ErrTooManyRoutingRules, got %v", err)
+	}
+	if len(shortURL.RoutingRules()) != 2 {
+		t.Errorf("expected the rules to be kept after a rejected update, got %d", len(shortURL.RoutingRules()))
+	}
+	if err := shortURL.SetRoutingRules(nil); err != nil || shortURL.RoutingRules() != nil {
+		t.Errorf("expected the rules to be removed, got %v (%v)", shortURL.RoutingRules(), err)
+	}
+}
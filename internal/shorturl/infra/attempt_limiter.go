@@ -0,0 +1,129 @@
+package infra
+
+import (
+	"sync"
+	"time"
+)
+
+// AttemptLimiterOptions configures a MemoryAttemptLimiter.
+type AttemptLimiterOptions struct {
+	MaxFailures int           // Failed attempts within Window that lock the key
+	Window      time.Duration // Period in which failures are counted
+	Lockout     time.Duration // How long a key stays locked
+}
+
+// DefaultAttemptLimiterOptions returns options that lock a key for 15 minutes after
+// 5 failed attempts within 15 minutes.
+//
+// Returns:
+//   - AttemptLimiterOptions: The default options
+func DefaultAttemptLimiterOptions() AttemptLimiterOptions {
+	return AttemptLimiterOptions{
+		MaxFailures: 5,
+		Window:      15 * time.Minute,
+		Lockout:     15 * time.Minute,
+	}
+}
+
+// maxTrackedAttempts is the number of tracked keys above which stale entries are pruned.
+const maxTrackedAttempts = 10000
+
+// attemptState is the attempt count of one key.
+type attemptState struct {
+	failures    int       // Attempts since windowStart that did not succeed, or have not yet
+	windowStart time.Time // Time of the first attempt counted
+	lockedUntil time.Time // Attempts are refused before this time; zero if not locked
+}
+
+// MemoryAttemptLimiter implements the AttemptLimiter interface in memory. Attempts are
+// counted as failures when they are acquired, and after MaxFailures of them within Window
+// the key is locked for Lockout; a successful attempt resets the count. State is per process, so each instance of a multi-instance
+// deployment throttles on its own.
+type MemoryAttemptLimiter struct {
+	mu     sync.Mutex
+	opts   AttemptLimiterOptions
+	states map[string]*attemptState
+}
+
+// NewMemoryAttemptLimiter creates an in-memory attempt limiter.
+//
+// Parameters:
+//   - opts: Thresholds; zero fields take their value from DefaultAttemptLimiterOptions
+//
+// Returns:
+//   - *MemoryAttemptLimiter: Limiter instance
+func NewMemoryAttemptLimiter(opts AttemptLimiterOptions) *MemoryAttemptLimiter {
+	defaults := DefaultAttemptLimiterOptions()
+	if opts.MaxFailures <= 0 {
+		opts.MaxFailures = defaults.MaxFailures
+	}
+	if opts.Window <= 0 {
+		opts.Window = defaults.Window
+	}
+	if opts.Lockout <= 0 {
+		opts.Lockout = defaults.Lockout
+	}
+	return &MemoryAttemptLimiter{
+		opts:   opts,
+		states: make(map[string]*attemptState),
+	}
+}
+
+// Acquire counts an attempt unless the key is locked, and locks the key once MaxFailures
+// attempts are counted. The attempt that reaches MaxFailures is still allowed.
+//
+// Parameters:
+//   - key: The throttled key, e.g. a short URL ID
+//   - now: Time of the attempt
+//
+// Returns:
+//   - bool: Whether the attempt may be made; false while the key is locked
+func (l *MemoryAttemptLimiter) Acquire(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.states[key]
+	if ok && now.Before(state.lockedUntil) {
+		return false
+	}
+	if !ok {
+		if len(l.states) >= maxTrackedAttempts {
+			l.prune(now)
+		}
+		state = &attemptState{}
+		l.states[key] = state
+	}
+	if state.failures == 0 || now.Sub(state.windowStart) > l.opts.Window {
+		state.failures = 0
+		state.windowStart = now
+	}
+
+	state.failures++
+	if state.failures >= l.opts.MaxFailures {
+		state.lockedUntil = now.Add(l.opts.Lockout)
+		state.failures = 0
+	}
+	return true
+}
+
+// Reset forgets the counted attempts of the key.
+//
+// Parameters:
+//   - key: The throttled key
+func (l *MemoryAttemptLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.states, key)
+}
+
+// prune removes keys that are neither locked nor inside their counting window.
+// The caller must hold l.mu.
+func (l *MemoryAttemptLimiter) prune(now time.Time) {
+	for key, state := range l.states {
+		if now.Before(state.lockedUntil) || now.Sub(state.windowStart) <= l.opts.Window {
+			continue
+		}
+		delete(l.states, key)
+	}
+}
@@ -0,0 +1,93 @@
+package infra
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryAttemptLimiter(t *testing.T) {
+	limiter := NewMemoryAttemptLimiter(AttemptLimiterOptions{MaxFailures: 3, Window: time.Minute, Lockout: 10 * time.Minute})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Attempts up to the threshold are allowed, the one reaching it included
+	for i := range 3 {
+		if !limiter.Acquire("abc123", now.Add(time.Duration(i)*time.Second)) {
+			t.Fatalf("expected attempt %d to be allowed", i+1)
+		}
+	}
+
+	// Then the key is locked, and only that key
+	if limiter.Acquire("abc123", now.Add(3*time.Second)) {
+		t.Error("expected the key to be locked")
+	}
+	if !limiter.Acquire("def456", now.Add(3*time.Second)) {
+		t.Error("expected other keys not to be locked")
+	}
+
+	// The lock ends after the lockout
+	if !limiter.Acquire("abc123", now.Add(2*time.Second+10*time.Minute)) {
+		t.Error("expected the lock to end after the lockout")
+	}
+}
+
+func TestMemoryAttemptLimiter_Window(t *testing.T) {
+	limiter := NewMemoryAttemptLimiter(AttemptLimiterOptions{MaxFailures: 2, Window: time.Minute, Lockout: time.Hour})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Attempts further apart than the window are not added up
+	limiter.Acquire("abc123", now)
+	limiter.Acquire("abc123", now.Add(2*time.Minute))
+	if !limiter.Acquire("abc123", now.Add(2*time.Minute)) {
+		t.Error("expected attempts outside the window not to lock")
+	}
+
+	// A successful attempt resets the count
+	limiter.Reset("abc123")
+	limiter.Acquire("abc123", now.Add(3*time.Minute))
+	if !limiter.Acquire("abc123", now.Add(3*time.Minute)) {
+		t.Error("expected the count to restart after a reset")
+	}
+}
+
+func TestMemoryAttemptLimiter_Concurrent(t *testing.T) {
+	limiter := NewMemoryAttemptLimiter(AttemptLimiterOptions{MaxFailures: 5, Window: time.Minute, Lockout: time.Hour})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Parallel attempts cannot all get past the check before any of them is counted
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if limiter.Acquire("abc123", now) {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != 5 {
+		t.Errorf("expected 5 attempts to be allowed, got %d", got)
+	}
+}
+
+func TestMemoryAttemptLimiter_Prune(t *testing.T) {
+	limiter := NewMemoryAttemptLimiter(AttemptLimiterOptions{MaxFailures: 1, Window: time.Minute, Lockout: time.Minute})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	limiter.Acquire("locked", now.Add(time.Hour))
+	for i := 0; i < maxTrackedAttempts; i++ {
+		limiter.states[string(rune('a'+i%26))+time.Duration(i).String()] = &attemptState{failures: 1, windowStart: now}
+	}
+	limiter.Acquire("new", now.Add(time.Hour))
+
+	if len(limiter.states) != 2 {
+		t.Errorf("expected stale entries to be pruned, %d left", len(limiter.states))
+	}
+	if limiter.Acquire("locked", now.Add(time.Hour)) {
+		t.Error("expected locked keys to survive pruning")
+	}
+}
@@ -1,9 +1,15 @@
 package infra
 
 import (
+	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"math/big"
+	"math/bits"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,39 +18,179 @@ import (
 
 // base62Chars defines the character set used for Base62 encoding.
 // Includes digits (0-9), lowercase letters (a-z), and uppercase letters (A-Z).
-const base62Chars = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+const base62Chars = domain.AlphabetBase62
+
+const (
+	// base62IDLength is the fixed length of counter-derived identifiers.
+	base62IDLength = 7
+	// base62IDSpace is the number of distinct 7-character Base62 identifiers (62^7).
+	base62IDSpace = 3521614606208
+	// defaultLeaseSize is the number of counter values leased from a HighWaterMarkStore at once.
+	defaultLeaseSize = 10000
+	// scrambleMultiplier and scrambleOffset define the affine bijection applied to leased
+	// counters. The multiplier is prime and therefore coprime to 62^7 = 2^7 * 31^7.
+	scrambleMultiplier = 2654435761
+	scrambleOffset     = 1013904223
+	// defaultBufferLowWatermark and defaultBufferHighWatermark bound the pre-generated ID
+	// buffer: a refill starts below the low watermark and fills up to the high watermark.
+	defaultBufferLowWatermark  = 100
+	defaultBufferHighWatermark = 1000
+	// refillChunkSize is the number of IDs generated per mutex acquisition during a refill,
+	// so that a large refill does not block concurrent GenerateUniqueID calls for long.
+	refillChunkSize = 1000
+	// defaultRefillInterval is how often the background refiller checks the buffer when
+	// it is not woken up by a consumer.
+	defaultRefillInterval = time.Second
+)
+
+// ErrIDSpaceExhausted is returned when every identifier of the ID space has been issued.
+var ErrIDSpaceExhausted = errors.New("ID space exhausted")
+
+// base62Profile is the profile of 7-character Base62 IDs, used when no profile is configured.
+var base62Profile = domain.DefaultIDProfile()
+
+// BufferStats is a snapshot of the pre-generated ID buffer, used to size the watermarks.
+type BufferStats struct {
+	Depth          int   `json:"depth"`          // IDs currently buffered
+	LowWatermark   int   `json:"lowWatermark"`   // Depth below which a refill starts
+	HighWatermark  int   `json:"highWatermark"`  // Depth a refill fills up to
+	Running        bool  `json:"running"`        // Whether the background refiller is running
+	Hits           int64 `json:"hits"`           // IDs served from the buffer
+	Misses         int64 `json:"misses"`         // IDs generated on demand because the buffer was empty
+	Refills        int64 `json:"refills"`        // Completed refills
+	RefillFailures int64 `json:"refillFailures"` // Refills that stopped on an error
+}
 
 // Base62KeyGenerationService implements the KeyGenerationService interface
 // using Base62 encoding with randomization to prevent predictable IDs.
 // This provides short, URL-safe identifiers with guaranteed uniqueness.
+//
+// When configured with a HighWaterMarkStore, the counter is instead leased from the
+// store in ranges, and each counter value is mapped through a bijection of the 62^7 ID
+// space. Leased ranges never overlap, so IDs stay unique across restarts and instances.
 type Base62KeyGenerationService struct {
-	mu      sync.Mutex // Mutex for thread-safe counter operations
-	counter int64      // Counter for uniqueness (with randomization)
-	buffer  []string   // Pre-generated IDs buffer for performance optimization
+	mu        sync.Mutex         // Mutex for thread-safe counter operations
+	counter   int64              // Counter for uniqueness (with randomization)
+	buffer    []string           // Pre-generated IDs buffer for performance optimization
+	store     HighWaterMarkStore // Durable source of counter ranges; nil for the randomized counter
+	leaseSize int64              // Number of counter values leased at once
+	leaseEnd  int64              // End (exclusive) of the current lease
+	profile   *domain.IDProfile  // Alphabet and length of generated IDs
+
+	lowWatermark  int           // Buffer depth below which a refill starts
+	highWatermark int           // Buffer depth a refill fills up to
+	refillSignal  chan struct{} // Wakes the background refiller when the buffer runs low
+	stopRefiller  func()        // Cancels the background refiller; nil when it is not running
+	refillerDone  chan struct{} // Closed when the background refiller has exited
+	stats         BufferStats   // Buffer counters; depth and settings are filled in by Stats
+}
+
+// Base62Option configures optional behavior of the Base62KeyGenerationService.
+type Base62Option func(*Base62KeyGenerationService)
+
+// WithCounterStore makes the service lease counter ranges from a durable high-water-mark
+// store instead of starting from a random counter on every start.
+//
+// Parameters:
+//   - store: The store to lease counter ranges from
+//   - leaseSize: Number of counter values per lease; defaultLeaseSize when zero or negative
+//
+// Returns:
+//   - Base62Option: Option to pass to NewBase62KeyGenerationService
+func WithCounterStore(store HighWaterMarkStore, leaseSize int64) Base62Option {
+	return func(k *Base62KeyGenerationService) {
+		if leaseSize <= 0 {
+			leaseSize = defaultLeaseSize
+		}
+		k.store = store
+		k.leaseSize = leaseSize
+	}
+}
+
+// WithIDProfile sets the alphabet and length of generated IDs. Both counter-derived and
+// randomized IDs always have exactly the profile's length.
+//
+// Parameters:
+//   - profile: The ID profile; the default 7-character Base62 profile when nil
+//
+// Returns:
+//   - Base62Option: Option to pass to NewBase62KeyGenerationService
+func WithIDProfile(profile *domain.IDProfile) Base62Option {
+	return func(k *Base62KeyGenerationService) {
+		if profile != nil {
+			k.profile = profile
+		}
+	}
+}
+
+// WithBufferWatermarks sets the bounds of the pre-generated ID buffer. A refill starts
+// once the buffer holds fewer than low IDs and fills it up to high IDs.
+//
+// Parameters:
+//   - low: Depth below which a refill starts; high/10 when negative or not below high
+//   - high: Depth a refill fills up to; defaultBufferHighWatermark when zero or negative
+//
+// Returns:
+//   - Base62Option: Option to pass to NewBase62KeyGenerationService
+func WithBufferWatermarks(low, high int) Base62Option {
+	return func(k *Base62KeyGenerationService) {
+		if high <= 0 {
+			high = defaultBufferHighWatermark
+		}
+		if low < 0 || low >= high {
+			low = high / 10
+		}
+		k.lowWatermark = low
+		k.highWatermark = high
+	}
 }
 
 // NewBase62KeyGenerationService creates a new instance of the Base62 key generation service.
-// Initializes the counter with a random starting value to prevent predictable sequences.
+// Initializes the counter with a random starting value to prevent predictable sequences,
+// unless a counter store is configured, in which case the first lease sets the counter.
+//
+// Parameters:
+//   - opts: Optional settings such as WithCounterStore
 //
 // Returns:
 //   - domain.KeyGenerationService: Service interface implementation
-func NewBase62KeyGenerationService() domain.KeyGenerationService {
+func NewBase62KeyGenerationService(opts ...Base62Option) domain.KeyGenerationService {
 	// Initialize counter with a random value to prevent predictable sequences
 	randomStart, _ := rand.Int(rand.Reader, big.NewInt(1000000))
 
-	return &Base62KeyGenerationService{
-		counter: randomStart.Int64() + time.Now().Unix(), // Combine random value with timestamp
-		buffer:  make([]string, 0),
+	k := &Base62KeyGenerationService{
+		counter:       randomStart.Int64() + time.Now().Unix(), // Combine random value with timestamp
+		buffer:        make([]string, 0),
+		lowWatermark:  defaultBufferLowWatermark,
+		highWatermark: defaultBufferHighWatermark,
+		refillSignal:  make(chan struct{}, 1),
+		profile:       base62Profile,
 	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	if k.store != nil {
+		// Force a lease before the first ID is issued
+		k.counter, k.leaseEnd = 0, 0
+	}
+	return k
 }
 
 // GenerateUniqueID generates a single unique Base62-encoded identifier.
 // Uses buffered IDs when available for better performance, otherwise generates new ones.
+// When the buffer drops below the low watermark, the background refiller is woken up.
+//
+// Parameters:
+//   - ctx: Context for cancellation
 //
 // Returns:
 //   - string: A unique 7-character Base62-encoded identifier
-//   - error: Generation error if any
-func (k *Base62KeyGenerationService) GenerateUniqueID() (string, error) {
+//   - error: Context error if the context is canceled
+func (k *Base62KeyGenerationService) GenerateUniqueID(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
@@ -52,11 +198,17 @@ func (k *Base62KeyGenerationService) GenerateUniqueID() (string, error) {
 	if len(k.buffer) > 0 {
 		id := k.buffer[0]
 		k.buffer = k.buffer[1:]
+		k.stats.Hits++
+		if len(k.buffer) < k.lowWatermark {
+			k.signalRefill()
+		}
 		return id, nil
 	}
 
 	// Generate new ID on demand
-	ids, err := k.generateMultipleIDsInternal(1)
+	k.stats.Misses++
+	k.signalRefill()
+	ids, err := k.generateMultipleIDsInternal(ctx, 1)
 	if err != nil {
 		return "", err
 	}
@@ -67,16 +219,17 @@ func (k *Base62KeyGenerationService) GenerateUniqueID() (string, error) {
 // This is more efficient than calling GenerateUniqueID multiple times.
 //
 // Parameters:
+//   - ctx: Context for cancellation, checked while the batch is being generated
 //   - count: Number of IDs to generate
 //
 // Returns:
 //   - []string: Slice of unique Base62-encoded identifiers
-//   - error: Generation error if any
-func (k *Base62KeyGenerationService) GetMultipleIDs(count int) ([]string, error) {
+//   - error: Context error if the context is canceled
+func (k *Base62KeyGenerationService) GetMultipleIDs(ctx context.Context, count int) ([]string, error) {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
-	return k.generateMultipleIDsInternal(count)
+	return k.generateMultipleIDsInternal(ctx, count)
 }
 
 // generateMultipleIDsInternal is the internal implementation for generating multiple IDs.
@@ -84,25 +237,86 @@ func (k *Base62KeyGenerationService) GetMultipleIDs(count int) ([]string, error)
 // It combines counter values with random elements to prevent predictable sequences.
 //
 // Parameters:
+//   - ctx: Context for cancellation, checked before each ID is generated
 //   - count: Number of IDs to generate
 //
 // Returns:
 //   - []string: Slice of generated IDs
-//   - error: Always nil for this implementation
-//
-//nolint:unparam // error return is kept for future extensibility
-func (k *Base62KeyGenerationService) generateMultipleIDsInternal(count int) ([]string, error) {
+//   - error: Context error if the context is canceled
+func (k *Base62KeyGenerationService) generateMultipleIDsInternal(ctx context.Context, count int) ([]string, error) {
 	ids := make([]string, count)
 	for i := range count {
-		// Generate a non-sequential ID by combining counter with random elements
-		uniqueValue := k.generateNonSequentialValue()
-		id := k.encodeBase62(uniqueValue)
-		ids[i] = k.padToLength(id, 7)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if k.store != nil {
+			value, err := k.nextLeasedValue(ctx)
+			if err != nil {
+				return nil, err
+			}
+			ids[i] = k.idProfile().Encode(value)
+			continue
+		}
+
+		// Generate a non-sequential ID by combining counter with random elements,
+		// reduced to the profile's ID space so that it has exactly the profile's length
+		uniqueValue := uint64(k.generateNonSequentialValue()) % k.idProfile().Space()
+		ids[i] = k.idProfile().Encode(uniqueValue)
 		k.counter++
 	}
 	return ids, nil
 }
 
+// nextLeasedValue returns the scrambled value of the next leased counter, leasing a new
+// range from the store when the current one is used up. The lease is durable before any
+// of its values is returned, so a crash can only waste the rest of a range, never reuse it.
+// This method assumes the caller has already acquired the mutex lock.
+//
+// Parameters:
+//   - ctx: Context for cancellation of the lease
+//
+// Returns:
+//   - uint64: A value below the profile's ID space that no other lease can produce
+//   - error: Lease error or ErrIDSpaceExhausted
+func (k *Base62KeyGenerationService) nextLeasedValue(ctx context.Context) (uint64, error) {
+	if k.counter >= k.leaseEnd {
+		start, err := k.store.Lease(ctx, k.leaseSize)
+		if err != nil {
+			return 0, fmt.Errorf("failed to lease counter range: %w", err)
+		}
+		k.counter, k.leaseEnd = start, start+k.leaseSize
+	}
+
+	space := k.idProfile().Space()
+	if k.counter < 0 || uint64(k.counter) >= space {
+		return 0, ErrIDSpaceExhausted
+	}
+
+	value := scrambleCounter(uint64(k.counter), space)
+	k.counter++
+	return value, nil
+}
+
+// scrambleCounter maps a counter in [0, space) to (multiplier*counter + offset) mod space.
+// ID spaces are powers of the alphabet size, whose prime factors are all far smaller than
+// the prime multiplier, so the multiplier is coprime to the space and the mapping is a
+// bijection: distinct counters always yield distinct IDs, while consecutive counters yield
+// IDs that do not look sequential. The mapping only obscures the order; it is not a secret
+// permutation.
+//
+// Parameters:
+//   - counter: Counter value below space
+//   - space: Size of the ID space, e.g. 62^7
+//
+// Returns:
+//   - uint64: Scrambled value below space
+func scrambleCounter(counter, space uint64) uint64 {
+	hi, lo := bits.Mul64(scrambleMultiplier, counter)
+	lo, carry := bits.Add64(lo, scrambleOffset, 0)
+	return bits.Rem64(hi+carry, lo, space)
+}
+
 // generateNonSequentialValue creates a non-sequential value by combining
 // the counter with random elements and bit manipulation to prevent predictability.
 //
@@ -131,28 +345,20 @@ func (k *Base62KeyGenerationService) generateNonSequentialValue() int64 {
 	return scrambled
 }
 
-// encodeBase62 converts a decimal number to Base62 encoding.
-// Uses the character set: 0-9, a-z, A-Z (62 total characters).
+// encodeBase62 converts a decimal number to the configured alphabet, Base62 by default.
+// Uses the character set: 0-9, a-z, A-Z (62 total characters) unless a profile is set.
 //
 // Parameters:
 //   - num: The decimal number to encode
 //
 // Returns:
-//   - string: Base62-encoded representation
+//   - string: Encoded representation
 func (k *Base62KeyGenerationService) encodeBase62(num int64) string {
-	if num == 0 {
-		return "0"
-	}
-
-	result := ""
-	for num > 0 {
-		result = string(base62Chars[num%62]) + result
-		num /= 62
-	}
-	return result
+	return k.idProfile().EncodeVariable(uint64(num), 0)
 }
 
-// padToLength pads a string with leading zeros to reach the specified length.
+// padToLength pads a string with leading zero digits of the configured alphabet
+// ("0" for Base62) to reach the specified length.
 // This ensures consistent ID length across all generated identifiers.
 //
 // Parameters:
@@ -166,66 +372,215 @@ func (k *Base62KeyGenerationService) padToLength(str string, length int) string
 		return str
 	}
 
-	padding := length - len(str)
-	for i := 0; i < padding; i++ {
-		str = "0" + str
+	zero := k.idProfile().Alphabet()[:1]
+	return strings.Repeat(zero, length-len(str)) + str
+}
+
+// idProfile returns the configured ID profile, or the Base62 profile for a zero value.
+func (k *Base62KeyGenerationService) idProfile() *domain.IDProfile {
+	if k.profile == nil {
+		return base62Profile
 	}
-	return str
+	return k.profile
 }
 
 // RefillBuffer pre-generates IDs and stores them in the buffer for faster access.
-// This method can be called periodically to maintain a buffer of ready-to-use IDs.
+// When the buffer holds fewer IDs than the low watermark, it is filled up to the high
+// watermark in chunks, releasing the mutex between chunks so that concurrent requests
+// are served while a large refill is in progress. The background refiller started by
+// Start calls this method; it can also be called directly.
+//
+// Parameters:
+//   - ctx: Context for cancellation
 //
 // Returns:
-//   - error: Buffer refill error if any
-func (k *Base62KeyGenerationService) RefillBuffer() error {
+//   - error: Buffer refill error if any; IDs generated before the error stay buffered
+func (k *Base62KeyGenerationService) RefillBuffer(ctx context.Context) error {
 	k.mu.Lock()
-	defer k.mu.Unlock()
+	low := len(k.buffer) < k.lowWatermark
+	k.mu.Unlock()
+	if !low {
+		return nil
+	}
 
-	// Refill buffer when it gets low
-	if len(k.buffer) < 100 {
-		newIDs, err := k.generateMultipleIDsInternal(1000)
+	for {
+		done, err := k.refillChunk(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to refill buffer: %w", err)
 		}
-		k.buffer = append(k.buffer, newIDs...)
+		if done {
+			return nil
+		}
+	}
+}
+
+// refillChunk generates up to refillChunkSize IDs into the buffer under the mutex lock.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//
+// Returns:
+//   - bool: True once the buffer has reached the high watermark
+//   - error: ID generation error if any
+func (k *Base62KeyGenerationService) refillChunk(ctx context.Context) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	missing := k.highWatermark - len(k.buffer)
+	if missing <= 0 {
+		k.stats.Refills++
+		return true, nil
+	}
+
+	newIDs, err := k.generateMultipleIDsInternal(ctx, min(missing, refillChunkSize))
+	if err != nil {
+		k.stats.RefillFailures++
+		return false, err
+	}
+	k.buffer = append(k.buffer, newIDs...)
+	return false, nil
+}
+
+// Start launches the background refiller, which keeps the buffer between the low and
+// high watermarks. It refills immediately, whenever a consumer drops the buffer below
+// the low watermark, and at least once per interval. Refill errors are logged and
+// retried on the next wake-up, while consumers fall back to on-demand generation.
+//
+// Parameters:
+//   - interval: Maximum time between buffer checks; defaultRefillInterval when zero or negative
+//
+// Returns:
+//   - error: Error if the refiller is already running
+func (k *Base62KeyGenerationService) Start(interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultRefillInterval
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.stopRefiller != nil {
+		return errors.New("buffer refiller already running")
 	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	k.stopRefiller = cancel
+	k.refillerDone = make(chan struct{})
+	go k.runRefiller(ctx, interval, k.refillerDone)
 	return nil
 }
 
+// Stop stops the background refiller and waits for it to exit. A refill in progress is
+// canceled; IDs already buffered remain available. Stop is a no-op when the refiller is
+// not running, and the refiller can be started again afterwards.
+func (k *Base62KeyGenerationService) Stop() {
+	k.mu.Lock()
+	stop, done := k.stopRefiller, k.refillerDone
+	k.stopRefiller, k.refillerDone = nil, nil
+	k.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	stop()
+	<-done
+}
+
+// Stats returns a snapshot of the buffer depth, watermarks and counters.
+//
+// Returns:
+//   - BufferStats: Current buffer statistics
+func (k *Base62KeyGenerationService) Stats() BufferStats {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stats := k.stats
+	stats.Depth = len(k.buffer)
+	stats.LowWatermark = k.lowWatermark
+	stats.HighWatermark = k.highWatermark
+	stats.Running = k.stopRefiller != nil
+	return stats
+}
+
+// runRefiller is the background refiller loop started by Start.
+//
+// Parameters:
+//   - ctx: Context canceled by Stop
+//   - interval: Maximum time between buffer checks
+//   - done: Channel closed when the loop exits
+func (k *Base62KeyGenerationService) runRefiller(ctx context.Context, interval time.Duration, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := k.RefillBuffer(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Background buffer refill failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-k.refillSignal:
+		case <-ticker.C:
+		}
+	}
+}
+
+// signalRefill wakes the background refiller without blocking. Signals sent while no
+// refiller is running are absorbed by the channel buffer and trigger one extra check later.
+// This method assumes the caller has already acquired the mutex lock.
+func (k *Base62KeyGenerationService) signalRefill() {
+	select {
+	case k.refillSignal <- struct{}{}:
+	default:
+	}
+}
+
 // DecodeBase62 converts a Base62-encoded string back to its decimal representation.
 // This utility function can be used for reverse operations or validation.
+// IDs of other alphabets are decoded with DecodeID.
 //
 // Parameters:
 //   - encoded: The Base62-encoded string to decode
 //
 // Returns:
-//   - int64: The decimal representation
-//   - error: Decoding error if invalid characters are found
+//   - int64: The decimal representation; 0 for an empty string
+//   - error: Decoding error if invalid characters are found or the value overflows int64
 func DecodeBase62(encoded string) (int64, error) {
-	result := int64(0)
-	power := int64(1)
-
-	// Process characters from right to left
-	for i := len(encoded) - 1; i >= 0; i-- {
-		char := encoded[i]
-		var digit int64
-
-		// Convert character to its numeric value
-		switch {
-		case char >= '0' && char <= '9':
-			digit = int64(char - '0')
-		case char >= 'a' && char <= 'z':
-			digit = int64(char-'a') + 10
-		case char >= 'A' && char <= 'Z':
-			digit = int64(char-'A') + 36
-		default:
-			return 0, fmt.Errorf("invalid character in base62 string: %c", char)
-		}
+	if encoded == "" {
+		return 0, nil
+	}
+	return DecodeID(base62Profile, encoded)
+}
 
-		result += digit * power
-		power *= 62
+// DecodeID converts an ID written in the alphabet of profile back to its decimal value.
+//
+// Parameters:
+//   - profile: The ID profile whose alphabet the ID uses
+//   - encoded: The encoded ID
+//
+// Returns:
+//   - int64: The decimal representation
+//   - error: Decoding error if the ID is empty, has invalid characters or overflows int64
+func DecodeID(profile *domain.IDProfile, encoded string) (int64, error) {
+	value, err := profile.Decode(encoded)
+	if err != nil {
+		return 0, err
+	}
+	if value > math.MaxInt64 {
+		return 0, fmt.Errorf("ID %q overflows int64", encoded)
 	}
+	return int64(value), nil
+}
 
-	return result, nil
+// encodeFixedBase62 encodes value in Base62, left-padded with zeros to length characters.
+func encodeFixedBase62(value uint64, length int) string {
+	encoded := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		encoded[i] = base62Chars[value%62]
+		value /= 62
+	}
+	return string(encoded)
 }
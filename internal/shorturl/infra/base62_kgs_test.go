@@ -1,8 +1,14 @@
 package infra
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
 )
 
 func TestNewBase62KeyGenerationService(t *testing.T) {
@@ -25,7 +31,7 @@ func TestBase62KeyGenerationService_GenerateUniqueID(t *testing.T) {
 	ids := make(map[string]bool)
 	duplicateCount := 0
 	for i := 0; i < 100; i++ {
-		id, err := kgs.GenerateUniqueID()
+		id, err := kgs.GenerateUniqueID(context.Background())
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -79,7 +85,7 @@ func TestBase62KeyGenerationService_GetMultipleIDs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ids, err := kgs.GetMultipleIDs(tt.count)
+			ids, err := kgs.GetMultipleIDs(context.Background(), tt.count)
 			if err != nil {
 				t.Errorf("unexpected error: %v", err)
 			}
@@ -114,7 +120,7 @@ func TestBase62KeyGenerationService_NonSequential(t *testing.T) {
 	// Generate a sequence of IDs
 	ids := make([]string, 20)
 	for i := 0; i < 20; i++ {
-		id, err := kgs.GenerateUniqueID()
+		id, err := kgs.GenerateUniqueID(context.Background())
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -142,7 +148,7 @@ func TestBase62KeyGenerationService_NonSequential(t *testing.T) {
 func TestBase62KeyGenerationService_RefillBuffer(t *testing.T) {
 	kgs := NewBase62KeyGenerationService().(*Base62KeyGenerationService)
 
-	err := kgs.RefillBuffer()
+	err := kgs.RefillBuffer(context.Background())
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -154,7 +160,7 @@ func TestBase62KeyGenerationService_RefillBuffer(t *testing.T) {
 
 	// Test that buffer is used
 	initialBufferSize := len(kgs.buffer)
-	id, err := kgs.GenerateUniqueID()
+	id, err := kgs.GenerateUniqueID(context.Background())
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -169,6 +175,138 @@ func TestBase62KeyGenerationService_RefillBuffer(t *testing.T) {
 	}
 }
 
+func TestWithBufferWatermarks(t *testing.T) {
+	tests := []struct {
+		name         string
+		low, high    int
+		expectedLow  int
+		expectedHigh int
+	}{
+		{"explicit", 2000, 10000, 2000, 10000},
+		{"default high", 50, 0, 50, defaultBufferHighWatermark},
+		{"negative low", -1, 500, 50, 500},
+		{"low not below high", 500, 500, 50, 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kgs := NewBase62KeyGenerationService(WithBufferWatermarks(tt.low, tt.high)).(*Base62KeyGenerationService)
+			stats := kgs.Stats()
+			if stats.LowWatermark != tt.expectedLow || stats.HighWatermark != tt.expectedHigh {
+				t.Errorf("expected watermarks %d/%d, got %d/%d", tt.expectedLow, tt.expectedHigh, stats.LowWatermark, stats.HighWatermark)
+			}
+		})
+	}
+}
+
+func TestBase62KeyGenerationService_RefillWatermarks(t *testing.T) {
+	kgs := NewBase62KeyGenerationService(WithBufferWatermarks(1000, 2500)).(*Base62KeyGenerationService)
+
+	// An empty buffer is filled up to the high watermark across several chunks
+	if err := kgs.RefillBuffer(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := kgs.Stats().Depth; got != 2500 {
+		t.Errorf("expected depth 2500, got %d", got)
+	}
+
+	// Above the low watermark nothing is generated
+	for i := 0; i < 1500; i++ {
+		kgs.GenerateUniqueID(context.Background())
+	}
+	kgs.RefillBuffer(context.Background())
+	if got := kgs.Stats().Depth; got != 1000 {
+		t.Errorf("expected depth 1000 at the low watermark, got %d", got)
+	}
+
+	// Below the low watermark the buffer is topped up again
+	kgs.GenerateUniqueID(context.Background())
+	kgs.RefillBuffer(context.Background())
+
+	stats := kgs.Stats()
+	if stats.Depth != 2500 {
+		t.Errorf("expected depth 2500 after refill, got %d", stats.Depth)
+	}
+	if stats.Refills != 2 || stats.Hits != 1501 || stats.Misses != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestBase62KeyGenerationService_BackgroundRefiller(t *testing.T) {
+	kgs := NewBase62KeyGenerationService(WithBufferWatermarks(10, 50)).(*Base62KeyGenerationService)
+
+	if err := kgs.Start(time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := kgs.Start(time.Hour); err == nil {
+		t.Error("expected error when starting the refiller twice")
+	}
+
+	// The refiller fills the buffer right away, and is woken up again by consumers
+	// dropping it below the low watermark long before the interval elapses
+	waitForDepth(t, kgs, 50)
+	for i := 0; i < 45; i++ {
+		if _, err := kgs.GenerateUniqueID(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	waitForDepth(t, kgs, 50)
+
+	if !kgs.Stats().Running {
+		t.Error("expected refiller to be reported as running")
+	}
+
+	kgs.Stop()
+	kgs.Stop() // Stopping twice is a no-op
+
+	stats := kgs.Stats()
+	if stats.Running {
+		t.Error("expected refiller to be reported as stopped")
+	}
+	if stats.Refills < 2 {
+		t.Errorf("expected at least 2 refills, got %d", stats.Refills)
+	}
+
+	// Buffered IDs stay available after Stop, and the refiller can be restarted
+	if _, err := kgs.GenerateUniqueID(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := kgs.Start(time.Hour); err != nil {
+		t.Errorf("expected restart to succeed, got %v", err)
+	}
+	kgs.Stop()
+}
+
+func TestBase62KeyGenerationService_BackgroundRefillerFailure(t *testing.T) {
+	store, _ := NewFileHighWaterMarkStore(filepath.Join(t.TempDir(), "missing", "kgs.hwm"))
+	kgs := NewBase62KeyGenerationService(WithCounterStore(store, 0)).(*Base62KeyGenerationService)
+
+	kgs.Start(time.Hour)
+	deadline := time.Now().Add(5 * time.Second)
+	for kgs.Stats().RefillFailures == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	kgs.Stop()
+
+	stats := kgs.Stats()
+	if stats.RefillFailures == 0 || stats.Depth != 0 {
+		t.Errorf("expected a failed refill and an empty buffer, got %+v", stats)
+	}
+}
+
+// waitForDepth waits until the buffer holds exactly depth IDs.
+func waitForDepth(t *testing.T, kgs *Base62KeyGenerationService, depth int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for kgs.Stats().Depth != depth {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected buffer depth %d, got %d", depth, kgs.Stats().Depth)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
 func TestBase62KeyGenerationService_generateNonSequentialValue(t *testing.T) {
 	kgs := NewBase62KeyGenerationService().(*Base62KeyGenerationService)
 
@@ -385,6 +523,186 @@ func TestBase62KeyGenerationService_EncodeDecode_RoundTrip(t *testing.T) {
 	}
 }
 
+func TestBase62KeyGenerationService_CanceledContext(t *testing.T) {
+	kgs := NewBase62KeyGenerationService().(*Base62KeyGenerationService)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := kgs.GenerateUniqueID(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GenerateUniqueID: expected context.Canceled, got %v", err)
+	}
+	if _, err := kgs.GetMultipleIDs(ctx, 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetMultipleIDs: expected context.Canceled, got %v", err)
+	}
+	if err := kgs.RefillBuffer(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("RefillBuffer: expected context.Canceled, got %v", err)
+	}
+	if len(kgs.buffer) != 0 {
+		t.Errorf("expected empty buffer after canceled refill, got %d IDs", len(kgs.buffer))
+	}
+}
+
+func TestBase62KeyGenerationService_CounterStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kgs.hwm")
+	store, _ := NewFileHighWaterMarkStore(path)
+	kgs := NewBase62KeyGenerationService(WithCounterStore(store, 100)).(*Base62KeyGenerationService)
+
+	first, err := kgs.GetMultipleIDs(context.Background(), 150)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Two leases were taken and persisted before the IDs were handed out
+	if store.hwm != 200 {
+		t.Errorf("expected high-water mark 200, got %d", store.hwm)
+	}
+
+	// Simulate a crash: a new instance on the reopened store must not reuse any range,
+	// including the unused rest of the second lease
+	reopened, _ := NewFileHighWaterMarkStore(path)
+	restarted := NewBase62KeyGenerationService(WithCounterStore(reopened, 100))
+	second, err := restarted.GetMultipleIDs(context.Background(), 150)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Every ID the first lease range could have produced must be distinct from the new ones
+	issued := make(map[string]bool)
+	for counter := uint64(0); counter < 200; counter++ {
+		issued[encodeFixedBase62(scrambleCounter(counter, base62IDSpace), base62IDLength)] = true
+	}
+	for _, id := range first {
+		if !issued[id] {
+			t.Errorf("ID %q does not come from the first leases", id)
+		}
+	}
+	for _, id := range second {
+		if len(id) != base62IDLength || !isValidBase62(id) {
+			t.Errorf("invalid ID %q", id)
+		}
+		if issued[id] {
+			t.Errorf("ID %q reused after restart", id)
+		}
+		issued[id] = true
+	}
+}
+
+func TestBase62KeyGenerationService_CounterStoreErrors(t *testing.T) {
+	t.Run("lease failure", func(t *testing.T) {
+		store, _ := NewFileHighWaterMarkStore(filepath.Join(t.TempDir(), "missing", "kgs.hwm"))
+		kgs := NewBase62KeyGenerationService(WithCounterStore(store, 0))
+
+		if _, err := kgs.GenerateUniqueID(context.Background()); err == nil {
+			t.Error("expected error when the lease cannot be persisted")
+		}
+	})
+
+	t.Run("ID space exhausted", func(t *testing.T) {
+		store := NewMemoryHighWaterMarkStore(base62IDSpace - 1)
+		kgs := NewBase62KeyGenerationService(WithCounterStore(store, 10))
+
+		if _, err := kgs.GenerateUniqueID(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := kgs.GenerateUniqueID(context.Background()); !errors.Is(err, ErrIDSpaceExhausted) {
+			t.Errorf("expected ErrIDSpaceExhausted, got %v", err)
+		}
+	})
+}
+
+func TestBase62KeyGenerationService_IDProfile(t *testing.T) {
+	base58, _ := domain.NewIDProfile(domain.AlphabetBase58, 8)
+	lowercase, _ := domain.NewIDProfile(domain.AlphabetLowercase, 6)
+
+	for _, profile := range []*domain.IDProfile{base58, lowercase} {
+		t.Run(profile.Alphabet(), func(t *testing.T) {
+			// Counter-derived IDs have exactly the profile's length and are unique
+			leased := NewBase62KeyGenerationService(WithCounterStore(NewMemoryHighWaterMarkStore(0), 0), WithIDProfile(profile))
+			ids, err := leased.GetMultipleIDs(context.Background(), 1000)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			seen := make(map[string]bool)
+			for _, id := range ids {
+				if !profile.Matches(id) {
+					t.Errorf("ID %q does not match the profile", id)
+				}
+				if seen[id] {
+					t.Errorf("duplicate ID %q", id)
+				}
+				seen[id] = true
+			}
+
+			// Randomized IDs also use only the alphabet and have exactly the profile's length
+			randomized := NewBase62KeyGenerationService(WithIDProfile(profile))
+			ids, err = randomized.GetMultipleIDs(context.Background(), 1000)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, id := range ids {
+				if len(id) != profile.Length() || !profile.Matches(id) {
+					t.Errorf("randomized ID %q does not match the profile", id)
+				}
+			}
+		})
+	}
+}
+
+func TestBase62KeyGenerationService_IDProfileExhausted(t *testing.T) {
+	profile, _ := domain.NewIDProfile(domain.AlphabetLowercase, 2)
+	kgs := NewBase62KeyGenerationService(WithCounterStore(NewMemoryHighWaterMarkStore(0), 0), WithIDProfile(profile))
+
+	// A 2-character lowercase profile has 36^2 IDs, all distinct
+	ids, err := kgs.GetMultipleIDs(context.Background(), 36*36)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		seen[id] = true
+	}
+	if len(seen) != 36*36 {
+		t.Errorf("expected %d distinct IDs, got %d", 36*36, len(seen))
+	}
+
+	if _, err := kgs.GenerateUniqueID(context.Background()); !errors.Is(err, ErrIDSpaceExhausted) {
+		t.Errorf("expected ErrIDSpaceExhausted, got %v", err)
+	}
+}
+
+func TestDecodeID(t *testing.T) {
+	base58, _ := domain.NewIDProfile(domain.AlphabetBase58, 7)
+
+	value, err := DecodeID(base58, "21")
+	if err != nil || value != 58 {
+		t.Errorf("expected 58, got %d, %v", value, err)
+	}
+	if _, err := DecodeID(base58, "0"); err == nil {
+		t.Error("expected error for a character outside the Base58 alphabet")
+	}
+	if _, err := DecodeID(domain.DefaultIDProfile(), "ZZZZZZZZZZZ"); err == nil {
+		t.Error("expected error for a value beyond int64")
+	}
+}
+
+func TestScrambleCounter(t *testing.T) {
+	// Injectivity over a window of counters, including the top of the ID space
+	seen := make(map[uint64]bool)
+	for _, base := range []uint64{0, base62IDSpace - 10000} {
+		for counter := base; counter < base+10000; counter++ {
+			value := scrambleCounter(counter, base62IDSpace)
+			if value >= base62IDSpace {
+				t.Fatalf("scrambleCounter(%d) = %d is outside the ID space", counter, value)
+			}
+			if seen[value] {
+				t.Fatalf("scrambleCounter(%d) = %d collides", counter, value)
+			}
+			seen[value] = true
+		}
+	}
+}
+
 // Helper function to check if string contains only valid Base62 characters
 func isValidBase62(s string) bool {
 	for _, r := range s {
@@ -394,3 +712,21 @@ func isValidBase62(s string) bool {
 	}
 	return true
 }
+
+func TestEncodeFixedBase62(t *testing.T) {
+	tests := []struct {
+		value    uint64
+		expected string
+	}{
+		{0, "0000000"},
+		{61, "000000Z"},
+		{62, "0000010"},
+		{base62IDSpace - 1, "ZZZZZZZ"},
+	}
+
+	for _, tt := range tests {
+		if got := encodeFixedBase62(tt.value, base62IDLength); got != tt.expected {
+			t.Errorf("encodeFixedBase62(%d) = %q, expected %q", tt.value, got, tt.expected)
+		}
+	}
+}
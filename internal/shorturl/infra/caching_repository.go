@@ -0,0 +1,312 @@
+package infra
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+)
+
+// CacheOptions configures the CachingShortURLRepository.
+type CacheOptions struct {
+	Capacity    int           // Maximum number of cached IDs, found and not found combined
+	TTL         time.Duration // How long a found entity stays cached
+	NegativeTTL time.Duration // How long an unknown ID stays cached (0 disables negative caching)
+}
+
+// DefaultCacheOptions returns options suited to the redirect path: hot links stay
+// cached for a few minutes and unknown IDs are remembered briefly to absorb scans.
+func DefaultCacheOptions() CacheOptions {
+	return CacheOptions{
+		Capacity:    10000,
+		TTL:         5 * time.Minute,
+		NegativeTTL: 30 * time.Second,
+	}
+}
+
+// CacheStats is a point-in-time snapshot of the cache counters.
+type CacheStats struct {
+	Hits         uint64 `json:"hits"`         // Lookups answered from the cache, including negative hits
+	NegativeHits uint64 `json:"negativeHits"` // Lookups answered from a cached "not found"
+	Misses       uint64 `json:"misses"`       // Lookups that had to wait for the underlying repository
+	Loads        uint64 `json:"loads"`        // Calls actually made to the underlying repository
+	Evictions    uint64 `json:"evictions"`    // Entries dropped because the cache was full
+	Entries      int    `json:"entries"`      // Entries currently cached
+}
+
+// cacheEntry is a cached lookup result. A nil shortURL records that the ID does not exist.
+type cacheEntry struct {
+	id        string
+	shortURL  *domain.ShortURL
+	expiresAt time.Time
+}
+
+// cacheLoad is an in-flight lookup of the underlying repository shared by all
+// concurrent callers asking for the same ID.
+type cacheLoad struct {
+	done        chan struct{}    // Closed when the lookup has finished
+	shortURL    *domain.ShortURL // Lookup result, valid after done is closed
+	err         error            // Lookup error, valid after done is closed
+	invalidated bool             // Set when a write raced with the lookup; the result is not cached
+}
+
+// CachingShortURLRepository is a read-through caching decorator for any ShortURLRepository.
+// It keeps a bounded LRU cache of FindByID results with a TTL, remembers unknown IDs for
+// a shorter time (negative caching), and collapses concurrent misses for the same ID into
+// a single lookup so that a sudden burst of traffic on one hot link hits the repository once.
+// Save, Insert, Update and Delete invalidate the affected ID after the underlying write succeeds.
+type CachingShortURLRepository struct {
+	next domain.ShortURLRepository // Underlying repository
+	opts CacheOptions              // Cache configuration
+	now  func() time.Time          // Clock, replaceable in tests
+
+	mu      sync.Mutex               // Guards all fields below
+	entries map[string]*list.Element // Cached entries by ID
+	lru     *list.List               // Entries ordered from most to least recently used
+	loads   map[string]*cacheLoad    // In-flight lookups by ID
+	stats   CacheStats               // Counters reported by Stats
+}
+
+// NewCachingShortURLRepository wraps a repository with a read-through cache.
+//
+// Parameters:
+//   - next: The repository whose lookups are cached
+//   - opts: Cache capacity and expiration settings
+//
+// Returns:
+//   - *CachingShortURLRepository: Caching repository; use Stats to observe its effectiveness
+func NewCachingShortURLRepository(next domain.ShortURLRepository, opts CacheOptions) *CachingShortURLRepository {
+	return &CachingShortURLRepository{
+		next:    next,
+		opts:    opts,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		loads:   make(map[string]*cacheLoad),
+	}
+}
+
+// Save persists the entity through the underlying repository and invalidates its cache entry.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - shortURL: The entity to save or update
+//
+// Returns:
+//   - error: Error from the underlying repository
+func (r *CachingShortURLRepository) Save(ctx context.Context, shortURL *domain.ShortURL) error {
+	if err := r.next.Save(ctx, shortURL); err != nil {
+		return err
+	}
+	r.invalidate(shortURL.ID())
+	return nil
+}
+
+// Insert inserts the entity through the underlying repository and invalidates its cache entry,
+// which drops any cached "not found" for the newly taken ID.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - shortURL: The entity to insert
+//
+// Returns:
+//   - error: Error from the underlying repository, including domain.ErrShortURLAlreadyExists
+func (r *CachingShortURLRepository) Insert(ctx context.Context, shortURL *domain.ShortURL) error {
+	if err := r.next.Insert(ctx, shortURL); err != nil {
+		return err
+	}
+	r.invalidate(shortURL.ID())
+	return nil
+}
+
+// Update updates the entity through the underlying repository and invalidates its cache
+// entry. The read-modify-write runs against the underlying repository, never against a
+// cached copy, so the atomicity of the underlying Update is preserved.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - id: The unique identifier of the entity to update
+//   - fn: Modification to apply
+//
+// Returns:
+//   - *domain.ShortURL: The updated entity
+//   - error: Error from the underlying repository or from fn
+func (r *CachingShortURLRepository) Update(ctx context.Context, id string, fn func(*domain.ShortURL) error) (*domain.ShortURL, error) {
+	updated, err := r.next.Update(ctx, id, fn)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(id)
+	return updated, nil
+}
+
+// FindByID returns the cached entity when available and otherwise loads it from the
+// underlying repository. Concurrent misses for the same ID share a single load, which runs
+// detached from any one caller's cancellation; each caller stops waiting when its own
+// context is canceled.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - id: The unique identifier to search for
+//
+// Returns:
+//   - *domain.ShortURL: The found entity or nil if not found
+//   - error: Error from the underlying repository or the context error
+func (r *CachingShortURLRepository) FindByID(ctx context.Context, id string) (*domain.ShortURL, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if elem, ok := r.entries[id]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if r.now().Before(entry.expiresAt) {
+			r.lru.MoveToFront(elem)
+			r.stats.Hits++
+			if entry.shortURL == nil {
+				r.stats.NegativeHits++
+			}
+			r.mu.Unlock()
+			return entry.shortURL, nil
+		}
+		r.removeElement(elem)
+	}
+
+	r.stats.Misses++
+	load, inFlight := r.loads[id]
+	if !inFlight {
+		load = &cacheLoad{done: make(chan struct{})}
+		r.loads[id] = load
+		r.stats.Loads++
+		go r.load(context.WithoutCancel(ctx), id, load)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-load.done:
+		return load.shortURL, load.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// FindAll is passed through to the underlying repository without caching.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//
+// Returns:
+//   - []*domain.ShortURL: Slice of all stored entities
+//   - error: Error from the underlying repository
+func (r *CachingShortURLRepository) FindAll(ctx context.Context) ([]*domain.ShortURL, error) {
+	return r.next.FindAll(ctx)
+}
+
+// FindPage is passed through to the underlying repository without caching.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - query: Filters, sort order, cursor and page size
+//
+// Returns:
+//   - *domain.ShortURLPage: The requested page and the cursor for the next one
+//   - error: Error from the underlying repository
+func (r *CachingShortURLRepository) FindPage(ctx context.Context, query domain.ShortURLQuery) (*domain.ShortURLPage, error) {
+	return r.next.FindPage(ctx, query)
+}
+
+// Delete removes the entity through the underlying repository and invalidates its cache entry.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - id: The unique identifier of the entity to delete
+//
+// Returns:
+//   - error: Error from the underlying repository, including domain.ErrShortURLNotFound
+func (r *CachingShortURLRepository) Delete(ctx context.Context, id string) error {
+	if err := r.next.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.invalidate(id)
+	return nil
+}
+
+// Stats returns a snapshot of the cache counters.
+//
+// Returns:
+//   - CacheStats: Current hit, miss, load and eviction counts
+func (r *CachingShortURLRepository) Stats() CacheStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats
+	stats.Entries = r.lru.Len()
+	return stats
+}
+
+// load performs a shared lookup against the underlying repository and caches the
+// result unless a write invalidated it while the lookup was in flight.
+func (r *CachingShortURLRepository) load(ctx context.Context, id string, load *cacheLoad) {
+	shortURL, err := r.next.FindByID(ctx, id)
+
+	r.mu.Lock()
+	if r.loads[id] == load {
+		delete(r.loads, id)
+	}
+	if err == nil && !load.invalidated {
+		r.store(id, shortURL)
+	}
+	load.shortURL, load.err = shortURL, err
+	r.mu.Unlock()
+
+	close(load.done)
+}
+
+// store adds a lookup result to the cache, evicting the least recently used entries
+// when the capacity is exceeded. The caller must hold the mutex lock.
+func (r *CachingShortURLRepository) store(id string, shortURL *domain.ShortURL) {
+	ttl := r.opts.TTL
+	if shortURL == nil {
+		ttl = r.opts.NegativeTTL
+	}
+	if ttl <= 0 || r.opts.Capacity <= 0 {
+		return
+	}
+
+	entry := &cacheEntry{id: id, shortURL: shortURL, expiresAt: r.now().Add(ttl)}
+	if elem, ok := r.entries[id]; ok {
+		elem.Value = entry
+		r.lru.MoveToFront(elem)
+		return
+	}
+	r.entries[id] = r.lru.PushFront(entry)
+
+	for r.lru.Len() > r.opts.Capacity {
+		r.removeElement(r.lru.Back())
+		r.stats.Evictions++
+	}
+}
+
+// invalidate drops the cached entry for id and prevents an in-flight lookup
+// from caching a result that may predate the write.
+func (r *CachingShortURLRepository) invalidate(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if elem, ok := r.entries[id]; ok {
+		r.removeElement(elem)
+	}
+	if load, ok := r.loads[id]; ok {
+		load.invalidated = true
+		delete(r.loads, id)
+	}
+}
+
+// removeElement removes an entry from both the LRU list and the index.
+// The caller must hold the mutex lock.
+func (r *CachingShortURLRepository) removeElement(elem *list.Element) {
+	r.lru.Remove(elem)
+	delete(r.entries, elem.Value.(*cacheEntry).id)
+}
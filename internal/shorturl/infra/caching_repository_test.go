@@ -0,0 +1,348 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+)
+
+// countingRepository counts FindByID calls and can hold them until release is closed.
+type countingRepository struct {
+	domain.ShortURLRepository
+	finds   atomic.Int64
+	release chan struct{}
+	findErr error
+}
+
+func (r *countingRepository) FindByID(ctx context.Context, id string) (*domain.ShortURL, error) {
+	r.finds.Add(1)
+	if r.release != nil {
+		<-r.release
+	}
+	if r.findErr != nil {
+		return nil, r.findErr
+	}
+	return r.ShortURLRepository.FindByID(ctx, id)
+}
+
+// newTestCache returns a cache over a counting memory repository and a settable clock.
+func newTestCache(opts CacheOptions) (*CachingShortURLRepository, *countingRepository, *time.Time) {
+	backing := &countingRepository{ShortURLRepository: NewMemoryShortURLRepository()}
+	cache := NewCachingShortURLRepository(backing, opts)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	return cache, backing, &now
+}
+
+func TestCachingShortURLRepository_FindByID(t *testing.T) {
+	tests := []struct {
+		name          string
+		id            string
+		lookups       int
+		expectFound   bool
+		expectFinds   int64
+		expectHits    uint64
+		expectNegHits uint64
+	}{
+		{
+			name:        "existing ID is loaded once",
+			id:          "abc123",
+			lookups:     3,
+			expectFound: true,
+			expectFinds: 1,
+			expectHits:  2,
+		},
+		{
+			name:          "unknown ID is negatively cached",
+			id:            "missing",
+			lookups:       3,
+			expectFound:   false,
+			expectFinds:   1,
+			expectHits:    2,
+			expectNegHits: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, backing, _ := newTestCache(DefaultCacheOptions())
+			shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+			cache.Save(context.Background(), shortURL)
+
+			for i := 0; i < tt.lookups; i++ {
+				found, err := cache.FindByID(context.Background(), tt.id)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if (found != nil) != tt.expectFound {
+					t.Errorf("expected found=%v, got %v", tt.expectFound, found != nil)
+				}
+			}
+
+			if got := backing.finds.Load(); got != tt.expectFinds {
+				t.Errorf("expected %d repository lookups, got %d", tt.expectFinds, got)
+			}
+			stats := cache.Stats()
+			if stats.Hits != tt.expectHits {
+				t.Errorf("expected %d hits, got %d", tt.expectHits, stats.Hits)
+			}
+			if stats.NegativeHits != tt.expectNegHits {
+				t.Errorf("expected %d negative hits, got %d", tt.expectNegHits, stats.NegativeHits)
+			}
+			if stats.Misses != 1 {
+				t.Errorf("expected 1 miss, got %d", stats.Misses)
+			}
+		})
+	}
+}
+
+func TestCachingShortURLRepository_TTL(t *testing.T) {
+	cache, backing, now := newTestCache(CacheOptions{Capacity: 10, TTL: time.Minute, NegativeTTL: time.Second})
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	cache.Save(context.Background(), shortURL)
+
+	cache.FindByID(context.Background(), "abc123")
+	cache.FindByID(context.Background(), "missing")
+
+	// The negative entry expires first
+	*now = now.Add(2 * time.Second)
+	cache.FindByID(context.Background(), "abc123")
+	cache.FindByID(context.Background(), "missing")
+	if got := backing.finds.Load(); got != 3 {
+		t.Errorf("expected 3 repository lookups after negative TTL, got %d", got)
+	}
+
+	*now = now.Add(time.Minute)
+	cache.FindByID(context.Background(), "abc123")
+	if got := backing.finds.Load(); got != 4 {
+		t.Errorf("expected 4 repository lookups after TTL, got %d", got)
+	}
+}
+
+func TestCachingShortURLRepository_NegativeCachingDisabled(t *testing.T) {
+	cache, backing, _ := newTestCache(CacheOptions{Capacity: 10, TTL: time.Minute})
+
+	cache.FindByID(context.Background(), "missing")
+	cache.FindByID(context.Background(), "missing")
+
+	if got := backing.finds.Load(); got != 2 {
+		t.Errorf("expected 2 repository lookups, got %d", got)
+	}
+}
+
+func TestCachingShortURLRepository_LRUEviction(t *testing.T) {
+	cache, backing, _ := newTestCache(CacheOptions{Capacity: 2, TTL: time.Minute})
+	for _, id := range []string{"a", "b", "c"} {
+		shortURL, _ := domain.NewShortURL(id, domain.MustParseLongURL("https://example.com"), "http://short.ly/"+id, nil, nil)
+		cache.Save(context.Background(), shortURL)
+	}
+
+	cache.FindByID(context.Background(), "a")
+	cache.FindByID(context.Background(), "b")
+	cache.FindByID(context.Background(), "a") // "b" becomes least recently used
+	cache.FindByID(context.Background(), "c") // evicts "b"
+
+	stats := cache.Stats()
+	if stats.Entries != 2 {
+		t.Errorf("expected 2 entries, got %d", stats.Entries)
+	}
+	if stats.Evictions != 1 {
+		t.Errorf("expected 1 eviction, got %d", stats.Evictions)
+	}
+
+	before := backing.finds.Load()
+	cache.FindByID(context.Background(), "a")
+	if backing.finds.Load() != before {
+		t.Error("expected recently used entry to stay cached")
+	}
+	cache.FindByID(context.Background(), "b")
+	if backing.finds.Load() != before+1 {
+		t.Error("expected least recently used entry to be evicted")
+	}
+}
+
+func TestCachingShortURLRepository_Invalidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(cache *CachingShortURLRepository, shortURL *domain.ShortURL) error
+		check func(t *testing.T, found *domain.ShortURL)
+	}{
+		{
+			name: "save replaces cached entity",
+			write: func(cache *CachingShortURLRepository, shortURL *domain.ShortURL) error {
+				updated, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.org"), "http://short.ly/abc123", nil, nil)
+				return cache.Save(context.Background(), updated)
+			},
+			check: func(t *testing.T, found *domain.ShortURL) {
+				if found == nil || found.LongURL() != "https://example.org" {
+					t.Errorf("expected updated entity, got %v", found)
+				}
+			},
+		},
+		{
+			name: "update replaces cached entity",
+			write: func(cache *CachingShortURLRepository, shortURL *domain.ShortURL) error {
+				_, err := cache.Update(context.Background(), "abc123", func(u *domain.ShortURL) error {
+					return u.Transition(domain.StatePaused, "", "", time.Now())
+				})
+				return err
+			},
+			check: func(t *testing.T, found *domain.ShortURL) {
+				if found == nil || found.State() != domain.StatePaused {
+					t.Errorf("expected updated entity, got %v", found)
+				}
+			},
+		},
+		{
+			name: "delete drops cached entity",
+			write: func(cache *CachingShortURLRepository, shortURL *domain.ShortURL) error {
+				return cache.Delete(context.Background(), "abc123")
+			},
+			check: func(t *testing.T, found *domain.ShortURL) {
+				if found != nil {
+					t.Error("expected entity to be gone after delete")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, _, _ := newTestCache(DefaultCacheOptions())
+			shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+			cache.Save(context.Background(), shortURL)
+			cache.FindByID(context.Background(), "abc123")
+
+			if err := tt.write(cache, shortURL); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			found, err := cache.FindByID(context.Background(), "abc123")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.check(t, found)
+		})
+	}
+}
+
+func TestCachingShortURLRepository_InsertClearsNegativeEntry(t *testing.T) {
+	cache, _, _ := newTestCache(DefaultCacheOptions())
+
+	if found, _ := cache.FindByID(context.Background(), "abc123"); found != nil {
+		t.Fatal("expected ID to be unknown")
+	}
+
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	if err := cache.Insert(context.Background(), shortURL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if found, _ := cache.FindByID(context.Background(), "abc123"); found == nil {
+		t.Error("expected inserted entity to replace negative cache entry")
+	}
+
+	if err := cache.Insert(context.Background(), shortURL); !errors.Is(err, domain.ErrShortURLAlreadyExists) {
+		t.Errorf("expected ErrShortURLAlreadyExists, got %v", err)
+	}
+}
+
+func TestCachingShortURLRepository_CollapsesConcurrentMisses(t *testing.T) {
+	cache, backing, _ := newTestCache(DefaultCacheOptions())
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	cache.Save(context.Background(), shortURL)
+	backing.release = make(chan struct{})
+
+	const callers = 50
+	var wg sync.WaitGroup
+	results := make(chan *domain.ShortURL, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found, _ := cache.FindByID(context.Background(), "abc123")
+			results <- found
+		}()
+	}
+
+	// Wait until every caller has registered as a miss before letting the load finish
+	for cache.Stats().Misses < callers {
+		time.Sleep(time.Millisecond)
+	}
+	close(backing.release)
+	wg.Wait()
+	close(results)
+
+	for found := range results {
+		if found == nil || found.ID() != "abc123" {
+			t.Errorf("expected every caller to receive the entity, got %v", found)
+		}
+	}
+	if got := backing.finds.Load(); got != 1 {
+		t.Errorf("expected 1 repository lookup, got %d", got)
+	}
+	if stats := cache.Stats(); stats.Loads != 1 {
+		t.Errorf("expected 1 load, got %d", stats.Loads)
+	}
+}
+
+func TestCachingShortURLRepository_WriteDuringLoad(t *testing.T) {
+	cache, backing, _ := newTestCache(DefaultCacheOptions())
+	backing.release = make(chan struct{})
+
+	done := make(chan *domain.ShortURL)
+	go func() {
+		found, _ := cache.FindByID(context.Background(), "abc123")
+		done <- found
+	}()
+	for cache.Stats().Loads < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The insert lands while the stale "not found" lookup is still in flight
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	cache.Insert(context.Background(), shortURL)
+	close(backing.release)
+	<-done
+
+	if found, _ := cache.FindByID(context.Background(), "abc123"); found == nil {
+		t.Error("expected stale lookup result not to be cached")
+	}
+}
+
+func TestCachingShortURLRepository_Errors(t *testing.T) {
+	cache, backing, _ := newTestCache(DefaultCacheOptions())
+	backing.findErr = errors.New("storage unavailable")
+
+	if _, err := cache.FindByID(context.Background(), "abc123"); err == nil {
+		t.Error("expected repository error")
+	}
+	backing.findErr = nil
+	if _, err := cache.FindByID(context.Background(), "abc123"); err != nil {
+		t.Errorf("expected error not to be cached, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cache.FindByID(ctx, "abc123"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCachingShortURLRepository_CallerCancellation(t *testing.T) {
+	cache, backing, _ := newTestCache(DefaultCacheOptions())
+	backing.release = make(chan struct{})
+	defer close(backing.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := cache.FindByID(ctx, "abc123"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HighWaterMarkStore durably tracks the highest counter value handed out to any
+// key generation service instance. Instances lease ranges of counter values from it,
+// so no two leases ever overlap, even across restarts and multiple instances.
+// A database implementation can lease with a single atomic statement such as
+// "UPDATE kgs_counter SET hwm = hwm + $1 RETURNING hwm - $1".
+type HighWaterMarkStore interface {
+	// Lease reserves the next size counter values and returns the first of them.
+	// The new high-water mark must be durable before Lease returns, so that a
+	// range is never leased twice, even if the process crashes right afterwards.
+	Lease(ctx context.Context, size int64) (int64, error)
+}
+
+// MemoryHighWaterMarkStore is an in-memory HighWaterMarkStore for tests and for
+// deployments whose repository is not durable either.
+type MemoryHighWaterMarkStore struct {
+	mu  sync.Mutex // Serializes leases
+	hwm int64      // First counter value not yet leased
+}
+
+// NewMemoryHighWaterMarkStore creates an in-memory store starting at the given mark.
+//
+// Parameters:
+//   - start: First counter value to lease
+//
+// Returns:
+//   - *MemoryHighWaterMarkStore: Store instance
+func NewMemoryHighWaterMarkStore(start int64) *MemoryHighWaterMarkStore {
+	return &MemoryHighWaterMarkStore{hwm: start}
+}
+
+// Lease reserves the next size counter values.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - size: Number of counter values to reserve; must be positive
+//
+// Returns:
+//   - int64: First counter value of the leased range
+//   - error: Error if size is not positive, or the context error
+func (s *MemoryHighWaterMarkStore) Lease(ctx context.Context, size int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if size <= 0 {
+		return 0, fmt.Errorf("lease size must be positive, got %d", size)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := s.hwm
+	s.hwm += size
+	return start, nil
+}
+
+// FileHighWaterMarkStore is a HighWaterMarkStore persisted in a local file.
+// Every lease rewrites the file atomically and fsyncs it before returning.
+// The file must be used by a single process at a time; instances on different
+// hosts need a shared store such as a database.
+type FileHighWaterMarkStore struct {
+	mu   sync.Mutex // Serializes leases
+	path string     // Location of the high-water mark file
+	hwm  int64      // First counter value not yet leased, mirrored from the file
+}
+
+// NewFileHighWaterMarkStore opens the high-water mark file at path, creating it
+// on the first lease if it does not exist yet.
+//
+// Parameters:
+//   - path: Location of the high-water mark file
+//
+// Returns:
+//   - *FileHighWaterMarkStore: Store instance
+//   - error: Error if the file exists but cannot be read or parsed
+func NewFileHighWaterMarkStore(path string) (*FileHighWaterMarkStore, error) {
+	store := &FileHighWaterMarkStore{path: path}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- path is supplied by the operator
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read high-water mark: %w", err)
+	}
+
+	hwm, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || hwm < 0 {
+		return nil, fmt.Errorf("invalid high-water mark in %s: %q", path, strings.TrimSpace(string(data)))
+	}
+	store.hwm = hwm
+	return store, nil
+}
+
+// Lease reserves the next size counter values and persists the new high-water mark
+// before returning. If persisting fails, nothing is leased.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - size: Number of counter values to reserve; must be positive
+//
+// Returns:
+//   - int64: First counter value of the leased range
+//   - error: Error if size is not positive, the file cannot be written, or the context error
+func (s *FileHighWaterMarkStore) Lease(ctx context.Context, size int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if size <= 0 {
+		return 0, fmt.Errorf("lease size must be positive, got %d", size)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.hwm + size
+	if err := writeFileAtomic(s.path, []byte(strconv.FormatInt(next, 10)+"\n")); err != nil {
+		return 0, fmt.Errorf("failed to persist high-water mark: %w", err)
+	}
+
+	start := s.hwm
+	s.hwm = next
+	return start, nil
+}
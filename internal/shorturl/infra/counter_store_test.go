@@ -0,0 +1,115 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHighWaterMarkStore_Lease(t *testing.T) {
+	stores := map[string]func(t *testing.T) HighWaterMarkStore{
+		"memory": func(t *testing.T) HighWaterMarkStore {
+			return NewMemoryHighWaterMarkStore(0)
+		},
+		"file": func(t *testing.T) HighWaterMarkStore {
+			store, err := NewFileHighWaterMarkStore(filepath.Join(t.TempDir(), "kgs.hwm"))
+			if err != nil {
+				t.Fatalf("failed to open store: %v", err)
+			}
+			return store
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+
+			tests := []struct {
+				size          int64
+				expectedStart int64
+				expectError   bool
+			}{
+				{size: 100, expectedStart: 0},
+				{size: 50, expectedStart: 100},
+				{size: 0, expectError: true},
+				{size: -1, expectError: true},
+				{size: 1, expectedStart: 150},
+			}
+
+			for _, tt := range tests {
+				start, err := store.Lease(context.Background(), tt.size)
+				if tt.expectError {
+					if err == nil {
+						t.Errorf("Lease(%d): expected error but got none", tt.size)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("Lease(%d): unexpected error: %v", tt.size, err)
+				}
+				if start != tt.expectedStart {
+					t.Errorf("Lease(%d): expected start %d, got %d", tt.size, tt.expectedStart, start)
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if _, err := store.Lease(ctx, 1); !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFileHighWaterMarkStore_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kgs.hwm")
+
+	store, _ := NewFileHighWaterMarkStore(path)
+	store.Lease(context.Background(), 10000)
+
+	// A reopened store continues after every range leased before
+	reopened, err := NewFileHighWaterMarkStore(path)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	start, err := reopened.Lease(context.Background(), 10000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if start != 10000 {
+		t.Errorf("expected lease to start at 10000, got %d", start)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.TrimSpace(string(data)) != "20000" {
+		t.Errorf("expected persisted mark 20000, got %q", data)
+	}
+}
+
+func TestFileHighWaterMarkStore_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	corrupt := filepath.Join(dir, "corrupt.hwm")
+	os.WriteFile(corrupt, []byte("not a number"), 0o600)
+	if _, err := NewFileHighWaterMarkStore(corrupt); err == nil {
+		t.Error("expected error for corrupt high-water mark")
+	}
+
+	negative := filepath.Join(dir, "negative.hwm")
+	os.WriteFile(negative, []byte("-5\n"), 0o600)
+	if _, err := NewFileHighWaterMarkStore(negative); err == nil {
+		t.Error("expected error for negative high-water mark")
+	}
+
+	// A failed write must not advance the mark
+	store, _ := NewFileHighWaterMarkStore(filepath.Join(dir, "missing", "kgs.hwm"))
+	if _, err := store.Lease(context.Background(), 10); err == nil {
+		t.Fatal("expected error when the file cannot be written")
+	}
+	if store.hwm != 0 {
+		t.Errorf("expected mark to stay at 0 after failed lease, got %d", store.hwm)
+	}
+}
@@ -0,0 +1,276 @@
+package infra
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"sync"
+)
+
+const (
+	// feistelHalfBits is the width of each Feistel half; 2^(2*21) is the smallest
+	// even power of two covering base62IDSpace.
+	feistelHalfBits = 21
+	// feistelRounds is the number of Feistel rounds. Four rounds already give a
+	// pseudorandom permutation; the extra rounds add margin at negligible cost.
+	feistelRounds = 8
+)
+
+// feistelPermutation is a keyed bijection on [0, space). It runs a balanced Feistel
+// network over 2*halfBits bits and uses cycle walking to stay inside the domain:
+// values that land outside [0, space) are permuted again until they fall inside,
+// which keeps the mapping a bijection on the smaller domain.
+type feistelPermutation struct {
+	key      []byte // Secret key for the HMAC round function
+	space    uint64 // Size of the domain
+	halfBits uint   // Width of each half of the Feistel network
+}
+
+// permute maps a value in [0, space) to its image in [0, space).
+func (p feistelPermutation) permute(value uint64) uint64 {
+	for {
+		value = p.encrypt(value)
+		if value < p.space {
+			return value
+		}
+	}
+}
+
+// invert maps an image in [0, space) back to the value that produced it.
+func (p feistelPermutation) invert(value uint64) uint64 {
+	for {
+		value = p.decrypt(value)
+		if value < p.space {
+			return value
+		}
+	}
+}
+
+// encrypt runs the Feistel network forward over the full 2*halfBits-bit block.
+func (p feistelPermutation) encrypt(value uint64) uint64 {
+	mask := uint64(1)<<p.halfBits - 1
+	left, right := value>>p.halfBits, value&mask
+
+	mac := hmac.New(sha256.New, p.key)
+	for round := 0; round < feistelRounds; round++ {
+		left, right = right, left^p.round(mac, round, right)
+	}
+	return left<<p.halfBits | right
+}
+
+// decrypt runs the Feistel network backward, undoing encrypt.
+func (p feistelPermutation) decrypt(value uint64) uint64 {
+	mask := uint64(1)<<p.halfBits - 1
+	left, right := value>>p.halfBits, value&mask
+
+	mac := hmac.New(sha256.New, p.key)
+	for round := feistelRounds - 1; round >= 0; round-- {
+		left, right = right^p.round(mac, round, left), left
+	}
+	return left<<p.halfBits | right
+}
+
+// round is the keyed round function: HMAC-SHA256 of the round number and the half,
+// truncated to halfBits bits.
+func (p feistelPermutation) round(mac hash.Hash, round int, half uint64) uint64 {
+	var input [9]byte
+	input[0] = byte(round)
+	binary.BigEndian.PutUint64(input[1:], half)
+
+	mac.Reset()
+	mac.Write(input[:])
+	sum := mac.Sum(nil)
+	return binary.BigEndian.Uint64(sum[:8]) & (uint64(1)<<p.halfBits - 1)
+}
+
+// FeistelKeyGenerationService implements the KeyGenerationService interface by passing a
+// monotonically increasing counter through a keyed Feistel permutation of the 62^7 ID space.
+// Because the permutation is a bijection, distinct counter values always yield distinct IDs:
+// uniqueness is guaranteed without consulting the repository, every ID is exactly
+// 7 characters long, and without the key consecutive IDs look unrelated.
+//
+// When created with a HighWaterMarkStore, the counter is leased from the store in ranges
+// instead, so restarts and other instances never issue a counter value twice.
+type FeistelKeyGenerationService struct {
+	mu          sync.Mutex         // Mutex for thread-safe counter operations
+	permutation feistelPermutation // Keyed bijection from counter values to ID values
+	counter     uint64             // Next counter value to issue
+	store       HighWaterMarkStore // Durable source of counter ranges; nil for a counter counting up from start
+	leaseSize   int64              // Number of counter values leased at once
+	leaseEnd    uint64             // End (exclusive) of the current lease
+}
+
+// NewFeistelKeyGenerationService creates a Feistel-based key generation service.
+// The key must stay the same for the lifetime of the data set, and start must be at or
+// above every counter value issued before; otherwise previously issued IDs are repeated.
+//
+// Parameters:
+//   - key: Secret key of the permutation; must not be empty
+//   - start: First counter value to issue
+//
+// Returns:
+//   - *FeistelKeyGenerationService: Service instance; use CounterForID to map IDs back
+//   - error: Error if the key is empty or start lies outside the ID space
+func NewFeistelKeyGenerationService(key []byte, start uint64) (*FeistelKeyGenerationService, error) {
+	if len(key) == 0 {
+		return nil, errors.New("feistel key must not be empty")
+	}
+	if start >= base62IDSpace {
+		return nil, fmt.Errorf("start counter %d exceeds ID space of %d", start, uint64(base62IDSpace))
+	}
+
+	return &FeistelKeyGenerationService{
+		permutation: feistelPermutation{
+			key:      append([]byte(nil), key...),
+			space:    base62IDSpace,
+			halfBits: feistelHalfBits,
+		},
+		counter: start,
+	}, nil
+}
+
+// NewLeasedFeistelKeyGenerationService creates a Feistel-based key generation service
+// that leases its counter ranges from a durable high-water-mark store. Each lease is
+// durable before any of its values is issued, so a crash can only waste the rest of a
+// range, never reissue it. The key must stay the same for the lifetime of the data set.
+//
+// Parameters:
+//   - key: Secret key of the permutation; must not be empty
+//   - store: The store to lease counter ranges from
+//   - leaseSize: Number of counter values per lease; defaultLeaseSize when zero or negative
+//
+// Returns:
+//   - *FeistelKeyGenerationService: Service instance; use CounterForID to map IDs back
+//   - error: Error if the key is empty
+func NewLeasedFeistelKeyGenerationService(key []byte, store HighWaterMarkStore, leaseSize int64) (*FeistelKeyGenerationService, error) {
+	k, err := NewFeistelKeyGenerationService(key, 0)
+	if err != nil {
+		return nil, err
+	}
+	if leaseSize <= 0 {
+		leaseSize = defaultLeaseSize
+	}
+	k.store = store
+	k.leaseSize = leaseSize
+	return k, nil
+}
+
+// GenerateUniqueID issues the identifier for the next counter value.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//
+// Returns:
+//   - string: A unique 7-character Base62-encoded identifier
+//   - error: ErrIDSpaceExhausted when all IDs are issued, or the context error
+func (k *FeistelKeyGenerationService) GenerateUniqueID(ctx context.Context) (string, error) {
+	ids, err := k.GetMultipleIDs(ctx, 1)
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// GetMultipleIDs issues the identifiers for the next count counter values.
+// Either all IDs are issued or, on error, none are.
+//
+// Parameters:
+//   - ctx: Context for cancellation, checked while the batch is being generated
+//   - count: Number of IDs to generate
+//
+// Returns:
+//   - []string: Slice of unique Base62-encoded identifiers
+//   - error: ErrIDSpaceExhausted if fewer than count IDs remain, a lease error, or the context error
+func (k *FeistelKeyGenerationService) GetMultipleIDs(ctx context.Context, count int) ([]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.store != nil {
+		return k.getLeasedIDs(ctx, count)
+	}
+	if uint64(count) > base62IDSpace-k.counter {
+		return nil, ErrIDSpaceExhausted
+	}
+
+	ids := make([]string, count)
+	for i := range count {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ids[i] = encodeFixedBase62(k.permutation.permute(k.counter+uint64(i)), base62IDLength)
+	}
+	k.counter += uint64(count)
+	return ids, nil
+}
+
+// getLeasedIDs issues the identifiers for the next count leased counter values, leasing
+// new ranges from the store as the current one is used up. Values of a failed batch are
+// not reissued. This method assumes the caller has already acquired the mutex lock.
+//
+// Parameters:
+//   - ctx: Context for cancellation of the batch and the leases
+//   - count: Number of IDs to generate
+//
+// Returns:
+//   - []string: Slice of unique Base62-encoded identifiers
+//   - error: ErrIDSpaceExhausted, a lease error, or the context error
+func (k *FeistelKeyGenerationService) getLeasedIDs(ctx context.Context, count int) ([]string, error) {
+	ids := make([]string, count)
+	for i := range count {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if k.counter >= k.leaseEnd {
+			start, err := k.store.Lease(ctx, k.leaseSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to lease counter range: %w", err)
+			}
+			k.counter, k.leaseEnd = uint64(start), uint64(start+k.leaseSize)
+		}
+		if k.counter >= base62IDSpace {
+			return nil, ErrIDSpaceExhausted
+		}
+		ids[i] = encodeFixedBase62(k.permutation.permute(k.counter), base62IDLength)
+		k.counter++
+	}
+	return ids, nil
+}
+
+// CounterForID maps an identifier back to the counter value it was issued for.
+// Every 7-character Base62 string maps to some counter value, so operators can use it
+// to order IDs by issue time or, by comparing with the current counter, to tell whether
+// an ID was ever issued by this service or was chosen by someone else.
+//
+// Parameters:
+//   - id: A 7-character Base62 identifier
+//
+// Returns:
+//   - uint64: The counter value that produces id
+//   - error: Error if id is not a 7-character Base62 string
+func (k *FeistelKeyGenerationService) CounterForID(id string) (uint64, error) {
+	if len(id) != base62IDLength {
+		return 0, fmt.Errorf("ID must be %d characters, got %d", base62IDLength, len(id))
+	}
+
+	value, err := DecodeBase62(id)
+	if err != nil {
+		return 0, err
+	}
+	return k.permutation.invert(uint64(value)), nil
+}
+
+// Counter returns the next counter value to be issued. Persisting it and passing it
+// as start on the next run resumes issuance without repeating IDs.
+//
+// Returns:
+//   - uint64: The next counter value
+func (k *FeistelKeyGenerationService) Counter() uint64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.counter
+}
@@ -0,0 +1,240 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func newTestFeistelKGS(t *testing.T, start uint64) *FeistelKeyGenerationService {
+	t.Helper()
+
+	kgs, err := NewFeistelKeyGenerationService([]byte("test-key"), start)
+	if err != nil {
+		t.Fatalf("failed to create KGS: %v", err)
+	}
+	return kgs
+}
+
+func TestNewFeistelKeyGenerationService(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         []byte
+		start       uint64
+		expectError bool
+	}{
+		{"valid", []byte("secret"), 0, false},
+		{"last counter", []byte("secret"), base62IDSpace - 1, false},
+		{"empty key", nil, 0, true},
+		{"start outside ID space", []byte("secret"), base62IDSpace, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kgs, err := NewFeistelKeyGenerationService(tt.key, tt.start)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if kgs.Counter() != tt.start {
+				t.Errorf("expected counter %d, got %d", tt.start, kgs.Counter())
+			}
+		})
+	}
+}
+
+func TestFeistelPermutation_Bijective(t *testing.T) {
+	// A small domain (62^2 inside 2^12) can be checked exhaustively
+	p := feistelPermutation{key: []byte("test-key"), space: 62 * 62, halfBits: 6}
+
+	seen := make(map[uint64]bool)
+	for value := uint64(0); value < p.space; value++ {
+		image := p.permute(value)
+		if image >= p.space {
+			t.Fatalf("permute(%d) = %d is outside the domain", value, image)
+		}
+		if seen[image] {
+			t.Fatalf("permute(%d) = %d collides with an earlier value", value, image)
+		}
+		seen[image] = true
+
+		if back := p.invert(image); back != value {
+			t.Fatalf("invert(permute(%d)) = %d", value, back)
+		}
+	}
+}
+
+func TestFeistelKeyGenerationService_GetMultipleIDs(t *testing.T) {
+	kgs := newTestFeistelKGS(t, 0)
+
+	ids, err := kgs.GetMultipleIDs(context.Background(), 10000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if len(id) != base62IDLength {
+			t.Errorf("expected ID length %d, got %q", base62IDLength, id)
+		}
+		if !isValidBase62(id) {
+			t.Errorf("invalid Base62 ID %q", id)
+		}
+		if seen[id] {
+			t.Errorf("duplicate ID %q", id)
+		}
+		seen[id] = true
+	}
+
+	if kgs.Counter() != 10000 {
+		t.Errorf("expected counter 10000, got %d", kgs.Counter())
+	}
+}
+
+func TestFeistelKeyGenerationService_Deterministic(t *testing.T) {
+	first := newTestFeistelKGS(t, 42)
+	second := newTestFeistelKGS(t, 42)
+	otherKey, _ := NewFeistelKeyGenerationService([]byte("other-key"), 42)
+
+	a, _ := first.GenerateUniqueID(context.Background())
+	b, _ := second.GenerateUniqueID(context.Background())
+	c, _ := otherKey.GenerateUniqueID(context.Background())
+
+	if a != b {
+		t.Errorf("expected same key and counter to produce the same ID, got %q and %q", a, b)
+	}
+	if a == c {
+		t.Errorf("expected different keys to produce different IDs, both got %q", a)
+	}
+}
+
+func TestFeistelKeyGenerationService_NonSequential(t *testing.T) {
+	kgs := newTestFeistelKGS(t, 0)
+	ids, _ := kgs.GetMultipleIDs(context.Background(), 100)
+
+	// Consecutive counters should differ in most character positions
+	similar := 0
+	for i := 1; i < len(ids); i++ {
+		same := 0
+		for j := 0; j < base62IDLength; j++ {
+			if ids[i][j] == ids[i-1][j] {
+				same++
+			}
+		}
+		if same > base62IDLength/2 {
+			similar++
+		}
+	}
+	if similar > 5 {
+		t.Errorf("expected consecutive IDs to look unrelated, %d of 99 pairs were similar", similar)
+	}
+}
+
+func TestFeistelKeyGenerationService_CounterForID(t *testing.T) {
+	kgs := newTestFeistelKGS(t, base62IDSpace-3)
+	ids, err := kgs.GetMultipleIDs(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, id := range ids {
+		counter, err := kgs.CounterForID(id)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", id, err)
+		}
+		if want := uint64(base62IDSpace - 3 + i); counter != want {
+			t.Errorf("CounterForID(%q) = %d, expected %d", id, counter, want)
+		}
+	}
+
+	for _, invalid := range []string{"abc", "abcdefgh", "abc-def"} {
+		if _, err := kgs.CounterForID(invalid); err == nil {
+			t.Errorf("CounterForID(%q): expected error", invalid)
+		}
+	}
+}
+
+func TestFeistelKeyGenerationService_Exhausted(t *testing.T) {
+	kgs := newTestFeistelKGS(t, base62IDSpace-2)
+
+	if _, err := kgs.GetMultipleIDs(context.Background(), 3); !errors.Is(err, ErrIDSpaceExhausted) {
+		t.Errorf("expected ErrIDSpaceExhausted, got %v", err)
+	}
+	if kgs.Counter() != base62IDSpace-2 {
+		t.Error("expected failed batch not to consume counters")
+	}
+
+	if _, err := kgs.GetMultipleIDs(context.Background(), 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := kgs.GenerateUniqueID(context.Background()); !errors.Is(err, ErrIDSpaceExhausted) {
+		t.Errorf("expected ErrIDSpaceExhausted, got %v", err)
+	}
+}
+
+func TestFeistelKeyGenerationService_CanceledContext(t *testing.T) {
+	kgs := newTestFeistelKGS(t, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := kgs.GenerateUniqueID(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if kgs.Counter() != 0 {
+		t.Errorf("expected canceled call not to consume counters, got %d", kgs.Counter())
+	}
+}
+
+func TestFeistelKeyGenerationService_CounterStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kgs.hwm")
+	seen := make(map[string]bool)
+
+	// Each run resumes beyond the leases of the previous ones, even though they never
+	// used up their last lease
+	for run := range 3 {
+		store, err := NewFileHighWaterMarkStore(path)
+		if err != nil {
+			t.Fatalf("failed to open store: %v", err)
+		}
+		kgs, err := NewLeasedFeistelKeyGenerationService([]byte("test-key"), store, 100)
+		if err != nil {
+			t.Fatalf("failed to create KGS: %v", err)
+		}
+
+		ids, err := kgs.GetMultipleIDs(context.Background(), 150)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, id := range ids {
+			if seen[id] {
+				t.Fatalf("run %d reissued ID %q", run, id)
+			}
+			seen[id] = true
+		}
+	}
+
+	t.Run("canceled context", func(t *testing.T) {
+		kgs, err := NewLeasedFeistelKeyGenerationService([]byte("test-key"), NewMemoryHighWaterMarkStore(0), 0)
+		if err != nil {
+			t.Fatalf("failed to create KGS: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := kgs.GenerateUniqueID(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("empty key", func(t *testing.T) {
+		if _, err := NewLeasedFeistelKeyGenerationService(nil, NewMemoryHighWaterMarkStore(0), 0); err == nil {
+			t.Error("expected error but got none")
+		}
+	})
+}
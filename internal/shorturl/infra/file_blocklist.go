@@ -0,0 +1,166 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+)
+
+// defaultBlocklistPollInterval is how often the blocklist file is checked for changes.
+const defaultBlocklistPollInterval = 10 * time.Second
+
+// FileDestinationBlocklist implements the DestinationBlocklist interface with rules read
+// from a local file in the syntax of domain.ParseDestinationRules. The file can be
+// replaced while the server runs: Reload, or the watcher started by Start, picks up the
+// new rules without interrupting lookups. A file that fails to parse is reported and the
+// previous rules stay in effect, so a bad edit never disables the blocklist.
+type FileDestinationBlocklist struct {
+	path  string                                  // Path of the rule file
+	rules atomic.Pointer[domain.DestinationRules] // Rules currently in effect
+
+	mu          sync.Mutex // Guards the fields below
+	modTime     time.Time  // Modification time of the loaded file
+	size        int64      // Size of the loaded file
+	stopWatcher func()     // Cancels the watcher; nil when not running
+	watcherDone chan struct{}
+}
+
+// NewFileDestinationBlocklist loads a blocklist file.
+//
+// Parameters:
+//   - path: Path of the rule file
+//
+// Returns:
+//   - *FileDestinationBlocklist: Blocklist instance
+//   - error: Error if the file cannot be read or contains an invalid rule
+func NewFileDestinationBlocklist(path string) (*FileDestinationBlocklist, error) {
+	b := &FileDestinationBlocklist{path: path}
+	if _, err := b.Reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Match returns the rule that blocks the long URL, if any.
+//
+// Parameters:
+//   - longURL: The long URL, normalized as by domain.ParseLongURL
+//
+// Returns:
+//   - domain.DestinationRule: The matching rule
+//   - bool: Whether a rule matches
+func (b *FileDestinationBlocklist) Match(longURL string) (domain.DestinationRule, bool) {
+	return b.rules.Load().Match(longURL)
+}
+
+// Len returns the number of rules in effect.
+func (b *FileDestinationBlocklist) Len() int {
+	return b.rules.Load().Len()
+}
+
+// Reload reads the file again if its modification time or size changed since it was
+// last loaded. On error the previous rules stay in effect.
+//
+// Returns:
+//   - bool: Whether new rules were loaded
+//   - error: Error if the file cannot be read or contains an invalid rule
+func (b *FileDestinationBlocklist) Reload() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+	if b.rules.Load() != nil && info.ModTime().Equal(b.modTime) && info.Size() == b.size {
+		return false, nil
+	}
+
+	file, err := os.Open(b.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	rules, err := domain.ParseDestinationRules(file)
+	if err != nil {
+		return false, fmt.Errorf("invalid blocklist %s: %w", b.path, err)
+	}
+
+	b.rules.Store(rules)
+	b.modTime, b.size = info.ModTime(), info.Size()
+	return true, nil
+}
+
+// Start begins watching the file in the background, reloading it when it changes.
+//
+// Parameters:
+//   - interval: Time between checks of the file; 10 seconds when zero
+//
+// Returns:
+//   - error: Error if the watcher is already running
+func (b *FileDestinationBlocklist) Start(interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultBlocklistPollInterval
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopWatcher != nil {
+		return errors.New("blocklist watcher already running")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.stopWatcher = cancel
+	b.watcherDone = make(chan struct{})
+	go b.runWatcher(ctx, interval, b.watcherDone)
+	return nil
+}
+
+// Stop stops the watcher and waits for it to exit. Stop is a no-op when the watcher is
+// not running, and the watcher can be started again afterwards.
+func (b *FileDestinationBlocklist) Stop() {
+	b.mu.Lock()
+	stop, done := b.stopWatcher, b.watcherDone
+	b.stopWatcher, b.watcherDone = nil, nil
+	b.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	stop()
+	<-done
+}
+
+// runWatcher is the background loop started by Start.
+//
+// Parameters:
+//   - ctx: Context canceled by Stop
+//   - interval: Time between checks of the file
+//   - done: Channel closed when the loop exits
+func (b *FileDestinationBlocklist) runWatcher(ctx context.Context, interval time.Duration, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := b.Reload()
+			if err != nil {
+				log.Printf("Keeping previous blocklist: %v", err)
+			} else if reloaded {
+				log.Printf("Reloaded blocklist %s with %d rules", b.path, b.Len())
+			}
+		}
+	}
+}
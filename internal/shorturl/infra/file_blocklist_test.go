@@ -0,0 +1,123 @@
+package infra
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeBlocklist writes a rule file and moves its modification time forward, so that
+// rewrites within the file system's timestamp granularity are still noticed.
+func writeBlocklist(t *testing.T, path, content string, age time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write blocklist: %v", err)
+	}
+	modTime := time.Now().Add(age)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+}
+
+func TestNewFileDestinationBlocklist(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("loads rules", func(t *testing.T) {
+		path := filepath.Join(dir, "valid.txt")
+		writeBlocklist(t, path, "# feed\nevil.example\n*.phish.example\n", 0)
+
+		blocklist, err := NewFileDestinationBlocklist(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if blocklist.Len() != 2 {
+			t.Errorf("expected 2 rules, got %d", blocklist.Len())
+		}
+		if rule, blocked := blocklist.Match("https://login.phish.example/"); !blocked || rule.String() != "suffix:phish.example" {
+			t.Errorf("expected suffix rule to match, got %v %v", rule, blocked)
+		}
+		if _, blocked := blocklist.Match("https://example.com/"); blocked {
+			t.Error("expected clean URL not to match")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := NewFileDestinationBlocklist(filepath.Join(dir, "missing.txt")); err == nil {
+			t.Error("expected error for missing file")
+		}
+	})
+
+	t.Run("invalid rule", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.txt")
+		writeBlocklist(t, path, "prefix:ftp://files.example/\n", 0)
+		if _, err := NewFileDestinationBlocklist(path); err == nil {
+			t.Error("expected error for invalid rule")
+		}
+	})
+}
+
+func TestFileDestinationBlocklist_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path, "evil.example\n", -2*time.Minute)
+
+	blocklist, err := NewFileDestinationBlocklist(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An unchanged file is not parsed again
+	if reloaded, err := blocklist.Reload(); err != nil || reloaded {
+		t.Errorf("expected no reload for unchanged file, got %v %v", reloaded, err)
+	}
+
+	// A bad edit is reported and the previous rules stay in effect
+	writeBlocklist(t, path, "evil.example\nhost:\n", -time.Minute)
+	if reloaded, err := blocklist.Reload(); err == nil || reloaded {
+		t.Errorf("expected error for invalid edit, got %v %v", reloaded, err)
+	}
+	if _, blocked := blocklist.Match("https://evil.example/"); !blocked {
+		t.Error("expected previous rules to stay in effect")
+	}
+
+	// A fixed file replaces the rules
+	writeBlocklist(t, path, "other.example\n", 0)
+	if reloaded, err := blocklist.Reload(); err != nil || !reloaded {
+		t.Fatalf("expected reload, got %v %v", reloaded, err)
+	}
+	if _, blocked := blocklist.Match("https://evil.example/"); blocked {
+		t.Error("expected removed rule not to match")
+	}
+	if _, blocked := blocklist.Match("https://other.example/"); !blocked {
+		t.Error("expected new rule to match")
+	}
+}
+
+func TestFileDestinationBlocklist_Watcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path, "evil.example\n", -time.Minute)
+
+	blocklist, err := NewFileDestinationBlocklist(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := blocklist.Start(10 * time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := blocklist.Start(10 * time.Millisecond); err == nil {
+		t.Error("expected error when starting twice")
+	}
+
+	writeBlocklist(t, path, "evil.example\nnew.example\n", 0)
+	deadline := time.Now().Add(2 * time.Second)
+	for blocklist.Len() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("watcher did not reload the changed file")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	blocklist.Stop()
+	blocklist.Stop() // Stopping twice is a no-op
+}
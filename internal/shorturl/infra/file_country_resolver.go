@@ -0,0 +1,125 @@
+package infra
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+)
+
+// FileCountryResolver implements the CountryResolver interface with a table of IP
+// prefixes read from a local file, such as an export of a GeoIP database. Each line holds
+// a prefix in CIDR notation and an ISO 3166-1 alpha-2 country code, separated by a comma
+// or whitespace, e.g. "203.0.113.0/24,JP". Empty lines and lines starting with "#" are
+// ignored. The most specific prefix containing an address wins.
+type FileCountryResolver struct {
+	prefixes map[netip.Prefix]string // Country codes by masked prefix
+	bits4    []int                   // Lengths of the IPv4 prefixes in the table, longest first
+	bits6    []int                   // Lengths of the IPv6 prefixes in the table, longest first
+}
+
+// NewFileCountryResolver loads a prefix table file.
+//
+// Parameters:
+//   - path: Path of the prefix table
+//
+// Returns:
+//   - *FileCountryResolver: Resolver instance
+//   - error: Error if the file cannot be read or contains an invalid line
+func NewFileCountryResolver(path string) (*FileCountryResolver, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read country table: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	resolver, err := ParseCountryTable(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid country table %s: %w", path, err)
+	}
+	return resolver, nil
+}
+
+// ParseCountryTable reads a prefix table in the syntax described at FileCountryResolver.
+//
+// Parameters:
+//   - r: Reader of the prefix table
+//
+// Returns:
+//   - *FileCountryResolver: Resolver instance
+//   - error: Error naming the line of the first invalid entry, or a read error
+func ParseCountryTable(r io.Reader) (*FileCountryResolver, error) {
+	resolver := &FileCountryResolver{prefixes: make(map[netip.Prefix]string)}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.FieldsFunc(text, func(c rune) bool { return c == ',' || c == ' ' || c == '\t' })
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a prefix and a country code", line)
+		}
+		prefix, err := netip.ParsePrefix(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		country := strings.ToUpper(fields[1])
+		if len(country) != 2 || strings.Trim(country, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return nil, fmt.Errorf("line %d: country %q is not a two-letter ISO 3166-1 code", line, fields[1])
+		}
+
+		prefix = prefix.Masked()
+		resolver.prefixes[prefix] = country
+		lengths := &resolver.bits4
+		if prefix.Addr().Is6() {
+			lengths = &resolver.bits6
+		}
+		if !slices.Contains(*lengths, prefix.Bits()) {
+			*lengths = append(*lengths, prefix.Bits())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// Trying the longest prefixes first finds the most specific entry
+	slices.Sort(resolver.bits4)
+	slices.Reverse(resolver.bits4)
+	slices.Sort(resolver.bits6)
+	slices.Reverse(resolver.bits6)
+	return resolver, nil
+}
+
+// Country returns the country of the most specific prefix containing the IP address.
+//
+// Parameters:
+//   - ip: The client's IP address; IPv4-mapped IPv6 addresses are looked up as IPv4
+//
+// Returns:
+//   - string: ISO 3166-1 alpha-2 code in upper case; empty if no prefix contains the address
+func (r *FileCountryResolver) Country(ip netip.Addr) string {
+	ip = ip.Unmap()
+	lengths := r.bits4
+	if ip.Is6() {
+		lengths = r.bits6
+	}
+	for _, bits := range lengths {
+		prefix, err := ip.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if country, ok := r.prefixes[prefix]; ok {
+			return country
+		}
+	}
+	return ""
+}
+
+// Len returns the number of prefixes in the table.
+func (r *FileCountryResolver) Len() int {
+	return len(r.prefixes)
+}
@@ -0,0 +1,91 @@
+package infra
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCountryTable(t *testing.T) {
+	resolver, err := ParseCountryTable(strings.NewReader(`# GeoIP export
+203.0.113.0/24,JP
+203.0.113.128/25 kr
+
+198.51.100.7/32	us
+2001:db8::/32,DE
+10.1.2.3/8,gb
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolver.Len() != 5 {
+		t.Errorf("expected 5 prefixes, got %d", resolver.Len())
+	}
+
+	tests := []struct {
+		name     string
+		ip       string
+		expected string
+	}{
+		{"IPv4 prefix", "203.0.113.7", "JP"},
+		{"most specific prefix wins", "203.0.113.200", "KR"},
+		{"single address", "198.51.100.7", "US"},
+		{"neighbor of single address", "198.51.100.8", ""},
+		{"prefix given with host bits", "10.200.0.1", "GB"},
+		{"IPv6 prefix", "2001:db8:1::1", "DE"},
+		{"IPv4-mapped IPv6 address", "::ffff:203.0.113.7", "JP"},
+		{"unknown address", "192.0.2.1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if country := resolver.Country(netip.MustParseAddr(tt.ip)); country != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, country)
+			}
+		})
+	}
+}
+
+func TestParseCountryTable_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		table    string
+		expected string
+	}{
+		{"missing country", "203.0.113.0/24\n", "line 1: expected a prefix and a country code"},
+		{"invalid prefix", "# header\n203.0.113.0/33,JP\n", "line 2:"},
+		{"bare address", "203.0.113.7,JP\n", "line 1:"},
+		{"invalid country", "203.0.113.0/24,JPN\n", `line 1: country "JPN" is not a two-letter ISO 3166-1 code`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCountryTable(strings.NewReader(tt.table))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+				t.Errorf("expected error starting with %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestNewFileCountryResolver(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "countries.csv")
+	if err := os.WriteFile(path, []byte("203.0.113.0/24,JP\n"), 0o600); err != nil {
+		t.Fatalf("failed to write country table: %v", err)
+	}
+	resolver, err := NewFileCountryResolver(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if country := resolver.Country(netip.MustParseAddr("203.0.113.1")); country != "JP" {
+		t.Errorf("expected JP, got %q", country)
+	}
+
+	if _, err := NewFileCountryResolver(filepath.Join(dir, "missing.csv")); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
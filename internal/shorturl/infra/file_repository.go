@@ -0,0 +1,820 @@
+package infra
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+)
+
+const (
+	// walFileName is the name of the write-ahead log inside the data directory.
+	walFileName = "shorturls.wal"
+	// snapshotFileName is the name of the compacted snapshot inside the data directory.
+	snapshotFileName = "shorturls.snapshot"
+	// snapshotVersion is the format version written into every snapshot.
+	snapshotVersion = 1
+)
+
+// walOp identifies the kind of mutation stored in a write-ahead log record.
+type walOp string
+
+const (
+	walOpSave   walOp = "save"
+	walOpDelete walOp = "delete"
+)
+
+// SyncPolicy controls when the write-ahead log is flushed to stable storage.
+type SyncPolicy int
+
+const (
+	// SyncAlways calls fsync after every appended record. This is the safest
+	// and slowest option: an acknowledged write survives a power loss.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval calls fsync periodically from a background goroutine.
+	// Writes acknowledged within the last interval may be lost on power loss.
+	SyncInterval
+	// SyncNever leaves flushing entirely to the operating system.
+	SyncNever
+)
+
+// FileRepositoryOptions configures the durability behaviour of FileShortURLRepository.
+type FileRepositoryOptions struct {
+	SyncPolicy        SyncPolicy    // When to fsync the write-ahead log
+	SyncInterval      time.Duration // Flush period used with SyncInterval
+	SnapshotThreshold int           // Number of log records that triggers a snapshot (0 disables)
+}
+
+// DefaultFileRepositoryOptions returns options that favour durability:
+// every write is synced and the log is compacted every 10,000 records.
+func DefaultFileRepositoryOptions() FileRepositoryOptions {
+	return FileRepositoryOptions{
+		SyncPolicy:        SyncAlways,
+		SyncInterval:      time.Second,
+		SnapshotThreshold: 10000,
+	}
+}
+
+// shortURLRecord is the serialized form of a ShortURL entity used in
+// both the write-ahead log and snapshots.
+type shortURLRecord struct {
+	ID           string                    `json:"id"`
+	LongURL      string                    `json:"longUrl"`
+	ShortURL     string                    `json:"shortUrl"`
+	CreatedAt    time.Time                 `json:"createdAt"`
+	Expiry       *time.Time                `json:"expiry,omitempty"`
+	IsActive     bool                      `json:"isActive"`
+	UserMetadata map[string]interface{}    `json:"userMetadata,omitempty"`
+	Region       string                    `json:"region,omitempty"`
+	History      []destinationChangeRecord `json:"history,omitempty"`
+	State        string                    `json:"state,omitempty"` // Lifecycle state; derived from IsActive when empty (older records)
+	Transitions  []stateTransitionRecord   `json:"transitions,omitempty"`
+	MaxClicks    int                       `json:"maxClicks,omitempty"`
+	Clicks       int                       `json:"clicks,omitempty"`
+	NotBefore    *time.Time                `json:"notBefore,omitempty"`
+	PreLaunchURL string                    `json:"preLaunchUrl,omitempty"`
+	PasswordHash string                    `json:"passwordHash,omitempty"`
+	RoutingRules []routingRuleRecord       `json:"routingRules,omitempty"`
+}
+
+// routingRuleRecord is the serialized form of a domain.RoutingRule.
+type routingRuleRecord struct {
+	Devices   []string `json:"devices,omitempty"`
+	Countries []string `json:"countries,omitempty"`
+	Languages []string `json:"languages,omitempty"`
+	Referrers []string `json:"referrers,omitempty"`
+	LongURL   string   `json:"longUrl"`
+}
+
+// stateTransitionRecord is the serialized form of a domain.StateTransition.
+type stateTransitionRecord struct {
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Reason string    `json:"reason,omitempty"`
+	Actor  string    `json:"actor,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// destinationChangeRecord is the serialized form of a domain.DestinationChange.
+type destinationChangeRecord struct {
+	LongURL   string    `json:"longUrl"`
+	ChangedAt time.Time `json:"changedAt"`
+	Actor     string    `json:"actor,omitempty"`
+}
+
+// walEntry is a single mutation appended to the write-ahead log.
+type walEntry struct {
+	Op     walOp           `json:"op"`
+	ID     string          `json:"id"`
+	Record *shortURLRecord `json:"record,omitempty"`
+}
+
+// snapshotFile is the on-disk layout of a compacted snapshot.
+type snapshotFile struct {
+	Version int               `json:"version"`
+	Records []*shortURLRecord `json:"records"`
+}
+
+// walFile is the subset of *os.File used for the write-ahead log.
+type walFile interface {
+	WriteString(s string) (int, error)
+	Seek(offset int64, whence int) (int64, error)
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// FileShortURLRepository is a durable implementation of the ShortURLRepository interface.
+// Every mutation is appended to a write-ahead log before it is applied to an in-memory
+// index, and the log is periodically compacted into a snapshot. On startup the snapshot
+// and the remaining log are replayed to rebuild the index.
+//
+// Each log record is stored on its own line prefixed with a CRC-32 checksum, so a record
+// torn by a crash in the middle of a write is detected and discarded during recovery.
+type FileShortURLRepository struct {
+	mu         sync.Mutex                // Serializes writes to the log and index
+	dir        string                    // Data directory holding the log and snapshot
+	opts       FileRepositoryOptions     // Durability configuration
+	index      *MemoryShortURLRepository // In-memory view used to serve reads
+	wal        walFile                   // Open write-ahead log, positioned at its end
+	walRecords int                       // Records appended since the last snapshot
+	walErr     error                     // Set once a failed append could not be rolled back
+	dirty      bool                      // Whether the log has unsynced writes
+	stop       chan struct{}             // Closed to stop the background syncer
+	done       chan struct{}             // Closed when the background syncer exits
+	closed     bool                      // Whether Close has been called
+}
+
+// NewFileShortURLRepository opens (or creates) a durable repository in the given directory.
+// Existing data is recovered by loading the latest snapshot and replaying the write-ahead log.
+// A torn record at the end of the log is truncated; corruption anywhere else is reported as an error.
+//
+// Parameters:
+//   - dir: Directory where the log and snapshot files are stored
+//   - opts: Durability options such as the fsync policy and snapshot threshold
+//
+// Returns:
+//   - *FileShortURLRepository: Repository ready for use; call Close when done
+//   - error: Error if the directory cannot be prepared or the data cannot be recovered
+func NewFileShortURLRepository(dir string, opts FileRepositoryOptions) (*FileShortURLRepository, error) {
+	if opts.SyncPolicy == SyncInterval && opts.SyncInterval <= 0 {
+		return nil, errors.New("sync interval must be positive")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	r := &FileShortURLRepository{
+		dir:   dir,
+		opts:  opts,
+		index: NewMemoryShortURLRepository().(*MemoryShortURLRepository),
+	}
+
+	if err := r.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := r.replayLog(); err != nil {
+		return nil, err
+	}
+
+	if opts.SyncPolicy == SyncInterval {
+		r.stop = make(chan struct{})
+		r.done = make(chan struct{})
+		go r.syncLoop()
+	}
+
+	return r, nil
+}
+
+// Save appends the entity to the write-ahead log and then updates the in-memory index.
+// If the entity already exists, it will be updated.
+//
+// Parameters:
+//   - ctx: Context for cancellation, checked before anything is logged
+//   - shortURL: The entity to save or update
+//
+// Returns:
+//   - error: Error if the context is canceled or the log record cannot be written
+func (r *FileShortURLRepository) Save(ctx context.Context, shortURL *domain.ShortURL) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.append(walEntry{Op: walOpSave, ID: shortURL.ID(), Record: toRecord(shortURL)}); err != nil {
+		return err
+	}
+	// Once logged, the mutation must reach the index even if ctx is canceled meanwhile
+	if err := r.index.Save(context.Background(), shortURL); err != nil {
+		return err
+	}
+	return r.maybeSnapshot()
+}
+
+// Insert logs and stores a new ShortURL entity only if its identifier is not taken yet.
+// Nothing is written to the log when the identifier already exists.
+//
+// Parameters:
+//   - ctx: Context for cancellation, checked before anything is logged
+//   - shortURL: The entity to insert
+//
+// Returns:
+//   - error: domain.ErrShortURLAlreadyExists on conflict, or an error if the log record cannot be written
+func (r *FileShortURLRepository) Insert(ctx context.Context, shortURL *domain.ShortURL) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, _ := r.index.FindByID(context.Background(), shortURL.ID()); existing != nil {
+		return domain.ErrShortURLAlreadyExists
+	}
+	if err := r.append(walEntry{Op: walOpSave, ID: shortURL.ID(), Record: toRecord(shortURL)}); err != nil {
+		return err
+	}
+	if err := r.index.Insert(context.Background(), shortURL); err != nil {
+		return err
+	}
+	return r.maybeSnapshot()
+}
+
+// Update applies fn to a copy of the stored entity, logs the result and then stores it
+// in the index. The whole read-modify-write runs under the write lock, so concurrent
+// updates of the same entity are applied one after another. Nothing is logged when fn fails.
+//
+// Parameters:
+//   - ctx: Context for cancellation, checked before anything is logged
+//   - id: The unique identifier of the entity to update
+//   - fn: Modification to apply
+//
+// Returns:
+//   - *domain.ShortURL: The updated entity
+//   - error: domain.ErrShortURLNotFound if the entity does not exist, the error of fn,
+//     or an error if the log record cannot be written
+func (r *FileShortURLRepository) Update(ctx context.Context, id string, fn func(*domain.ShortURL) error) (*domain.ShortURL, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, _ := r.index.FindByID(context.Background(), id)
+	if existing == nil {
+		return nil, domain.ErrShortURLNotFound
+	}
+	updated := existing.Clone()
+	if err := fn(updated); err != nil {
+		return nil, err
+	}
+
+	if err := r.append(walEntry{Op: walOpSave, ID: id, Record: toRecord(updated)}); err != nil {
+		return nil, err
+	}
+	if err := r.index.Save(context.Background(), updated); err != nil {
+		return nil, err
+	}
+	return updated, r.maybeSnapshot()
+}
+
+// FindByID retrieves a ShortURL entity by its unique identifier from the in-memory index.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - id: The unique identifier to search for
+//
+// Returns:
+//   - *domain.ShortURL: The found entity or nil if not found
+//   - error: Context error if the context is canceled
+func (r *FileShortURLRepository) FindByID(ctx context.Context, id string) (*domain.ShortURL, error) {
+	return r.index.FindByID(ctx, id)
+}
+
+// FindAll retrieves all ShortURL entities from the in-memory index. Order is not guaranteed.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//
+// Returns:
+//   - []*domain.ShortURL: Slice of all stored entities
+//   - error: Context error if the context is canceled
+func (r *FileShortURLRepository) FindAll(ctx context.Context) ([]*domain.ShortURL, error) {
+	return r.index.FindAll(ctx)
+}
+
+// FindPage retrieves one page of entities matching the query from the in-memory index.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - query: Filters, sort order, cursor and page size
+//
+// Returns:
+//   - *domain.ShortURLPage: The requested page and the cursor for the next one
+//   - error: domain.ErrInvalidCursor if the cursor is malformed, or the context error
+func (r *FileShortURLRepository) FindPage(ctx context.Context, query domain.ShortURLQuery) (*domain.ShortURLPage, error) {
+	return r.index.FindPage(ctx, query)
+}
+
+// Delete appends a deletion record to the write-ahead log and removes the entity from the index.
+//
+// Parameters:
+//   - ctx: Context for cancellation, checked before anything is logged
+//   - id: The unique identifier of the entity to delete
+//
+// Returns:
+//   - error: domain.ErrShortURLNotFound if the entity was not found, or an error if the log record cannot be written
+func (r *FileShortURLRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, _ := r.index.FindByID(context.Background(), id); existing == nil {
+		return domain.ErrShortURLNotFound
+	}
+	if err := r.append(walEntry{Op: walOpDelete, ID: id}); err != nil {
+		return err
+	}
+	if err := r.index.Delete(context.Background(), id); err != nil {
+		return err
+	}
+	return r.maybeSnapshot()
+}
+
+// Snapshot writes the current state to a new snapshot file and truncates the write-ahead log.
+// The snapshot is written to a temporary file and atomically renamed, so a crash at any
+// point leaves either the old or the new snapshot in place. Replaying the log on top of
+// a newer snapshot is harmless because every record is idempotent.
+//
+// Returns:
+//   - error: Error if the snapshot cannot be written or the log cannot be reset
+func (r *FileShortURLRepository) Snapshot() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.snapshotLocked()
+}
+
+// Close flushes the write-ahead log, stops the background syncer and releases the log file.
+// The repository must not be used after Close returns.
+//
+// Returns:
+//   - error: Error if the final flush or close fails
+func (r *FileShortURLRepository) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.mu.Unlock()
+
+	if r.stop != nil {
+		close(r.stop)
+		<-r.done
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.wal.Sync(); err != nil {
+		_ = r.wal.Close()
+		return fmt.Errorf("failed to sync write-ahead log: %w", err)
+	}
+	return r.wal.Close()
+}
+
+// append writes a single checksummed record to the write-ahead log, honouring the
+// configured sync policy.
+// This method assumes the caller has already acquired the mutex lock.
+func (r *FileShortURLRepository) append(entry walEntry) error {
+	if r.closed {
+		return errors.New("repository is closed")
+	}
+	if r.walErr != nil {
+		return r.walErr
+	}
+
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode log record: %w", err)
+	}
+
+	offset, err := r.wal.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to locate end of write-ahead log: %w", err)
+	}
+
+	line := fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(payload), payload)
+	if _, err := r.wal.WriteString(line); err != nil {
+		// A partial write would leave a torn record that later appends bury in the
+		// middle of the log, which recovery rejects as corruption. Cut it off, and
+		// refuse further writes if that is not possible.
+		if rollbackErr := r.rollback(offset); rollbackErr != nil {
+			r.walErr = fmt.Errorf("write-ahead log is unusable after a failed append: %w", rollbackErr)
+		}
+		return fmt.Errorf("failed to append log record: %w", err)
+	}
+
+	if r.opts.SyncPolicy == SyncAlways {
+		if err := r.wal.Sync(); err != nil {
+			return fmt.Errorf("failed to sync write-ahead log: %w", err)
+		}
+	} else {
+		r.dirty = true
+	}
+
+	r.walRecords++
+	return nil
+}
+
+// rollback truncates the write-ahead log back to offset and repositions it there,
+// discarding the bytes of a failed append.
+// This method assumes the caller has already acquired the mutex lock.
+func (r *FileShortURLRepository) rollback(offset int64) error {
+	if err := r.wal.Truncate(offset); err != nil {
+		return err
+	}
+	_, err := r.wal.Seek(offset, io.SeekStart)
+	return err
+}
+
+// maybeSnapshot compacts the log once the configured threshold is reached.
+// It must only be called after the logged mutation has been applied to the index,
+// otherwise the snapshot would miss it. The caller must hold the mutex lock.
+func (r *FileShortURLRepository) maybeSnapshot() error {
+	if r.opts.SnapshotThreshold > 0 && r.walRecords >= r.opts.SnapshotThreshold {
+		return r.snapshotLocked()
+	}
+	return nil
+}
+
+// snapshotLocked implements Snapshot. The caller must hold the mutex lock.
+func (r *FileShortURLRepository) snapshotLocked() error {
+	shortURLs, err := r.index.FindAll(context.Background())
+	if err != nil {
+		return err
+	}
+
+	snapshot := snapshotFile{Version: snapshotVersion, Records: make([]*shortURLRecord, 0, len(shortURLs))}
+	for _, shortURL := range shortURLs {
+		snapshot.Records = append(snapshot.Records, toRecord(shortURL))
+	}
+
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	if err := writeFileAtomic(filepath.Join(r.dir, snapshotFileName), payload); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	// The snapshot now contains every logged mutation, so the log can start over.
+	if err := r.wal.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate write-ahead log: %w", err)
+	}
+	if _, err := r.wal.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind write-ahead log: %w", err)
+	}
+	if err := r.wal.Sync(); err != nil {
+		return fmt.Errorf("failed to sync write-ahead log: %w", err)
+	}
+
+	r.walRecords = 0
+	r.dirty = false
+	return nil
+}
+
+// loadSnapshot populates the index from the snapshot file, if one exists.
+func (r *FileShortURLRepository) loadSnapshot() error {
+	payload, err := os.ReadFile(filepath.Join(r.dir, snapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snapshot snapshotFile
+	if err := json.Unmarshal(payload, &snapshot); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	if snapshot.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version: %d", snapshot.Version)
+	}
+
+	for _, record := range snapshot.Records {
+		if err := r.index.Save(context.Background(), fromRecord(record)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayLog applies every valid record of the write-ahead log to the index and opens
+// the log for appending. A torn or corrupted final record is truncated away, while a
+// bad record followed by valid data is treated as unrecoverable corruption.
+func (r *FileShortURLRepository) replayLog() error {
+	path := filepath.Join(r.dir, walFileName)
+	wal, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600) // #nosec G304 -- path is built from the configured data directory
+	if err != nil {
+		return fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+
+	var goodOffset int64
+	reader := bufio.NewReader(wal)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) == 0 && errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			_ = wal.Close()
+			return fmt.Errorf("failed to read write-ahead log: %w", readErr)
+		}
+
+		entry, decodeErr := decodeWALLine(line)
+		if decodeErr != nil {
+			// Only the final record may be damaged; anything else means corruption.
+			if rest, _ := reader.Peek(1); len(rest) > 0 {
+				_ = wal.Close()
+				return fmt.Errorf("corrupted write-ahead log at offset %d: %w", goodOffset, decodeErr)
+			}
+			if err := wal.Truncate(goodOffset); err != nil {
+				_ = wal.Close()
+				return fmt.Errorf("failed to truncate torn log record: %w", err)
+			}
+			break
+		}
+
+		r.applyEntry(entry)
+		goodOffset += int64(len(line))
+		r.walRecords++
+	}
+
+	if _, err := wal.Seek(goodOffset, io.SeekStart); err != nil {
+		_ = wal.Close()
+		return fmt.Errorf("failed to position write-ahead log: %w", err)
+	}
+
+	r.wal = wal
+	return nil
+}
+
+// applyEntry replays a single log record against the index.
+func (r *FileShortURLRepository) applyEntry(entry *walEntry) {
+	switch entry.Op {
+	case walOpSave:
+		_ = r.index.Save(context.Background(), fromRecord(entry.Record))
+	case walOpDelete:
+		// The entity may already be absent when replaying over a newer snapshot.
+		_ = r.index.Delete(context.Background(), entry.ID)
+	}
+}
+
+// syncLoop periodically flushes the write-ahead log when SyncInterval is configured.
+func (r *FileShortURLRepository) syncLoop() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.opts.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			if r.dirty && !r.closed {
+				if err := r.wal.Sync(); err == nil {
+					r.dirty = false
+				}
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+// decodeWALLine verifies the checksum of a log line and decodes its payload.
+// A line is only valid if it is newline-terminated, its checksum matches and
+// it describes a known operation.
+func decodeWALLine(line []byte) (*walEntry, error) {
+	if len(line) == 0 || line[len(line)-1] != '\n' {
+		return nil, errors.New("incomplete record")
+	}
+
+	checksum, payload, found := bytes.Cut(bytes.TrimSuffix(line, []byte("\n")), []byte(" "))
+	if !found {
+		return nil, errors.New("malformed record")
+	}
+
+	var expected uint32
+	if _, err := fmt.Sscanf(string(checksum), "%08x", &expected); err != nil {
+		return nil, fmt.Errorf("malformed checksum: %w", err)
+	}
+	if crc32.ChecksumIEEE(payload) != expected {
+		return nil, errors.New("checksum mismatch")
+	}
+
+	var entry walEntry
+	if err := json.Unmarshal(payload, &entry); err != nil {
+		return nil, fmt.Errorf("malformed payload: %w", err)
+	}
+	switch {
+	case entry.Op == walOpSave && entry.Record != nil:
+	case entry.Op == walOpDelete && entry.ID != "":
+	default:
+		return nil, fmt.Errorf("unknown operation %q", entry.Op)
+	}
+	return &entry, nil
+}
+
+// writeFileAtomic writes data to a temporary file, syncs it and renames it over path,
+// then syncs the directory so the rename itself is durable.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600) // #nosec G304 -- path is built from the configured data directory
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dir.Close() }()
+	return dir.Sync()
+}
+
+// toRecord converts a domain entity into its serialized form.
+func toRecord(shortURL *domain.ShortURL) *shortURLRecord {
+	return &shortURLRecord{
+		ID:           shortURL.ID(),
+		LongURL:      shortURL.LongURL(),
+		ShortURL:     shortURL.ShortURL(),
+		CreatedAt:    shortURL.CreatedAt(),
+		Expiry:       shortURL.Expiry(),
+		IsActive:     shortURL.IsActive(),
+		UserMetadata: shortURL.UserMetadata(),
+		Region:       shortURL.Region(),
+		History:      toHistoryRecords(shortURL.History()),
+		State:        string(shortURL.State()),
+		Transitions:  toTransitionRecords(shortURL.Transitions()),
+		MaxClicks:    shortURL.MaxClicks(),
+		Clicks:       shortURL.Clicks(),
+		NotBefore:    shortURL.NotBefore(),
+		PreLaunchURL: shortURL.PreLaunchURL(),
+		PasswordHash: shortURL.PasswordHash(),
+		RoutingRules: toRoutingRuleRecords(shortURL.RoutingRules()),
+	}
+}
+
+// fromRecord reconstructs a domain entity from its serialized form.
+func fromRecord(record *shortURLRecord) *domain.ShortURL {
+	shortURL := domain.ReconstructShortURL(
+		record.ID,
+		record.LongURL,
+		record.ShortURL,
+		record.CreatedAt,
+		record.Expiry,
+		record.IsActive,
+		record.UserMetadata,
+	)
+	shortURL.AssignRegion(record.Region)
+	shortURL.RestoreHistory(fromHistoryRecords(record.History))
+	shortURL.RestoreClicks(record.MaxClicks, record.Clicks)
+	shortURL.RestoreSchedule(record.NotBefore, record.PreLaunchURL)
+	shortURL.Protect(record.PasswordHash)
+	// Stored rules were validated on creation, so they never exceed the limit
+	_ = shortURL.SetRoutingRules(fromRoutingRuleRecords(record.RoutingRules))
+	if record.State != "" {
+		shortURL.RestoreLifecycle(domain.LifecycleState(record.State), fromTransitionRecords(record.Transitions))
+	}
+	return shortURL
+}
+
+// toHistoryRecords converts a destination change history into its serialized form.
+func toHistoryRecords(history []domain.DestinationChange) []destinationChangeRecord {
+	if len(history) == 0 {
+		return nil
+	}
+	records := make([]destinationChangeRecord, len(history))
+	for i, change := range history {
+		records[i] = destinationChangeRecord{LongURL: change.LongURL, ChangedAt: change.ChangedAt, Actor: change.Actor}
+	}
+	return records
+}
+
+// fromHistoryRecords reconstructs a destination change history from its serialized form.
+func fromHistoryRecords(records []destinationChangeRecord) []domain.DestinationChange {
+	history := make([]domain.DestinationChange, len(records))
+	for i, record := range records {
+		history[i] = domain.DestinationChange{LongURL: record.LongURL, ChangedAt: record.ChangedAt, Actor: record.Actor}
+	}
+	return history
+}
+
+// toTransitionRecords converts a lifecycle transition log into its serialized form.
+func toTransitionRecords(transitions []domain.StateTransition) []stateTransitionRecord {
+	if len(transitions) == 0 {
+		return nil
+	}
+	records := make([]stateTransitionRecord, len(transitions))
+	for i, transition := range transitions {
+		records[i] = stateTransitionRecord{
+			From:   string(transition.From),
+			To:     string(transition.To),
+			Reason: transition.Reason,
+			Actor:  transition.Actor,
+			At:     transition.At,
+		}
+	}
+	return records
+}
+
+// fromTransitionRecords reconstructs a lifecycle transition log from its serialized form.
+func fromTransitionRecords(records []stateTransitionRecord) []domain.StateTransition {
+	transitions := make([]domain.StateTransition, len(records))
+	for i, record := range records {
+		transitions[i] = domain.StateTransition{
+			From:   domain.LifecycleState(record.From),
+			To:     domain.LifecycleState(record.To),
+			Reason: record.Reason,
+			Actor:  record.Actor,
+			At:     record.At,
+		}
+	}
+	return transitions
+}
+
+// toRoutingRuleRecords converts routing rules into their serialized form.
+func toRoutingRuleRecords(rules []domain.RoutingRule) []routingRuleRecord {
+	if len(rules) == 0 {
+		return nil
+	}
+	records := make([]routingRuleRecord, len(rules))
+	for i, rule := range rules {
+		devices := make([]string, len(rule.Devices))
+		for j, device := range rule.Devices {
+			devices[j] = string(device)
+		}
+		records[i] = routingRuleRecord{
+			Devices:   devices,
+			Countries: rule.Countries,
+			Languages: rule.Languages,
+			Referrers: rule.Referrers,
+			LongURL:   rule.LongURL,
+		}
+	}
+	return records
+}
+
+// fromRoutingRuleRecords reconstructs routing rules from their serialized form.
+func fromRoutingRuleRecords(records []routingRuleRecord) []domain.RoutingRule {
+	rules := make([]domain.RoutingRule, len(records))
+	for i, record := range records {
+		var devices []domain.Device
+		for _, device := range record.Devices {
+			devices = append(devices, domain.Device(device))
+		}
+		rules[i] = domain.RoutingRule{
+			Devices:   devices,
+			Countries: record.Countries,
+			Languages: record.Languages,
+			Referrers: record.Referrers,
+			LongURL:   record.LongURL,
+		}
+	}
+	return rules
+}
@@ -0,0 +1,639 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+)
+
+// openTestFileRepository opens a repository in dir and closes it when the test ends.
+func openTestFileRepository(t *testing.T, dir string, opts FileRepositoryOptions) *FileShortURLRepository {
+	t.Helper()
+
+	repo, err := NewFileShortURLRepository(dir, opts)
+	if err != nil {
+		t.Fatalf("failed to open repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestNewFileShortURLRepository(t *testing.T) {
+	repo := openTestFileRepository(t, t.TempDir(), DefaultFileRepositoryOptions())
+	if repo == nil {
+		t.Error("expected repository to be created")
+	}
+
+	if _, err := NewFileShortURLRepository(t.TempDir(), FileRepositoryOptions{SyncPolicy: SyncInterval}); err == nil {
+		t.Error("expected error for non-positive sync interval")
+	}
+}
+
+func TestFileShortURLRepository_Save(t *testing.T) {
+	repo := openTestFileRepository(t, t.TempDir(), DefaultFileRepositoryOptions())
+
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+
+	if err := repo.Save(context.Background(), shortURL); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	saved, _ := repo.FindByID(context.Background(), "abc123")
+	if saved == nil {
+		t.Fatal("expected URL to be saved")
+	}
+
+	if saved.ID() != "abc123" {
+		t.Errorf("expected ID 'abc123', got %q", saved.ID())
+	}
+}
+
+func TestFileShortURLRepository_Save_Update(t *testing.T) {
+	repo := openTestFileRepository(t, t.TempDir(), DefaultFileRepositoryOptions())
+
+	// Save initial URL
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	repo.Save(context.Background(), shortURL)
+
+	// Update the URL (deactivate it)
+	shortURL.Deactivate()
+	if err := repo.Save(context.Background(), shortURL); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	saved, _ := repo.FindByID(context.Background(), "abc123")
+	if saved.IsActive() {
+		t.Error("expected URL to be deactivated")
+	}
+}
+
+func TestFileShortURLRepository_Insert(t *testing.T) {
+	dir := t.TempDir()
+	repo := openTestFileRepository(t, dir, DefaultFileRepositoryOptions())
+
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	if err := repo.Insert(context.Background(), shortURL); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	duplicate, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://other.com"), "http://short.ly/abc123", nil, nil)
+	if err := repo.Insert(context.Background(), duplicate); !errors.Is(err, domain.ErrShortURLAlreadyExists) {
+		t.Errorf("expected ErrShortURLAlreadyExists, got %v", err)
+	}
+	repo.Close()
+
+	// Only the first insert may have reached the log
+	reopened := openTestFileRepository(t, dir, DefaultFileRepositoryOptions())
+	found, _ := reopened.FindByID(context.Background(), "abc123")
+	if found == nil || found.LongURL() != "https://example.com" {
+		t.Errorf("expected original URL after recovery, got %v", found)
+	}
+}
+
+func TestFileShortURLRepository_FindByID(t *testing.T) {
+	repo := openTestFileRepository(t, t.TempDir(), DefaultFileRepositoryOptions())
+
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	repo.Save(context.Background(), shortURL)
+
+	tests := []struct {
+		name      string
+		id        string
+		expectNil bool
+	}{
+		{
+			name:      "existing URL",
+			id:        "abc123",
+			expectNil: false,
+		},
+		{
+			name:      "non-existing URL",
+			id:        "notfound",
+			expectNil: true,
+		},
+		{
+			name:      "empty ID",
+			id:        "",
+			expectNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := repo.FindByID(context.Background(), tt.id)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if tt.expectNil && found != nil {
+				t.Error("expected nil but got URL")
+				return
+			}
+
+			if !tt.expectNil && found == nil {
+				t.Error("expected URL but got nil")
+				return
+			}
+
+			if found != nil && found.ID() != tt.id {
+				t.Errorf("expected ID %q, got %q", tt.id, found.ID())
+			}
+		})
+	}
+}
+
+func TestFileShortURLRepository_FindAll(t *testing.T) {
+	tests := []struct {
+		name        string
+		setupURLs   int
+		expectCount int
+	}{
+		{
+			name:        "empty repository",
+			setupURLs:   0,
+			expectCount: 0,
+		},
+		{
+			name:        "single URL",
+			setupURLs:   1,
+			expectCount: 1,
+		},
+		{
+			name:        "multiple URLs",
+			setupURLs:   3,
+			expectCount: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := openTestFileRepository(t, t.TempDir(), DefaultFileRepositoryOptions())
+
+			for i := 0; i < tt.setupURLs; i++ {
+				id := "url" + string(rune('0'+i))
+				url := "https://example" + string(rune('0'+i)) + ".com"
+				shortURL, _ := domain.NewShortURL(id, domain.MustParseLongURL(url), "http://short.ly/"+id, nil, nil)
+				repo.Save(context.Background(), shortURL)
+			}
+
+			urls, err := repo.FindAll(context.Background())
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if len(urls) != tt.expectCount {
+				t.Errorf("expected %d URLs, got %d", tt.expectCount, len(urls))
+			}
+		})
+	}
+}
+
+func TestFileShortURLRepository_Delete(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "delete existing URL",
+			id:          "abc123",
+			expectError: false,
+		},
+		{
+			name:        "delete non-existing URL",
+			id:          "notfound",
+			expectError: true,
+			errorMsg:    "short URL not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := openTestFileRepository(t, t.TempDir(), DefaultFileRepositoryOptions())
+			shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+			repo.Save(context.Background(), shortURL)
+
+			err := repo.Delete(context.Background(), tt.id)
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+					return
+				}
+				if err.Error() != tt.errorMsg {
+					t.Errorf("expected error message %q, got %q", tt.errorMsg, err.Error())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if found, _ := repo.FindByID(context.Background(), tt.id); found != nil {
+				t.Error("expected URL to be deleted")
+			}
+		})
+	}
+}
+
+func TestFileShortURLRepository_ConcurrentAccess(t *testing.T) {
+	repo := openTestFileRepository(t, t.TempDir(), DefaultFileRepositoryOptions())
+
+	done := make(chan bool, 10)
+
+	for i := 0; i < 10; i++ {
+		go func(index int) {
+			id := "url" + string(rune('0'+index))
+			url := "https://example" + string(rune('0'+index)) + ".com"
+			shortURL, _ := domain.NewShortURL(id, domain.MustParseLongURL(url), "http://short.ly/"+id, nil, nil)
+			repo.Save(context.Background(), shortURL)
+			done <- true
+		}(i)
+	}
+
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+
+	urls, _ := repo.FindAll(context.Background())
+	if len(urls) != 10 {
+		t.Errorf("expected 10 URLs after concurrent saves, got %d", len(urls))
+	}
+
+	for i := 0; i < 10; i++ {
+		go func(index int) {
+			id := "url" + string(rune('0'+index))
+			found, _ := repo.FindByID(context.Background(), id)
+			if found == nil {
+				t.Errorf("expected to find URL with ID %q", id)
+			}
+			done <- true
+		}(i)
+	}
+
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+}
+
+func TestFileShortURLRepository_Recovery(t *testing.T) {
+	tests := []struct {
+		name string
+		opts FileRepositoryOptions
+	}{
+		{
+			name: "sync always",
+			opts: FileRepositoryOptions{SyncPolicy: SyncAlways},
+		},
+		{
+			name: "sync interval",
+			opts: FileRepositoryOptions{SyncPolicy: SyncInterval, SyncInterval: 10 * time.Millisecond},
+		},
+		{
+			name: "sync never",
+			opts: FileRepositoryOptions{SyncPolicy: SyncNever},
+		},
+		{
+			name: "with snapshots",
+			opts: FileRepositoryOptions{SyncPolicy: SyncAlways, SnapshotThreshold: 2},
+		},
+		{
+			name: "snapshot after every write",
+			opts: FileRepositoryOptions{SyncPolicy: SyncAlways, SnapshotThreshold: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			expiry := time.Now().Add(24 * time.Hour).UTC()
+			notBefore := time.Now().Add(-time.Hour).UTC()
+
+			repo, err := NewFileShortURLRepository(dir, tt.opts)
+			if err != nil {
+				t.Fatalf("failed to open repository: %v", err)
+			}
+
+			kept, _ := domain.NewShortURL("keep", domain.MustParseLongURL("https://example.com/keep"), "http://short.ly/keep", &expiry, map[string]interface{}{"source": "api"})
+			kept.AssignRegion("1")
+			kept.LimitClicks(5)
+			kept.Protect("pbkdf2-sha256$1$c2FsdA$aGFzaA")
+			appStore, _ := domain.NewRoutingRule([]string{"ios"}, []string{"JP"}, []string{"ja"}, []string{"news.example"}, domain.MustParseLongURL("https://apps.apple.com/jp/app/id1"))
+			kept.SetRoutingRules([]domain.RoutingRule{appStore})
+			kept.Schedule(notBefore, domain.MustParseLongURL("https://example.com/soon"))
+			deactivated, _ := domain.NewShortURL("off", domain.MustParseLongURL("https://example.com/off"), "http://short.ly/off", nil, nil)
+			deleted, _ := domain.NewShortURL("gone", domain.MustParseLongURL("https://example.com/gone"), "http://short.ly/gone", nil, nil)
+			repo.Save(context.Background(), kept)
+			repo.Save(context.Background(), deactivated)
+			repo.Save(context.Background(), deleted)
+			changedAt := time.Now().UTC()
+			deactivated.UpdateDestination(domain.MustParseLongURL("https://example.com/moved"), "alice", changedAt)
+			deactivated.Transition(domain.StateBlocked, "phishing report", "trust-team", changedAt)
+			repo.Save(context.Background(), deactivated)
+			repo.Delete(context.Background(), "gone")
+			repo.Update(context.Background(), "keep", func(u *domain.ShortURL) error {
+				_, err := u.RecordClick(time.Now())
+				return err
+			})
+
+			if err := repo.Close(); err != nil {
+				t.Fatalf("failed to close repository: %v", err)
+			}
+
+			reopened := openTestFileRepository(t, dir, tt.opts)
+
+			urls, _ := reopened.FindAll(context.Background())
+			if len(urls) != 2 {
+				t.Errorf("expected 2 URLs after recovery, got %d", len(urls))
+			}
+
+			found, _ := reopened.FindByID(context.Background(), "keep")
+			if found == nil {
+				t.Fatal("expected URL to survive restart")
+			}
+			if found.LongURL() != "https://example.com/keep" {
+				t.Errorf("expected long URL 'https://example.com/keep', got %q", found.LongURL())
+			}
+			if found.Expiry() == nil || !found.Expiry().Equal(expiry) {
+				t.Errorf("expected expiry %v, got %v", expiry, found.Expiry())
+			}
+			if found.UserMetadata()["source"] != "api" {
+				t.Errorf("expected metadata source 'api', got %v", found.UserMetadata()["source"])
+			}
+			if found.Region() != "1" {
+				t.Errorf("expected region '1', got %q", found.Region())
+			}
+			if found.NotBefore() == nil || !found.NotBefore().Equal(notBefore) || found.PreLaunchURL() != "https://example.com/soon" {
+				t.Errorf("expected schedule to survive, got %v %q", found.NotBefore(), found.PreLaunchURL())
+			}
+			if found.PasswordHash() != "pbkdf2-sha256$1$c2FsdA$aGFzaA" {
+				t.Errorf("expected password hash to survive, got %q", found.PasswordHash())
+			}
+			if rules := found.RoutingRules(); len(rules) != 1 || !reflect.DeepEqual(rules[0], appStore) {
+				t.Errorf("expected routing rules to survive, got %+v", rules)
+			}
+			if found.MaxClicks() != 5 || found.Clicks() != 1 {
+				t.Errorf("expected 1 of 5 clicks, got %d of %d", found.Clicks(), found.MaxClicks())
+			}
+
+			off, _ := reopened.FindByID(context.Background(), "off")
+			if off == nil || off.IsActive() {
+				t.Fatal("expected deactivated URL to stay inactive after restart")
+			}
+			history := off.History()
+			if off.LongURL() != "https://example.com/moved" || len(history) != 1 ||
+				history[0].LongURL != "https://example.com/off" || !history[0].ChangedAt.Equal(changedAt) || history[0].Actor != "alice" {
+				t.Errorf("expected destination change to survive restart, got %q with history %+v", off.LongURL(), history)
+			}
+			transitions := off.Transitions()
+			if off.State() != domain.StateBlocked || len(transitions) != 1 || transitions[0].Reason != "phishing report" ||
+				transitions[0].Actor != "trust-team" || !transitions[0].At.Equal(changedAt) {
+				t.Errorf("expected lifecycle to survive restart, got %q with transitions %+v", off.State(), transitions)
+			}
+
+			if gone, _ := reopened.FindByID(context.Background(), "gone"); gone != nil {
+				t.Error("expected deleted URL to stay deleted after restart")
+			}
+		})
+	}
+}
+
+func TestFileShortURLRepository_Snapshot(t *testing.T) {
+	dir := t.TempDir()
+	repo, _ := NewFileShortURLRepository(dir, DefaultFileRepositoryOptions())
+
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	repo.Save(context.Background(), shortURL)
+
+	if err := repo.Snapshot(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, walFileName))
+	if err != nil {
+		t.Fatalf("failed to stat log: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("expected log to be truncated after snapshot, got %d bytes", info.Size())
+	}
+
+	// Writes after the snapshot go to the fresh log and must be replayed on top of it.
+	other, _ := domain.NewShortURL("def456", domain.MustParseLongURL("https://example.org"), "http://short.ly/def456", nil, nil)
+	repo.Save(context.Background(), other)
+	repo.Close()
+
+	reopened := openTestFileRepository(t, dir, DefaultFileRepositoryOptions())
+	urls, _ := reopened.FindAll(context.Background())
+	if len(urls) != 2 {
+		t.Errorf("expected 2 URLs after recovery, got %d", len(urls))
+	}
+}
+
+func TestFileShortURLRepository_TornRecord(t *testing.T) {
+	dir := t.TempDir()
+	repo, _ := NewFileShortURLRepository(dir, DefaultFileRepositoryOptions())
+
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	repo.Save(context.Background(), shortURL)
+	repo.Close()
+
+	// Simulate a crash in the middle of appending the next record.
+	walPath := filepath.Join(dir, walFileName)
+	intact, _ := os.ReadFile(walPath)
+	f, _ := os.OpenFile(walPath, os.O_APPEND|os.O_WRONLY, 0o600)
+	f.WriteString(`1234abcd {"op":"save","id":"def4`)
+	f.Close()
+
+	reopened := openTestFileRepository(t, dir, DefaultFileRepositoryOptions())
+
+	urls, _ := reopened.FindAll(context.Background())
+	if len(urls) != 1 {
+		t.Errorf("expected 1 URL after recovery, got %d", len(urls))
+	}
+
+	recovered, _ := os.ReadFile(walPath)
+	if len(recovered) != len(intact) {
+		t.Errorf("expected torn record to be truncated to %d bytes, got %d", len(intact), len(recovered))
+	}
+
+	// The repository must keep appending cleanly after recovery.
+	other, _ := domain.NewShortURL("def456", domain.MustParseLongURL("https://example.org"), "http://short.ly/def456", nil, nil)
+	if err := reopened.Save(context.Background(), other); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// shortWriteWAL wraps the write-ahead log and fails appends after writing only part
+// of the record, as a full disk would.
+type shortWriteWAL struct {
+	walFile
+	truncateErr error // Returned by Truncate when set
+}
+
+func (w *shortWriteWAL) WriteString(s string) (int, error) {
+	n, _ := w.walFile.WriteString(s[:len(s)/2])
+	return n, errors.New("no space left on device")
+}
+
+func (w *shortWriteWAL) Truncate(size int64) error {
+	if w.truncateErr != nil {
+		return w.truncateErr
+	}
+	return w.walFile.Truncate(size)
+}
+
+func TestFileShortURLRepository_FailedAppend(t *testing.T) {
+	first, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	failed, _ := domain.NewShortURL("def456", domain.MustParseLongURL("https://example.org"), "http://short.ly/def456", nil, nil)
+	last, _ := domain.NewShortURL("ghi789", domain.MustParseLongURL("https://example.net"), "http://short.ly/ghi789", nil, nil)
+
+	t.Run("torn bytes are rolled back", func(t *testing.T) {
+		dir := t.TempDir()
+		repo, _ := NewFileShortURLRepository(dir, DefaultFileRepositoryOptions())
+		repo.Save(context.Background(), first)
+
+		wal := repo.wal
+		repo.wal = &shortWriteWAL{walFile: wal}
+		if err := repo.Save(context.Background(), failed); err == nil {
+			t.Fatal("expected error for a failed append")
+		}
+		repo.wal = wal
+
+		// The repository keeps working once the disk recovers.
+		if err := repo.Save(context.Background(), last); err != nil {
+			t.Fatalf("unexpected error after a failed append: %v", err)
+		}
+		repo.Close()
+
+		reopened, err := NewFileShortURLRepository(dir, DefaultFileRepositoryOptions())
+		if err != nil {
+			t.Fatalf("expected recovery to succeed, got %v", err)
+		}
+		defer reopened.Close()
+
+		for _, id := range []string{"abc123", "ghi789"} {
+			if found, _ := reopened.FindByID(context.Background(), id); found == nil {
+				t.Errorf("expected %s to be recovered", id)
+			}
+		}
+		if found, _ := reopened.FindByID(context.Background(), "def456"); found != nil {
+			t.Error("expected the failed save not to be recovered")
+		}
+	})
+
+	t.Run("failed rollback stops writes", func(t *testing.T) {
+		dir := t.TempDir()
+		repo, _ := NewFileShortURLRepository(dir, DefaultFileRepositoryOptions())
+		repo.Save(context.Background(), first)
+
+		wal := repo.wal
+		repo.wal = &shortWriteWAL{walFile: wal, truncateErr: errors.New("read-only file system")}
+		if err := repo.Save(context.Background(), failed); err == nil {
+			t.Fatal("expected error for a failed append")
+		}
+		repo.wal = wal
+
+		// Appending after the torn bytes would corrupt the log, so writes are refused.
+		if err := repo.Save(context.Background(), last); err == nil {
+			t.Error("expected writes to be refused after a failed rollback")
+		}
+		repo.Close()
+
+		reopened, err := NewFileShortURLRepository(dir, DefaultFileRepositoryOptions())
+		if err != nil {
+			t.Fatalf("expected recovery to succeed, got %v", err)
+		}
+		defer reopened.Close()
+
+		urls, _ := reopened.FindAll(context.Background())
+		if len(urls) != 1 {
+			t.Errorf("expected 1 URL after recovery, got %d", len(urls))
+		}
+	})
+}
+
+func TestFileShortURLRepository_CorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+	repo, _ := NewFileShortURLRepository(dir, DefaultFileRepositoryOptions())
+
+	first, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	second, _ := domain.NewShortURL("def456", domain.MustParseLongURL("https://example.org"), "http://short.ly/def456", nil, nil)
+	repo.Save(context.Background(), first)
+	repo.Save(context.Background(), second)
+	repo.Close()
+
+	// Flip a byte inside the first record so its checksum no longer matches.
+	walPath := filepath.Join(dir, walFileName)
+	data, _ := os.ReadFile(walPath)
+	data[20] ^= 0xff
+	os.WriteFile(walPath, data, 0o600)
+
+	if _, err := NewFileShortURLRepository(dir, DefaultFileRepositoryOptions()); err == nil {
+		t.Error("expected error for corrupted record followed by valid data")
+	}
+}
+
+func TestFileShortURLRepository_Closed(t *testing.T) {
+	repo, _ := NewFileShortURLRepository(t.TempDir(), DefaultFileRepositoryOptions())
+	repo.Close()
+
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	if err := repo.Save(context.Background(), shortURL); err == nil {
+		t.Error("expected error when saving to a closed repository")
+	}
+
+	if err := repo.Close(); err != nil {
+		t.Errorf("expected second close to be a no-op, got %v", err)
+	}
+}
+
+func TestFileShortURLRepository_CanceledContext(t *testing.T) {
+	dir := t.TempDir()
+	repo := openTestFileRepository(t, dir, DefaultFileRepositoryOptions())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	if err := repo.Save(ctx, shortURL); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	// A canceled write must not reach the log
+	info, _ := os.Stat(filepath.Join(dir, walFileName))
+	if info.Size() != 0 {
+		t.Errorf("expected empty log after canceled write, got %d bytes", info.Size())
+	}
+}
+
+func TestFromRecord_LegacyState(t *testing.T) {
+	// Records written before lifecycle states only carry the active flag
+	tests := []struct {
+		name     string
+		record   shortURLRecord
+		expected domain.LifecycleState
+	}{
+		{"legacy active", shortURLRecord{ID: "a", IsActive: true}, domain.StateActive},
+		{"legacy inactive", shortURLRecord{ID: "b", IsActive: false}, domain.StateDeactivated},
+		{"explicit state", shortURLRecord{ID: "c", IsActive: false, State: "paused"}, domain.StatePaused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if state := fromRecord(&tt.record).State(); state != tt.expected {
+				t.Errorf("expected state %q, got %q", tt.expected, state)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+)
+
+// maxFilteredDraws bounds how many IDs are drawn from the underlying generator for a
+// single allowed ID. A blocklist rejects a small share of random IDs, so hitting the
+// bound means the blocklist is misconfigured rather than unlucky.
+const maxFilteredDraws = 100
+
+// FilteringKeyGenerationService implements the KeyGenerationService interface by
+// discarding IDs of an underlying generator that a blocklist rejects, such as IDs that
+// spell offensive words or equal a route prefix. Discarded IDs are never handed out.
+// It should wrap the outermost generator, so that the complete ID is checked.
+type FilteringKeyGenerationService struct {
+	kgs       domain.KeyGenerationService // Generator of the candidate IDs
+	blocklist *domain.Blocklist           // Words the IDs must not contain or equal
+}
+
+// NewFilteringKeyGenerationService creates a key generation service that filters the IDs
+// of kgs through a blocklist.
+//
+// Parameters:
+//   - kgs: Generator of the candidate IDs
+//   - blocklist: Words the IDs must not contain or equal
+//
+// Returns:
+//   - *FilteringKeyGenerationService: Service instance
+func NewFilteringKeyGenerationService(kgs domain.KeyGenerationService, blocklist *domain.Blocklist) *FilteringKeyGenerationService {
+	return &FilteringKeyGenerationService{
+		kgs:       kgs,
+		blocklist: blocklist,
+	}
+}
+
+// GenerateUniqueID generates a single identifier that the blocklist allows.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//
+// Returns:
+//   - string: An allowed ID of the underlying generator
+//   - error: Error of the underlying generator, or an error if no allowed ID was drawn
+func (k *FilteringKeyGenerationService) GenerateUniqueID(ctx context.Context) (string, error) {
+	for range maxFilteredDraws {
+		id, err := k.kgs.GenerateUniqueID(ctx)
+		if err != nil {
+			return "", err
+		}
+		if k.blocklist.Allows(id) {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("blocklist rejected %d generated IDs in a row", maxFilteredDraws)
+}
+
+// GetMultipleIDs generates multiple identifiers that the blocklist allows. Rejected IDs
+// are replaced by drawing further batches from the underlying generator.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - count: Number of IDs to generate
+//
+// Returns:
+//   - []string: count allowed IDs of the underlying generator
+//   - error: Error of the underlying generator, or an error if too many IDs were rejected
+func (k *FilteringKeyGenerationService) GetMultipleIDs(ctx context.Context, count int) ([]string, error) {
+	allowed := make([]string, 0, count)
+	for draws := 0; len(allowed) < count; {
+		if draws >= count*maxFilteredDraws {
+			return nil, fmt.Errorf("blocklist rejected %d of %d generated IDs", draws-len(allowed), draws)
+		}
+		ids, err := k.kgs.GetMultipleIDs(ctx, count-len(allowed))
+		if err != nil {
+			return nil, err
+		}
+		draws += len(ids)
+		for _, id := range ids {
+			if k.blocklist.Allows(id) {
+				allowed = append(allowed, id)
+			}
+		}
+	}
+	return allowed, nil
+}
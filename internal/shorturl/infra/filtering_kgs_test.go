@@ -0,0 +1,107 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+)
+
+// sequenceKGS hands out a fixed sequence of IDs, then fails.
+type sequenceKGS struct {
+	ids []string
+}
+
+func (k *sequenceKGS) GenerateUniqueID(ctx context.Context) (string, error) {
+	if len(k.ids) == 0 {
+		return "", ErrIDSpaceExhausted
+	}
+	id := k.ids[0]
+	k.ids = k.ids[1:]
+	return id, nil
+}
+
+func (k *sequenceKGS) GetMultipleIDs(ctx context.Context, count int) ([]string, error) {
+	if len(k.ids) == 0 {
+		return nil, ErrIDSpaceExhausted
+	}
+	n := min(count, len(k.ids))
+	ids := k.ids[:n]
+	k.ids = k.ids[n:]
+	return ids, nil
+}
+
+func TestFilteringKeyGenerationService(t *testing.T) {
+	blocklist := domain.NewBlocklist([]string{"hell"}, domain.DefaultReservedWords())
+	sequence := []string{"admin", "aaa0001", "xh3llox", "aaa0002", "aaa0003", "he11000", "aaa0004"}
+
+	tests := []struct {
+		name        string
+		generate    func(k *FilteringKeyGenerationService) ([]string, error)
+		expected    []string
+		expectError error
+	}{
+		{
+			name: "GenerateUniqueID skips rejected IDs",
+			generate: func(k *FilteringKeyGenerationService) ([]string, error) {
+				var ids []string
+				for range 3 {
+					id, err := k.GenerateUniqueID(context.Background())
+					if err != nil {
+						return nil, err
+					}
+					ids = append(ids, id)
+				}
+				return ids, nil
+			},
+			expected: []string{"aaa0001", "aaa0002", "aaa0003"},
+		},
+		{
+			name: "GetMultipleIDs tops up rejected IDs",
+			generate: func(k *FilteringKeyGenerationService) ([]string, error) {
+				return k.GetMultipleIDs(context.Background(), 4)
+			},
+			expected: []string{"aaa0001", "aaa0002", "aaa0003", "aaa0004"},
+		},
+		{
+			name: "underlying error is returned",
+			generate: func(k *FilteringKeyGenerationService) ([]string, error) {
+				return k.GetMultipleIDs(context.Background(), 5)
+			},
+			expectError: ErrIDSpaceExhausted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kgs := NewFilteringKeyGenerationService(&sequenceKGS{ids: append([]string(nil), sequence...)}, blocklist)
+			ids, err := tt.generate(kgs)
+			if tt.expectError != nil {
+				if !errors.Is(err, tt.expectError) {
+					t.Errorf("expected %v, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
+
+func TestFilteringKeyGenerationService_AllRejected(t *testing.T) {
+	ids := make([]string, maxFilteredDraws)
+	for i := range ids {
+		ids[i] = "admin"
+	}
+	kgs := NewFilteringKeyGenerationService(&sequenceKGS{ids: ids}, domain.NewBlocklist(nil, []string{"admin"}))
+
+	if _, err := kgs.GenerateUniqueID(context.Background()); err == nil || errors.Is(err, ErrIDSpaceExhausted) {
+		t.Errorf("expected blocklist error, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package infra
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"math/big"
+	"net/url"
+	"strings"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+)
+
+// SHA256HashKeyGenerationService implements the HashKeyGenerationService interface by
+// hashing the normalized long URL with SHA-256 and writing the digest in the profile's
+// alphabet. The first candidate is the leading window of the encoded digest; further
+// candidates slide the window one character at a time, and once a digest is used up
+// the digest is hashed again to extend the sequence. Equal URLs therefore always
+// produce the same candidates, while a collision only costs a repository lookup.
+type SHA256HashKeyGenerationService struct {
+	profile *domain.IDProfile // Alphabet and length of the candidate IDs
+}
+
+// NewSHA256HashKeyGenerationService creates a hash-based key generation service.
+//
+// Parameters:
+//   - profile: Alphabet and length of generated IDs; the 7-character Base62 profile when nil
+//
+// Returns:
+//   - *SHA256HashKeyGenerationService: Service instance
+func NewSHA256HashKeyGenerationService(profile *domain.IDProfile) *SHA256HashKeyGenerationService {
+	if profile == nil {
+		profile = base62Profile
+	}
+	return &SHA256HashKeyGenerationService{
+		profile: profile,
+	}
+}
+
+// NormalizeURL returns the canonical form of a long URL: the scheme and host are
+// lowercased, default ports and the fragment are removed, and an empty path becomes "/".
+// The path and query are kept as they are, since servers may treat them case-sensitively.
+//
+// Parameters:
+//   - longURL: The long URL to normalize
+//
+// Returns:
+//   - string: The canonical URL
+//   - error: Error if the URL cannot be parsed
+func (k *SHA256HashKeyGenerationService) NormalizeURL(longURL string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(longURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid long URL: %w", err)
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	if port := parsed.Port(); (parsed.Scheme == "http" && port == "80") || (parsed.Scheme == "https" && port == "443") {
+		parsed.Host = parsed.Hostname()
+	}
+	if parsed.Host != "" && parsed.Path == "" {
+		parsed.Path = "/"
+	}
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+
+	return parsed.String(), nil
+}
+
+// CandidateIDs returns count candidate identifiers for the long URL in probing order.
+//
+// Parameters:
+//   - longURL: The long URL; it is normalized first
+//   - count: Number of candidates to return
+//
+// Returns:
+//   - []string: Candidate IDs of the profile's length
+//   - error: Error if the URL cannot be parsed
+func (k *SHA256HashKeyGenerationService) CandidateIDs(longURL string, count int) ([]string, error) {
+	normalized, err := k.NormalizeURL(longURL)
+	if err != nil {
+		return nil, err
+	}
+
+	length := k.profile.Length()
+	candidates := make([]string, 0, count)
+	digest := sha256.Sum256([]byte(normalized))
+	for len(candidates) < count {
+		encoded := k.encodeDigest(digest)
+		for start := 0; start+length <= len(encoded) && len(candidates) < count; start++ {
+			candidates = append(candidates, encoded[start:start+length])
+		}
+		digest = sha256.Sum256(digest[:])
+	}
+	return candidates, nil
+}
+
+// encodeDigest writes a digest in the profile's alphabet, keeping only the digits that
+// carry a full digit's worth of hash bits so that every character is close to uniform.
+// At least one window is always returned, even for profiles longer than the digest.
+func (k *SHA256HashKeyGenerationService) encodeDigest(digest [sha256.Size]byte) string {
+	alphabet := k.profile.Alphabet()
+	base := big.NewInt(int64(len(alphabet)))
+	digits := int(float64(sha256.Size*8) / math.Log2(float64(len(alphabet))))
+	digits = max(digits, k.profile.Length())
+
+	value := new(big.Int).SetBytes(digest[:])
+	digit := new(big.Int)
+	encoded := make([]byte, digits)
+	for i := range encoded {
+		value.DivMod(value, base, digit)
+		encoded[i] = alphabet[digit.Int64()]
+	}
+	return string(encoded)
+}
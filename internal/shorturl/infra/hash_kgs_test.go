@@ -0,0 +1,107 @@
+package infra
+
+import (
+	"testing"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+)
+
+func TestSHA256HashKeyGenerationService_NormalizeURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		longURL  string
+		expected string
+	}{
+		{"already canonical", "https://example.com/path?q=1", "https://example.com/path?q=1"},
+		{"case of scheme and host", "HTTPS://Example.COM/Path", "https://example.com/Path"},
+		{"default https port", "https://example.com:443/a", "https://example.com/a"},
+		{"default http port", "http://example.com:80/a", "http://example.com/a"},
+		{"non-default port kept", "https://example.com:8443/a", "https://example.com:8443/a"},
+		{"empty path", "https://example.com", "https://example.com/"},
+		{"fragment dropped", "https://example.com/a#section", "https://example.com/a"},
+		{"surrounding space", "  https://example.com/a ", "https://example.com/a"},
+	}
+
+	k := NewSHA256HashKeyGenerationService(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized, err := k.NormalizeURL(tt.longURL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if normalized != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, normalized)
+			}
+		})
+	}
+
+	if _, err := k.NormalizeURL("http://[::1"); err == nil {
+		t.Error("expected error for unparsable URL")
+	}
+}
+
+func TestSHA256HashKeyGenerationService_CandidateIDs(t *testing.T) {
+	k := NewSHA256HashKeyGenerationService(nil)
+
+	// Candidates are deterministic and shared by equivalent URLs
+	first, err := k.CandidateIDs("https://example.com/a", 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := k.CandidateIDs("HTTPS://EXAMPLE.com:443/a#top", 100)
+	if len(first) != 100 || len(second) != 100 {
+		t.Fatalf("expected 100 candidates, got %d and %d", len(first), len(second))
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("candidate %d differs: %q vs %q", i, first[i], second[i])
+		}
+		if !base62Profile.Matches(first[i]) {
+			t.Errorf("candidate %q is not a 7-character Base62 ID", first[i])
+		}
+	}
+
+	// Successive candidates are sliding windows over the encoded digest
+	if first[0][1:] != first[1][:base62IDLength-1] {
+		t.Errorf("expected overlapping windows, got %q and %q", first[0], first[1])
+	}
+
+	// Different destinations get different candidates
+	other, _ := k.CandidateIDs("https://example.com/b", 1)
+	if other[0] == first[0] {
+		t.Errorf("expected different first candidates, both %q", first[0])
+	}
+}
+
+func TestSHA256HashKeyGenerationService_IDProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		alphabet string
+		length   int
+	}{
+		{"lowercase", domain.AlphabetLowercase, 6},
+		{"base58", domain.AlphabetBase58, 9},
+		{"binary longer than one digest", "01", 62},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := domain.NewIDProfile(tt.alphabet, tt.length)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			candidates, err := NewSHA256HashKeyGenerationService(profile).CandidateIDs("https://example.com", 300)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(candidates) != 300 {
+				t.Fatalf("expected 300 candidates, got %d", len(candidates))
+			}
+			for _, id := range candidates {
+				if !profile.Matches(id) {
+					t.Errorf("candidate %q does not match the profile", id)
+				}
+			}
+		})
+	}
+}
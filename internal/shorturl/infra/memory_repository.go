@@ -3,7 +3,8 @@
 package infra
 
 import (
-	"errors"
+	"context"
+	"sort"
 	"sync"
 
 	"github.com/oharai/short-url/internal/shorturl/domain"
@@ -32,11 +33,16 @@ func NewMemoryShortURLRepository() domain.ShortURLRepository {
 // Uses write lock to ensure thread safety during updates.
 //
 // Parameters:
+//   - ctx: Context for cancellation
 //   - shortURL: The entity to save or update
 //
 // Returns:
-//   - error: Always nil for this implementation
-func (r *MemoryShortURLRepository) Save(shortURL *domain.ShortURL) error {
+//   - error: Context error if the context is canceled
+func (r *MemoryShortURLRepository) Save(ctx context.Context, shortURL *domain.ShortURL) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -44,16 +50,79 @@ func (r *MemoryShortURLRepository) Save(shortURL *domain.ShortURL) error {
 	return nil
 }
 
+// Insert persists a new ShortURL entity only if its identifier is not taken yet.
+// The check and the write happen under the same write lock, making the operation atomic.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - shortURL: The entity to insert
+//
+// Returns:
+//   - error: domain.ErrShortURLAlreadyExists if the identifier is already in use, or the context error
+func (r *MemoryShortURLRepository) Insert(ctx context.Context, shortURL *domain.ShortURL) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.data[shortURL.ID()]; exists {
+		return domain.ErrShortURLAlreadyExists
+	}
+
+	r.data[shortURL.ID()] = shortURL
+	return nil
+}
+
+// Update applies fn to a copy of the stored entity and stores the copy, all under the
+// write lock, so concurrent updates of the same entity are applied one after another.
+// Readers holding the previous entity never observe the change half-applied.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - id: The unique identifier of the entity to update
+//   - fn: Modification to apply; returning an error leaves the stored entity unchanged
+//
+// Returns:
+//   - *domain.ShortURL: The updated entity
+//   - error: domain.ErrShortURLNotFound if the entity does not exist, the error of fn, or the context error
+func (r *MemoryShortURLRepository) Update(ctx context.Context, id string, fn func(*domain.ShortURL) error) (*domain.ShortURL, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.data[id]
+	if !exists {
+		return nil, domain.ErrShortURLNotFound
+	}
+
+	updated := existing.Clone()
+	if err := fn(updated); err != nil {
+		return nil, err
+	}
+	r.data[id] = updated
+	return updated, nil
+}
+
 // FindByID retrieves a ShortURL entity by its unique identifier.
 // Uses read lock to allow concurrent reads while maintaining data integrity.
 //
 // Parameters:
+//   - ctx: Context for cancellation
 //   - id: The unique identifier to search for
 //
 // Returns:
 //   - *domain.ShortURL: The found entity or nil if not found
-//   - error: Always nil for this implementation
-func (r *MemoryShortURLRepository) FindByID(id string) (*domain.ShortURL, error) {
+//   - error: Context error if the context is canceled
+func (r *MemoryShortURLRepository) FindByID(ctx context.Context, id string) (*domain.ShortURL, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -68,10 +137,17 @@ func (r *MemoryShortURLRepository) FindByID(id string) (*domain.ShortURL, error)
 // FindAll retrieves all ShortURL entities from memory storage.
 // Returns a slice containing all stored entities. Order is not guaranteed.
 //
+// Parameters:
+//   - ctx: Context for cancellation
+//
 // Returns:
 //   - []*domain.ShortURL: Slice of all stored entities
-//   - error: Always nil for this implementation
-func (r *MemoryShortURLRepository) FindAll() ([]*domain.ShortURL, error) {
+//   - error: Context error if the context is canceled
+func (r *MemoryShortURLRepository) FindAll(ctx context.Context) ([]*domain.ShortURL, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -83,20 +159,77 @@ func (r *MemoryShortURLRepository) FindAll() ([]*domain.ShortURL, error) {
 	return shortURLs, nil
 }
 
+// FindPage retrieves one page of entities matching the query.
+// Matching entities are filtered and sorted on every call, which is adequate for
+// in-memory data sets; a database-backed implementation would use an index on
+// (created_at, id) and a keyset condition instead.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - query: Filters, sort order, cursor and page size
+//
+// Returns:
+//   - *domain.ShortURLPage: The requested page and the cursor for the next one
+//   - error: domain.ErrInvalidCursor if the cursor is malformed, domain.ErrCursorMismatch if it
+//     belongs to other filters or another order, or the context error
+func (r *MemoryShortURLRepository) FindPage(ctx context.Context, query domain.ShortURLQuery) (*domain.ShortURLPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var cursor *domain.PageCursor
+	if query.Cursor != "" {
+		decoded, err := query.DecodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = &decoded
+	}
+
+	r.mu.RLock()
+	var matches []*domain.ShortURL
+	for _, shortURL := range r.data {
+		if cursor != nil && !query.After(shortURL, *cursor) {
+			continue
+		}
+		if query.Matches(shortURL) {
+			matches = append(matches, shortURL)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		return query.Less(matches[i], matches[j])
+	})
+
+	page := &domain.ShortURLPage{Items: matches}
+	if query.Limit > 0 && len(matches) > query.Limit {
+		page.Items = matches[:query.Limit]
+		page.NextCursor = query.EncodeCursor(page.Items[query.Limit-1])
+	}
+
+	return page, nil
+}
+
 // Delete removes a ShortURL entity from memory storage by its identifier.
 // Uses write lock to ensure thread safety during deletion.
 //
 // Parameters:
+//   - ctx: Context for cancellation
 //   - id: The unique identifier of the entity to delete
 //
 // Returns:
-//   - error: Error if the entity was not found
-func (r *MemoryShortURLRepository) Delete(id string) error {
+//   - error: domain.ErrShortURLNotFound if the entity was not found, or the context error
+func (r *MemoryShortURLRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if _, exists := r.data[id]; !exists {
-		return errors.New("short URL not found")
+		return domain.ErrShortURLNotFound
 	}
 
 	delete(r.data, id)
@@ -1,7 +1,11 @@
 package infra
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/oharai/short-url/internal/shorturl/domain"
 )
@@ -16,9 +20,9 @@ func TestNewMemoryShortURLRepository(t *testing.T) {
 func TestMemoryShortURLRepository_Save(t *testing.T) {
 	repo := NewMemoryShortURLRepository().(*MemoryShortURLRepository)
 
-	shortURL, _ := domain.NewShortURL("abc123", "https://example.com", "http://short.ly/abc123", nil, nil)
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
 
-	err := repo.Save(shortURL)
+	err := repo.Save(context.Background(), shortURL)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -42,12 +46,12 @@ func TestMemoryShortURLRepository_Save_Update(t *testing.T) {
 	repo := NewMemoryShortURLRepository().(*MemoryShortURLRepository)
 
 	// Save initial URL
-	shortURL, _ := domain.NewShortURL("abc123", "https://example.com", "http://short.ly/abc123", nil, nil)
-	repo.Save(shortURL)
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	repo.Save(context.Background(), shortURL)
 
 	// Update the URL (deactivate it)
 	shortURL.Deactivate()
-	err := repo.Save(shortURL)
+	err := repo.Save(context.Background(), shortURL)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -59,12 +63,133 @@ func TestMemoryShortURLRepository_Save_Update(t *testing.T) {
 	}
 }
 
+func TestMemoryShortURLRepository_Insert(t *testing.T) {
+	repo := NewMemoryShortURLRepository().(*MemoryShortURLRepository)
+
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	if err := repo.Insert(context.Background(), shortURL); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// A second insert with the same ID must not overwrite the first one
+	duplicate, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://other.com"), "http://short.ly/abc123", nil, nil)
+	if err := repo.Insert(context.Background(), duplicate); !errors.Is(err, domain.ErrShortURLAlreadyExists) {
+		t.Errorf("expected ErrShortURLAlreadyExists, got %v", err)
+	}
+
+	if repo.data["abc123"].LongURL() != "https://example.com" {
+		t.Errorf("expected original long URL to be kept, got %q", repo.data["abc123"].LongURL())
+	}
+}
+
+func TestMemoryShortURLRepository_Insert_Concurrent(t *testing.T) {
+	repo := NewMemoryShortURLRepository().(*MemoryShortURLRepository)
+
+	// Many concurrent inserts of the same ID: exactly one must win
+	results := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		go func() {
+			alias, _ := domain.NewAlias("same-alias", domain.DefaultAliasPolicy())
+			shortURL, _ := domain.NewCustomShortURL(alias, domain.MustParseLongURL("https://example.com"), nil, nil)
+			results <- repo.Insert(context.Background(), shortURL)
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < 20; i++ {
+		err := <-results
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, domain.ErrShortURLAlreadyExists):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if succeeded != 1 {
+		t.Errorf("expected exactly 1 successful insert, got %d", succeeded)
+	}
+}
+
+func TestMemoryShortURLRepository_Update(t *testing.T) {
+	repo := NewMemoryShortURLRepository().(*MemoryShortURLRepository)
+	original, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	repo.Save(context.Background(), original)
+
+	updated, err := repo.Update(context.Background(), "abc123", func(u *domain.ShortURL) error {
+		return u.Transition(domain.StatePaused, "", "", time.Now())
+	})
+	if err != nil || updated.State() != domain.StatePaused {
+		t.Fatalf("expected paused entity, got %v, %v", updated, err)
+	}
+	if original.State() != domain.StateActive {
+		t.Error("expected the previously stored entity not to be modified")
+	}
+	if found, _ := repo.FindByID(context.Background(), "abc123"); found != updated {
+		t.Error("expected the updated entity to be stored")
+	}
+
+	// A failing update stores nothing
+	failure := errors.New("rejected")
+	_, err = repo.Update(context.Background(), "abc123", func(u *domain.ShortURL) error {
+		u.Transition(domain.StateArchived, "", "", time.Now())
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("expected error from fn, got %v", err)
+	}
+	if found, _ := repo.FindByID(context.Background(), "abc123"); found.State() != domain.StatePaused {
+		t.Errorf("expected failed update to be discarded, got %q", found.State())
+	}
+
+	if _, err := repo.Update(context.Background(), "missing", func(*domain.ShortURL) error { return nil }); !errors.Is(err, domain.ErrShortURLNotFound) {
+		t.Errorf("expected ErrShortURLNotFound, got %v", err)
+	}
+}
+
+func TestMemoryShortURLRepository_Update_Concurrent(t *testing.T) {
+	repo := NewMemoryShortURLRepository().(*MemoryShortURLRepository)
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	shortURL.LimitClicks(10)
+	repo.Save(context.Background(), shortURL)
+
+	// Many concurrent clicks on a link limited to 10: exactly 10 must succeed
+	results := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		go func() {
+			_, err := repo.Update(context.Background(), "abc123", func(u *domain.ShortURL) error {
+				_, err := u.RecordClick(time.Now())
+				return err
+			})
+			results <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < 50; i++ {
+		err := <-results
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, domain.ErrShortURLExhausted):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if succeeded != 10 {
+		t.Errorf("expected exactly 10 successful clicks, got %d", succeeded)
+	}
+	if found, _ := repo.FindByID(context.Background(), "abc123"); found.Clicks() != 10 || found.State() != domain.StateExhausted {
+		t.Errorf("expected exhausted entity with 10 clicks, got %q with %d", found.State(), found.Clicks())
+	}
+}
+
 func TestMemoryShortURLRepository_FindByID(t *testing.T) {
 	repo := NewMemoryShortURLRepository().(*MemoryShortURLRepository)
 
 	// Save a URL
-	shortURL, _ := domain.NewShortURL("abc123", "https://example.com", "http://short.ly/abc123", nil, nil)
-	repo.Save(shortURL)
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	repo.Save(context.Background(), shortURL)
 
 	tests := []struct {
 		name      string
@@ -94,7 +219,7 @@ func TestMemoryShortURLRepository_FindByID(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			found, err := repo.FindByID(tt.id)
+			found, err := repo.FindByID(context.Background(), tt.id)
 
 			if tt.expectErr && err == nil {
 				t.Error("expected error but got none")
@@ -157,11 +282,11 @@ func TestMemoryShortURLRepository_FindAll(t *testing.T) {
 			for i := 0; i < tt.setupURLs; i++ {
 				id := "url" + string(rune('0'+i))
 				url := "https://example" + string(rune('0'+i)) + ".com"
-				shortURL, _ := domain.NewShortURL(id, url, "http://short.ly/"+id, nil, nil)
-				repo.Save(shortURL)
+				shortURL, _ := domain.NewShortURL(id, domain.MustParseLongURL(url), "http://short.ly/"+id, nil, nil)
+				repo.Save(context.Background(), shortURL)
 			}
 
-			urls, err := repo.FindAll()
+			urls, err := repo.FindAll(context.Background())
 			if err != nil {
 				t.Errorf("unexpected error: %v", err)
 				return
@@ -178,8 +303,8 @@ func TestMemoryShortURLRepository_Delete(t *testing.T) {
 	repo := NewMemoryShortURLRepository().(*MemoryShortURLRepository)
 
 	// Save a URL
-	shortURL, _ := domain.NewShortURL("abc123", "https://example.com", "http://short.ly/abc123", nil, nil)
-	repo.Save(shortURL)
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	repo.Save(context.Background(), shortURL)
 
 	tests := []struct {
 		name        string
@@ -205,11 +330,11 @@ func TestMemoryShortURLRepository_Delete(t *testing.T) {
 			// Reset repository state for each test
 			if tt.name == "delete existing URL" {
 				repo.data = make(map[string]*domain.ShortURL)
-				shortURL, _ := domain.NewShortURL("abc123", "https://example.com", "http://short.ly/abc123", nil, nil)
-				repo.Save(shortURL)
+				shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+				repo.Save(context.Background(), shortURL)
 			}
 
-			err := repo.Delete(tt.id)
+			err := repo.Delete(context.Background(), tt.id)
 
 			if tt.expectError {
 				if err == nil {
@@ -245,8 +370,8 @@ func TestMemoryShortURLRepository_ConcurrentAccess(t *testing.T) {
 		go func(index int) {
 			id := "url" + string(rune('0'+index))
 			url := "https://example" + string(rune('0'+index)) + ".com"
-			shortURL, _ := domain.NewShortURL(id, url, "http://short.ly/"+id, nil, nil)
-			repo.Save(shortURL)
+			shortURL, _ := domain.NewShortURL(id, domain.MustParseLongURL(url), "http://short.ly/"+id, nil, nil)
+			repo.Save(context.Background(), shortURL)
 			done <- true
 		}(i)
 	}
@@ -257,7 +382,7 @@ func TestMemoryShortURLRepository_ConcurrentAccess(t *testing.T) {
 	}
 
 	// Verify all URLs were saved
-	urls, _ := repo.FindAll()
+	urls, _ := repo.FindAll(context.Background())
 	if len(urls) != 10 {
 		t.Errorf("expected 10 URLs after concurrent saves, got %d", len(urls))
 	}
@@ -266,7 +391,7 @@ func TestMemoryShortURLRepository_ConcurrentAccess(t *testing.T) {
 	for i := 0; i < 10; i++ {
 		go func(index int) {
 			id := "url" + string(rune('0'+index))
-			found, _ := repo.FindByID(id)
+			found, _ := repo.FindByID(context.Background(), id)
 			if found == nil {
 				t.Errorf("expected to find URL with ID %q", id)
 			}
@@ -279,3 +404,134 @@ func TestMemoryShortURLRepository_ConcurrentAccess(t *testing.T) {
 		<-done
 	}
 }
+
+func TestMemoryShortURLRepository_CanceledContext(t *testing.T) {
+	repo := NewMemoryShortURLRepository().(*MemoryShortURLRepository)
+	shortURL, _ := domain.NewShortURL("abc123", domain.MustParseLongURL("https://example.com"), "http://short.ly/abc123", nil, nil)
+	repo.Save(context.Background(), shortURL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := repo.Save(ctx, shortURL); !errors.Is(err, context.Canceled) {
+		t.Errorf("Save: expected context.Canceled, got %v", err)
+	}
+	if err := repo.Insert(ctx, shortURL); !errors.Is(err, context.Canceled) {
+		t.Errorf("Insert: expected context.Canceled, got %v", err)
+	}
+	if _, err := repo.FindByID(ctx, "abc123"); !errors.Is(err, context.Canceled) {
+		t.Errorf("FindByID: expected context.Canceled, got %v", err)
+	}
+	if _, err := repo.FindAll(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("FindAll: expected context.Canceled, got %v", err)
+	}
+	if _, err := repo.FindPage(ctx, domain.ShortURLQuery{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("FindPage: expected context.Canceled, got %v", err)
+	}
+	if err := repo.Delete(ctx, "abc123"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Delete: expected context.Canceled, got %v", err)
+	}
+
+	// Nothing may have been deleted by the canceled call
+	if _, exists := repo.data["abc123"]; !exists {
+		t.Error("expected URL to survive canceled delete")
+	}
+}
+
+func TestMemoryShortURLRepository_FindPage(t *testing.T) {
+	repo := NewMemoryShortURLRepository()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// IDs are deliberately out of creation order; "e" and "f" share a timestamp
+	seed := []struct {
+		id        string
+		createdAt time.Time
+	}{
+		{"c", base},
+		{"a", base.Add(time.Minute)},
+		{"d", base.Add(2 * time.Minute)},
+		{"f", base.Add(3 * time.Minute)},
+		{"e", base.Add(3 * time.Minute)},
+		{"b", base.Add(4 * time.Minute)},
+	}
+	for _, s := range seed {
+		repo.Save(context.Background(), domain.ReconstructShortURL(s.id, "https://example.com/"+s.id, "http://short.ly/"+s.id, s.createdAt, nil, true, nil))
+	}
+
+	tests := []struct {
+		name     string
+		query    domain.ShortURLQuery
+		expected []string
+	}{
+		{
+			name:     "ascending",
+			query:    domain.ShortURLQuery{Limit: 2},
+			expected: []string{"c", "a", "d", "e", "f", "b"},
+		},
+		{
+			name:     "descending",
+			query:    domain.ShortURLQuery{Order: domain.SortDescending, Limit: 4},
+			expected: []string{"b", "f", "e", "d", "a", "c"},
+		},
+		{
+			name:     "created range",
+			query:    domain.ShortURLQuery{CreatedFrom: timePtr(base.Add(time.Minute)), CreatedTo: timePtr(base.Add(3 * time.Minute)), Limit: 1},
+			expected: []string{"a", "d"},
+		},
+		{
+			name:     "no limit",
+			query:    domain.ShortURLQuery{},
+			expected: []string{"c", "a", "d", "e", "f", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			query := tt.query
+			for pages := 0; ; pages++ {
+				if pages > len(seed) {
+					t.Fatal("pagination did not terminate")
+				}
+				page, err := repo.FindPage(context.Background(), query)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if tt.query.Limit > 0 && len(page.Items) > tt.query.Limit {
+					t.Errorf("page exceeds limit: %d items", len(page.Items))
+				}
+				for _, item := range page.Items {
+					got = append(got, item.ID())
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := repo.FindPage(context.Background(), domain.ShortURLQuery{Cursor: "not a cursor"}); !errors.Is(err, domain.ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	// A cursor only continues the listing it was issued for
+	page, err := repo.FindPage(context.Background(), domain.ShortURLQuery{Status: domain.StatusActive, Limit: 1})
+	if err != nil || page.NextCursor == "" {
+		t.Fatalf("expected a first page with a cursor, got %v", err)
+	}
+	if _, err := repo.FindPage(context.Background(), domain.ShortURLQuery{Status: domain.StatusInactive, Cursor: page.NextCursor}); !errors.Is(err, domain.ErrCursorMismatch) {
+		t.Errorf("expected ErrCursorMismatch for other filters, got %v", err)
+	}
+	if _, err := repo.FindPage(context.Background(), domain.ShortURLQuery{Status: domain.StatusActive, Order: domain.SortDescending, Cursor: page.NextCursor}); !errors.Is(err, domain.ErrCursorMismatch) {
+		t.Errorf("expected ErrCursorMismatch for another order, got %v", err)
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
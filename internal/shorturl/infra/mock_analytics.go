@@ -1,6 +1,7 @@
 package infra
 
 import (
+	"context"
 	"log"
 
 	"github.com/oharai/short-url/internal/shorturl/domain"
@@ -25,11 +26,16 @@ func NewMockAnalyticsService() domain.AnalyticsService {
 // such as Kafka, HTTP endpoints, or cloud analytics services.
 //
 // Parameters:
+//   - ctx: Context for cancellation
 //   - event: The analytics event to process
 //
 // Returns:
-//   - error: Always nil for this mock implementation
-func (a *MockAnalyticsService) SendEvent(event domain.AnalyticsEvent) error {
+//   - error: Context error if the context is canceled, otherwise nil
+func (a *MockAnalyticsService) SendEvent(ctx context.Context, event domain.AnalyticsEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	// Log the event for debugging and demonstration purposes
 	log.Printf("Analytics Event: %+v", event)
 	return nil
@@ -1,6 +1,8 @@
 package infra
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -34,7 +36,7 @@ func TestMockAnalyticsService_SendEvent(t *testing.T) {
 		Timestamp: time.Now(),
 	}
 
-	err := analytics.SendEvent(event)
+	err := analytics.SendEvent(context.Background(), event)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -68,7 +70,7 @@ func TestMockAnalyticsService_SendEvent_Multiple(t *testing.T) {
 	}
 
 	for i, event := range events {
-		err := analytics.SendEvent(event)
+		err := analytics.SendEvent(context.Background(), event)
 		if err != nil {
 			t.Errorf("unexpected error for event %d: %v", i, err)
 		}
@@ -80,7 +82,7 @@ func TestMockAnalyticsService_SendEvent_EmptyEvent(t *testing.T) {
 
 	emptyEvent := domain.AnalyticsEvent{}
 
-	err := analytics.SendEvent(emptyEvent)
+	err := analytics.SendEvent(context.Background(), emptyEvent)
 	if err != nil {
 		t.Errorf("unexpected error for empty event: %v", err)
 	}
@@ -97,8 +99,20 @@ func TestMockAnalyticsService_SendEvent_NilMetadata(t *testing.T) {
 		Timestamp:    time.Now(),
 	}
 
-	err := analytics.SendEvent(event)
+	err := analytics.SendEvent(context.Background(), event)
 	if err != nil {
 		t.Errorf("unexpected error for event with nil metadata: %v", err)
 	}
 }
+
+func TestMockAnalyticsService_SendEvent_CanceledContext(t *testing.T) {
+	analytics := NewMockAnalyticsService().(*MockAnalyticsService)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := analytics.SendEvent(ctx, domain.AnalyticsEvent{EventType: "url_created"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package infra
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pbkdf2Scheme identifies hashes produced by PBKDF2PasswordHasher.
+const pbkdf2Scheme = "pbkdf2-sha256"
+
+// DefaultPBKDF2Iterations is the iteration count recommended for PBKDF2-HMAC-SHA256 by OWASP.
+const DefaultPBKDF2Iterations = 600000
+
+// Salt and derived key sizes in bytes.
+const (
+	pbkdf2SaltSize = 16
+	pbkdf2KeySize  = 32
+)
+
+// PBKDF2PasswordHasher implements the PasswordHasher interface with PBKDF2-HMAC-SHA256
+// and a random salt per password. Hashes are encoded as
+// "pbkdf2-sha256$<iterations>$<salt>$<key>" with unpadded Base64, so the iteration count
+// can be raised later without invalidating existing hashes.
+type PBKDF2PasswordHasher struct {
+	iterations int // Iteration count for new hashes
+}
+
+// NewPBKDF2PasswordHasher creates a PBKDF2 password hasher.
+//
+// Parameters:
+//   - iterations: Iteration count for new hashes; DefaultPBKDF2Iterations when 0 or less
+//
+// Returns:
+//   - *PBKDF2PasswordHasher: Hasher instance
+func NewPBKDF2PasswordHasher(iterations int) *PBKDF2PasswordHasher {
+	if iterations <= 0 {
+		iterations = DefaultPBKDF2Iterations
+	}
+	return &PBKDF2PasswordHasher{
+		iterations: iterations,
+	}
+}
+
+// Hash derives a key from the password with a fresh random salt.
+//
+// Parameters:
+//   - password: The password in plain text
+//
+// Returns:
+//   - string: The encoded hash, including salt and iteration count
+//   - error: Error if no random salt could be read
+func (h *PBKDF2PasswordHasher) Hash(password string) (string, error) {
+	salt := make([]byte, pbkdf2SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, h.iterations, pbkdf2KeySize)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	return strings.Join([]string{
+		pbkdf2Scheme,
+		strconv.Itoa(h.iterations),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	}, "$"), nil
+}
+
+// Verify reports whether the password matches the encoded hash. The keys are compared
+// in constant time. Malformed hashes never match.
+//
+// Parameters:
+//   - encodedHash: A hash returned by Hash
+//   - password: The password to check
+//
+// Returns:
+//   - bool: Whether the password is correct
+func (h *PBKDF2PasswordHasher) Verify(encodedHash, password string) bool {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 4 || parts[0] != pbkdf2Scheme {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(expected) == 0 {
+		return false
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(expected))
+	return err == nil && hmac.Equal(key, expected)
+}
@@ -0,0 +1,62 @@
+package infra
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPBKDF2PasswordHasher_HashAndVerify(t *testing.T) {
+	hasher := NewPBKDF2PasswordHasher(1000)
+
+	hash, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(hash, "pbkdf2-sha256$1000$") {
+		t.Errorf("expected encoded scheme and iterations, got %q", hash)
+	}
+	if strings.Contains(hash, "correct horse") {
+		t.Error("expected the hash not to contain the password")
+	}
+
+	if !hasher.Verify(hash, "correct horse") {
+		t.Error("expected the right password to match")
+	}
+	if hasher.Verify(hash, "correct horse ") {
+		t.Error("expected a different password not to match")
+	}
+
+	// Every hash has its own salt
+	other, _ := hasher.Hash("correct horse")
+	if other == hash {
+		t.Error("expected two hashes of the same password to differ")
+	}
+}
+
+func TestPBKDF2PasswordHasher_IterationsFromHash(t *testing.T) {
+	// Hashes keep verifying after the iteration count for new hashes changes
+	hash, _ := NewPBKDF2PasswordHasher(1000).Hash("correct horse")
+	if !NewPBKDF2PasswordHasher(2000).Verify(hash, "correct horse") {
+		t.Error("expected the hash's own iteration count to be used")
+	}
+	if NewPBKDF2PasswordHasher(0).iterations != DefaultPBKDF2Iterations {
+		t.Error("expected default iterations")
+	}
+}
+
+func TestPBKDF2PasswordHasher_MalformedHash(t *testing.T) {
+	hasher := NewPBKDF2PasswordHasher(1000)
+	for _, hash := range []string{
+		"",
+		"plaintext",
+		"bcrypt$1000$c2FsdA$aGFzaA",
+		"pbkdf2-sha256$abc$c2FsdA$aGFzaA",
+		"pbkdf2-sha256$0$c2FsdA$aGFzaA",
+		"pbkdf2-sha256$1000$!!!$aGFzaA",
+		"pbkdf2-sha256$1000$c2FsdA$",
+	} {
+		if hasher.Verify(hash, "") {
+			t.Errorf("expected malformed hash %q not to match", hash)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultResolveTimeout bounds a single redirect lookup, so that a slow shortener
+// cannot hold up URL creation.
+const defaultResolveTimeout = 3 * time.Second
+
+// HTTPRedirectResolver implements the RedirectResolver interface by requesting the URL
+// and reading the Location header of a redirect response. Redirects are never followed,
+// so only hosts the caller chose to resolve are contacted. A HEAD request is tried first;
+// servers that do not allow HEAD are asked with GET, whose body is not read.
+type HTTPRedirectResolver struct {
+	client *http.Client // HTTP client that does not follow redirects
+}
+
+// NewHTTPRedirectResolver creates a redirect resolver.
+//
+// Parameters:
+//   - timeout: Timeout of a single lookup; 3 seconds when zero
+//
+// Returns:
+//   - *HTTPRedirectResolver: Resolver instance
+func NewHTTPRedirectResolver(timeout time.Duration) *HTTPRedirectResolver {
+	if timeout <= 0 {
+		timeout = defaultResolveTimeout
+	}
+	return &HTTPRedirectResolver{
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// ResolveRedirect returns the target of the redirect served at url.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - url: Absolute URL to look up
+//
+// Returns:
+//   - string: The absolute redirect target, or url itself if the response is not a redirect
+//   - error: Error if the request fails or a redirect has no usable Location header
+func (r *HTTPRedirectResolver) ResolveRedirect(ctx context.Context, url string) (string, error) {
+	resp, err := r.do(ctx, http.MethodHead, url)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+		if resp, err = r.do(ctx, http.MethodGet, url); err != nil {
+			return "", err
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return url, nil
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return "", fmt.Errorf("redirect from %s has no valid Location header: %w", url, err)
+	}
+	return location.String(), nil
+}
+
+// do sends a request and closes the response body right away; only the status and
+// headers are needed.
+func (r *HTTPRedirectResolver) do(ctx context.Context, method, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
@@ -0,0 +1,85 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPRedirectResolver(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/absolute":
+			http.Redirect(w, r, "https://example.com/landing", http.StatusMovedPermanently)
+		case "/relative":
+			http.Redirect(w, r, "/next", http.StatusFound)
+		case "/chained":
+			http.Redirect(w, r, "/absolute", http.StatusFound)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			http.Redirect(w, r, "https://example.com/from-get", http.StatusTemporaryRedirect)
+		case "/missing-location":
+			w.WriteHeader(http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name            string
+		path            string
+		expected        string
+		expectedMethods []string
+		expectError     bool
+	}{
+		{"absolute redirect", "/absolute", "https://example.com/landing", []string{"HEAD /absolute"}, false},
+		{"relative redirect", "/relative", server.URL + "/next", []string{"HEAD /relative"}, false},
+		{"one hop only", "/chained", server.URL + "/absolute", []string{"HEAD /chained"}, false},
+		{"GET when HEAD is not allowed", "/no-head", "https://example.com/from-get", []string{"HEAD /no-head", "GET /no-head"}, false},
+		{"not a redirect", "/page", server.URL + "/page", []string{"HEAD /page"}, false},
+		{"redirect without location", "/missing-location", "", []string{"HEAD /missing-location"}, true},
+	}
+
+	resolver := NewHTTPRedirectResolver(time.Second)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			methods = nil
+			target, err := resolver.ResolveRedirect(context.Background(), server.URL+tt.path)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected error=%v, got %v", tt.expectError, err)
+			}
+			if target != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, target)
+			}
+			if len(methods) != len(tt.expectedMethods) {
+				t.Fatalf("expected requests %v, got %v", tt.expectedMethods, methods)
+			}
+			for i := range methods {
+				if methods[i] != tt.expectedMethods[i] {
+					t.Errorf("expected requests %v, got %v", tt.expectedMethods, methods)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPRedirectResolver_Canceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewHTTPRedirectResolver(0).ResolveRedirect(ctx, server.URL); err == nil {
+		t.Error("expected error for canceled context")
+	}
+}
@@ -0,0 +1,75 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+)
+
+// RegionPrefixKeyGenerationService implements the KeyGenerationService interface by
+// prefixing every ID of an underlying generator with the code of the local region.
+// Each region runs its own generator, and the prefix keeps IDs of different regions
+// apart even when those generators issue the same value, without any cross-region
+// coordination. Prefixed IDs are one character longer than those of the underlying generator.
+type RegionPrefixKeyGenerationService struct {
+	code string                      // Region code prefixed to every ID
+	kgs  domain.KeyGenerationService // Generator of the region-local part of the ID
+}
+
+// NewRegionPrefixKeyGenerationService creates a key generation service that prefixes
+// the IDs of kgs with a region code.
+//
+// Parameters:
+//   - code: Region code, a single Base62 character
+//   - kgs: Generator of the region-local part of the ID
+//
+// Returns:
+//   - *RegionPrefixKeyGenerationService: Service instance
+//   - error: Error if the code is not a single Base62 character
+func NewRegionPrefixKeyGenerationService(code string, kgs domain.KeyGenerationService) (*RegionPrefixKeyGenerationService, error) {
+	if !domain.IsRegionCode(code) {
+		return nil, fmt.Errorf("region code %q must be a single Base62 character", code)
+	}
+
+	return &RegionPrefixKeyGenerationService{
+		code: code,
+		kgs:  kgs,
+	}, nil
+}
+
+// GenerateUniqueID generates a single identifier starting with the region code.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//
+// Returns:
+//   - string: The region code followed by an ID of the underlying generator
+//   - error: Error of the underlying generator
+func (k *RegionPrefixKeyGenerationService) GenerateUniqueID(ctx context.Context) (string, error) {
+	id, err := k.kgs.GenerateUniqueID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return k.code + id, nil
+}
+
+// GetMultipleIDs generates multiple identifiers starting with the region code.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - count: Number of IDs to generate
+//
+// Returns:
+//   - []string: The region code followed by IDs of the underlying generator
+//   - error: Error of the underlying generator
+func (k *RegionPrefixKeyGenerationService) GetMultipleIDs(ctx context.Context, count int) ([]string, error) {
+	ids, err := k.kgs.GetMultipleIDs(ctx, count)
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range ids {
+		ids[i] = k.code + id
+	}
+	return ids, nil
+}
@@ -0,0 +1,70 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewRegionPrefixKeyGenerationService(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        string
+		expectError bool
+	}{
+		{"digit", "1", false},
+		{"letter", "Z", false},
+		{"empty", "", true},
+		{"too long", "12", true},
+		{"not Base62", "_", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRegionPrefixKeyGenerationService(tt.code, NewBase62KeyGenerationService())
+			if (err != nil) != tt.expectError {
+				t.Errorf("expected error=%v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestRegionPrefixKeyGenerationService(t *testing.T) {
+	// Two regions whose generators issue the same values still mint distinct IDs
+	us, _ := NewRegionPrefixKeyGenerationService("1", NewBase62KeyGenerationService(WithCounterStore(NewMemoryHighWaterMarkStore(0), 0)))
+	eu, _ := NewRegionPrefixKeyGenerationService("2", NewBase62KeyGenerationService(WithCounterStore(NewMemoryHighWaterMarkStore(0), 0)))
+
+	usID, err := us.GenerateUniqueID(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	euIDs, err := eu.GetMultipleIDs(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(usID) != base62IDLength+1 || !strings.HasPrefix(usID, "1") {
+		t.Errorf("expected 8-character ID starting with '1', got %q", usID)
+	}
+	for _, id := range euIDs {
+		if !strings.HasPrefix(id, "2") {
+			t.Errorf("expected ID starting with '2', got %q", id)
+		}
+	}
+	if usID[1:] != euIDs[0][1:] || usID == euIDs[0] {
+		t.Errorf("expected same local part with different prefixes, got %q and %q", usID, euIDs[0])
+	}
+}
+
+func TestRegionPrefixKeyGenerationService_Error(t *testing.T) {
+	store := NewMemoryHighWaterMarkStore(base62IDSpace)
+	kgs, _ := NewRegionPrefixKeyGenerationService("1", NewBase62KeyGenerationService(WithCounterStore(store, 0)))
+
+	if _, err := kgs.GenerateUniqueID(context.Background()); !errors.Is(err, ErrIDSpaceExhausted) {
+		t.Errorf("GenerateUniqueID: expected ErrIDSpaceExhausted, got %v", err)
+	}
+	if _, err := kgs.GetMultipleIDs(context.Background(), 2); !errors.Is(err, ErrIDSpaceExhausted) {
+		t.Errorf("GetMultipleIDs: expected ErrIDSpaceExhausted, got %v", err)
+	}
+}
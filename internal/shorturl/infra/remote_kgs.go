@@ -0,0 +1,384 @@
+package infra
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+)
+
+// ErrKGSUnavailable is returned when the remote KGS cannot be reached, no buffered
+// IDs are left and no fallback is configured.
+var ErrKGSUnavailable = errors.New("key generation service unavailable")
+
+// maxRemoteBatchSize is the largest number of IDs the KGS endpoint issues per call.
+// Larger requests are split into several calls.
+const maxRemoteBatchSize = 10000
+
+// RemoteKGSOptions configures the RemoteKeyGenerationService.
+type RemoteKGSOptions struct {
+	BatchSize      int                         // IDs requested per call to the KGS
+	LowWatermark   int                         // Buffer size below which an asynchronous refill starts
+	RequestTimeout time.Duration               // Timeout of a single call to the KGS
+	RetryBackoff   time.Duration               // Minimum delay between refill attempts after a failure
+	Fallback       domain.KeyGenerationService // Local generator used when the KGS is unreachable; nil to fail instead
+}
+
+// DefaultRemoteKGSOptions returns options matching the README design of fetching
+// 1000 IDs per call and refilling well before the buffer runs dry.
+func DefaultRemoteKGSOptions() RemoteKGSOptions {
+	return RemoteKGSOptions{
+		BatchSize:      1000,
+		LowWatermark:   200,
+		RequestTimeout: 2 * time.Second,
+		RetryBackoff:   time.Second,
+	}
+}
+
+// NewFallbackKeyGenerationService creates a local generator to use as the fallback of a
+// RemoteKeyGenerationService. Its IDs are one character longer than those of the profile,
+// which the KGS issues with exactly the profile's length, so fallback IDs never collide
+// with IDs the KGS has issued or will issue later. They are also drawn from a
+// cryptographic random source rather than a counter starting at zero, so instances that
+// fall back at the same time do not issue the same sequence; the rare collision between
+// them is detected by the repository's atomic Insert.
+//
+// Parameters:
+//   - profile: ID profile of the KGS; the default 7-character Base62 profile when nil
+//
+// Returns:
+//   - domain.KeyGenerationService: Generator of IDs outside the KGS's ID space
+//   - error: Error if the longer IDs do not fit in the ID space of the profile's alphabet
+func NewFallbackKeyGenerationService(profile *domain.IDProfile) (domain.KeyGenerationService, error) {
+	if profile == nil {
+		profile = base62Profile
+	}
+	longer, err := domain.NewIDProfile(profile.Alphabet(), profile.Length()+1)
+	if err != nil {
+		return nil, fmt.Errorf("no room for fallback IDs: %w", err)
+	}
+	return &randomKeyGenerationService{profile: longer}, nil
+}
+
+// randomKeyGenerationService implements the KeyGenerationService interface by drawing
+// every ID uniformly from the ID space of its profile with a cryptographic random source.
+type randomKeyGenerationService struct {
+	profile *domain.IDProfile // Alphabet and length of generated IDs
+}
+
+// GenerateUniqueID draws a single random identifier.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//
+// Returns:
+//   - string: A random identifier of the profile's length
+//   - error: Context error, or an error of the random source
+func (k *randomKeyGenerationService) GenerateUniqueID(ctx context.Context) (string, error) {
+	ids, err := k.GetMultipleIDs(ctx, 1)
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// GetMultipleIDs draws count random identifiers.
+//
+// Parameters:
+//   - ctx: Context for cancellation, checked before each ID is drawn
+//   - count: Number of IDs to generate
+//
+// Returns:
+//   - []string: Random identifiers of the profile's length
+//   - error: Context error, or an error of the random source
+func (k *randomKeyGenerationService) GetMultipleIDs(ctx context.Context, count int) ([]string, error) {
+	space := new(big.Int).SetUint64(k.profile.Space())
+	ids := make([]string, count)
+	for i := range ids {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		value, err := rand.Int(rand.Reader, space)
+		if err != nil {
+			return nil, fmt.Errorf("failed to draw random ID: %w", err)
+		}
+		ids[i] = k.profile.Encode(value.Uint64())
+	}
+	return ids, nil
+}
+
+// RemoteKeyGenerationService implements the KeyGenerationService interface by fetching
+// batches of IDs from a standalone KGS over HTTP. IDs are served from a local buffer;
+// when the buffer drops below the low watermark a refill runs in the background, so
+// requests normally never wait for the network. When the KGS is unreachable, buffered
+// IDs keep being served, refills are retried with a backoff, and once the buffer is empty
+// the optional fallback generator takes over. Fallback IDs are not coordinated with the
+// KGS, so the fallback must issue IDs the KGS never issues, as the generator of
+// NewFallbackKeyGenerationService does.
+type RemoteKeyGenerationService struct {
+	baseURL string           // Base URL of the KGS, e.g. "http://kgs:8081"
+	client  *http.Client     // HTTP client used for KGS calls
+	opts    RemoteKGSOptions // Buffering and degradation settings
+	now     func() time.Time // Clock, replaceable in tests
+
+	mu          sync.Mutex     // Guards the fields below
+	buffer      []string       // Fetched IDs not handed out yet
+	refilling   bool           // Set while a background refill is running
+	lastFailure time.Time      // Time of the last failed KGS call
+	refills     sync.WaitGroup // Tracks background refills
+}
+
+// NewRemoteKeyGenerationService creates a client for a standalone KGS.
+//
+// Parameters:
+//   - baseURL: Base URL of the KGS
+//   - client: HTTP client for KGS calls; http.DefaultClient when nil
+//   - opts: Buffering and degradation settings
+//
+// Returns:
+//   - *RemoteKeyGenerationService: Client instance
+//   - error: Error if the options are inconsistent
+func NewRemoteKeyGenerationService(baseURL string, client *http.Client, opts RemoteKGSOptions) (*RemoteKeyGenerationService, error) {
+	if baseURL == "" {
+		return nil, errors.New("KGS base URL is required")
+	}
+	if opts.BatchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive, got %d", opts.BatchSize)
+	}
+	if opts.LowWatermark < 0 || opts.LowWatermark >= opts.BatchSize {
+		return nil, fmt.Errorf("low watermark must be between 0 and the batch size, got %d", opts.LowWatermark)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &RemoteKeyGenerationService{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+		opts:    opts,
+		now:     time.Now,
+	}, nil
+}
+
+// GenerateUniqueID returns a buffered ID, fetching a batch synchronously only when
+// the buffer is empty.
+//
+// Parameters:
+//   - ctx: Context for cancellation of a synchronous fetch
+//
+// Returns:
+//   - string: A unique identifier issued by the KGS, or by the fallback when the KGS is down
+//   - error: ErrKGSUnavailable without fallback, or the context error
+func (k *RemoteKeyGenerationService) GenerateUniqueID(ctx context.Context) (string, error) {
+	ids, err := k.GetMultipleIDs(ctx, 1)
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// GetMultipleIDs returns count IDs, taking as many as possible from the buffer and
+// fetching the rest synchronously. Surplus IDs of a synchronous fetch are buffered.
+//
+// Parameters:
+//   - ctx: Context for cancellation of a synchronous fetch
+//   - count: Number of IDs to return
+//
+// Returns:
+//   - []string: Unique identifiers
+//   - error: ErrKGSUnavailable without fallback, or the context error
+func (k *RemoteKeyGenerationService) GetMultipleIDs(ctx context.Context, count int) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	taken := min(count, len(k.buffer))
+	ids := append(make([]string, 0, count), k.buffer[:taken]...)
+	k.buffer = k.buffer[taken:]
+	if taken == count {
+		k.maybeStartRefill()
+	}
+	k.mu.Unlock()
+
+	if taken == count {
+		return ids, nil
+	}
+
+	// The buffer ran dry: fetch synchronously, or degrade to the fallback. While the KGS
+	// is known to be down, requests go straight to the fallback instead of each waiting
+	// for another timeout.
+	missing := count - len(ids)
+	var err error
+	if k.opts.Fallback != nil && k.backingOff() {
+		err = errors.New("backing off after a recent failure")
+	} else {
+		var fetched []string
+		fetched, err = k.fetch(ctx, max(missing, k.opts.BatchSize))
+		if err == nil {
+			k.mu.Lock()
+			k.lastFailure = time.Time{}
+			k.buffer = append(k.buffer, fetched[missing:]...)
+			k.mu.Unlock()
+			return append(ids, fetched[:missing]...), nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		k.recordFailure()
+	}
+
+	if k.opts.Fallback == nil {
+		return nil, fmt.Errorf("%w: %v", ErrKGSUnavailable, err)
+	}
+	log.Printf("KGS unavailable, using fallback generator: %v", err)
+	fallback, fallbackErr := k.opts.Fallback.GetMultipleIDs(ctx, missing)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("fallback key generation failed: %w", fallbackErr)
+	}
+	return append(ids, fallback...), nil
+}
+
+// Buffered returns the number of IDs currently held in the local buffer.
+//
+// Returns:
+//   - int: Number of buffered IDs
+func (k *RemoteKeyGenerationService) Buffered() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return len(k.buffer)
+}
+
+// maybeStartRefill starts a background refill when the buffer is below the low watermark,
+// no refill is running and the backoff after the last failure has elapsed.
+// The caller must hold the mutex lock.
+func (k *RemoteKeyGenerationService) maybeStartRefill() {
+	if k.refilling || len(k.buffer) >= k.opts.LowWatermark {
+		return
+	}
+	if k.backingOffLocked() {
+		return
+	}
+
+	k.refilling = true
+	k.refills.Add(1)
+	go k.refill()
+}
+
+// refill fetches one batch in the background and appends it to the buffer.
+// It runs detached from any request, bounded by the request timeout.
+func (k *RemoteKeyGenerationService) refill() {
+	defer k.refills.Done()
+
+	ids, err := k.fetch(context.Background(), k.opts.BatchSize)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.refilling = false
+	if err != nil {
+		k.lastFailure = k.now()
+		log.Printf("Failed to refill KGS buffer: %v", err)
+		return
+	}
+	k.lastFailure = time.Time{}
+	k.buffer = append(k.buffer, ids...)
+}
+
+// backingOff reports whether the last KGS call failed less than RetryBackoff ago.
+func (k *RemoteKeyGenerationService) backingOff() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.backingOffLocked()
+}
+
+// backingOffLocked is backingOff for callers that already hold the mutex lock.
+func (k *RemoteKeyGenerationService) backingOffLocked() bool {
+	return !k.lastFailure.IsZero() && k.now().Sub(k.lastFailure) < k.opts.RetryBackoff
+}
+
+// recordFailure notes a failed KGS call so that refills back off.
+func (k *RemoteKeyGenerationService) recordFailure() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.lastFailure = k.now()
+}
+
+// fetch requests count IDs from the KGS, split into calls of at most maxRemoteBatchSize IDs.
+//
+// Parameters:
+//   - ctx: Context for cancellation; each call is further bounded by the request timeout
+//   - count: Number of IDs to request
+//
+// Returns:
+//   - []string: Exactly count identifiers
+//   - error: Transport, status or decoding error of any call
+func (k *RemoteKeyGenerationService) fetch(ctx context.Context, count int) ([]string, error) {
+	ids := make([]string, 0, count)
+	for len(ids) < count {
+		batch, err := k.fetchBatch(ctx, min(count-len(ids), maxRemoteBatchSize))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, batch...)
+	}
+	return ids, nil
+}
+
+// fetchBatch requests count IDs from the KGS in a single call.
+//
+// Parameters:
+//   - ctx: Context for cancellation, further bounded by the request timeout
+//   - count: Number of IDs to request, at most maxRemoteBatchSize
+//
+// Returns:
+//   - []string: Exactly count identifiers
+//   - error: Transport, status or decoding error
+func (k *RemoteKeyGenerationService) fetchBatch(ctx context.Context, count int) ([]string, error) {
+	if k.opts.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, k.opts.RequestTimeout)
+		defer cancel()
+	}
+
+	url := k.baseURL + "/v1/ids?count=" + strconv.Itoa(count)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("KGS returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode KGS response: %w", err)
+	}
+	if len(body.IDs) != count {
+		return nil, fmt.Errorf("KGS returned %d IDs, expected %d", len(body.IDs), count)
+	}
+
+	return body.IDs, nil
+}
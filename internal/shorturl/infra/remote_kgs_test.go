@@ -0,0 +1,311 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+)
+
+// fakeKGS is an HTTP KGS that issues sequential IDs and can be switched off.
+type fakeKGS struct {
+	mu    sync.Mutex
+	next  int
+	calls int
+	down  bool
+}
+
+func (f *fakeKGS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	// Like the real endpoint, refuse batches above the server limit
+	count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+	if count > maxRemoteBatchSize {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	ids := make([]string, count)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("remote%04d", f.next)
+		f.next++
+	}
+	json.NewEncoder(w).Encode(map[string][]string{"ids": ids})
+}
+
+func (f *fakeKGS) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.down = down
+}
+
+func (f *fakeKGS) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
+
+func newTestRemoteKGS(t *testing.T, opts RemoteKGSOptions) (*RemoteKeyGenerationService, *fakeKGS) {
+	t.Helper()
+
+	fake := &fakeKGS{}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	kgs, err := NewRemoteKeyGenerationService(server.URL, server.Client(), opts)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return kgs, fake
+}
+
+func TestNewRemoteKeyGenerationService(t *testing.T) {
+	tests := []struct {
+		name        string
+		baseURL     string
+		opts        RemoteKGSOptions
+		expectError bool
+	}{
+		{"defaults", "http://kgs", DefaultRemoteKGSOptions(), false},
+		{"missing URL", "", DefaultRemoteKGSOptions(), true},
+		{"zero batch size", "http://kgs", RemoteKGSOptions{BatchSize: 0}, true},
+		{"watermark not below batch size", "http://kgs", RemoteKGSOptions{BatchSize: 10, LowWatermark: 10}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRemoteKeyGenerationService(tt.baseURL, nil, tt.opts)
+			if (err != nil) != tt.expectError {
+				t.Errorf("expected error=%v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestRemoteKeyGenerationService_Buffering(t *testing.T) {
+	kgs, fake := newTestRemoteKGS(t, RemoteKGSOptions{BatchSize: 10, LowWatermark: 3, RequestTimeout: time.Second})
+
+	// The first request fetches a batch synchronously and buffers the surplus
+	id, err := kgs.GenerateUniqueID(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != "remote0000" {
+		t.Errorf("expected first ID 'remote0000', got %q", id)
+	}
+	if kgs.Buffered() != 9 {
+		t.Errorf("expected 9 buffered IDs, got %d", kgs.Buffered())
+	}
+
+	// Dropping below the low watermark triggers a background refill
+	if _, err := kgs.GetMultipleIDs(context.Background(), 7); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kgs.refills.Wait()
+	if got := fake.callCount(); got != 2 {
+		t.Errorf("expected 2 KGS calls, got %d", got)
+	}
+	if kgs.Buffered() != 12 {
+		t.Errorf("expected 12 buffered IDs after refill, got %d", kgs.Buffered())
+	}
+
+	// Requests larger than the buffer combine buffered and freshly fetched IDs
+	ids, err := kgs.GetMultipleIDs(context.Background(), 15)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if seen[id] {
+			t.Errorf("duplicate ID %q", id)
+		}
+		seen[id] = true
+	}
+	if len(ids) != 15 {
+		t.Errorf("expected 15 IDs, got %d", len(ids))
+	}
+}
+
+func TestRemoteKeyGenerationService_LargeRequest(t *testing.T) {
+	kgs, fake := newTestRemoteKGS(t, RemoteKGSOptions{BatchSize: 10, LowWatermark: 3, RequestTimeout: time.Second})
+
+	// Requests above the server limit are split into several calls
+	count := 2*maxRemoteBatchSize + 5
+	ids, err := kgs.GetMultipleIDs(context.Background(), count)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ids) != count {
+		t.Errorf("expected %d IDs, got %d", count, len(ids))
+	}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate ID %q", id)
+		}
+		seen[id] = true
+	}
+	if got := fake.callCount(); got != 3 {
+		t.Errorf("expected 3 KGS calls, got %d", got)
+	}
+}
+
+func TestRemoteKeyGenerationService_Unavailable(t *testing.T) {
+	t.Run("buffered IDs are served while the KGS is down", func(t *testing.T) {
+		kgs, fake := newTestRemoteKGS(t, RemoteKGSOptions{BatchSize: 10, LowWatermark: 5, RetryBackoff: time.Minute})
+		kgs.GenerateUniqueID(context.Background())
+		fake.setDown(true)
+
+		for i := 0; i < 9; i++ {
+			if _, err := kgs.GenerateUniqueID(context.Background()); err != nil {
+				t.Fatalf("expected buffered ID, got %v", err)
+			}
+			kgs.refills.Wait()
+		}
+
+		// Only one refill was attempted; later ones wait for the backoff
+		if got := fake.callCount(); got != 2 {
+			t.Errorf("expected 2 KGS calls, got %d", got)
+		}
+
+		if _, err := kgs.GenerateUniqueID(context.Background()); !errors.Is(err, ErrKGSUnavailable) {
+			t.Errorf("expected ErrKGSUnavailable once the buffer is empty, got %v", err)
+		}
+	})
+
+	t.Run("fallback takes over once the buffer is empty", func(t *testing.T) {
+		fallbackKGS, _ := NewTicketKeyGenerationService(mustTicketServer(t))
+		opts := RemoteKGSOptions{BatchSize: 10, LowWatermark: 5, RetryBackoff: time.Minute, Fallback: fallbackKGS}
+		kgs, fake := newTestRemoteKGS(t, opts)
+		fake.setDown(true)
+
+		id, err := kgs.GenerateUniqueID(context.Background())
+		if err != nil {
+			t.Fatalf("expected fallback ID, got %v", err)
+		}
+		if len(id) != base62IDLength {
+			t.Errorf("expected fallback ID, got %q", id)
+		}
+
+		// During the backoff the KGS is not called again
+		kgs.GenerateUniqueID(context.Background())
+		if got := fake.callCount(); got != 1 {
+			t.Errorf("expected 1 KGS call during backoff, got %d", got)
+		}
+	})
+
+	t.Run("recovery after the backoff", func(t *testing.T) {
+		kgs, fake := newTestRemoteKGS(t, RemoteKGSOptions{BatchSize: 10, LowWatermark: 5, RetryBackoff: time.Minute})
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		kgs.now = func() time.Time { return now }
+
+		fake.setDown(true)
+		kgs.GenerateUniqueID(context.Background())
+		fake.setDown(false)
+		now = now.Add(time.Minute)
+
+		if _, err := kgs.GenerateUniqueID(context.Background()); err != nil {
+			t.Errorf("expected KGS to be used again, got %v", err)
+		}
+	})
+}
+
+func TestNewFallbackKeyGenerationService(t *testing.T) {
+	lowercase, _ := domain.NewIDProfile(domain.AlphabetLowercase, 6)
+
+	for _, profile := range []*domain.IDProfile{nil, lowercase} {
+		name := "default"
+		if profile != nil {
+			name = profile.Alphabet()
+		}
+		t.Run(name, func(t *testing.T) {
+			// The KGS leases counters from zero, as cmd/kgs does
+			primary := NewBase62KeyGenerationService(WithCounterStore(NewMemoryHighWaterMarkStore(0), 0), WithIDProfile(profile))
+			fallback, err := NewFallbackKeyGenerationService(profile)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			primaryIDs, err := primary.GetMultipleIDs(context.Background(), 5000)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			issued := make(map[string]bool)
+			for _, id := range primaryIDs {
+				issued[id] = true
+			}
+
+			fallbackIDs, err := fallback.GetMultipleIDs(context.Background(), 5000)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := domain.DefaultIDProfile()
+			if profile != nil {
+				want = profile
+			}
+			drawn := make(map[string]bool)
+			for _, id := range fallbackIDs {
+				if issued[id] {
+					t.Fatalf("fallback ID %q was also issued by the KGS", id)
+				}
+				if drawn[id] {
+					t.Errorf("duplicate fallback ID %q", id)
+				}
+				drawn[id] = true
+				if len(id) != want.Length()+1 || want.Validate(id) != nil {
+					t.Errorf("fallback ID %q is not one character longer than the profile", id)
+				}
+			}
+		})
+	}
+
+	t.Run("no room for longer IDs", func(t *testing.T) {
+		profile, err := domain.NewIDProfile(domain.AlphabetBase62, 10)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := NewFallbackKeyGenerationService(profile); err == nil {
+			t.Error("expected an error when the longer IDs overflow the ID space")
+		}
+	})
+}
+
+func TestRemoteKeyGenerationService_CanceledContext(t *testing.T) {
+	kgs, fake := newTestRemoteKGS(t, DefaultRemoteKGSOptions())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := kgs.GenerateUniqueID(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if got := fake.callCount(); got != 0 {
+		t.Errorf("expected no KGS call, got %d", got)
+	}
+}
+
+func mustTicketServer(t *testing.T) *MemoryTicketServer {
+	t.Helper()
+
+	server, err := NewMemoryTicketServer(1, 1)
+	if err != nil {
+		t.Fatalf("failed to create ticket server: %v", err)
+	}
+	return server
+}
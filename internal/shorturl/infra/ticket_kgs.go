@@ -0,0 +1,163 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ticketServerCooldown is how long a ticket server that failed is skipped before it is
+// tried again, so that an outage does not add a failed call to every ID generation.
+const ticketServerCooldown = 5 * time.Second
+
+// TicketKeyGenerationService implements the KeyGenerationService interface on top of
+// a set of ticket servers. Requests are spread over the servers round-robin; when a
+// server fails, the next one is tried and the failed server is skipped for a cooldown
+// period. Tickets from servers with distinct offsets never collide, and each ticket is
+// mapped through the same bijection of the 62^7 ID space as leased counters, so IDs are
+// unique, 7 characters long and not visibly sequential.
+type TicketKeyGenerationService struct {
+	mu          sync.Mutex       // Guards next and failedUntil
+	servers     []TicketServer   // Ticket servers in round-robin order
+	next        int              // Index of the server to try first for the next ticket
+	failedUntil []time.Time      // Per server, the time until which it is skipped after a failure
+	now         func() time.Time // Clock, replaceable in tests
+}
+
+// NewTicketKeyGenerationService creates a key generation service backed by ticket servers.
+// The servers must share the same increment and use distinct offsets.
+//
+// Parameters:
+//   - servers: Ticket servers to pull tickets from
+//
+// Returns:
+//   - *TicketKeyGenerationService: Service instance
+//   - error: Error if no ticket server is given
+func NewTicketKeyGenerationService(servers ...TicketServer) (*TicketKeyGenerationService, error) {
+	if len(servers) == 0 {
+		return nil, errors.New("at least one ticket server is required")
+	}
+
+	return &TicketKeyGenerationService{
+		servers:     servers,
+		failedUntil: make([]time.Time, len(servers)),
+		now:         time.Now,
+	}, nil
+}
+
+// GenerateUniqueID pulls a ticket from the next available ticket server and encodes it.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//
+// Returns:
+//   - string: A unique 7-character Base62-encoded identifier
+//   - error: Error if every ticket server failed, ErrIDSpaceExhausted, or the context error
+func (k *TicketKeyGenerationService) GenerateUniqueID(ctx context.Context) (string, error) {
+	ticket, err := k.nextTicket(ctx)
+	if err != nil {
+		return "", err
+	}
+	if ticket < 0 || ticket >= base62IDSpace {
+		return "", ErrIDSpaceExhausted
+	}
+	return encodeFixedBase62(scrambleCounter(uint64(ticket), base62IDSpace), base62IDLength), nil
+}
+
+// GetMultipleIDs generates multiple unique identifiers, one ticket per identifier.
+//
+// Parameters:
+//   - ctx: Context for cancellation, checked before each ticket is requested
+//   - count: Number of IDs to generate
+//
+// Returns:
+//   - []string: Slice of unique Base62-encoded identifiers
+//   - error: Error if every ticket server failed, ErrIDSpaceExhausted, or the context error
+func (k *TicketKeyGenerationService) GetMultipleIDs(ctx context.Context, count int) ([]string, error) {
+	ids := make([]string, count)
+	for i := range count {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		id, err := k.GenerateUniqueID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// nextTicket tries the ticket servers in round-robin order, starting with the servers
+// that are not cooling down after a failure. Servers in cooldown are still tried as a
+// last resort, so a recovered server is used again as soon as it is the only option.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//
+// Returns:
+//   - int64: The issued ticket
+//   - error: Error wrapping the last server error if every server failed, or the context error
+func (k *TicketKeyGenerationService) nextTicket(ctx context.Context) (int64, error) {
+	order := k.tryOrder()
+
+	var lastErr error
+	for _, i := range order {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		ticket, err := k.servers[i].NextTicket(ctx)
+		if err == nil {
+			k.markHealthy(i)
+			return ticket, nil
+		}
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		k.markFailed(i)
+		lastErr = err
+	}
+
+	return 0, fmt.Errorf("all %d ticket servers failed: %w", len(k.servers), lastErr)
+}
+
+// tryOrder returns the server indexes to try for one ticket and advances the round-robin
+// position: healthy servers first in round-robin order, then those in cooldown.
+func (k *TicketKeyGenerationService) tryOrder() []int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	healthy := make([]int, 0, len(k.servers))
+	var coolingDown []int
+	for n := 0; n < len(k.servers); n++ {
+		i := (k.next + n) % len(k.servers)
+		if now.Before(k.failedUntil[i]) {
+			coolingDown = append(coolingDown, i)
+		} else {
+			healthy = append(healthy, i)
+		}
+	}
+	k.next = (k.next + 1) % len(k.servers)
+
+	return append(healthy, coolingDown...)
+}
+
+// markFailed puts a server into cooldown after a failed request.
+func (k *TicketKeyGenerationService) markFailed(i int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.failedUntil[i] = k.now().Add(ticketServerCooldown)
+}
+
+// markHealthy ends the cooldown of a server that answered successfully.
+func (k *TicketKeyGenerationService) markHealthy(i int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.failedUntil[i] = time.Time{}
+}
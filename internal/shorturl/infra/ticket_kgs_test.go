@@ -0,0 +1,135 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingTicketServer records how many tickets were requested from the wrapped server.
+type countingTicketServer struct {
+	*MemoryTicketServer
+	mu    sync.Mutex
+	calls int
+}
+
+func (s *countingTicketServer) NextTicket(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+	return s.MemoryTicketServer.NextTicket(ctx)
+}
+
+func newOddEvenServers(t *testing.T) (*countingTicketServer, *countingTicketServer) {
+	t.Helper()
+
+	odd, _ := NewMemoryTicketServer(2, 1)
+	even, _ := NewMemoryTicketServer(2, 2)
+	return &countingTicketServer{MemoryTicketServer: odd}, &countingTicketServer{MemoryTicketServer: even}
+}
+
+func TestNewTicketKeyGenerationService(t *testing.T) {
+	if _, err := NewTicketKeyGenerationService(); err == nil {
+		t.Error("expected error without ticket servers")
+	}
+
+	server, _ := NewMemoryTicketServer(1, 1)
+	if _, err := NewTicketKeyGenerationService(server); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTicketKeyGenerationService_RoundRobin(t *testing.T) {
+	odd, even := newOddEvenServers(t)
+	kgs, _ := NewTicketKeyGenerationService(odd, even)
+
+	ids, err := kgs.GetMultipleIDs(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if len(id) != base62IDLength || !isValidBase62(id) {
+			t.Errorf("invalid ID %q", id)
+		}
+		if seen[id] {
+			t.Errorf("duplicate ID %q", id)
+		}
+		seen[id] = true
+	}
+
+	if odd.calls != 50 || even.calls != 50 {
+		t.Errorf("expected requests to alternate between servers, got %d and %d", odd.calls, even.calls)
+	}
+
+	// Tickets 1..100 were issued, so the IDs are exactly their images
+	for ticket := uint64(1); ticket <= 100; ticket++ {
+		if !seen[encodeFixedBase62(scrambleCounter(ticket, base62IDSpace), base62IDLength)] {
+			t.Errorf("expected ID for ticket %d", ticket)
+		}
+	}
+}
+
+func TestTicketKeyGenerationService_Failover(t *testing.T) {
+	odd, even := newOddEvenServers(t)
+	kgs, _ := NewTicketKeyGenerationService(odd, even)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	kgs.now = func() time.Time { return now }
+
+	odd.SetAvailable(false)
+	for i := 0; i < 4; i++ {
+		if _, err := kgs.GenerateUniqueID(context.Background()); err != nil {
+			t.Fatalf("unexpected error during failover: %v", err)
+		}
+	}
+	if even.calls != 4 {
+		t.Errorf("expected the even server to serve all 4 requests, got %d", even.calls)
+	}
+	if odd.calls != 1 {
+		t.Errorf("expected the failed server to be skipped during cooldown, got %d calls", odd.calls)
+	}
+
+	// After the cooldown the recovered server is used again
+	odd.SetAvailable(true)
+	now = now.Add(ticketServerCooldown)
+	kgs.GenerateUniqueID(context.Background())
+	kgs.GenerateUniqueID(context.Background())
+	if odd.calls != 2 {
+		t.Errorf("expected the recovered server to be retried, got %d calls", odd.calls)
+	}
+}
+
+func TestTicketKeyGenerationService_AllServersDown(t *testing.T) {
+	odd, even := newOddEvenServers(t)
+	kgs, _ := NewTicketKeyGenerationService(odd, even)
+
+	odd.SetAvailable(false)
+	even.SetAvailable(false)
+	if _, err := kgs.GenerateUniqueID(context.Background()); !errors.Is(err, ErrTicketServerUnavailable) {
+		t.Errorf("expected ErrTicketServerUnavailable, got %v", err)
+	}
+
+	// Servers in cooldown are still tried when nothing else is left
+	even.SetAvailable(true)
+	if _, err := kgs.GenerateUniqueID(context.Background()); err != nil {
+		t.Errorf("expected recovered server to be used as a last resort, got %v", err)
+	}
+}
+
+func TestTicketKeyGenerationService_CanceledContext(t *testing.T) {
+	odd, even := newOddEvenServers(t)
+	kgs, _ := NewTicketKeyGenerationService(odd, even)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := kgs.GenerateUniqueID(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GenerateUniqueID: expected context.Canceled, got %v", err)
+	}
+	if _, err := kgs.GetMultipleIDs(ctx, 5); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetMultipleIDs: expected context.Canceled, got %v", err)
+	}
+}
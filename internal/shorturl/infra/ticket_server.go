@@ -0,0 +1,91 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrTicketServerUnavailable is returned by a ticket server that cannot issue tickets.
+var ErrTicketServerUnavailable = errors.New("ticket server unavailable")
+
+// TicketServer issues unique, increasing integers in the style of Flickr's ticket servers:
+// a database table with an auto-increment column where each server is configured with the
+// same auto_increment_increment and a distinct auto_increment_offset. With increment 2 and
+// offsets 1 and 2, one server issues odd and the other even tickets, so either can fail
+// without the other ever issuing a duplicate.
+type TicketServer interface {
+	// NextTicket returns the next ticket of this server.
+	NextTicket(ctx context.Context) (int64, error)
+}
+
+// MemoryTicketServer is an in-process TicketServer that behaves like an auto-increment
+// column with the given increment and offset. Its availability can be toggled to
+// simulate outages in tests.
+type MemoryTicketServer struct {
+	mu          sync.Mutex // Serializes ticket issuance
+	increment   int64      // Step between consecutive tickets (auto_increment_increment)
+	next        int64      // Next ticket to issue
+	unavailable bool       // When set, NextTicket fails with ErrTicketServerUnavailable
+}
+
+// NewMemoryTicketServer creates an in-process ticket server. Like MySQL, the first
+// ticket equals the offset and every following ticket adds the increment.
+//
+// Parameters:
+//   - increment: Step between tickets; typically the number of ticket servers
+//   - offset: First ticket; must be between 1 and increment so servers never overlap
+//
+// Returns:
+//   - *MemoryTicketServer: Ticket server instance
+//   - error: Error if increment or offset is out of range
+func NewMemoryTicketServer(increment, offset int64) (*MemoryTicketServer, error) {
+	if increment <= 0 {
+		return nil, fmt.Errorf("ticket increment must be positive, got %d", increment)
+	}
+	if offset < 1 || offset > increment {
+		return nil, fmt.Errorf("ticket offset must be between 1 and %d, got %d", increment, offset)
+	}
+
+	return &MemoryTicketServer{
+		increment: increment,
+		next:      offset,
+	}, nil
+}
+
+// NextTicket returns the next ticket and advances the server by its increment.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//
+// Returns:
+//   - int64: The issued ticket
+//   - error: ErrTicketServerUnavailable if the server is marked unavailable, or the context error
+func (s *MemoryTicketServer) NextTicket(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.unavailable {
+		return 0, ErrTicketServerUnavailable
+	}
+
+	ticket := s.next
+	s.next += s.increment
+	return ticket, nil
+}
+
+// SetAvailable marks the server as available or unavailable.
+//
+// Parameters:
+//   - available: False to make NextTicket fail until the server is made available again
+func (s *MemoryTicketServer) SetAvailable(available bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.unavailable = !available
+}
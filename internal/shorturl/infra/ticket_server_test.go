@@ -0,0 +1,70 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNewMemoryTicketServer(t *testing.T) {
+	tests := []struct {
+		name        string
+		increment   int64
+		offset      int64
+		expectError bool
+		expected    []int64
+	}{
+		{name: "single server", increment: 1, offset: 1, expected: []int64{1, 2, 3}},
+		{name: "odd tickets", increment: 2, offset: 1, expected: []int64{1, 3, 5}},
+		{name: "even tickets", increment: 2, offset: 2, expected: []int64{2, 4, 6}},
+		{name: "zero increment", increment: 0, offset: 1, expectError: true},
+		{name: "zero offset", increment: 2, offset: 0, expectError: true},
+		{name: "offset above increment", increment: 2, offset: 3, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := NewMemoryTicketServer(tt.increment, tt.offset)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, want := range tt.expected {
+				got, err := server.NextTicket(context.Background())
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got != want {
+					t.Errorf("expected ticket %d, got %d", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryTicketServer_Availability(t *testing.T) {
+	server, _ := NewMemoryTicketServer(1, 1)
+
+	server.SetAvailable(false)
+	if _, err := server.NextTicket(context.Background()); !errors.Is(err, ErrTicketServerUnavailable) {
+		t.Errorf("expected ErrTicketServerUnavailable, got %v", err)
+	}
+
+	// No ticket is consumed while the server is down
+	server.SetAvailable(true)
+	if ticket, _ := server.NextTicket(context.Background()); ticket != 1 {
+		t.Errorf("expected ticket 1, got %d", ticket)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := server.NextTicket(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
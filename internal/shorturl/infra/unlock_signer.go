@@ -0,0 +1,112 @@
+package infra
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultUnlockTTL is how long a visitor stays unlocked after entering the right password.
+const DefaultUnlockTTL = 15 * time.Minute
+
+// minUnlockKeySize is the minimum length of the signing key in bytes.
+const minUnlockKeySize = 32
+
+// HMACUnlockSigner implements the UnlockSigner interface with HMAC-SHA256. A token is
+// "<expiry>.<signature>", where expiry is a Unix time and the signature covers the short
+// URL ID, its password hash and the expiry, so a token can neither be moved to another URL
+// nor extended, and stops working once the password is replaced. The hash itself is never
+// part of the token.
+type HMACUnlockSigner struct {
+	key []byte        // Secret signing key
+	ttl time.Duration // Validity of issued tokens
+}
+
+// NewHMACUnlockSigner creates an unlock token signer.
+//
+// Parameters:
+//   - key: Secret signing key of at least 32 bytes; a random key when nil, so that
+//     tokens do not survive a restart
+//   - ttl: Validity of issued tokens; DefaultUnlockTTL when 0 or less
+//
+// Returns:
+//   - *HMACUnlockSigner: Signer instance
+//   - error: Error if the key is too short or no random key could be generated
+func NewHMACUnlockSigner(key []byte, ttl time.Duration) (*HMACUnlockSigner, error) {
+	if key == nil {
+		key = make([]byte, minUnlockKeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	if len(key) < minUnlockKeySize {
+		return nil, errors.New("unlock signing key must be at least 32 bytes")
+	}
+	if ttl <= 0 {
+		ttl = DefaultUnlockTTL
+	}
+	return &HMACUnlockSigner{
+		key: append([]byte(nil), key...),
+		ttl: ttl,
+	}, nil
+}
+
+// Sign issues a token for the short URL ID and password hash that is valid for the
+// signer's TTL.
+//
+// Parameters:
+//   - id: Identifier of the unlocked short URL
+//   - passwordHash: Encoded hash of the password the visitor entered
+//   - now: Time of issue
+//
+// Returns:
+//   - string: The token
+//   - time.Time: When the token stops being valid
+func (s *HMACUnlockSigner) Sign(id, passwordHash string, now time.Time) (string, time.Time) {
+	expires := now.Add(s.ttl).Truncate(time.Second)
+	expiry := strconv.FormatInt(expires.Unix(), 10)
+	return expiry + "." + base64.RawURLEncoding.EncodeToString(s.mac(id, passwordHash, expiry)), expires
+}
+
+// Verify reports whether the token was issued for the ID and password hash by this signer
+// and has not expired.
+//
+// Parameters:
+//   - token: Token returned by Sign
+//   - id: Identifier of the short URL being visited
+//   - passwordHash: Current encoded hash of the short URL's password
+//   - now: Current time
+//
+// Returns:
+//   - bool: Whether the token is valid
+func (s *HMACUnlockSigner) Verify(token, id, passwordHash string, now time.Time) bool {
+	expiry, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	seconds, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || !now.Before(time.Unix(seconds, 0)) {
+		return false
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(decoded, s.mac(id, passwordHash, expiry))
+}
+
+// mac computes the signature of a token for the ID, the password hash and the encoded expiry.
+func (s *HMACUnlockSigner) mac(id, passwordHash, expiry string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(id))
+	mac.Write([]byte{0})
+	mac.Write([]byte(passwordHash))
+	mac.Write([]byte{0})
+	mac.Write([]byte(expiry))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,70 @@
+package infra
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHMACUnlockSigner(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	signer, err := NewHMACUnlockSigner(key, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	token, expires := signer.Sign("abc123", "hash-1", now)
+	if !expires.Equal(now.Add(10 * time.Minute)) {
+		t.Errorf("expected expiry after the TTL, got %v", expires)
+	}
+
+	_, signature, _ := strings.Cut(token, ".")
+	other, _ := NewHMACUnlockSigner(bytes.Repeat([]byte("x"), 32), 10*time.Minute)
+	tests := []struct {
+		name     string
+		signer   *HMACUnlockSigner
+		token    string
+		id       string
+		hash     string
+		now      time.Time
+		expected bool
+	}{
+		{"valid", signer, token, "abc123", "hash-1", now.Add(time.Minute), true},
+		{"expired", signer, token, "abc123", "hash-1", expires, false},
+		{"other short URL", signer, token, "def456", "hash-1", now, false},
+		{"password replaced", signer, token, "abc123", "hash-2", now, false},
+		{"other key", other, token, "abc123", "hash-1", now, false},
+		{"extended expiry", signer, "9999999999." + signature, "abc123", "hash-1", now, false},
+		{"malformed", signer, "garbage", "abc123", "hash-1", now, false},
+		{"empty", signer, "", "abc123", "hash-1", now, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.signer.Verify(tt.token, tt.id, tt.hash, tt.now); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewHMACUnlockSigner_Key(t *testing.T) {
+	if _, err := NewHMACUnlockSigner([]byte("short"), 0); err == nil {
+		t.Error("expected a short key to be rejected")
+	}
+
+	// Without a key, tokens are signed with a random one
+	first, err := NewHMACUnlockSigner(nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := NewHMACUnlockSigner(nil, 0)
+	token, _ := first.Sign("abc123", "hash", time.Now())
+	if !first.Verify(token, "abc123", "hash", time.Now()) || second.Verify(token, "abc123", "hash", time.Now()) {
+		t.Error("expected tokens to verify only with the signer that issued them")
+	}
+	if first.ttl != DefaultUnlockTTL {
+		t.Errorf("expected default TTL, got %v", first.ttl)
+	}
+}
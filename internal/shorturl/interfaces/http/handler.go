@@ -4,21 +4,39 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
-	"strings"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/oharai/short-url/internal/shorturl/app"
+	"github.com/oharai/short-url/internal/shorturl/domain"
 )
 
-// ShortURLServiceInterface defines the interface for the application service
+// ShortURLServiceInterface defines the interface for the application service.
+// Handlers pass the request context so that client disconnects and server
+// timeouts cancel the work in progress.
 type ShortURLServiceInterface interface {
-	CreateShortURL(req app.CreateShortURLRequest) (*app.CreateShortURLResponse, error)
-	GetLongURL(req app.GetLongURLRequest) (string, error)
-	GetAllShortURLs() ([]*app.ShortURLResponse, error)
-	DeactivateShortURL(id string) error
+	CreateShortURL(ctx context.Context, req app.CreateShortURLRequest) (*app.CreateShortURLResponse, error)
+	GetLongURL(ctx context.Context, req app.GetLongURLRequest) (string, error)
+	ListShortURLs(ctx context.Context, req app.ListShortURLsRequest) (*app.ListShortURLsResponse, error)
+	ChangeState(ctx context.Context, req app.ChangeStateRequest) (*app.ShortURLResponse, error)
+	UpdateDestination(ctx context.Context, req app.UpdateDestinationRequest) (*app.ShortURLResponse, error)
+	UnlockShortURL(ctx context.Context, req app.UnlockShortURLRequest) (*app.UnlockShortURLResponse, error)
 }
 
+// unlockCookie is the name of the cookie holding the unlock token of a password-protected
+// short URL. It is scoped to the short URL's path, so each URL has its own.
+const unlockCookie = "shorturl_unlock"
+
+// maxPasswordFormBytes limits the size of a submitted password form.
+const maxPasswordFormBytes = 4096
+
 // ShortURLHandler handles HTTP requests for the URL shortening service.
 // It acts as the presentation layer, converting HTTP requests into application
 // service calls and formatting responses according to REST API conventions.
@@ -51,38 +69,25 @@ func NewShortURLHandler(service ShortURLServiceInterface) *ShortURLHandler {
 //
 // Response Format:
 //   - Success: 200 OK with CreateShortURLResponse JSON
-//   - Error: 400/409/500 with error message JSON
+//   - Error: 400/409/500 with application/problem+json body
 func (h *ShortURLHandler) CreateShortURL(w http.ResponseWriter, r *http.Request) {
 	// Validate HTTP method
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r, http.MethodPost)
 		return
 	}
 
 	// Parse and validate request body
 	var req app.CreateShortURLRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		if encodeErr := json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON"}); encodeErr != nil {
-			http.Error(w, "Failed to encode error response", http.StatusInternalServerError)
-		}
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid JSON")
 		return
 	}
 
 	// Process request through application service
-	resp, err := h.service.CreateShortURL(req)
+	resp, err := h.service.CreateShortURL(r.Context(), req)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		// Determine appropriate HTTP status code based on error type
-		if strings.Contains(err.Error(), "already exists") {
-			w.WriteHeader(http.StatusConflict)
-		} else {
-			w.WriteHeader(http.StatusBadRequest)
-		}
-		if encodeErr := json.NewEncoder(w).Encode(map[string]string{"error": err.Error()}); encodeErr != nil {
-			http.Error(w, "Failed to encode error response", http.StatusInternalServerError)
-		}
+		writeError(w, r, err)
 		return
 	}
 
@@ -106,38 +111,29 @@ func (h *ShortURLHandler) CreateShortURL(w http.ResponseWriter, r *http.Request)
 //
 // Response Format:
 //   - Success: 302 Found with Location header
-//   - Error: 400/404 with error message JSON
+//   - Other region: 307 Temporary Redirect to the region that owns the URL
+//   - Error: 400/404/410/500 with application/problem+json body
 func (h *ShortURLHandler) GetLongURL(w http.ResponseWriter, r *http.Request) {
 	// Validate HTTP method
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r, http.MethodGet)
 		return
 	}
 
 	// Parse and validate request body
 	var req app.GetLongURLRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		if encodeErr := json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON"}); encodeErr != nil {
-			http.Error(w, "Failed to encode error response", http.StatusInternalServerError)
-		}
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid JSON")
 		return
 	}
 
 	// Resolve short URL through application service
-	longURL, err := h.service.GetLongURL(req)
+	longURL, err := h.service.GetLongURL(r.Context(), req)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		// Determine appropriate HTTP status code based on error type
-		if strings.Contains(err.Error(), "not found") {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusBadRequest)
-		}
-		if encodeErr := json.NewEncoder(w).Encode(map[string]string{"error": err.Error()}); encodeErr != nil {
-			http.Error(w, "Failed to encode error response", http.StatusInternalServerError)
+		if redirectToOwningRegion(w, r, err) {
+			return
 		}
+		writeError(w, r, err)
 		return
 	}
 
@@ -149,53 +145,52 @@ func (h *ShortURLHandler) GetLongURL(w http.ResponseWriter, r *http.Request) {
 // RedirectShortURL handles GET /<shortId> requests for direct URL redirection.
 // This endpoint extracts the short URL identifier from the path, resolves it
 // to the original URL, tracks the access event, and performs an HTTP redirect.
+// Password-protected short URLs answer with an HTML form that POSTs the password
+// back to the same path; see unlockShortURL.
 //
 // Request Format:
-//   - Method: GET
+//   - Method: GET, or POST with a form-encoded password
 //   - Path: /<shortId>
-//   - No body required
+//   - No body required for GET
 //
 // Response Format:
-//   - Success: 302 Found redirect to original URL
-//   - Error: 404/400 with error message JSON
+//   - Success: 302 Found redirect to original URL, or to the destination of the first
+//     routing rule matching the visitor
+//   - Other region: 307 Temporary Redirect to the region that owns the URL
+//   - Before the activation time: 302 Found redirect to the pre-launch URL, or
+//     503 Service Unavailable with a Retry-After header if there is none
+//   - Password protected and not unlocked: 401 Unauthorized with an HTML password form
+//   - Blocked destination: 403 Forbidden with an HTML warning page
+//   - Error: 400/404/410/500 with application/problem+json body
 func (h *ShortURLHandler) RedirectShortURL(w http.ResponseWriter, r *http.Request) {
 	// Validate HTTP method
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		h.unlockShortURL(w, r)
+		return
+	default:
+		writeMethodNotAllowed(w, r, http.MethodGet+", "+http.MethodPost)
 		return
-	}
-
-	// Reconstruct the complete short URL from the request
-	shortURL := r.Host + r.URL.Path
-	if r.URL.Scheme != "" {
-		shortURL = r.URL.Scheme + "://" + shortURL
-	} else {
-		shortURL = "http://" + shortURL
 	}
 
 	// Prepare request with user context for analytics tracking
 	req := app.GetLongURLRequest{
-		ShortURL: shortURL,
-		UserMetadata: map[string]interface{}{
-			"ip":         r.RemoteAddr,  // Client IP address
-			"user_agent": r.UserAgent(), // Browser/client information
-			"referer":    r.Referer(),   // Referring page
-		},
+		ShortURL:     requestShortURL(r),
+		UserMetadata: visitorMetadata(r),
+	}
+	if cookie, err := r.Cookie(unlockCookie); err == nil {
+		req.UnlockToken = cookie.Value
 	}
 
 	// Resolve short URL through application service
-	longURL, err := h.service.GetLongURL(req)
+	longURL, err := h.service.GetLongURL(r.Context(), req)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		// Determine appropriate HTTP status code based on error type
-		if strings.Contains(err.Error(), "not found") {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusBadRequest)
-		}
-		if encodeErr := json.NewEncoder(w).Encode(map[string]string{"error": err.Error()}); encodeErr != nil {
-			http.Error(w, "Failed to encode error response", http.StatusInternalServerError)
+		if errors.Is(err, domain.ErrPasswordRequired) {
+			writePasswordPage(w, r, http.StatusUnauthorized, "")
+			return
 		}
+		writeRedirectError(w, r, err)
 		return
 	}
 
@@ -203,35 +198,133 @@ func (h *ShortURLHandler) RedirectShortURL(w http.ResponseWriter, r *http.Reques
 	http.Redirect(w, r, longURL, http.StatusFound)
 }
 
-// GetAllShortURLs handles GET /admin/shorturls requests.
-// This administrative endpoint retrieves all short URLs for management purposes.
-// It returns a JSON array containing complete information about all URLs.
+// unlockShortURL handles POST /<shortId> requests from the password form. On the right
+// password it stores the unlock token in a cookie scoped to the short URL and sends the
+// browser back to the short URL, which then redirects as usual.
+//
+// Response Format:
+//   - Success: 303 See Other to the short URL, with a Set-Cookie header
+//   - Wrong password: 401 Unauthorized with the HTML password form
+//   - Too many wrong passwords: 429 Too Many Requests with the HTML password form
+//   - Other errors: as for GET requests
+func (h *ShortURLHandler) unlockShortURL(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasswordFormBytes)
+	if err := r.ParseForm(); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidForm, "Invalid form")
+		return
+	}
+
+	resp, err := h.service.UnlockShortURL(r.Context(), app.UnlockShortURLRequest{
+		ShortURL:     requestShortURL(r),
+		Password:     r.PostForm.Get("password"),
+		UserMetadata: visitorMetadata(r),
+	})
+	switch {
+	case errors.Is(err, domain.ErrWrongPassword):
+		writePasswordPage(w, r, http.StatusUnauthorized, "Wrong password. Please try again.")
+		return
+	case errors.Is(err, domain.ErrTooManyAttempts):
+		writePasswordPage(w, r, http.StatusTooManyRequests, "Too many wrong passwords. Please try again later.")
+		return
+	case err != nil:
+		writeRedirectError(w, r, err)
+		return
+	}
+
+	if resp.Token != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     unlockCookie,
+			Value:    resp.Token,
+			Path:     r.URL.Path,
+			Expires:  resp.ExpiresAt,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	http.Redirect(w, r, r.URL.RequestURI(), http.StatusSeeOther)
+}
+
+// requestShortURL reconstructs the complete short URL from a redirect request.
+func requestShortURL(r *http.Request) string {
+	shortURL := r.Host + r.URL.Path
+	if r.URL.Scheme != "" {
+		return r.URL.Scheme + "://" + shortURL
+	}
+	return "http://" + shortURL
+}
+
+// visitorMetadata collects the user context of a redirect request for analytics tracking
+// and for matching the short URL's routing rules.
+func visitorMetadata(r *http.Request) map[string]interface{} {
+	return map[string]interface{}{
+		"ip":              r.RemoteAddr,                    // Client IP address
+		"user_agent":      r.UserAgent(),                   // Browser/client information
+		"referer":         r.Referer(),                     // Referring page
+		"accept_language": r.Header.Get("Accept-Language"), // Preferred languages
+	}
+}
+
+// writeRedirectError answers a redirect request that cannot be redirected to the
+// destination: requests are routed to the owning region or the pre-launch URL where
+// possible, blocked destinations get the HTML warning page, and any other error a
+// problem details response.
+func writeRedirectError(w http.ResponseWriter, r *http.Request, err error) {
+	if redirectToOwningRegion(w, r, err) {
+		return
+	}
+	if redirectBeforeLaunch(w, r, err) {
+		return
+	}
+	if errors.Is(err, domain.ErrBlocked) {
+		// Browsers follow redirects blindly, so explain the block instead of returning JSON
+		writeWarningPage(w, r)
+		return
+	}
+	writeError(w, r, err)
+}
+
+// ListShortURLs handles GET /admin/shorturls requests.
+// This administrative endpoint returns one page of short URLs ordered by creation time.
+// Follow nextCursor with the same filters and order to fetch the following pages; a cursor
+// given with other filters or another order is rejected. Before pagination the endpoint
+// returned every short URL as a bare JSON array; clients must now read the items field.
 //
 // Request Format:
 //   - Method: GET
 //   - Path: /admin/shorturls
-//   - No body required
+//   - Query parameters (all optional):
+//     status=active|inactive|expired|scheduled, createdFrom=<RFC 3339>, createdTo=<RFC 3339>,
+//     domain=<host>, metadataKey=<key>, metadataValue=<value>, order=asc|desc,
+//     cursor=<nextCursor>, limit=<1-1000>
 //
 // Response Format:
-//   - Success: 200 OK with ShortURLResponse array JSON
-//   - Error: 500 with error message
-func (h *ShortURLHandler) GetAllShortURLs(w http.ResponseWriter, r *http.Request) {
+//   - Success: 200 OK with ListShortURLsResponse JSON, an object with items and nextCursor
+//   - Error: 400 (invalid_query, invalid_cursor, cursor_mismatch) or 500 with application/problem+json body
+func (h *ShortURLHandler) ListShortURLs(w http.ResponseWriter, r *http.Request) {
 	// Validate HTTP method
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r, http.MethodGet)
 		return
 	}
 
-	// Retrieve all URLs through application service
-	shortURLs, err := h.service.GetAllShortURLs()
+	// Parse query parameters into the listing request
+	req, err := parseListRequest(r.URL.Query())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidQuery, err.Error())
+		return
+	}
+
+	// Retrieve the page through application service
+	page, err := h.service.ListShortURLs(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	// Return JSON response with all URLs
+	// Return JSON response with the page
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(shortURLs); err != nil {
+	if err := json.NewEncoder(w).Encode(page); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -244,31 +337,250 @@ func (h *ShortURLHandler) GetAllShortURLs(w http.ResponseWriter, r *http.Request
 // Request Format:
 //   - Method: DELETE
 //   - Path: /admin/deactivate?id=<url_id>
-//   - No body required
+//   - Optional body: {"reason": "<why>", "actor": "<who>"}
 //
 // Response Format:
 //   - Success: 204 No Content
-//   - Error: 400/404 with error message
+//   - Error: 400/404/409/500 with application/problem+json body
 func (h *ShortURLHandler) DeactivateShortURL(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.changeState(w, r, http.MethodDelete, domain.StateDeactivated); !ok {
+		return
+	}
+
+	// Return successful response with no content
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ActivateShortURL handles POST /admin/activate requests, reactivating a paused,
+// deactivated or blocked short URL. See changeState for the request format.
+func (h *ShortURLHandler) ActivateShortURL(w http.ResponseWriter, r *http.Request) {
+	h.writeStateChange(w, r, domain.StateActive)
+}
+
+// PauseShortURL handles POST /admin/pause requests, temporarily switching off an
+// active short URL. See changeState for the request format.
+func (h *ShortURLHandler) PauseShortURL(w http.ResponseWriter, r *http.Request) {
+	h.writeStateChange(w, r, domain.StatePaused)
+}
+
+// BlockShortURL handles POST /admin/block requests, switching off a short URL for
+// abuse so that visitors see a warning page. See changeState for the request format.
+func (h *ShortURLHandler) BlockShortURL(w http.ResponseWriter, r *http.Request) {
+	h.writeStateChange(w, r, domain.StateBlocked)
+}
+
+// ArchiveShortURL handles POST /admin/archive requests, retiring a short URL for good.
+// See changeState for the request format.
+func (h *ShortURLHandler) ArchiveShortURL(w http.ResponseWriter, r *http.Request) {
+	h.writeStateChange(w, r, domain.StateArchived)
+}
+
+// writeStateChange performs a lifecycle transition requested with POST and responds
+// with the updated short URL.
+//
+// Response Format:
+//   - Success: 200 OK with ShortURLResponse JSON including the transition log
+//   - Error: 400/404/409/500 with application/problem+json body
+func (h *ShortURLHandler) writeStateChange(w http.ResponseWriter, r *http.Request, state domain.LifecycleState) {
+	resp, ok := h.changeState(w, r, http.MethodPost, state)
+	if !ok {
+		return
+	}
+
+	// Return JSON response with the updated URL
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// changeState moves the short URL named by the id query parameter to the given state.
+// The request body is optional; when present it is a JSON object with the reason for
+// the change and the acting user. Errors are written to the response, in which case
+// ok is false.
+//
+// Request Format:
+//   - Method: method
+//   - Path: /admin/<action>?id=<url_id>
+//   - Optional body: {"reason": "<why>", "actor": "<who>"}
+func (h *ShortURLHandler) changeState(w http.ResponseWriter, r *http.Request, method string, state domain.LifecycleState) (*app.ShortURLResponse, bool) {
 	// Validate HTTP method
-	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if r.Method != method {
+		writeMethodNotAllowed(w, r, method)
+		return nil, false
+	}
+
+	// Extract and validate ID parameter
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeError(w, r, domain.ErrIDRequired)
+		return nil, false
+	}
+
+	// Parse the optional reason and actor
+	var body struct {
+		Reason string `json:"reason"`
+		Actor  string `json:"actor"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid JSON")
+		return nil, false
+	}
+
+	// Change the state through application service
+	resp, err := h.service.ChangeState(r.Context(), app.ChangeStateRequest{
+		ID:     id,
+		State:  string(state),
+		Reason: body.Reason,
+		Actor:  body.Actor,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	return resp, true
+}
+
+// UpdateDestination handles PATCH /admin/destination requests.
+// This administrative endpoint points an existing short URL to a new long URL,
+// keeping the previous destination in the URL's history.
+// The ID is provided as a query parameter.
+//
+// Request Format:
+//   - Method: PATCH
+//   - Path: /admin/destination?id=<url_id>
+//   - Content-Type: application/json
+//   - Body: {"longUrl": "<new destination>", "actor": "<who makes the change>"}
+//
+// Response Format:
+//   - Success: 200 OK with ShortURLResponse JSON including the history
+//   - Error: 400/404/500 with application/problem+json body
+func (h *ShortURLHandler) UpdateDestination(w http.ResponseWriter, r *http.Request) {
+	// Validate HTTP method
+	if r.Method != http.MethodPatch {
+		writeMethodNotAllowed(w, r, http.MethodPatch)
 		return
 	}
 
 	// Extract and validate ID parameter
 	id := r.URL.Query().Get("id")
 	if id == "" {
-		http.Error(w, "ID parameter is required", http.StatusBadRequest)
+		writeError(w, r, domain.ErrIDRequired)
 		return
 	}
 
-	// Deactivate URL through application service
-	if err := h.service.DeactivateShortURL(id); err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	// Parse and validate request body
+	var req app.UpdateDestinationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid JSON")
 		return
 	}
+	req.ID = id
 
-	// Return successful response with no content
-	w.WriteHeader(http.StatusNoContent)
+	// Update the destination through application service
+	resp, err := h.service.UpdateDestination(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return JSON response with the updated URL
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// NotFound handles requests that do not match any endpoint or short URL.
+// It is used by the router for the root path and for unknown /v1/ and /admin/ paths,
+// so that those responses follow the same problem details format as every other error.
+//
+// Response Format:
+//   - 404 Not Found with application/problem+json body
+func (h *ShortURLHandler) NotFound(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/" {
+		writeError(w, r, domain.ErrShortURLNotFound)
+		return
+	}
+	writeProblem(w, r, http.StatusNotFound, codeEndpointNotFound, "endpoint not found")
+}
+
+// redirectToOwningRegion answers a lookup of a short URL owned by another region with a
+// 307 redirect to that region, keeping the query string. It reports whether err was such
+// a routing error and the response has been written.
+func redirectToOwningRegion(w http.ResponseWriter, r *http.Request, err error) bool {
+	var foreign *domain.ForeignRegionError
+	if !errors.As(err, &foreign) {
+		return false
+	}
+
+	location := foreign.Location()
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, location, http.StatusTemporaryRedirect)
+	return true
+}
+
+// redirectBeforeLaunch answers a lookup of a short URL before its activation time. Visitors
+// are sent to the pre-launch URL if one is configured; otherwise the 503 problem response
+// tells them when to come back. It reports whether err was such a scheduling error and
+// the response has been written.
+func redirectBeforeLaunch(w http.ResponseWriter, r *http.Request, err error) bool {
+	var notYetActive *domain.NotYetActiveError
+	if !errors.As(err, &notYetActive) {
+		return false
+	}
+
+	if notYetActive.PreLaunchURL != "" {
+		http.Redirect(w, r, notYetActive.PreLaunchURL, http.StatusFound)
+		return true
+	}
+	w.Header().Set("Retry-After", notYetActive.ActivatesAt.UTC().Format(http.TimeFormat))
+	writeError(w, r, err)
+	return true
+}
+
+// parseListRequest converts the query string of a listing request into a ListShortURLsRequest.
+// Only syntax is checked here; the application service validates the values.
+func parseListRequest(values url.Values) (app.ListShortURLsRequest, error) {
+	req := app.ListShortURLsRequest{
+		Status:        values.Get("status"),
+		Domain:        values.Get("domain"),
+		MetadataKey:   values.Get("metadataKey"),
+		MetadataValue: values.Get("metadataValue"),
+		Order:         values.Get("order"),
+		Cursor:        values.Get("cursor"),
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			return req, fmt.Errorf("limit must be an integer")
+		}
+		req.Limit = limit
+	}
+
+	timeParams := []struct {
+		name   string
+		target **time.Time
+	}{
+		{"createdFrom", &req.CreatedFrom},
+		{"createdTo", &req.CreatedTo},
+	}
+	for _, param := range timeParams {
+		raw := values.Get(param.name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return req, fmt.Errorf("%s must be an RFC 3339 timestamp", param.name)
+		}
+		*param.target = &parsed
+	}
+
+	return req, nil
 }
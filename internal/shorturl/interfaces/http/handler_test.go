@@ -2,8 +2,10 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -11,6 +13,7 @@ import (
 	"time"
 
 	"github.com/oharai/short-url/internal/shorturl/app"
+	"github.com/oharai/short-url/internal/shorturl/domain"
 )
 
 // Mock service for testing
@@ -19,34 +22,66 @@ type mockShortURLService struct {
 	createError     error
 	longURL         string
 	getLongError    error
-	allURLs         []*app.ShortURLResponse
-	getAllError     error
-	deactivateError error
+	listResponse    *app.ListShortURLsResponse
+	listError       error
+	lastListRequest app.ListShortURLsRequest
+	changeResponse  *app.ShortURLResponse
+	changeError     error
+	lastChange      app.ChangeStateRequest
+	updateResponse  *app.ShortURLResponse
+	updateError     error
+	lastUpdate      app.UpdateDestinationRequest
+	lastGetLong     app.GetLongURLRequest
+	unlockResponse  *app.UnlockShortURLResponse
+	unlockError     error
+	lastUnlock      app.UnlockShortURLRequest
 }
 
-func (m *mockShortURLService) CreateShortURL(req app.CreateShortURLRequest) (*app.CreateShortURLResponse, error) {
+func (m *mockShortURLService) CreateShortURL(ctx context.Context, req app.CreateShortURLRequest) (*app.CreateShortURLResponse, error) {
 	if m.createError != nil {
 		return nil, m.createError
 	}
 	return m.createResponse, nil
 }
 
-func (m *mockShortURLService) GetLongURL(req app.GetLongURLRequest) (string, error) {
+func (m *mockShortURLService) GetLongURL(ctx context.Context, req app.GetLongURLRequest) (string, error) {
+	m.lastGetLong = req
 	if m.getLongError != nil {
 		return "", m.getLongError
 	}
 	return m.longURL, nil
 }
 
-func (m *mockShortURLService) GetAllShortURLs() ([]*app.ShortURLResponse, error) {
-	if m.getAllError != nil {
-		return nil, m.getAllError
+func (m *mockShortURLService) ListShortURLs(ctx context.Context, req app.ListShortURLsRequest) (*app.ListShortURLsResponse, error) {
+	m.lastListRequest = req
+	if m.listError != nil {
+		return nil, m.listError
 	}
-	return m.allURLs, nil
+	return m.listResponse, nil
 }
 
-func (m *mockShortURLService) DeactivateShortURL(id string) error {
-	return m.deactivateError
+func (m *mockShortURLService) ChangeState(ctx context.Context, req app.ChangeStateRequest) (*app.ShortURLResponse, error) {
+	m.lastChange = req
+	if m.changeError != nil {
+		return nil, m.changeError
+	}
+	return m.changeResponse, nil
+}
+
+func (m *mockShortURLService) UpdateDestination(ctx context.Context, req app.UpdateDestinationRequest) (*app.ShortURLResponse, error) {
+	m.lastUpdate = req
+	if m.updateError != nil {
+		return nil, m.updateError
+	}
+	return m.updateResponse, nil
+}
+
+func (m *mockShortURLService) UnlockShortURL(ctx context.Context, req app.UnlockShortURLRequest) (*app.UnlockShortURLResponse, error) {
+	m.lastUnlock = req
+	if m.unlockError != nil {
+		return nil, m.unlockError
+	}
+	return m.unlockResponse, nil
 }
 
 func TestNewShortURLHandler(t *testing.T) {
@@ -71,6 +106,7 @@ func TestShortURLHandler_CreateShortURL(t *testing.T) {
 		setupService   func(*mockShortURLService)
 		expectedStatus int
 		expectedBody   string
+		expectedCode   string
 		checkResponse  func(*testing.T, *httptest.ResponseRecorder)
 	}{
 		{
@@ -102,6 +138,7 @@ func TestShortURLHandler_CreateShortURL(t *testing.T) {
 			body:           nil,
 			setupService:   func(m *mockShortURLService) {},
 			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCode:   "method_not_allowed",
 		},
 		{
 			name:           "invalid JSON",
@@ -109,7 +146,7 @@ func TestShortURLHandler_CreateShortURL(t *testing.T) {
 			body:           "invalid json",
 			setupService:   func(m *mockShortURLService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"Invalid JSON"}`,
+			expectedCode:   "invalid_json",
 		},
 		{
 			name:   "service error - conflict",
@@ -119,10 +156,10 @@ func TestShortURLHandler_CreateShortURL(t *testing.T) {
 				CustomURL: "existing",
 			},
 			setupService: func(m *mockShortURLService) {
-				m.createError = errors.New("custom URL already exists")
+				m.createError = domain.ErrCustomURLAlreadyExists
 			},
 			expectedStatus: http.StatusConflict,
-			expectedBody:   `{"error":"custom URL already exists"}`,
+			expectedCode:   "custom_url_exists",
 		},
 		{
 			name:   "service error - bad request",
@@ -131,10 +168,10 @@ func TestShortURLHandler_CreateShortURL(t *testing.T) {
 				LongURL: "",
 			},
 			setupService: func(m *mockShortURLService) {
-				m.createError = errors.New("longUrl is required")
+				m.createError = domain.NewError(domain.ErrInvalidInput, "long_url_required", "longUrl is required")
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"longUrl is required"}`,
+			expectedCode:   "long_url_required",
 		},
 	}
 
@@ -170,6 +207,10 @@ func TestShortURLHandler_CreateShortURL(t *testing.T) {
 				}
 			}
 
+			if tt.expectedCode != "" {
+				checkProblem(t, w, tt.expectedStatus, tt.expectedCode)
+			}
+
 			if tt.checkResponse != nil {
 				tt.checkResponse(t, w)
 			}
@@ -185,6 +226,7 @@ func TestShortURLHandler_GetLongURL(t *testing.T) {
 		setupService   func(*mockShortURLService)
 		expectedStatus int
 		expectedBody   string
+		expectedCode   string
 		checkHeaders   func(*testing.T, *httptest.ResponseRecorder)
 	}{
 		{
@@ -210,6 +252,7 @@ func TestShortURLHandler_GetLongURL(t *testing.T) {
 			body:           nil,
 			setupService:   func(m *mockShortURLService) {},
 			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCode:   "method_not_allowed",
 		},
 		{
 			name:           "invalid JSON",
@@ -217,7 +260,7 @@ func TestShortURLHandler_GetLongURL(t *testing.T) {
 			body:           "invalid json",
 			setupService:   func(m *mockShortURLService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"Invalid JSON"}`,
+			expectedCode:   "invalid_json",
 		},
 		{
 			name:   "URL not found",
@@ -226,10 +269,22 @@ func TestShortURLHandler_GetLongURL(t *testing.T) {
 				ShortURL: "http://test.com/notfound",
 			},
 			setupService: func(m *mockShortURLService) {
-				m.getLongError = errors.New("short URL not found")
+				m.getLongError = domain.ErrShortURLNotFound
 			},
 			expectedStatus: http.StatusNotFound,
-			expectedBody:   `{"error":"short URL not found"}`,
+			expectedCode:   "short_url_not_found",
+		},
+		{
+			name:   "request deadline exceeded",
+			method: "GET",
+			body: app.GetLongURLRequest{
+				ShortURL: "http://test.com/slow",
+			},
+			setupService: func(m *mockShortURLService) {
+				m.getLongError = context.DeadlineExceeded
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   "request_timeout",
 		},
 		{
 			name:   "service error",
@@ -240,8 +295,8 @@ func TestShortURLHandler_GetLongURL(t *testing.T) {
 			setupService: func(m *mockShortURLService) {
 				m.getLongError = errors.New("database error")
 			},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"database error"}`,
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   "internal_error",
 		},
 	}
 
@@ -277,6 +332,10 @@ func TestShortURLHandler_GetLongURL(t *testing.T) {
 				}
 			}
 
+			if tt.expectedCode != "" {
+				checkProblem(t, w, tt.expectedStatus, tt.expectedCode)
+			}
+
 			if tt.checkHeaders != nil {
 				tt.checkHeaders(t, w)
 			}
@@ -293,6 +352,7 @@ func TestShortURLHandler_RedirectShortURL(t *testing.T) {
 		setupService   func(*mockShortURLService)
 		expectedStatus int
 		expectedBody   string
+		expectedCode   string
 		checkLocation  func(*testing.T, *httptest.ResponseRecorder)
 	}{
 		{
@@ -313,11 +373,17 @@ func TestShortURLHandler_RedirectShortURL(t *testing.T) {
 		},
 		{
 			name:           "wrong method",
-			method:         "POST",
+			method:         "PUT",
 			path:           "/abc123",
 			host:           "test.com",
 			setupService:   func(m *mockShortURLService) {},
 			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCode:   "method_not_allowed",
+			checkLocation: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if allow := w.Header().Get("Allow"); allow != "GET, POST" {
+					t.Errorf("expected Allow 'GET, POST', got %q", allow)
+				}
+			},
 		},
 		{
 			name:   "URL not found",
@@ -325,10 +391,10 @@ func TestShortURLHandler_RedirectShortURL(t *testing.T) {
 			path:   "/notfound",
 			host:   "test.com",
 			setupService: func(m *mockShortURLService) {
-				m.getLongError = errors.New("short URL not found")
+				m.getLongError = domain.ErrShortURLNotFound
 			},
 			expectedStatus: http.StatusNotFound,
-			expectedBody:   `{"error":"short URL not found"}`,
+			expectedCode:   "short_url_not_found",
 		},
 		{
 			name:   "service error",
@@ -338,8 +404,115 @@ func TestShortURLHandler_RedirectShortURL(t *testing.T) {
 			setupService: func(m *mockShortURLService) {
 				m.getLongError = errors.New("database error")
 			},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"database error"}`,
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   "internal_error",
+		},
+		{
+			name:   "URL owned by another region",
+			method: "GET",
+			path:   "/2abcdefg?utm_source=mail",
+			host:   "us.short.ly",
+			setupService: func(m *mockShortURLService) {
+				m.getLongError = &domain.ForeignRegionError{
+					Region: domain.Region{Code: "2", Name: "eu-west-1", BaseURL: "https://eu.short.ly"},
+					ID:     "2abcdefg",
+				}
+			},
+			expectedStatus: http.StatusTemporaryRedirect,
+			checkLocation: func(t *testing.T, w *httptest.ResponseRecorder) {
+				location := w.Header().Get("Location")
+				if location != "https://eu.short.ly/2abcdefg?utm_source=mail" {
+					t.Errorf("expected redirect to the owning region, got %q", location)
+				}
+			},
+		},
+		{
+			name:   "before activation redirects to pre-launch URL",
+			method: "GET",
+			path:   "/sale",
+			host:   "test.com",
+			setupService: func(m *mockShortURLService) {
+				m.getLongError = &domain.NotYetActiveError{ActivatesAt: time.Now().Add(time.Hour), PreLaunchURL: "https://example.com/coming-soon"}
+			},
+			expectedStatus: http.StatusFound,
+			checkLocation: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if location := w.Header().Get("Location"); location != "https://example.com/coming-soon" {
+					t.Errorf("expected redirect to the pre-launch URL, got %q", location)
+				}
+			},
+		},
+		{
+			name:   "before activation without pre-launch URL",
+			method: "GET",
+			path:   "/sale",
+			host:   "test.com",
+			setupService: func(m *mockShortURLService) {
+				m.getLongError = &domain.NotYetActiveError{ActivatesAt: time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)}
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   "short_url_not_yet_active",
+			checkLocation: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if retryAfter := w.Header().Get("Retry-After"); retryAfter != "Tue, 01 Jan 2030 09:00:00 GMT" {
+					t.Errorf("expected Retry-After at the activation time, got %q", retryAfter)
+				}
+			},
+		},
+		{
+			name:   "blocked destination shows warning page",
+			method: "GET",
+			path:   "/phish1",
+			host:   "test.com",
+			setupService: func(m *mockShortURLService) {
+				m.getLongError = domain.ErrShortURLBlocked
+			},
+			expectedStatus: http.StatusForbidden,
+			checkLocation: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if location := w.Header().Get("Location"); location != "" {
+					t.Errorf("expected no redirect, got Location %q", location)
+				}
+				if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/html") {
+					t.Errorf("expected HTML warning page, got %q", contentType)
+				}
+				if body := w.Body.String(); !strings.Contains(body, "This link has been blocked") || !strings.Contains(body, "/phish1") {
+					t.Errorf("expected warning page naming the link, got %q", body)
+				}
+			},
+		},
+		{
+			name:   "warning page escapes the path",
+			method: "GET",
+			path:   "/%3Cscript%3E",
+			host:   "test.com",
+			setupService: func(m *mockShortURLService) {
+				m.getLongError = domain.ErrShortURLBlocked
+			},
+			expectedStatus: http.StatusForbidden,
+			checkLocation: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if strings.Contains(w.Body.String(), "<script>") {
+					t.Error("expected the path to be HTML-escaped")
+				}
+			},
+		},
+		{
+			name:   "password protected shows password form",
+			method: "GET",
+			path:   "/secret",
+			host:   "test.com",
+			setupService: func(m *mockShortURLService) {
+				m.getLongError = domain.ErrPasswordRequired
+			},
+			expectedStatus: http.StatusUnauthorized,
+			checkLocation: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/html") {
+					t.Errorf("expected HTML password form, got %q", contentType)
+				}
+				if body := w.Body.String(); !strings.Contains(body, `<form method="post">`) || !strings.Contains(body, `type="password"`) {
+					t.Errorf("expected password form, got %q", body)
+				}
+				if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "no-store" {
+					t.Errorf("expected Cache-Control 'no-store', got %q", cacheControl)
+				}
+			},
 		},
 	}
 
@@ -366,6 +539,10 @@ func TestShortURLHandler_RedirectShortURL(t *testing.T) {
 				}
 			}
 
+			if tt.expectedCode != "" {
+				checkProblem(t, w, tt.expectedStatus, tt.expectedCode)
+			}
+
 			if tt.checkLocation != nil {
 				tt.checkLocation(t, w)
 			}
@@ -373,70 +550,140 @@ func TestShortURLHandler_RedirectShortURL(t *testing.T) {
 	}
 }
 
-func TestShortURLHandler_GetAllShortURLs(t *testing.T) {
+func TestShortURLHandler_ListShortURLs(t *testing.T) {
 	tests := []struct {
 		name           string
 		method         string
+		query          string
 		setupService   func(*mockShortURLService)
 		expectedStatus int
 		expectedBody   string
+		expectedCode   string
 		checkResponse  func(*testing.T, *httptest.ResponseRecorder)
+		checkRequest   func(*testing.T, app.ListShortURLsRequest)
 	}{
 		{
 			name:   "successful retrieval",
 			method: "GET",
 			setupService: func(m *mockShortURLService) {
-				m.allURLs = []*app.ShortURLResponse{
-					{
-						ID:        "abc123",
-						LongURL:   "https://example1.com",
-						ShortURL:  "http://test.com/abc123",
-						CreatedAt: time.Now(),
-						IsActive:  true,
-					},
-					{
-						ID:        "def456",
-						LongURL:   "https://example2.com",
-						ShortURL:  "http://test.com/def456",
-						CreatedAt: time.Now(),
-						IsActive:  true,
+				m.listResponse = &app.ListShortURLsResponse{
+					Items: []*app.ShortURLResponse{
+						{
+							ID:        "abc123",
+							LongURL:   "https://example1.com",
+							ShortURL:  "http://test.com/abc123",
+							CreatedAt: time.Now(),
+							IsActive:  true,
+						},
+						{
+							ID:        "def456",
+							LongURL:   "https://example2.com",
+							ShortURL:  "http://test.com/def456",
+							CreatedAt: time.Now(),
+							IsActive:  true,
+						},
 					},
+					NextCursor: "next",
 				}
 			},
 			expectedStatus: http.StatusOK,
 			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
-				var resp []*app.ShortURLResponse
+				var resp app.ListShortURLsResponse
 				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
 					t.Errorf("failed to unmarshal response: %v", err)
 					return
 				}
-				if len(resp) != 2 {
-					t.Errorf("expected 2 URLs, got %d", len(resp))
+				if len(resp.Items) != 2 {
+					t.Errorf("expected 2 URLs, got %d", len(resp.Items))
+				}
+				if resp.NextCursor != "next" {
+					t.Errorf("expected next cursor 'next', got %q", resp.NextCursor)
+				}
+			},
+		},
+		{
+			name:   "query parameters",
+			method: "GET",
+			query:  "?status=active&createdFrom=2024-01-01T00:00:00Z&createdTo=2024-02-01T00:00:00Z&domain=example.com&metadataKey=campaign&metadataValue=spring&order=desc&cursor=abc&limit=10",
+			setupService: func(m *mockShortURLService) {
+				m.listResponse = &app.ListShortURLsResponse{Items: []*app.ShortURLResponse{}}
+			},
+			expectedStatus: http.StatusOK,
+			checkRequest: func(t *testing.T, req app.ListShortURLsRequest) {
+				if req.Status != "active" || req.Domain != "example.com" || req.Order != "desc" || req.Cursor != "abc" || req.Limit != 10 {
+					t.Errorf("unexpected request %+v", req)
+				}
+				if req.MetadataKey != "campaign" || req.MetadataValue != "spring" {
+					t.Errorf("unexpected metadata filter %q=%q", req.MetadataKey, req.MetadataValue)
+				}
+				if req.CreatedFrom == nil || !req.CreatedFrom.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+					t.Errorf("unexpected createdFrom %v", req.CreatedFrom)
+				}
+				if req.CreatedTo == nil || !req.CreatedTo.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+					t.Errorf("unexpected createdTo %v", req.CreatedTo)
 				}
 			},
 		},
+		{
+			name:           "invalid limit",
+			method:         "GET",
+			query:          "?limit=ten",
+			setupService:   func(m *mockShortURLService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_query",
+		},
+		{
+			name:           "invalid timestamp",
+			method:         "GET",
+			query:          "?createdFrom=yesterday",
+			setupService:   func(m *mockShortURLService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_query",
+		},
+		{
+			name:   "invalid cursor",
+			method: "GET",
+			query:  "?cursor=!!!",
+			setupService: func(m *mockShortURLService) {
+				m.listError = domain.ErrInvalidCursor
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_cursor",
+		},
+		{
+			name:   "cursor of other filters",
+			method: "GET",
+			query:  "?status=inactive&cursor=abc",
+			setupService: func(m *mockShortURLService) {
+				m.listError = domain.ErrCursorMismatch
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "cursor_mismatch",
+		},
 		{
 			name:           "wrong method",
 			method:         "POST",
 			setupService:   func(m *mockShortURLService) {},
 			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCode:   "method_not_allowed",
 		},
 		{
 			name:   "service error",
 			method: "GET",
 			setupService: func(m *mockShortURLService) {
-				m.getAllError = errors.New("database error")
+				m.listError = errors.New("database error")
 			},
 			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   "internal_error",
 		},
 		{
 			name:   "empty result",
 			method: "GET",
 			setupService: func(m *mockShortURLService) {
-				m.allURLs = []*app.ShortURLResponse{}
+				m.listResponse = &app.ListShortURLsResponse{Items: []*app.ShortURLResponse{}}
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   "[]",
+			expectedBody:   `{"items":[]}`,
 		},
 	}
 
@@ -446,10 +693,10 @@ func TestShortURLHandler_GetAllShortURLs(t *testing.T) {
 			tt.setupService(service)
 			handler := NewShortURLHandler(service)
 
-			req := httptest.NewRequest(tt.method, "/admin/shorturls", nil)
+			req := httptest.NewRequest(tt.method, "/admin/shorturls"+tt.query, nil)
 			w := httptest.NewRecorder()
 
-			handler.GetAllShortURLs(w, req)
+			handler.ListShortURLs(w, req)
 
 			if w.Code != tt.expectedStatus {
 				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
@@ -462,9 +709,17 @@ func TestShortURLHandler_GetAllShortURLs(t *testing.T) {
 				}
 			}
 
+			if tt.expectedCode != "" {
+				checkProblem(t, w, tt.expectedStatus, tt.expectedCode)
+			}
+
 			if tt.checkResponse != nil {
 				tt.checkResponse(t, w)
 			}
+
+			if tt.checkRequest != nil {
+				tt.checkRequest(t, service.lastListRequest)
+			}
 		})
 	}
 }
@@ -477,13 +732,14 @@ func TestShortURLHandler_DeactivateShortURL(t *testing.T) {
 		setupService   func(*mockShortURLService)
 		expectedStatus int
 		expectedBody   string
+		expectedCode   string
 	}{
 		{
 			name:        "successful deactivation",
 			method:      "DELETE",
 			queryParams: "?id=abc123",
 			setupService: func(m *mockShortURLService) {
-				m.deactivateError = nil
+				m.changeError = nil
 			},
 			expectedStatus: http.StatusNoContent,
 		},
@@ -493,6 +749,7 @@ func TestShortURLHandler_DeactivateShortURL(t *testing.T) {
 			queryParams:    "?id=abc123",
 			setupService:   func(m *mockShortURLService) {},
 			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCode:   "method_not_allowed",
 		},
 		{
 			name:           "missing ID parameter",
@@ -500,15 +757,17 @@ func TestShortURLHandler_DeactivateShortURL(t *testing.T) {
 			queryParams:    "",
 			setupService:   func(m *mockShortURLService) {},
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "id_required",
 		},
 		{
 			name:        "service error",
 			method:      "DELETE",
 			queryParams: "?id=notfound",
 			setupService: func(m *mockShortURLService) {
-				m.deactivateError = errors.New("short URL not found")
+				m.changeError = domain.ErrShortURLNotFound
 			},
 			expectedStatus: http.StatusNotFound,
+			expectedCode:   "short_url_not_found",
 		},
 	}
 
@@ -533,6 +792,567 @@ func TestShortURLHandler_DeactivateShortURL(t *testing.T) {
 					t.Errorf("expected body %q, got %q", tt.expectedBody, body)
 				}
 			}
+
+			if tt.expectedCode != "" {
+				checkProblem(t, w, tt.expectedStatus, tt.expectedCode)
+			}
+		})
+	}
+}
+
+func TestShortURLHandler_LifecycleEndpoints(t *testing.T) {
+	tests := []struct {
+		name           string
+		handle         func(*ShortURLHandler) http.HandlerFunc
+		method         string
+		target         string
+		body           string
+		changeError    error
+		expectedState  string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "activate",
+			handle:         func(h *ShortURLHandler) http.HandlerFunc { return h.ActivateShortURL },
+			method:         "POST",
+			target:         "/admin/activate?id=abc123",
+			body:           `{"reason":"false alarm","actor":"support"}`,
+			expectedState:  "active",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "pause without body",
+			handle:         func(h *ShortURLHandler) http.HandlerFunc { return h.PauseShortURL },
+			method:         "POST",
+			target:         "/admin/pause?id=abc123",
+			expectedState:  "paused",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "block",
+			handle:         func(h *ShortURLHandler) http.HandlerFunc { return h.BlockShortURL },
+			method:         "POST",
+			target:         "/admin/block?id=abc123",
+			body:           `{"reason":"phishing report","actor":"trust-team"}`,
+			expectedState:  "blocked",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "archive",
+			handle:         func(h *ShortURLHandler) http.HandlerFunc { return h.ArchiveShortURL },
+			method:         "POST",
+			target:         "/admin/archive?id=abc123",
+			expectedState:  "archived",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "deactivate with reason",
+			handle:         func(h *ShortURLHandler) http.HandlerFunc { return h.DeactivateShortURL },
+			method:         "DELETE",
+			target:         "/admin/deactivate?id=abc123",
+			body:           `{"reason":"customer request","actor":"support"}`,
+			expectedState:  "deactivated",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "wrong method",
+			handle:         func(h *ShortURLHandler) http.HandlerFunc { return h.PauseShortURL },
+			method:         "GET",
+			target:         "/admin/pause?id=abc123",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCode:   "method_not_allowed",
+		},
+		{
+			name:           "missing ID parameter",
+			handle:         func(h *ShortURLHandler) http.HandlerFunc { return h.BlockShortURL },
+			method:         "POST",
+			target:         "/admin/block",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "id_required",
+		},
+		{
+			name:           "invalid JSON",
+			handle:         func(h *ShortURLHandler) http.HandlerFunc { return h.ArchiveShortURL },
+			method:         "POST",
+			target:         "/admin/archive?id=abc123",
+			body:           `{"reason":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_json",
+		},
+		{
+			name:           "transition not allowed",
+			handle:         func(h *ShortURLHandler) http.HandlerFunc { return h.ActivateShortURL },
+			method:         "POST",
+			target:         "/admin/activate?id=abc123",
+			changeError:    domain.NewError(domain.ErrConflict, "invalid_state_transition", "cannot change state from archived to active"),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "invalid_state_transition",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &mockShortURLService{
+				changeError:    tt.changeError,
+				changeResponse: &app.ShortURLResponse{ID: "abc123", State: tt.expectedState},
+			}
+			handler := NewShortURLHandler(service)
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			tt.handle(handler)(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedCode != "" {
+				checkProblem(t, w, tt.expectedStatus, tt.expectedCode)
+				return
+			}
+
+			var body struct{ Reason, Actor string }
+			json.Unmarshal([]byte(tt.body), &body)
+			expected := app.ChangeStateRequest{ID: "abc123", State: tt.expectedState, Reason: body.Reason, Actor: body.Actor}
+			if service.lastChange != expected {
+				t.Errorf("expected service request %+v, got %+v", expected, service.lastChange)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				var resp app.ShortURLResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.State != tt.expectedState {
+					t.Errorf("expected state %q in response, got %+v, %v", tt.expectedState, resp, err)
+				}
+			}
+		})
+	}
+}
+
+func TestShortURLHandler_UpdateDestination(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		queryParams    string
+		body           string
+		setupService   func(*mockShortURLService)
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:        "successful update",
+			method:      "PATCH",
+			queryParams: "?id=abc123",
+			body:        `{"longUrl":"https://example.com/new","actor":"marketing"}`,
+			setupService: func(m *mockShortURLService) {
+				m.updateResponse = &app.ShortURLResponse{
+					ID:      "abc123",
+					LongURL: "https://example.com/new",
+					History: []app.DestinationChange{{LongURL: "https://example.com/old", Actor: "marketing"}},
+				}
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "wrong method",
+			method:         "POST",
+			queryParams:    "?id=abc123",
+			body:           `{"longUrl":"https://example.com/new"}`,
+			setupService:   func(m *mockShortURLService) {},
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCode:   "method_not_allowed",
+		},
+		{
+			name:           "missing ID parameter",
+			method:         "PATCH",
+			body:           `{"longUrl":"https://example.com/new"}`,
+			setupService:   func(m *mockShortURLService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "id_required",
+		},
+		{
+			name:           "invalid JSON",
+			method:         "PATCH",
+			queryParams:    "?id=abc123",
+			body:           `{"longUrl":`,
+			setupService:   func(m *mockShortURLService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_json",
+		},
+		{
+			name:        "service error",
+			method:      "PATCH",
+			queryParams: "?id=notfound",
+			body:        `{"longUrl":"https://example.com/new"}`,
+			setupService: func(m *mockShortURLService) {
+				m.updateError = domain.ErrShortURLNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "short_url_not_found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &mockShortURLService{}
+			tt.setupService(service)
+			handler := NewShortURLHandler(service)
+
+			req := httptest.NewRequest(tt.method, "/admin/destination"+tt.queryParams, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.UpdateDestination(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedCode != "" {
+				checkProblem(t, w, tt.expectedStatus, tt.expectedCode)
+				return
+			}
+
+			if service.lastUpdate.ID != "abc123" || service.lastUpdate.LongURL != "https://example.com/new" || service.lastUpdate.Actor != "marketing" {
+				t.Errorf("unexpected service request %+v", service.lastUpdate)
+			}
+			var resp app.ShortURLResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.LongURL != "https://example.com/new" || len(resp.History) != 1 || resp.History[0].LongURL != "https://example.com/old" {
+				t.Errorf("unexpected response %+v", resp)
+			}
 		})
 	}
 }
+
+func TestShortURLHandler_NotFound(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		expectedCode string
+	}{
+		{
+			name:         "root path",
+			path:         "/",
+			expectedCode: "short_url_not_found",
+		},
+		{
+			name:         "unknown API endpoint",
+			path:         "/v1/unknown",
+			expectedCode: "endpoint_not_found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewShortURLHandler(&mockShortURLService{})
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			handler.NotFound(w, req)
+
+			checkProblem(t, w, http.StatusNotFound, tt.expectedCode)
+		})
+	}
+}
+
+func TestShortURLHandler_RedirectShortURL_Unlock(t *testing.T) {
+	expiresAt := time.Now().Add(15 * time.Minute)
+
+	tests := []struct {
+		name           string
+		body           string
+		setupService   func(*mockShortURLService)
+		expectedStatus int
+		expectedCode   string
+		expectedText   string
+		expectCookie   bool
+	}{
+		{
+			name: "right password sets cookie and redirects back",
+			body: "password=correct+horse",
+			setupService: func(m *mockShortURLService) {
+				m.unlockResponse = &app.UnlockShortURLResponse{Token: "token123", ExpiresAt: expiresAt}
+			},
+			expectedStatus: http.StatusSeeOther,
+			expectCookie:   true,
+		},
+		{
+			name: "unprotected URL redirects back without cookie",
+			body: "password=anything",
+			setupService: func(m *mockShortURLService) {
+				m.unlockResponse = &app.UnlockShortURLResponse{}
+			},
+			expectedStatus: http.StatusSeeOther,
+		},
+		{
+			name: "wrong password shows form again",
+			body: "password=wrong",
+			setupService: func(m *mockShortURLService) {
+				m.unlockError = domain.ErrWrongPassword
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedText:   "Wrong password",
+		},
+		{
+			name: "too many attempts",
+			body: "password=wrong",
+			setupService: func(m *mockShortURLService) {
+				m.unlockError = domain.ErrTooManyAttempts
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedText:   "Too many wrong passwords",
+		},
+		{
+			name: "URL not found",
+			body: "password=anything",
+			setupService: func(m *mockShortURLService) {
+				m.unlockError = domain.ErrShortURLNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "short_url_not_found",
+		},
+		{
+			name:           "oversized form",
+			body:           "password=" + strings.Repeat("a", maxPasswordFormBytes),
+			setupService:   func(m *mockShortURLService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_form",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &mockShortURLService{}
+			tt.setupService(service)
+			handler := NewShortURLHandler(service)
+
+			req := httptest.NewRequest("POST", "/secret?ref=mail", strings.NewReader(tt.body))
+			req.Host = "test.com"
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+
+			handler.RedirectShortURL(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedCode != "" {
+				checkProblem(t, w, tt.expectedStatus, tt.expectedCode)
+			}
+			if tt.expectedText != "" && !strings.Contains(w.Body.String(), tt.expectedText) {
+				t.Errorf("expected body to contain %q, got %q", tt.expectedText, w.Body.String())
+			}
+
+			if w.Code == http.StatusSeeOther {
+				if location := w.Header().Get("Location"); location != "/secret?ref=mail" {
+					t.Errorf("expected redirect back to the short URL, got %q", location)
+				}
+				if service.lastUnlock.ShortURL != "http://test.com/secret" || service.lastUnlock.Password == "" {
+					t.Errorf("unexpected unlock request %+v", service.lastUnlock)
+				}
+			}
+
+			cookies := w.Result().Cookies()
+			if !tt.expectCookie {
+				if len(cookies) != 0 {
+					t.Errorf("expected no cookie, got %v", cookies)
+				}
+				return
+			}
+			if len(cookies) != 1 {
+				t.Fatalf("expected one cookie, got %v", cookies)
+			}
+			cookie := cookies[0]
+			if cookie.Name != unlockCookie || cookie.Value != "token123" {
+				t.Errorf("unexpected cookie %s=%s", cookie.Name, cookie.Value)
+			}
+			if cookie.Path != "/secret" || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+				t.Errorf("expected HttpOnly SameSite=Lax cookie scoped to /secret, got %+v", cookie)
+			}
+			if !cookie.Expires.Equal(expiresAt.Truncate(time.Second)) {
+				t.Errorf("expected cookie to expire at %v, got %v", expiresAt, cookie.Expires)
+			}
+		})
+	}
+}
+
+func TestShortURLHandler_RedirectShortURL_UnlockCookie(t *testing.T) {
+	service := &mockShortURLService{longURL: "https://example.com/private"}
+	handler := NewShortURLHandler(service)
+
+	req := httptest.NewRequest("GET", "/secret", nil)
+	req.Host = "test.com"
+	req.AddCookie(&http.Cookie{Name: unlockCookie, Value: "token123"})
+	w := httptest.NewRecorder()
+
+	handler.RedirectShortURL(w, req)
+
+	if w.Code != http.StatusFound {
+		t.Errorf("expected status %d, got %d", http.StatusFound, w.Code)
+	}
+	if service.lastGetLong.UnlockToken != "token123" {
+		t.Errorf("expected unlock token from cookie, got %q", service.lastGetLong.UnlockToken)
+	}
+}
+
+func TestShortURLHandler_RedirectShortURL_VisitorMetadata(t *testing.T) {
+	service := &mockShortURLService{longURL: "https://example.com/ja"}
+	handler := NewShortURLHandler(service)
+
+	req := httptest.NewRequest("GET", "/app", nil)
+	req.Host = "test.com"
+	req.RemoteAddr = "203.0.113.7:52000"
+	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone)")
+	req.Header.Set("Referer", "https://news.example/")
+	req.Header.Set("Accept-Language", "ja-JP,ja;q=0.9")
+	w := httptest.NewRecorder()
+
+	handler.RedirectShortURL(w, req)
+
+	// Routing rules match these attributes, so they must reach the service
+	expected := map[string]interface{}{
+		"ip":              "203.0.113.7:52000",
+		"user_agent":      "Mozilla/5.0 (iPhone)",
+		"referer":         "https://news.example/",
+		"accept_language": "ja-JP,ja;q=0.9",
+	}
+	for key, value := range expected {
+		if service.lastGetLong.UserMetadata[key] != value {
+			t.Errorf("expected %s %q, got %v", key, value, service.lastGetLong.UserMetadata[key])
+		}
+	}
+	if location := w.Header().Get("Location"); location != "https://example.com/ja" {
+		t.Errorf("expected redirect to the routed destination, got %q", location)
+	}
+}
+
+func TestStatusForError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{
+			name:           "invalid input",
+			err:            domain.ErrIDRequired,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "not found",
+			err:            domain.ErrShortURLNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "conflict",
+			err:            domain.ErrCustomURLAlreadyExists,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "expired",
+			err:            domain.ErrShortURLExpired,
+			expectedStatus: http.StatusGone,
+		},
+		{
+			name:           "inactive",
+			err:            domain.ErrShortURLInactive,
+			expectedStatus: http.StatusGone,
+		},
+		{
+			name:           "blocked",
+			err:            domain.ErrShortURLBlocked,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "paused",
+			err:            domain.ErrShortURLPaused,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "archived",
+			err:            domain.ErrShortURLArchived,
+			expectedStatus: http.StatusGone,
+		},
+		{
+			name:           "exhausted",
+			err:            domain.ErrShortURLExhausted,
+			expectedStatus: http.StatusGone,
+		},
+		{
+			name:           "not yet active",
+			err:            &domain.NotYetActiveError{ActivatesAt: time.Now().Add(time.Hour)},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "password required",
+			err:            domain.ErrPasswordRequired,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "too many attempts",
+			err:            domain.ErrTooManyAttempts,
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:           "wrapped domain error",
+			err:            fmt.Errorf("lookup failed: %w", domain.ErrShortURLNotFound),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unknown error",
+			err:            errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := statusForError(tt.err); status != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, status)
+			}
+		})
+	}
+}
+
+// checkProblem verifies that the response is an RFC 7807 problem with the given status and code.
+func checkProblem(t *testing.T, w *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/problem+json" {
+		t.Errorf("expected Content-Type 'application/problem+json', got %q", contentType)
+	}
+
+	var problem ProblemDetails
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Errorf("failed to unmarshal problem: %v", err)
+		return
+	}
+
+	if problem.Status != status {
+		t.Errorf("expected problem status %d, got %d", status, problem.Status)
+	}
+	if problem.Code != code {
+		t.Errorf("expected problem code %q, got %q", code, problem.Code)
+	}
+	if problem.Title != http.StatusText(status) {
+		t.Errorf("expected problem title %q, got %q", http.StatusText(status), problem.Title)
+	}
+}
+
+func TestWithRequestTimeout(t *testing.T) {
+	var deadline time.Time
+	var hasDeadline bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, hasDeadline = r.Context().Deadline()
+	})
+
+	handler := WithRequestTimeout(next, time.Minute)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/abc123", nil))
+
+	if !hasDeadline {
+		t.Fatal("expected request context to carry a deadline")
+	}
+	if remaining := time.Until(deadline); remaining <= 0 || remaining > time.Minute {
+		t.Errorf("expected deadline within one minute, got %v", remaining)
+	}
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+)
+
+// Batch size limits of the KGS endpoint. A request without a count gets defaultIDBatchSize IDs.
+const (
+	defaultIDBatchSize = 1000
+	maxIDBatchSize     = 10000
+)
+
+// IDBatchResponse is the body returned by the KGS endpoint.
+type IDBatchResponse struct {
+	IDs []string `json:"ids"` // Freshly issued unique identifiers
+}
+
+// KGSHandler exposes a KeyGenerationService over HTTP so that it can run as a
+// standalone service shared by all URL shortening instances.
+type KGSHandler struct {
+	kgs domain.KeyGenerationService // Key generation service handing out the IDs
+}
+
+// NewKGSHandler creates a new HTTP handler for the key generation service.
+//
+// Parameters:
+//   - kgs: The key generation service to expose
+//
+// Returns:
+//   - *KGSHandler: Configured HTTP handler ready to process requests
+func NewKGSHandler(kgs domain.KeyGenerationService) *KGSHandler {
+	return &KGSHandler{
+		kgs: kgs,
+	}
+}
+
+// GetMultipleIDs handles POST /v1/ids requests.
+// Every call issues new IDs that are never handed out again, so the endpoint
+// is deliberately not idempotent and only accepts POST.
+//
+// Request Format:
+//   - Method: POST
+//   - Path: /v1/ids?count=<1-10000>
+//   - No body required; count defaults to 1000
+//
+// Response Format:
+//   - Success: 200 OK with IDBatchResponse JSON
+//   - Error: 400/500/503 with application/problem+json body
+func (h *KGSHandler) GetMultipleIDs(w http.ResponseWriter, r *http.Request) {
+	// Validate HTTP method
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	// Parse and validate the batch size
+	count := defaultIDBatchSize
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxIDBatchSize {
+			writeProblem(w, r, http.StatusBadRequest, codeInvalidQuery, fmt.Sprintf("count must be between 1 and %d", maxIDBatchSize))
+			return
+		}
+		count = parsed
+	}
+
+	// Issue the batch through the key generation service
+	ids, err := h.kgs.GetMultipleIDs(r.Context(), count)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Return JSON response with the IDs
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(IDBatchResponse{IDs: ids}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
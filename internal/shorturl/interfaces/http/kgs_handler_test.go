@@ -0,0 +1,122 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// mockKGS issues sequential IDs and records the requested batch size.
+type mockKGS struct {
+	lastCount int
+	err       error
+}
+
+func (m *mockKGS) GenerateUniqueID(ctx context.Context) (string, error) {
+	ids, err := m.GetMultipleIDs(ctx, 1)
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+func (m *mockKGS) GetMultipleIDs(ctx context.Context, count int) ([]string, error) {
+	m.lastCount = count
+	if m.err != nil {
+		return nil, m.err
+	}
+	ids := make([]string, count)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("id%05d", i)
+	}
+	return ids, nil
+}
+
+func TestKGSHandler_GetMultipleIDs(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		query          string
+		kgsErr         error
+		expectedStatus int
+		expectedCode   string
+		expectedCount  int
+	}{
+		{
+			name:           "default batch size",
+			method:         "POST",
+			expectedStatus: http.StatusOK,
+			expectedCount:  1000,
+		},
+		{
+			name:           "explicit count",
+			method:         "POST",
+			query:          "?count=5",
+			expectedStatus: http.StatusOK,
+			expectedCount:  5,
+		},
+		{
+			name:           "count too large",
+			method:         "POST",
+			query:          "?count=10001",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_query",
+		},
+		{
+			name:           "count not a number",
+			method:         "POST",
+			query:          "?count=many",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_query",
+		},
+		{
+			name:           "wrong method",
+			method:         "GET",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCode:   "method_not_allowed",
+		},
+		{
+			name:           "generator error",
+			method:         "POST",
+			kgsErr:         errors.New("ID space exhausted"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   "internal_error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kgs := &mockKGS{err: tt.kgsErr}
+			handler := NewKGSHandler(kgs)
+
+			req := httptest.NewRequest(tt.method, "/v1/ids"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.GetMultipleIDs(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			if tt.expectedCode != "" {
+				checkProblem(t, w, tt.expectedStatus, tt.expectedCode)
+				return
+			}
+
+			var resp IDBatchResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if len(resp.IDs) != tt.expectedCount {
+				t.Errorf("expected %d IDs, got %d", tt.expectedCount, len(resp.IDs))
+			}
+			if kgs.lastCount != tt.expectedCount {
+				t.Errorf("expected generator to be asked for %d IDs, got %d", tt.expectedCount, kgs.lastCount)
+			}
+		})
+	}
+}
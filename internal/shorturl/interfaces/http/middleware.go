@@ -0,0 +1,27 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// WithRequestTimeout wraps a handler so that every request context carries a deadline.
+// http.Server timeouts only close the connection; they do not cancel the request context,
+// so without this middleware a slow repository or KGS call would keep running after the
+// client has been cut off. Handlers observe the deadline through r.Context().
+//
+// Parameters:
+//   - next: The handler to wrap
+//   - timeout: Maximum time a request may spend in the application
+//
+// Returns:
+//   - http.Handler: Handler that applies the deadline before delegating to next
+func WithRequestTimeout(next http.Handler, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// passwordPage asks for the password of a password-protected short URL. The form posts
+// back to the short URL itself, which sets the unlock cookie and redirects.
+var passwordPage = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+</head>
+<body>
+<h1>This link is password protected</h1>
+<p>Enter the password for <code>{{.Path}}</code> to continue.</p>
+{{if .Message}}<p role="alert">{{.Message}}</p>
+{{end}}<form method="post">
+<label for="password">Password</label>
+<input type="password" id="password" name="password" autocomplete="current-password" required autofocus>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// writePasswordPage responds with the HTML password form and the given status.
+//
+// Parameters:
+//   - status: 401 Unauthorized, or 429 Too Many Requests while attempts are throttled
+//   - message: Explanation of a failed attempt; empty on the first visit
+func writePasswordPage(w http.ResponseWriter, r *http.Request, status int, message string) {
+	var body bytes.Buffer
+	data := struct{ Path, Message string }{r.URL.Path, message}
+	if err := passwordPage.Execute(&body, data); err != nil {
+		log.Printf("Failed to render password page: %v", err)
+		writeProblem(w, r, http.StatusInternalServerError, codeInternalError, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY") // Keep the form from being framed by other sites
+	w.WriteHeader(status)
+	w.Write(body.Bytes())
+}
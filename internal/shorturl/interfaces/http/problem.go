@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/oharai/short-url/internal/shorturl/domain"
+)
+
+// problemContentType is the media type of RFC 7807 problem details responses.
+const problemContentType = "application/problem+json"
+
+// Error codes produced by the HTTP layer itself. Codes of domain errors are
+// defined next to the errors in the domain package.
+const (
+	codeInvalidJSON      = "invalid_json"
+	codeInvalidQuery     = "invalid_query"
+	codeInvalidForm      = "invalid_form"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeEndpointNotFound = "endpoint_not_found"
+	codeRequestTimeout   = "request_timeout"
+	codeRequestCanceled  = "request_canceled"
+	codeInternalError    = "internal_error"
+)
+
+// ProblemDetails is the RFC 7807 error body returned by every endpoint.
+// Clients should switch on Code, which is stable across releases, rather than on Detail.
+type ProblemDetails struct {
+	Type     string `json:"type"`               // Problem type URI; "about:blank" means the status code says it all
+	Title    string `json:"title"`              // Short summary of the HTTP status
+	Status   int    `json:"status"`             // HTTP status code
+	Detail   string `json:"detail,omitempty"`   // Human-readable explanation of this occurrence
+	Instance string `json:"instance,omitempty"` // Request path that produced the problem
+	Code     string `json:"code"`               // Stable machine-readable error code
+}
+
+// statusForError maps a domain error kind to the HTTP status code returned to clients.
+// Errors without a known kind are treated as internal server errors.
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrExpired), errors.Is(err, domain.ErrInactive):
+		return http.StatusGone
+	case errors.Is(err, domain.ErrBlocked):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, domain.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, domain.ErrRateLimited):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError converts an application error into a problem details response.
+// Domain errors keep their code and message; any other error is logged and
+// reported as a generic internal error so that implementation details do not leak.
+// Context errors are reported separately, since they mean the work was cut short
+// by a deadline or a disconnected client rather than by a failure.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		writeProblem(w, r, http.StatusServiceUnavailable, codeRequestTimeout, "request timed out")
+		return
+	case errors.Is(err, context.Canceled):
+		writeProblem(w, r, http.StatusServiceUnavailable, codeRequestCanceled, "request canceled")
+		return
+	}
+
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) {
+		log.Printf("Internal error handling %s %s: %v", r.Method, r.URL.Path, err)
+		writeProblem(w, r, http.StatusInternalServerError, codeInternalError, "internal server error")
+		return
+	}
+
+	writeProblem(w, r, statusForError(domainErr), domainErr.Code, domainErr.Message)
+}
+
+// writeProblem writes an RFC 7807 problem details response with the given status and code.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+
+	problem := ProblemDetails{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+	}
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		log.Printf("Failed to encode problem response: %v", err)
+	}
+}
+
+// writeMethodNotAllowed writes a 405 problem response advertising the allowed method.
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeProblem(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, "method not allowed")
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// StatsHandler exposes the statistics of an infrastructure component, such as the
+// read cache or the KGS buffer, as JSON for operators and monitoring.
+type StatsHandler struct {
+	stats func() interface{} // Returns a snapshot of the current statistics
+}
+
+// NewStatsHandler creates a new HTTP handler for component statistics.
+//
+// Parameters:
+//   - stats: Function returning a JSON-encodable snapshot of the statistics
+//
+// Returns:
+//   - *StatsHandler: Configured HTTP handler ready to process requests
+func NewStatsHandler(stats func() interface{}) *StatsHandler {
+	return &StatsHandler{
+		stats: stats,
+	}
+}
+
+// GetStats handles GET requests for the statistics snapshot.
+//
+// Request Format:
+//   - Method: GET
+//   - No body or query parameters
+//
+// Response Format:
+//   - Success: 200 OK with the statistics as JSON
+//   - Error: 405 with application/problem+json body, or 500 if the statistics cannot be encoded
+func (h *StatsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	// Validate HTTP method
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	// Return JSON response with the current snapshot
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.stats()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
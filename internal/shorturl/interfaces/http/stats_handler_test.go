@@ -0,0 +1,72 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatsHandler_GetStats(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		stats          interface{}
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "successful retrieval",
+			method:         "GET",
+			stats:          map[string]int{"hits": 3, "misses": 1},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "wrong method",
+			method:         "POST",
+			stats:          map[string]int{"hits": 3, "misses": 1},
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCode:   "method_not_allowed",
+		},
+		{
+			name:           "statistics cannot be encoded",
+			method:         "GET",
+			stats:          make(chan int),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewStatsHandler(func() interface{} { return tt.stats })
+
+			req := httptest.NewRequest(tt.method, "/admin/cache/stats", nil)
+			w := httptest.NewRecorder()
+
+			handler.GetStats(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			if tt.expectedCode != "" {
+				checkProblem(t, w, tt.expectedStatus, tt.expectedCode)
+				if allow := w.Header().Get("Allow"); allow != http.MethodGet {
+					t.Errorf("expected Allow header %q, got %q", http.MethodGet, allow)
+				}
+				return
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp map[string]int
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp["hits"] != 3 || resp["misses"] != 1 {
+				t.Errorf("unexpected statistics: %v", resp)
+			}
+		})
+	}
+}
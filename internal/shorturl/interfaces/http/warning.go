@@ -0,0 +1,43 @@
+package http
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// warningPage is shown instead of redirecting to a destination on the blocklist.
+// It deliberately offers no way to continue to the destination.
+var warningPage = template.Must(template.New("warning").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Warning: unsafe link</title>
+</head>
+<body>
+<h1>This link has been blocked</h1>
+<p>The short link <code>{{.Path}}</code> leads to a site reported as malicious, for example
+a phishing or malware site. It has been blocked to protect you.</p>
+<p>If you were asked to enter a password or payment details after following this link,
+do not do so.</p>
+</body>
+</html>
+`))
+
+// writeWarningPage responds with 403 Forbidden and the HTML warning page for a short URL
+// whose destination is blocked.
+func writeWarningPage(w http.ResponseWriter, r *http.Request) {
+	var body bytes.Buffer
+	if err := warningPage.Execute(&body, struct{ Path string }{r.URL.Path}); err != nil {
+		log.Printf("Failed to render warning page: %v", err)
+		writeProblem(w, r, http.StatusInternalServerError, codeInternalError, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store") // The destination may be delisted later
+	w.WriteHeader(http.StatusForbidden)
+	w.Write(body.Bytes())
+}
@@ -29,17 +29,11 @@ func setupTestServer() *httptest.Server {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/v1/createShortUrl", handler.CreateShortURL)
 	mux.HandleFunc("/v1/getLongUrl", handler.GetLongURL)
-	mux.HandleFunc("/admin/shorturls", handler.GetAllShortURLs)
+	mux.HandleFunc("/admin/shorturls", handler.ListShortURLs)
 	mux.HandleFunc("/admin/deactivate", handler.DeactivateShortURL)
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/" {
-			w.WriteHeader(http.StatusNotFound)
-			w.Write([]byte("Short URL not found"))
-			return
-		}
-		if strings.HasPrefix(r.URL.Path, "/v1/") || strings.HasPrefix(r.URL.Path, "/admin/") {
-			w.WriteHeader(http.StatusNotFound)
-			w.Write([]byte("Endpoint not found"))
+		if r.URL.Path == "/" || strings.HasPrefix(r.URL.Path, "/v1/") || strings.HasPrefix(r.URL.Path, "/admin/") {
+			handler.NotFound(w, r)
 			return
 		}
 		handler.RedirectShortURL(w, r)
@@ -231,10 +225,11 @@ func TestIntegration_AdminOperations(t *testing.T) {
 		t.Errorf("expected status 200, got %d", resp.StatusCode)
 	}
 
-	var allURLs []*app.ShortURLResponse
-	if err := json.NewDecoder(resp.Body).Decode(&allURLs); err != nil {
+	var page app.ListShortURLsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
 		t.Fatalf("failed to decode all URLs response: %v", err)
 	}
+	allURLs := page.Items
 
 	if len(allURLs) != 3 {
 		t.Errorf("expected 3 URLs, got %d", len(allURLs))
@@ -277,6 +272,77 @@ func TestIntegration_AdminOperations(t *testing.T) {
 	}
 }
 
+func TestIntegration_PaginatedListing(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	// Create URLs on two domains
+	for i := 0; i < 5; i++ {
+		host := "example.com"
+		if i%2 == 1 {
+			host = "example.org"
+		}
+		createReq := app.CreateShortURLRequest{
+			LongURL:      fmt.Sprintf("https://%s/page%d", host, i),
+			UserMetadata: map[string]interface{}{"index": i},
+		}
+		body, _ := json.Marshal(createReq)
+		resp, err := http.Post(server.URL+"/v1/createShortUrl", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create short URL: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// Walk all pages for one domain
+	seen := make(map[string]bool)
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		resp, err := http.Get(server.URL + "/admin/shorturls?domain=example.com&limit=2&cursor=" + cursor)
+		if err != nil {
+			t.Fatalf("failed to list URLs: %v", err)
+		}
+		var page app.ListShortURLsResponse
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("failed to decode listing: %v", err)
+		}
+
+		if len(page.Items) > 2 {
+			t.Errorf("expected at most 2 URLs per page, got %d", len(page.Items))
+		}
+		for _, item := range page.Items {
+			if !strings.HasPrefix(item.LongURL, "https://example.com/") {
+				t.Errorf("unexpected URL in domain listing: %s", item.LongURL)
+			}
+			if seen[item.ID] {
+				t.Errorf("URL %s listed twice", item.ID)
+			}
+			seen[item.ID] = true
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if len(seen) != 3 {
+		t.Errorf("expected 3 URLs on example.com, got %d", len(seen))
+	}
+
+	// Invalid cursors are rejected
+	resp, err := http.Get(server.URL + "/admin/shorturls?cursor=invalid!")
+	if err != nil {
+		t.Fatalf("failed to list URLs: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid cursor, got %d", resp.StatusCode)
+	}
+}
+
 func TestIntegration_URLExpiry(t *testing.T) {
 	server := setupTestServer()
 	defer server.Close()